@@ -0,0 +1,151 @@
+// Package aac reads and writes the headers of MPEG-4 AAC audio: the
+// AudioSpecificConfig that MP4 files store in their sample descriptions and
+// the ADTS headers that precede each frame of a raw .aac file or MPEG-2
+// transport stream, as specified in ISO/IEC 14496-3 and ISO/IEC 13818-7.
+//
+// Both are described once as a gobits.Syntax, so parsing one and
+// marshalling the result reproduces it bit for bit. Fields are named after
+// the syntax elements of the specifications. The raw data blocks that carry
+// the audio are not decoded.
+package aac
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+)
+
+// Audio object types.
+const (
+	ObjectTypeAACMain     = 1
+	ObjectTypeAACLC       = 2
+	ObjectTypeAACSSR      = 3
+	ObjectTypeAACLTP      = 4
+	ObjectTypeSBR         = 5
+	ObjectTypeAACScalable = 6
+	ObjectTypeTwinVQ      = 7
+	ObjectTypeERAACLC     = 17
+	ObjectTypeERAACLTP    = 19
+	ObjectTypeERAACScal   = 20
+	ObjectTypeERTwinVQ    = 21
+	ObjectTypeERBSAC      = 22
+	ObjectTypeERAACLD     = 23
+	ObjectTypePS          = 29
+	ObjectTypeEscape      = 31
+	ObjectTypeALS         = 36
+	ObjectTypeERAACELD    = 39
+	ObjectTypeUSAC        = 42
+)
+
+// SamplingFrequencyIndexEscape is the sampling frequency index that is
+// followed by an explicit 24-bit sampling frequency.
+const SamplingFrequencyIndexEscape = 0xf
+
+const (
+	syncExtensionTypeSBR = 0x2b7
+	syncExtensionTypePS  = 0x548
+)
+
+var samplingFrequencies = []uint32{
+	96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350,
+}
+
+// SamplingFrequency returns the sampling frequency in Hz that index
+// stands for, or 0 if it is reserved or the escape value.
+func SamplingFrequency(index byte) uint32 {
+	if int(index) >= len(samplingFrequencies) {
+		return 0
+	}
+	return samplingFrequencies[index]
+}
+
+// isGA reports whether the specific config of objectType is a
+// GASpecificConfig.
+func isGA(objectType byte) bool {
+	switch objectType {
+	case ObjectTypeAACMain, ObjectTypeAACLC, ObjectTypeAACSSR, ObjectTypeAACLTP,
+		ObjectTypeAACScalable, ObjectTypeTwinVQ, ObjectTypeERAACLC, ObjectTypeERAACLTP,
+		ObjectTypeERAACScal, ObjectTypeERTwinVQ, ObjectTypeERBSAC, ObjectTypeERAACLD:
+		return true
+	}
+	return false
+}
+
+// isER reports whether objectType is an error resilient type, whose config
+// carries epConfig.
+func isER(objectType byte) bool {
+	switch objectType {
+	case ObjectTypeERAACLC, ObjectTypeERAACLTP, ObjectTypeERAACScal, ObjectTypeERTwinVQ,
+		ObjectTypeERBSAC, ObjectTypeERAACLD, 24, 25, 26, 27, ObjectTypeERAACELD:
+		return true
+	}
+	return false
+}
+
+// audioObjectType handles GetAudioObjectType(), a 5-bit object type that
+// escapes to 6 more bits for types from 32 to 95.
+func audioObjectType(s *gobits.Syntax, val *byte, field string) {
+	if !s.Reading() && (*val == ObjectTypeEscape || *val > ObjectTypeEscape+1+0x3f) {
+		s.Fail(field, gobits.ErrOutOfRange)
+		return
+	}
+	objectType := *val
+	if objectType > ObjectTypeEscape {
+		objectType = ObjectTypeEscape
+	}
+	s.Byte(&objectType, 5, field)
+	if objectType != ObjectTypeEscape {
+		*val = objectType
+		return
+	}
+	ext := *val - ObjectTypeEscape - 1
+	s.Byte(&ext, 6, field+"Ext")
+	*val = ObjectTypeEscape + 1 + ext
+}
+
+// samplingFrequency handles a 4-bit sampling frequency index and the
+// 24-bit frequency that follows the escape value.
+func samplingFrequency(s *gobits.Syntax, index *byte, frequency *uint32, indexField, field string) {
+	s.Byte(index, 4, indexField)
+	if *index == SamplingFrequencyIndexEscape {
+		s.Bits(frequency, 24, field)
+	}
+}
+
+// count handles the number of elements in a list, which is taken from the
+// list when writing. It returns false if the list is too long or the field
+// cannot be read.
+func count(s *gobits.Syntax, n *int, bitCount byte, field string) bool {
+	if !s.Reading() && *n >= 1<<bitCount {
+		s.Fail(field, gobits.ErrOutOfRange)
+		return false
+	}
+	v := uint32(*n)
+	s.Bits(&v, bitCount, field)
+	*n = int(v)
+	return s.Err() == nil
+}
+
+var crcTable = func() [256]uint16 {
+	var table [256]uint16
+	for i := range table {
+		crc := uint16(i) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x8005
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+// CRC16 computes the CRC-16 of ISO/IEC 11172-3 over data, which ADTS
+// headers carry in their crc_check field.
+func CRC16(data []byte) uint16 {
+	crc := uint16(0xffff)
+	for _, b := range data {
+		crc = crc<<8 ^ crcTable[byte(crc>>8)^b]
+	}
+	return crc
+}
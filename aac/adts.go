@@ -0,0 +1,215 @@
+package aac
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+)
+
+// ADTSBufferFullnessVBR is the adts_buffer_fullness of variable bitrate
+// streams.
+const ADTSBufferFullnessVBR = 0x7ff
+
+const (
+	adtsSyncword     = 0xfff
+	adtsHeaderLength = 7
+)
+
+// ADTSHeader is the header of an ADTS frame: adts_fixed_header(),
+// adts_variable_header() and, when protection_absent is clear, the
+// error check that follows them.
+type ADTSHeader struct {
+	// ID is set for MPEG-2 AAC and clear for MPEG-4.
+	ID               bool
+	ProtectionAbsent bool
+	// ProfileObjectType is the audio object type minus one.
+	ProfileObjectType            byte
+	SamplingFrequencyIndex       byte
+	PrivateBit                   bool
+	ChannelConfiguration         byte
+	OriginalCopy                 bool
+	Home                         bool
+	CopyrightIdentificationBit   bool
+	CopyrightIdentificationStart bool
+	// AACFrameLength is the length of the frame in bytes, header included.
+	AACFrameLength     uint16
+	ADTSBufferFullness uint16
+	// NumberOfRawDataBlocksInFrame is the number of raw data blocks minus
+	// one.
+	NumberOfRawDataBlocksInFrame byte
+	// RawDataBlockPosition holds raw_data_block_position[1] onwards, when
+	// the header is protected and the frame has more than one raw data
+	// block.
+	RawDataBlockPosition []uint16
+	CRCCheck             uint16
+}
+
+// HeaderLength returns the length of the header in bytes.
+func (h *ADTSHeader) HeaderLength() int {
+	if h.ProtectionAbsent {
+		return adtsHeaderLength
+	}
+	return adtsHeaderLength + 2*int(h.NumberOfRawDataBlocksInFrame) + 2
+}
+
+// SampleRate returns the sampling frequency in Hz.
+func (h *ADTSHeader) SampleRate() uint32 {
+	return SamplingFrequency(h.SamplingFrequencyIndex)
+}
+
+func (h *ADTSHeader) syntax(s *gobits.Syntax) {
+	syncword := uint32(adtsSyncword)
+	s.Bits(&syncword, 12, "syncword")
+	if s.Err() == nil && syncword != adtsSyncword {
+		s.Fail("syncword", gobits.ErrInvalidSyntax)
+	}
+	s.Flag(&h.ID, "ID")
+	layer := byte(0)
+	s.Byte(&layer, 2, "layer")
+	if s.Err() == nil && layer != 0 {
+		s.Fail("layer", gobits.ErrInvalidSyntax)
+	}
+	s.Flag(&h.ProtectionAbsent, "protection_absent")
+	s.Byte(&h.ProfileObjectType, 2, "profile_ObjectType")
+	s.Byte(&h.SamplingFrequencyIndex, 4, "sampling_frequency_index")
+	if s.Err() == nil && int(h.SamplingFrequencyIndex) >= len(samplingFrequencies) {
+		s.Fail("sampling_frequency_index", gobits.ErrInvalidSyntax)
+	}
+	s.Flag(&h.PrivateBit, "private_bit")
+	s.Byte(&h.ChannelConfiguration, 3, "channel_configuration")
+	s.Flag(&h.OriginalCopy, "original_copy")
+	s.Flag(&h.Home, "home")
+	s.Flag(&h.CopyrightIdentificationBit, "copyright_identification_bit")
+	s.Flag(&h.CopyrightIdentificationStart, "copyright_identification_start")
+	length := uint32(h.AACFrameLength)
+	s.Bits(&length, 13, "aac_frame_length")
+	h.AACFrameLength = uint16(length)
+	fullness := uint32(h.ADTSBufferFullness)
+	s.Bits(&fullness, 11, "adts_buffer_fullness")
+	h.ADTSBufferFullness = uint16(fullness)
+	s.Byte(&h.NumberOfRawDataBlocksInFrame, 2, "number_of_raw_data_blocks_in_frame")
+	if s.Err() != nil || h.ProtectionAbsent {
+		return
+	}
+	if h.NumberOfRawDataBlocksInFrame > 0 {
+		if s.Reading() {
+			h.RawDataBlockPosition = make([]uint16, h.NumberOfRawDataBlocksInFrame)
+		} else if len(h.RawDataBlockPosition) != int(h.NumberOfRawDataBlocksInFrame) {
+			s.Fail("raw_data_block_position", gobits.ErrOutOfRange)
+			return
+		}
+		for i := range h.RawDataBlockPosition {
+			position := uint32(h.RawDataBlockPosition[i])
+			s.Bits(&position, 16, "raw_data_block_position")
+			h.RawDataBlockPosition[i] = uint16(position)
+		}
+	}
+	crc := uint32(h.CRCCheck)
+	s.Bits(&crc, 16, "crc_check")
+	h.CRCCheck = uint16(crc)
+}
+
+// ParseADTSHeader parses the ADTS header at the start of ba. It checks
+// that aac_frame_length covers the header, but not crc_check.
+func ParseADTSHeader(ba gobits.ByteAccessor) (*ADTSHeader, error) {
+	s := gobits.NewReadingSyntax(gobits.NewBitStream(ba))
+	h := &ADTSHeader{}
+	h.syntax(s)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	if int(h.AACFrameLength) < h.HeaderLength() {
+		return nil, &gobits.FieldError{Field: "aac_frame_length", Err: gobits.ErrInvalidSyntax}
+	}
+	return h, nil
+}
+
+// Marshal encodes the header as is, crc_check included.
+func (h *ADTSHeader) Marshal() ([]byte, error) {
+	ba := gobits.NewGrowableByteAccessor(nil)
+	s := gobits.NewWritingSyntax(gobits.NewBitStream(ba))
+	h.syntax(s)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	return ba.Bytes(), nil
+}
+
+// MarshalFrame encodes an ADTS frame of a single raw data block, with
+// AACFrameLength set to fit. The header must not be protected, as the CRC
+// of a single block depends on the elements within it.
+func (h *ADTSHeader) MarshalFrame(rawDataBlock []byte) ([]byte, error) {
+	if !h.ProtectionAbsent {
+		return nil, &gobits.FieldError{Field: "protection_absent", Err: gobits.ErrInvalidSyntax}
+	}
+	if h.NumberOfRawDataBlocksInFrame != 0 {
+		return nil, &gobits.FieldError{Field: "number_of_raw_data_blocks_in_frame", Err: gobits.ErrOutOfRange}
+	}
+	length := adtsHeaderLength + len(rawDataBlock)
+	if length >= 1<<13 {
+		return nil, &gobits.FieldError{Field: "aac_frame_length", Err: gobits.ErrOutOfRange}
+	}
+	header := *h
+	header.AACFrameLength = uint16(length)
+	data, err := header.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return append(data, rawDataBlock...), nil
+}
+
+// checkCRC verifies crc_check against header, the encoded header. Only the
+// check of a frame with several raw data blocks covers nothing but the
+// header; the check of a single block also covers the leading bits of each
+// channel element in it, which only a decoder can locate.
+func (h *ADTSHeader) checkCRC(header []byte) error {
+	if h.ProtectionAbsent || h.NumberOfRawDataBlocksInFrame == 0 {
+		return nil
+	}
+	if CRC16(header[:len(header)-2]) != h.CRCCheck {
+		return &gobits.FieldError{Field: "crc_check", Err: gobits.ErrChecksum}
+	}
+	return nil
+}
+
+// AudioSpecificConfig returns the config that describes the frames that
+// follow h, for storing them without their headers. It fails for a
+// channel_configuration of 0, as the program config element is then in
+// the raw data blocks.
+func (h *ADTSHeader) AudioSpecificConfig() (*AudioSpecificConfig, error) {
+	if h.ChannelConfiguration == 0 {
+		return nil, &gobits.FieldError{Field: "channel_configuration", Err: gobits.ErrInvalidSyntax}
+	}
+	return &AudioSpecificConfig{
+		AudioObjectType:        h.ProfileObjectType + 1,
+		SamplingFrequencyIndex: h.SamplingFrequencyIndex,
+		ChannelConfiguration:   h.ChannelConfiguration,
+	}, nil
+}
+
+// NewADTSHeader returns an unprotected MPEG-4 header for frames that c
+// describes, to be completed by MarshalFrame. ADTS only carries the core
+// of SBR and PS streams, which leaves decoders to find the extensions
+// implicitly.
+func NewADTSHeader(c *AudioSpecificConfig) (*ADTSHeader, error) {
+	fail := func(field string) (*ADTSHeader, error) {
+		return nil, &gobits.FieldError{Field: field, Err: gobits.ErrOutOfRange}
+	}
+	switch {
+	case c.AudioObjectType < ObjectTypeAACMain || c.AudioObjectType > ObjectTypeAACLTP:
+		return fail("audioObjectType")
+	case int(c.SamplingFrequencyIndex) >= len(samplingFrequencies):
+		return fail("samplingFrequencyIndex")
+	case c.ChannelConfiguration == 0 || c.ChannelConfiguration > 7:
+		return fail("channelConfiguration")
+	case c.GASpecificConfig.FrameLengthFlag:
+		return fail("frameLengthFlag")
+	case c.GASpecificConfig.DependsOnCoreCoder:
+		return fail("dependsOnCoreCoder")
+	}
+	return &ADTSHeader{
+		ProtectionAbsent:       true,
+		ProfileObjectType:      c.AudioObjectType - 1,
+		SamplingFrequencyIndex: c.SamplingFrequencyIndex,
+		ChannelConfiguration:   c.ChannelConfiguration,
+		ADTSBufferFullness:     ADTSBufferFullnessVBR,
+	}, nil
+}
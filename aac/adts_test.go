@@ -0,0 +1,181 @@
+package aac
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/stretchr/testify/assert"
+)
+
+// adtsFrame returns an unprotected AAC LC frame at 44.1 kHz in stereo, as
+// FFmpeg writes it, with a raw data block of length bytes.
+func adtsFrame(length int) []byte {
+	frameLength := length + adtsHeaderLength
+	header := []byte{
+		0xff, 0xf1, 0x50, 0x80 | byte(frameLength>>11),
+		byte(frameLength >> 3), byte(frameLength<<5) | 0x1f, 0xfc,
+	}
+	return append(header, bytes.Repeat([]byte{0x21}, length)...)
+}
+
+// protectedFrame returns a frame of two raw data blocks with a valid
+// header CRC.
+func protectedFrame(t *testing.T) []byte {
+	h := &ADTSHeader{
+		SamplingFrequencyIndex:       3,
+		ChannelConfiguration:         1,
+		AACFrameLength:               11 + 20,
+		ADTSBufferFullness:           ADTSBufferFullnessVBR,
+		NumberOfRawDataBlocksInFrame: 1,
+		RawDataBlockPosition:         []uint16{10},
+	}
+	header, err := h.Marshal()
+	assert.NoError(t, err)
+	h.CRCCheck = CRC16(header[:9])
+	header, err = h.Marshal()
+	assert.NoError(t, err)
+	return append(header, make([]byte, 20)...)
+}
+
+func TestCRC16(t *testing.T) {
+	assert.Equal(t, uint16(0xaee7), CRC16([]byte("123456789")))
+}
+
+func TestParseADTSHeader(t *testing.T) {
+	data := adtsFrame(249)
+	h, err := ParseADTSHeader(gobits.NewSliceByteAccessor(data))
+	assert.NoError(t, err)
+	assert.Equal(t, &ADTSHeader{
+		ProtectionAbsent:       true,
+		ProfileObjectType:      ObjectTypeAACLC - 1,
+		SamplingFrequencyIndex: 4,
+		ChannelConfiguration:   2,
+		AACFrameLength:         256,
+		ADTSBufferFullness:     ADTSBufferFullnessVBR,
+	}, h)
+	assert.Equal(t, []byte{0xff, 0xf1, 0x50, 0x80, 0x20, 0x1f, 0xfc}, data[:7])
+	assert.Equal(t, uint32(44100), h.SampleRate())
+	marshalled, err := h.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, data[:7], marshalled)
+
+	data = protectedFrame(t)
+	h, err = ParseADTSHeader(gobits.NewSliceByteAccessor(data))
+	assert.NoError(t, err)
+	assert.Equal(t, 11, h.HeaderLength())
+	assert.Equal(t, []uint16{10}, h.RawDataBlockPosition)
+	assert.NoError(t, h.checkCRC(data[:11]))
+
+	var fe *gobits.FieldError
+	corrupted := adtsFrame(0)
+	corrupted[4], corrupted[5] = 0, 0x1f
+	_, err = ParseADTSHeader(gobits.NewSliceByteAccessor(corrupted))
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "aac_frame_length", fe.Field)
+
+	corrupted = adtsFrame(0)
+	corrupted[2] = 0x7c
+	_, err = ParseADTSHeader(gobits.NewSliceByteAccessor(corrupted))
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "sampling_frequency_index", fe.Field)
+
+	corrupted = adtsFrame(0)
+	corrupted[1] = 0xf3
+	_, err = ParseADTSHeader(gobits.NewSliceByteAccessor(corrupted))
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "layer", fe.Field)
+	assert.True(t, errors.Is(err, gobits.ErrInvalidSyntax))
+}
+
+func TestADTSHeader_Conversion(t *testing.T) {
+	h, err := ParseADTSHeader(gobits.NewSliceByteAccessor(adtsFrame(100)))
+	assert.NoError(t, err)
+	c, err := h.AudioSpecificConfig()
+	assert.NoError(t, err)
+	data, err := c.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x12, 0x10}, data)
+
+	h, err = NewADTSHeader(c)
+	assert.NoError(t, err)
+	frame, err := h.MarshalFrame(bytes.Repeat([]byte{0x21}, 100))
+	assert.NoError(t, err)
+	assert.Equal(t, adtsFrame(100), frame)
+
+	// SBR is left for decoders to find.
+	c, err = ParseAudioSpecificConfig([]byte{0x2b, 0x92, 0x08, 0x00})
+	assert.NoError(t, err)
+	h, err = NewADTSHeader(c)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(ObjectTypeAACLC-1), h.ProfileObjectType)
+	assert.Equal(t, byte(7), h.SamplingFrequencyIndex)
+
+	var fe *gobits.FieldError
+	_, err = NewADTSHeader(&AudioSpecificConfig{AudioObjectType: ObjectTypeERAACLD, SamplingFrequencyIndex: 3, ChannelConfiguration: 1})
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "audioObjectType", fe.Field)
+
+	h.ChannelConfiguration = 0
+	_, err = h.AudioSpecificConfig()
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "channel_configuration", fe.Field)
+
+	h.ProtectionAbsent = false
+	_, err = h.MarshalFrame(nil)
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "protection_absent", fe.Field)
+}
+
+func TestScanner(t *testing.T) {
+	var data []byte
+	data = append(data, 'I', 'D', '3', 0xff, 0xff, 0x00)
+	data = append(data, adtsFrame(10)...)
+	data = append(data, adtsFrame(20)...)
+	data = append(data, protectedFrame(t)...)
+	data = append(data, 0xff, 0xf1, 0x00)
+	data = append(data, adtsFrame(30)...)
+
+	f, err := ioutil.TempFile("", "aac")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+	_, err = f.Write(data)
+	assert.NoError(t, err)
+
+	for _, ba := range []gobits.ByteAccessor{gobits.NewSliceByteAccessor(data), gobits.NewIOByteAccessor(f)} {
+		sc := NewScanner(ba)
+		offsets := []int64{}
+		lengths := []int64{}
+		for sc.Next() {
+			offsets = append(offsets, sc.Frame().Offset)
+			lengths = append(lengths, sc.Frame().Data.Length())
+		}
+		assert.NoError(t, sc.Err())
+		assert.Equal(t, []int64{6, 6 + 17, 6 + 17 + 27, 6 + 17 + 27 + 31 + 3}, offsets)
+		assert.Equal(t, []int64{10, 20, 20, 30}, lengths)
+		assert.Equal(t, int64(9), sc.Discarded())
+	}
+	assert.Equal(t, bytes.Repeat([]byte{0x21}, 10), data[6+7:6+17])
+
+	corrupted := append([]byte{}, data...)
+	corrupted[6+17+27+8]++
+	sc := NewScanner(gobits.NewSliceByteAccessor(corrupted))
+	count := 0
+	for sc.Next() {
+		count++
+	}
+	assert.Equal(t, 2, count)
+	assert.True(t, errors.Is(sc.Err(), gobits.ErrChecksum))
+
+	sc = NewScanner(gobits.NewSliceByteAccessor(data[:len(data)-1]))
+	for sc.Next() {
+	}
+	var fe *gobits.FieldError
+	assert.True(t, errors.As(sc.Err(), &fe))
+	assert.Equal(t, "adts_frame", fe.Field)
+	assert.True(t, errors.Is(sc.Err(), gobits.ErrUnexpectedEOF))
+}
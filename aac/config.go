@@ -0,0 +1,387 @@
+package aac
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+)
+
+// Signalling is the way an AudioSpecificConfig signals SBR and PS.
+type Signalling byte
+
+const (
+	// SignallingImplicit signals nothing; decoders find SBR and PS data in
+	// the raw data blocks, if there is any.
+	SignallingImplicit Signalling = iota
+	// SignallingHierarchical puts the SBR or PS object type first and the
+	// core object type after the extension sampling frequency.
+	SignallingHierarchical
+	// SignallingBackwardCompatible appends a sync extension to the config
+	// of the core, which decoders unaware of SBR ignore.
+	SignallingBackwardCompatible
+)
+
+// ChannelElement is a front, side or back channel element of a program
+// config element.
+type ChannelElement struct {
+	IsCPE     bool
+	TagSelect byte
+}
+
+// CCElement is a coupling channel element of a program config element.
+type CCElement struct {
+	IsIndSW   bool
+	TagSelect byte
+}
+
+// ProgramConfigElement is a program_config_element(), which describes the
+// channels when channelConfiguration is 0.
+type ProgramConfigElement struct {
+	ElementInstanceTag         byte
+	ObjectType                 byte
+	SamplingFrequencyIndex     byte
+	FrontChannelElements       []ChannelElement
+	SideChannelElements        []ChannelElement
+	BackChannelElements        []ChannelElement
+	LFEChannelElements         []byte
+	AssocDataElements          []byte
+	CCElements                 []CCElement
+	MonoMixdownPresent         bool
+	MonoMixdownElementNumber   byte
+	StereoMixdownPresent       bool
+	StereoMixdownElementNumber byte
+	MatrixMixdownIdxPresent    bool
+	MatrixMixdownIdx           byte
+	PseudoSurroundEnable       bool
+	CommentFieldData           []byte
+}
+
+func channelElements(s *gobits.Syntax, list []ChannelElement, field string) {
+	for i := range list {
+		s.Flag(&list[i].IsCPE, field+"_is_cpe")
+		s.Byte(&list[i].TagSelect, 4, field+"_tag_select")
+	}
+}
+
+func (p *ProgramConfigElement) syntax(s *gobits.Syntax) {
+	s.Byte(&p.ElementInstanceTag, 4, "element_instance_tag")
+	s.Byte(&p.ObjectType, 2, "object_type")
+	s.Byte(&p.SamplingFrequencyIndex, 4, "sampling_frequency_index")
+	front, side, back := len(p.FrontChannelElements), len(p.SideChannelElements), len(p.BackChannelElements)
+	lfe, assoc, cc := len(p.LFEChannelElements), len(p.AssocDataElements), len(p.CCElements)
+	if !count(s, &front, 4, "num_front_channel_elements") ||
+		!count(s, &side, 4, "num_side_channel_elements") ||
+		!count(s, &back, 4, "num_back_channel_elements") ||
+		!count(s, &lfe, 2, "num_lfe_channel_elements") ||
+		!count(s, &assoc, 3, "num_assoc_data_elements") ||
+		!count(s, &cc, 4, "num_valid_cc_elements") {
+		return
+	}
+	if s.Reading() {
+		p.FrontChannelElements = make([]ChannelElement, front)
+		p.SideChannelElements = make([]ChannelElement, side)
+		p.BackChannelElements = make([]ChannelElement, back)
+		p.LFEChannelElements = make([]byte, lfe)
+		p.AssocDataElements = make([]byte, assoc)
+		p.CCElements = make([]CCElement, cc)
+	}
+	s.Flag(&p.MonoMixdownPresent, "mono_mixdown_present")
+	if p.MonoMixdownPresent {
+		s.Byte(&p.MonoMixdownElementNumber, 4, "mono_mixdown_element_number")
+	}
+	s.Flag(&p.StereoMixdownPresent, "stereo_mixdown_present")
+	if p.StereoMixdownPresent {
+		s.Byte(&p.StereoMixdownElementNumber, 4, "stereo_mixdown_element_number")
+	}
+	s.Flag(&p.MatrixMixdownIdxPresent, "matrix_mixdown_idx_present")
+	if p.MatrixMixdownIdxPresent {
+		s.Byte(&p.MatrixMixdownIdx, 2, "matrix_mixdown_idx")
+		s.Flag(&p.PseudoSurroundEnable, "pseudo_surround_enable")
+	}
+	channelElements(s, p.FrontChannelElements, "front_element")
+	channelElements(s, p.SideChannelElements, "side_element")
+	channelElements(s, p.BackChannelElements, "back_element")
+	for i := range p.LFEChannelElements {
+		s.Byte(&p.LFEChannelElements[i], 4, "lfe_element_tag_select")
+	}
+	for i := range p.AssocDataElements {
+		s.Byte(&p.AssocDataElements[i], 4, "assoc_data_element_tag_select")
+	}
+	for i := range p.CCElements {
+		s.Flag(&p.CCElements[i].IsIndSW, "cc_element_is_ind_sw")
+		s.Byte(&p.CCElements[i].TagSelect, 4, "valid_cc_element_tag_select")
+	}
+	s.Align(8, gobits.PadZeros, "byte_alignment")
+
+	length := byte(len(p.CommentFieldData))
+	if !s.Reading() && len(p.CommentFieldData) > 0xff {
+		s.Fail("comment_field_bytes", gobits.ErrOutOfRange)
+		return
+	}
+	s.Byte(&length, 8, "comment_field_bytes")
+	if s.Err() != nil {
+		return
+	}
+	if s.Reading() {
+		p.CommentFieldData = make([]byte, length)
+	}
+	for i := range p.CommentFieldData {
+		s.Byte(&p.CommentFieldData[i], 8, "comment_field_data")
+	}
+}
+
+// GASpecificConfig is the GASpecificConfig() of the AAC and TwinVQ object
+// types.
+type GASpecificConfig struct {
+	// FrameLengthFlag selects frames of 960 samples rather than 1024, or
+	// 480 rather than 512 for AAC LD.
+	FrameLengthFlag    bool
+	DependsOnCoreCoder bool
+	CoreCoderDelay     uint16
+	ExtensionFlag      bool
+	// ProgramConfigElement is set when channelConfiguration is 0.
+	ProgramConfigElement             *ProgramConfigElement
+	LayerNr                          byte
+	NumOfSubFrame                    byte
+	LayerLength                      uint16
+	AACSectionDataResilienceFlag     bool
+	AACScalefactorDataResilienceFlag bool
+	AACSpectralDataResilienceFlag    bool
+	ExtensionFlag3                   bool
+}
+
+func (c *GASpecificConfig) syntax(s *gobits.Syntax, objectType, channelConfiguration byte) {
+	s.Flag(&c.FrameLengthFlag, "frameLengthFlag")
+	s.Flag(&c.DependsOnCoreCoder, "dependsOnCoreCoder")
+	if c.DependsOnCoreCoder {
+		delay := uint32(c.CoreCoderDelay)
+		s.Bits(&delay, 14, "coreCoderDelay")
+		c.CoreCoderDelay = uint16(delay)
+	}
+	s.Flag(&c.ExtensionFlag, "extensionFlag")
+	if channelConfiguration == 0 {
+		if s.Reading() {
+			c.ProgramConfigElement = &ProgramConfigElement{}
+		} else if c.ProgramConfigElement == nil {
+			s.Fail("program_config_element", gobits.ErrInvalidSyntax)
+			return
+		}
+		c.ProgramConfigElement.syntax(s)
+	}
+	if objectType == ObjectTypeAACScalable || objectType == ObjectTypeERAACScal {
+		s.Byte(&c.LayerNr, 3, "layerNr")
+	}
+	if !c.ExtensionFlag {
+		return
+	}
+	if objectType == ObjectTypeERBSAC {
+		s.Byte(&c.NumOfSubFrame, 5, "numOfSubFrame")
+		length := uint32(c.LayerLength)
+		s.Bits(&length, 11, "layer_length")
+		c.LayerLength = uint16(length)
+	}
+	switch objectType {
+	case ObjectTypeERAACLC, ObjectTypeERAACLTP, ObjectTypeERAACScal, ObjectTypeERAACLD:
+		s.Flag(&c.AACSectionDataResilienceFlag, "aacSectionDataResilienceFlag")
+		s.Flag(&c.AACScalefactorDataResilienceFlag, "aacScalefactorDataResilienceFlag")
+		s.Flag(&c.AACSpectralDataResilienceFlag, "aacSpectralDataResilienceFlag")
+	}
+	s.Flag(&c.ExtensionFlag3, "extensionFlag3")
+}
+
+// AudioSpecificConfig is an AudioSpecificConfig(), as found in the
+// DecoderSpecificInfo of an MP4 esds box.
+type AudioSpecificConfig struct {
+	// AudioObjectType is the object type of the core. With hierarchical
+	// signalling, the config starts with the SBR or PS object type
+	// instead, and AudioObjectType follows the extension sampling
+	// frequency.
+	AudioObjectType        byte
+	SamplingFrequencyIndex byte
+	// SamplingFrequency is set when SamplingFrequencyIndex is the escape
+	// value.
+	SamplingFrequency    uint32
+	ChannelConfiguration byte
+
+	Signalling Signalling
+	// ExtensionAudioObjectType is ObjectTypeSBR, or ObjectTypeERBSAC with
+	// backward compatible signalling.
+	ExtensionAudioObjectType        byte
+	SBRPresentFlag                  bool
+	PSPresentFlag                   bool
+	ExtensionSamplingFrequencyIndex byte
+	ExtensionSamplingFrequency      uint32
+	ExtensionChannelConfiguration   byte
+	// PSSyncExtension reports whether backward compatible signalling of
+	// SBR goes on to signal PS, present or not.
+	PSSyncExtension bool
+
+	GASpecificConfig GASpecificConfig
+	EPConfig         byte
+	// SpecificConfig holds the bits of configs that are not interpreted:
+	// the whole specific config of object types other than AAC and
+	// TwinVQ, or what follows an epConfig of 2 or 3.
+	SpecificConfig []bool
+}
+
+// SampleRate returns the sampling frequency of the core in Hz, or 0 if
+// the index is reserved.
+func (c *AudioSpecificConfig) SampleRate() uint32 {
+	if c.SamplingFrequencyIndex == SamplingFrequencyIndexEscape {
+		return c.SamplingFrequency
+	}
+	return SamplingFrequency(c.SamplingFrequencyIndex)
+}
+
+// ExtensionSampleRate returns the output sampling frequency of SBR in Hz,
+// or 0 if SBR is not signalled.
+func (c *AudioSpecificConfig) ExtensionSampleRate() uint32 {
+	if c.Signalling == SignallingImplicit || !c.SBRPresentFlag {
+		return 0
+	}
+	if c.ExtensionSamplingFrequencyIndex == SamplingFrequencyIndexEscape {
+		return c.ExtensionSamplingFrequency
+	}
+	return SamplingFrequency(c.ExtensionSamplingFrequencyIndex)
+}
+
+func (c *AudioSpecificConfig) extensionSamplingFrequency(s *gobits.Syntax) {
+	samplingFrequency(s, &c.ExtensionSamplingFrequencyIndex, &c.ExtensionSamplingFrequency,
+		"extensionSamplingFrequencyIndex", "extensionSamplingFrequency")
+}
+
+// remainingBits handles the bits up to the end of the data as a list of
+// flags.
+func remainingBits(s *gobits.Syntax, bits *[]bool, field string) {
+	if s.Reading() {
+		*bits = []bool{}
+		for s.BitStream().RemainingBits(1) {
+			bit := false
+			s.Flag(&bit, field)
+			*bits = append(*bits, bit)
+		}
+		return
+	}
+	for i := range *bits {
+		s.Flag(&(*bits)[i], field)
+	}
+}
+
+// peekSyncExtension reports whether the data goes on with a sync extension
+// of extensionType that has at least bitCount bits. It consumes nothing.
+func peekSyncExtension(s *gobits.Syntax, extensionType uint64, bitCount int64) bool {
+	bs := s.BitStream()
+	if !bs.RemainingBits(bitCount) {
+		return false
+	}
+	v, _ := bs.PeekBits(11)
+	return v == extensionType
+}
+
+func (c *AudioSpecificConfig) syntax(s *gobits.Syntax) {
+	objectType := c.AudioObjectType
+	if !s.Reading() && c.Signalling == SignallingHierarchical {
+		objectType = ObjectTypeSBR
+		if c.PSPresentFlag {
+			objectType = ObjectTypePS
+		}
+	}
+	audioObjectType(s, &objectType, "audioObjectType")
+	samplingFrequency(s, &c.SamplingFrequencyIndex, &c.SamplingFrequency, "samplingFrequencyIndex", "samplingFrequency")
+	s.Byte(&c.ChannelConfiguration, 4, "channelConfiguration")
+	if s.Err() != nil {
+		return
+	}
+	if objectType == ObjectTypeSBR || objectType == ObjectTypePS {
+		if s.Reading() {
+			c.Signalling = SignallingHierarchical
+			c.ExtensionAudioObjectType = ObjectTypeSBR
+			c.SBRPresentFlag = true
+			c.PSPresentFlag = objectType == ObjectTypePS
+		}
+		c.extensionSamplingFrequency(s)
+		audioObjectType(s, &c.AudioObjectType, "audioObjectType")
+		if c.AudioObjectType == ObjectTypeERBSAC {
+			s.Byte(&c.ExtensionChannelConfiguration, 4, "extensionChannelConfiguration")
+		}
+	} else {
+		c.AudioObjectType = objectType
+	}
+	if s.Err() != nil {
+		return
+	}
+
+	if !isGA(c.AudioObjectType) {
+		remainingBits(s, &c.SpecificConfig, "specificConfig")
+		return
+	}
+	c.GASpecificConfig.syntax(s, c.AudioObjectType, c.ChannelConfiguration)
+	if isER(c.AudioObjectType) {
+		s.Byte(&c.EPConfig, 2, "epConfig")
+		if c.EPConfig == 2 || c.EPConfig == 3 {
+			remainingBits(s, &c.SpecificConfig, "ErrorProtectionSpecificConfig")
+			return
+		}
+	}
+	if s.Err() != nil || c.Signalling == SignallingHierarchical {
+		return
+	}
+
+	if s.Reading() {
+		if !peekSyncExtension(s, syncExtensionTypeSBR, 16) {
+			return
+		}
+		c.Signalling = SignallingBackwardCompatible
+	} else if c.Signalling != SignallingBackwardCompatible {
+		return
+	}
+	syncExtensionType := uint32(syncExtensionTypeSBR)
+	s.Bits(&syncExtensionType, 11, "syncExtensionType")
+	audioObjectType(s, &c.ExtensionAudioObjectType, "extensionAudioObjectType")
+	switch c.ExtensionAudioObjectType {
+	case ObjectTypeSBR:
+		s.Flag(&c.SBRPresentFlag, "sbrPresentFlag")
+		if !c.SBRPresentFlag {
+			return
+		}
+		c.extensionSamplingFrequency(s)
+		if s.Reading() {
+			c.PSSyncExtension = s.Err() == nil && peekSyncExtension(s, syncExtensionTypePS, 12)
+		}
+		if c.PSSyncExtension {
+			syncExtensionType = syncExtensionTypePS
+			s.Bits(&syncExtensionType, 11, "syncExtensionType")
+			s.Flag(&c.PSPresentFlag, "psPresentFlag")
+		}
+	case ObjectTypeERBSAC:
+		s.Flag(&c.SBRPresentFlag, "sbrPresentFlag")
+		if c.SBRPresentFlag {
+			c.extensionSamplingFrequency(s)
+		}
+		s.Byte(&c.ExtensionChannelConfiguration, 4, "extensionChannelConfiguration")
+	}
+}
+
+// ParseAudioSpecificConfig parses an AudioSpecificConfig that takes up
+// data, whose end delimits the sync extensions that may follow the config
+// of the core. Bits that follow the config and do not start a sync
+// extension are ignored.
+func ParseAudioSpecificConfig(data []byte) (*AudioSpecificConfig, error) {
+	s := gobits.NewReadingSyntax(gobits.NewBitStream(gobits.NewSliceByteAccessor(data)))
+	c := &AudioSpecificConfig{}
+	c.syntax(s)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	return c, nil
+}
+
+// Marshal encodes the config, padded with zeros to a whole number of
+// bytes.
+func (c *AudioSpecificConfig) Marshal() ([]byte, error) {
+	ba := gobits.NewGrowableByteAccessor(nil)
+	s := gobits.NewWritingSyntax(gobits.NewBitStream(ba))
+	c.syntax(s)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	return ba.Bytes(), nil
+}
@@ -0,0 +1,183 @@
+package aac
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAudioSpecificConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		config *AudioSpecificConfig
+	}{
+		{
+			name: "LC",
+			data: []byte{0x12, 0x10},
+			config: &AudioSpecificConfig{
+				AudioObjectType:        ObjectTypeAACLC,
+				SamplingFrequencyIndex: 4,
+				ChannelConfiguration:   2,
+			},
+		},
+		{
+			name: "hierarchical SBR",
+			data: []byte{0x2b, 0x92, 0x08, 0x00},
+			config: &AudioSpecificConfig{
+				AudioObjectType:                 ObjectTypeAACLC,
+				SamplingFrequencyIndex:          7,
+				ChannelConfiguration:            2,
+				Signalling:                      SignallingHierarchical,
+				ExtensionAudioObjectType:        ObjectTypeSBR,
+				SBRPresentFlag:                  true,
+				ExtensionSamplingFrequencyIndex: 4,
+			},
+		},
+		{
+			name: "backward compatible SBR",
+			data: []byte{0x13, 0x10, 0x56, 0xe5, 0x98},
+			config: &AudioSpecificConfig{
+				AudioObjectType:                 ObjectTypeAACLC,
+				SamplingFrequencyIndex:          6,
+				ChannelConfiguration:            2,
+				Signalling:                      SignallingBackwardCompatible,
+				ExtensionAudioObjectType:        ObjectTypeSBR,
+				SBRPresentFlag:                  true,
+				ExtensionSamplingFrequencyIndex: 3,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseAudioSpecificConfig(tt.data)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.config, c)
+			data, err := c.Marshal()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.data, data)
+		})
+	}
+
+	c, err := ParseAudioSpecificConfig([]byte{0x2b, 0x92, 0x08, 0x00})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(22050), c.SampleRate())
+	assert.Equal(t, uint32(44100), c.ExtensionSampleRate())
+
+	_, err = ParseAudioSpecificConfig([]byte{0x12})
+	assert.True(t, errors.Is(err, gobits.ErrUnexpectedEOF))
+}
+
+func TestAudioSpecificConfig_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *AudioSpecificConfig
+	}{
+		{
+			name: "backward compatible PS",
+			config: &AudioSpecificConfig{
+				AudioObjectType:                 ObjectTypeAACLC,
+				SamplingFrequencyIndex:          6,
+				ChannelConfiguration:            1,
+				Signalling:                      SignallingBackwardCompatible,
+				ExtensionAudioObjectType:        ObjectTypeSBR,
+				SBRPresentFlag:                  true,
+				PSPresentFlag:                   true,
+				ExtensionSamplingFrequencyIndex: 3,
+				PSSyncExtension:                 true,
+			},
+		},
+		{
+			name: "hierarchical PS with explicit frequencies",
+			config: &AudioSpecificConfig{
+				AudioObjectType:                 ObjectTypeAACLC,
+				SamplingFrequencyIndex:          SamplingFrequencyIndexEscape,
+				SamplingFrequency:               22000,
+				ChannelConfiguration:            1,
+				Signalling:                      SignallingHierarchical,
+				ExtensionAudioObjectType:        ObjectTypeSBR,
+				SBRPresentFlag:                  true,
+				PSPresentFlag:                   true,
+				ExtensionSamplingFrequencyIndex: SamplingFrequencyIndexEscape,
+				ExtensionSamplingFrequency:      44000,
+			},
+		},
+		{
+			name: "program config element",
+			config: &AudioSpecificConfig{
+				AudioObjectType:        ObjectTypeAACLC,
+				SamplingFrequencyIndex: 3,
+				GASpecificConfig: GASpecificConfig{
+					FrameLengthFlag: true,
+					ProgramConfigElement: &ProgramConfigElement{
+						ObjectType:              1,
+						SamplingFrequencyIndex:  3,
+						FrontChannelElements:    []ChannelElement{{TagSelect: 0}, {IsCPE: true, TagSelect: 1}},
+						SideChannelElements:     []ChannelElement{},
+						BackChannelElements:     []ChannelElement{{IsCPE: true, TagSelect: 2}},
+						LFEChannelElements:      []byte{0},
+						AssocDataElements:       []byte{},
+						CCElements:              []CCElement{{IsIndSW: true, TagSelect: 3}},
+						MatrixMixdownIdxPresent: true,
+						MatrixMixdownIdx:        2,
+						PseudoSurroundEnable:    true,
+						CommentFieldData:        []byte("5.1"),
+					},
+				},
+			},
+		},
+		{
+			name: "ER AAC LD",
+			config: &AudioSpecificConfig{
+				AudioObjectType:        ObjectTypeERAACLD,
+				SamplingFrequencyIndex: 3,
+				ChannelConfiguration:   2,
+				GASpecificConfig: GASpecificConfig{
+					ExtensionFlag:                 true,
+					AACSpectralDataResilienceFlag: true,
+				},
+				EPConfig: 1,
+			},
+		},
+		{
+			name: "escaped object type",
+			config: &AudioSpecificConfig{
+				AudioObjectType:        ObjectTypeUSAC,
+				SamplingFrequencyIndex: 3,
+				ChannelConfiguration:   2,
+				SpecificConfig:         []bool{true, false, true, true, false, false, true, false, false, false, false, true, true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.config.Marshal()
+			assert.NoError(t, err)
+			parsed, err := ParseAudioSpecificConfig(data)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.config, parsed)
+		})
+	}
+}
+
+func TestAudioSpecificConfig_MarshalErrors(t *testing.T) {
+	var fe *gobits.FieldError
+	_, err := (&AudioSpecificConfig{AudioObjectType: ObjectTypeEscape}).Marshal()
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "audioObjectType", fe.Field)
+	assert.True(t, errors.Is(err, gobits.ErrOutOfRange))
+
+	_, err = (&AudioSpecificConfig{AudioObjectType: ObjectTypeAACLC}).Marshal()
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "program_config_element", fe.Field)
+
+	_, err = (&AudioSpecificConfig{
+		AudioObjectType:      ObjectTypeAACLC,
+		GASpecificConfig:     GASpecificConfig{ProgramConfigElement: &ProgramConfigElement{LFEChannelElements: make([]byte, 4)}},
+		ChannelConfiguration: 0,
+	}).Marshal()
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "num_lfe_channel_elements", fe.Field)
+}
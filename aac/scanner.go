@@ -0,0 +1,98 @@
+package aac
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+)
+
+const (
+	// syncChecks is how many consecutive headers the scanner requires
+	// before it locks on to a position.
+	syncChecks = 3
+)
+
+var (
+	// syncPattern matches a syncword followed by a layer of 0.
+	syncPattern = []byte{0xff, 0xf0}
+	syncMask    = []byte{0xff, 0xf6}
+)
+
+// Frame is an ADTS frame found by a Scanner. Data covers the raw data
+// blocks after the header and shares the scanned accessor.
+type Frame struct {
+	Offset int64
+	Header *ADTSHeader
+	Data   *gobits.SectionByteAccessor
+}
+
+// Scanner iterates over the frames of an ADTS stream, such as a .aac
+// file. It skips data that does not start a run of valid headers, an ID3
+// tag for example, and verifies crc_check where it covers the header
+// only. Call Next until it returns false, then check Err.
+type Scanner struct {
+	ba    gobits.ByteAccessor
+	sync  *gobits.FrameSync
+	frame *Frame
+	err   error
+}
+
+// header parses the header at byteOffset for the FrameSync.
+func (sc *Scanner) header(byteOffset int64) (int64, interface{}, bool) {
+	h, err := ParseADTSHeader(gobits.NewSectionByteAccessor(sc.ba, byteOffset, sc.ba.Length()-byteOffset))
+	if err != nil {
+		return 0, nil, false
+	}
+	return int64(h.AACFrameLength), nil, true
+}
+
+// Next advances to the next frame. It returns false at the end of the
+// data or on error.
+func (sc *Scanner) Next() bool {
+	if sc.err != nil {
+		return false
+	}
+	if !sc.sync.Next() {
+		if err := sc.sync.Err(); err != nil {
+			sc.err = &gobits.FieldError{Field: "adts_frame", Err: err}
+		}
+		return false
+	}
+	offset, length := sc.sync.Offset(), sc.sync.Length()
+	h, err := ParseADTSHeader(gobits.NewSectionByteAccessor(sc.ba, offset, length))
+	if err != nil {
+		sc.err = err
+		return false
+	}
+	headerLength := int64(h.HeaderLength())
+	if err := h.checkCRC(sc.ba.Slice(offset, headerLength)); err != nil {
+		sc.err = err
+		return false
+	}
+	sc.frame = &Frame{
+		Offset: offset,
+		Header: h,
+		Data:   gobits.NewSectionByteAccessor(sc.ba, offset+headerLength, length-headerLength),
+	}
+	return true
+}
+
+// Frame returns the frame found by the last successful call to Next.
+func (sc *Scanner) Frame() *Frame {
+	return sc.frame
+}
+
+// Discarded returns the number of bytes skipped so far while searching
+// for headers.
+func (sc *Scanner) Discarded() int64 {
+	return sc.sync.Discarded()
+}
+
+func (sc *Scanner) Err() error {
+	return sc.err
+}
+
+// NewScanner returns a scanner for the ADTS frames in ba.
+func NewScanner(ba gobits.ByteAccessor) *Scanner {
+	sc := &Scanner{ba: ba}
+	sc.sync = gobits.NewFrameSync(ba, syncPattern, syncMask, syncChecks, sc.header)
+	return sc
+}
@@ -0,0 +1,186 @@
+// Package av1 iterates AV1 OBUs and parses sequence headers and
+// uncompressed frame headers.
+//
+// Sequence headers are described once as a gobits.Syntax, so parsing one
+// and marshalling the result reproduces it bit for bit. Frame headers
+// depend on the state of the reference frames and are only parsed, by a
+// Parser that tracks that state. Fields are named after the syntax
+// elements of the AV1 Bitstream & Decoding Process Specification, section
+// 5. The su(n) descriptor maps to gobits.Syntax.SignedBits and f(n) to
+// Bits, Byte and Flag; leb128(), uvlc(), ns(n) and le(n) are implemented
+// here.
+package av1
+
+import (
+	"math"
+
+	"github.com/ibbbpbbbp/gobits"
+)
+
+// OBU types.
+const (
+	OBUTypeSequenceHeader       = 1
+	OBUTypeTemporalDelimiter    = 2
+	OBUTypeFrameHeader          = 3
+	OBUTypeTileGroup            = 4
+	OBUTypeMetadata             = 5
+	OBUTypeFrame                = 6
+	OBUTypeRedundantFrameHeader = 7
+	OBUTypeTileList             = 8
+	OBUTypePadding              = 15
+)
+
+// Frame types.
+const (
+	FrameTypeKey       = 0
+	FrameTypeInter     = 1
+	FrameTypeIntraOnly = 2
+	FrameTypeSwitch    = 3
+)
+
+// Reference frame names, which index the per-reference arrays of a frame
+// header.
+const (
+	RefFrameIntra   = 0
+	RefFrameLast    = 1
+	RefFrameLast2   = 2
+	RefFrameLast3   = 3
+	RefFrameGolden  = 4
+	RefFrameBwdRef  = 5
+	RefFrameAltRef2 = 6
+	RefFrameAltRef  = 7
+)
+
+// Color description values with special meaning in color_config().
+const (
+	ColorPrimariesBT709                = 1
+	ColorPrimariesUnspecified          = 2
+	TransferCharacteristicsSRGB        = 13
+	TransferCharacteristicsUnspecified = 2
+	MatrixCoefficientsIdentity         = 0
+	MatrixCoefficientsUnspecified      = 2
+)
+
+const (
+	// SelectScreenContentTools and SelectIntegerMV are the values of
+	// seq_force_screen_content_tools and seq_force_integer_mv that leave
+	// the choice to each frame header.
+	SelectScreenContentTools = 2
+	SelectIntegerMV          = 2
+
+	numRefFrames   = 8
+	refsPerFrame   = 7
+	primaryRefNone = 7
+)
+
+// leb128 handles a leb128() value, which the specification limits to eight
+// bytes and to values below 2^32.
+func leb128(s *gobits.Syntax, val *uint64, field string) {
+	if s.Err() != nil {
+		return
+	}
+	bs := s.BitStream()
+	if !s.Reading() {
+		if *val > math.MaxUint32 {
+			s.Fail(field, gobits.ErrOutOfRange)
+		} else if !bs.WriteULEB128(*val) {
+			s.Fail(field, bs.Err())
+		}
+		return
+	}
+	v, ok := bs.ReadULEB128(8)
+	if !ok {
+		s.Fail(field, bs.Err())
+		return
+	}
+	if v > math.MaxUint32 {
+		s.Fail(field, gobits.ErrOutOfRange)
+		return
+	}
+	*val = v
+}
+
+// uvlc handles a uvlc() value. Below 2^32 - 1 it is coded as ue(v); a run
+// of 32 or more leading zeros stands for 2^32 - 1 and has no value bits.
+func uvlc(s *gobits.Syntax, val *uint32, field string) {
+	if s.Err() != nil {
+		return
+	}
+	if !s.Reading() {
+		if *val != math.MaxUint32 {
+			s.ExponentialGolomb(val, field)
+			return
+		}
+		zeros := uint32(0)
+		s.Bits(&zeros, 32, field)
+		done := true
+		s.Flag(&done, field)
+		return
+	}
+
+	leadingZeros := byte(0)
+	for done := false; !done; {
+		s.Flag(&done, field)
+		if s.Err() != nil {
+			return
+		}
+		if !done && leadingZeros < 32 {
+			leadingZeros++
+		}
+	}
+	if leadingZeros >= 32 {
+		*val = math.MaxUint32
+		return
+	}
+	s.Bits(val, leadingZeros, field)
+	*val += 1<<leadingZeros - 1
+}
+
+// ns handles an ns(n) value, a truncated binary code for values below n.
+func ns(s *gobits.Syntax, val *uint32, n uint32, field string) {
+	if s.Err() != nil {
+		return
+	}
+	bs := s.BitStream()
+	if !s.Reading() {
+		if !bs.WriteTruncatedBinary(uint64(*val), uint64(n)) {
+			s.Fail(field, bs.Err())
+		}
+		return
+	}
+	v, ok := bs.ReadTruncatedBinary(uint64(n))
+	if !ok {
+		s.Fail(field, bs.Err())
+		return
+	}
+	*val = uint32(v)
+}
+
+// le handles an le(n) value, an unsigned little-endian integer of
+// byteCount bytes.
+func le(s *gobits.Syntax, val *uint64, byteCount int, field string) {
+	if byteCount > 8 {
+		s.Fail(field, gobits.ErrInvalidBitCount)
+		return
+	}
+	if s.Reading() {
+		*val = 0
+	}
+	for i := 0; i < byteCount; i++ {
+		b := byte(*val >> (8 * uint(i)))
+		s.Byte(&b, 8, field)
+		if s.Reading() {
+			*val |= uint64(b) << (8 * uint(i))
+		}
+	}
+}
+
+// trailingBits handles trailing_bits() up to the next byte boundary.
+func trailingBits(s *gobits.Syntax) {
+	s.Align(8, gobits.PadRBSPTrailingBits, "trailing_bits")
+}
+
+// byteAlignment handles byte_alignment().
+func byteAlignment(s *gobits.Syntax) {
+	s.Align(8, gobits.PadZeros, "zero_bit")
+}
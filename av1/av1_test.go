@@ -0,0 +1,155 @@
+package av1
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/stretchr/testify/assert"
+)
+
+// A temporal unit of a 1920x1080 stream: a temporal delimiter, a sequence
+// header, a frame header and a tile group with two tiles.
+var (
+	sequenceHeader1080p = []byte{0x00, 0x00, 0x00, 0x42, 0xab, 0xbf, 0xc3, 0x77, 0xff, 0xe6, 0x01}
+	keyFrameHeader      = []byte{
+		0x10, 0x00, 0xc6, 0xc8, 0x7e, 0x94, 0x51, 0x01, 0x88, 0x38, 0x20, 0x04,
+		0xae, 0x48, 0x03, 0x24, 0xd0,
+	}
+	keyFrameTileGroup = []byte{0x00, 0x02, 0x00, 0x00, 0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0xee}
+)
+
+func temporalUnit() []byte {
+	tu := []byte{0x12, 0x00}
+	tu = append(append(tu, 0x0a, byte(len(sequenceHeader1080p))), sequenceHeader1080p...)
+	tu = append(append(tu, 0x1a, byte(len(keyFrameHeader))), keyFrameHeader...)
+	return append(append(tu, 0x22, byte(len(keyFrameTileGroup))), keyFrameTileGroup...)
+}
+
+func TestDescriptors(t *testing.T) {
+	ba := gobits.NewGrowableByteAccessor(nil)
+	s := gobits.NewWritingSyntax(gobits.NewBitStream(ba))
+	for _, v := range []uint32{0, 1, 5, math.MaxUint32 - 1, math.MaxUint32} {
+		uvlc(s, &v, "uvlc")
+	}
+	for _, v := range []uint32{0, 2, 4} {
+		ns(s, &v, 5, "ns")
+	}
+	size := uint64(624485)
+	leb128(s, &size, "leb128")
+	tileSize := uint64(0x123456)
+	le(s, &tileSize, 3, "le")
+	assert.NoError(t, s.Err())
+
+	s = gobits.NewReadingSyntax(gobits.NewBitStream(ba))
+	for _, expect := range []uint32{0, 1, 5, math.MaxUint32 - 1, math.MaxUint32} {
+		v := uint32(0)
+		uvlc(s, &v, "uvlc")
+		assert.Equal(t, expect, v)
+	}
+	for _, expect := range []uint32{0, 2, 4} {
+		v := uint32(0)
+		ns(s, &v, 5, "ns")
+		assert.Equal(t, expect, v)
+	}
+	leb128(s, &size, "leb128")
+	assert.Equal(t, uint64(624485), size)
+	le(s, &tileSize, 3, "le")
+	assert.Equal(t, uint64(0x123456), tileSize)
+	assert.NoError(t, s.Err())
+
+	// More than 32 leading zeros also mean 2^32 - 1.
+	s = gobits.NewReadingSyntax(gobits.NewBitStream(gobits.NewSliceByteAccessor([]byte{0, 0, 0, 0, 0, 0x80})))
+	v := uint32(0)
+	uvlc(s, &v, "uvlc")
+	assert.NoError(t, s.Err())
+	assert.Equal(t, uint32(math.MaxUint32), v)
+
+	s = gobits.NewReadingSyntax(gobits.NewBitStream(gobits.NewSliceByteAccessor([]byte{0xff, 0xff, 0xff, 0xff, 0x1f})))
+	leb128(s, &size, "obu_size")
+	assert.True(t, errors.Is(s.Err(), gobits.ErrOutOfRange))
+}
+
+func TestScanner(t *testing.T) {
+	tu := temporalUnit()
+	sc := NewScanner(gobits.NewSliceByteAccessor(tu))
+	types := []byte{}
+	for sc.Next() {
+		types = append(types, sc.OBU().Header.Type)
+	}
+	assert.NoError(t, sc.Err())
+	assert.Equal(t, []byte{OBUTypeTemporalDelimiter, OBUTypeSequenceHeader, OBUTypeFrameHeader, OBUTypeTileGroup}, types)
+
+	sc = NewScanner(gobits.NewSliceByteAccessor(tu))
+	assert.True(t, sc.Next())
+	assert.True(t, sc.Next())
+	obu := sc.OBU()
+	assert.Equal(t, int64(2), obu.Offset)
+	assert.Equal(t, int64(2+len(sequenceHeader1080p)), obu.Length())
+	assert.Equal(t, sequenceHeader1080p, obu.Payload.Slice(0, obu.Payload.Length()))
+
+	// An OBU with an extension header and without obu_size runs to the
+	// end of the data.
+	data := []byte{0x34, 0x48, 0xaa, 0xbb}
+	sc = NewScanner(gobits.NewSliceByteAccessor(data))
+	assert.True(t, sc.Next())
+	obu = sc.OBU()
+	assert.Equal(t, OBUHeader{Type: OBUTypeFrame, ExtensionFlag: true, TemporalID: 2, SpatialID: 1}, obu.Header)
+	assert.Equal(t, []byte{0xaa, 0xbb}, obu.Payload.Slice(0, 2))
+	assert.False(t, sc.Next())
+	assert.NoError(t, sc.Err())
+
+	marshalled, err := obu.Header.Marshal([]byte{0xaa, 0xbb})
+	assert.NoError(t, err)
+	assert.Equal(t, data, marshalled)
+
+	sc = NewScanner(gobits.NewSliceByteAccessor(tu[:len(tu)-1]))
+	for sc.Next() {
+	}
+	var fe *gobits.FieldError
+	assert.True(t, errors.As(sc.Err(), &fe))
+	assert.Equal(t, "obu_size", fe.Field)
+	assert.True(t, errors.Is(sc.Err(), gobits.ErrUnexpectedEOF))
+}
+
+func TestIVFScanner(t *testing.T) {
+	tu := temporalUnit()
+	file := []byte{
+		'D', 'K', 'I', 'F', 0x00, 0x00, 0x20, 0x00, 'A', 'V', '0', '1',
+		0x80, 0x07, 0x38, 0x04, 0x19, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00,
+		0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+	for i := 0; i < 2; i++ {
+		file = append(file, byte(len(tu)), 0x00, 0x00, 0x00, byte(i), 0, 0, 0, 0, 0, 0, 0)
+		file = append(file, tu...)
+	}
+
+	sc := NewIVFScanner(gobits.NewSliceByteAccessor(file))
+	assert.NoError(t, sc.Err())
+	h := sc.Header()
+	assert.Equal(t, "AV01", string(h.FourCC[:]))
+	assert.Equal(t, uint16(1920), h.Width)
+	assert.Equal(t, uint16(1080), h.Height)
+	assert.Equal(t, uint32(25), h.TimebaseDenominator)
+	assert.Equal(t, uint32(2), h.FrameCount)
+
+	timestamps := []uint64{}
+	for sc.Next() {
+		frame := sc.Frame()
+		timestamps = append(timestamps, frame.Timestamp)
+		assert.Equal(t, tu, frame.Data.Slice(0, frame.Data.Length()))
+	}
+	assert.NoError(t, sc.Err())
+	assert.Equal(t, []uint64{0, 1}, timestamps)
+
+	sc = NewIVFScanner(gobits.NewSliceByteAccessor(file[:len(file)-1]))
+	assert.True(t, sc.Next())
+	assert.False(t, sc.Next())
+	assert.True(t, errors.Is(sc.Err(), gobits.ErrUnexpectedEOF))
+
+	file[0] = 'R'
+	sc = NewIVFScanner(gobits.NewSliceByteAccessor(file))
+	assert.False(t, sc.Next())
+	assert.True(t, errors.Is(sc.Err(), gobits.ErrInvalidSyntax))
+}
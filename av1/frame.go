@@ -0,0 +1,684 @@
+package av1
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+)
+
+const (
+	// InterpolationFilterSwitchable is the interpolation_filter of frames
+	// that choose the filter per block.
+	InterpolationFilterSwitchable = 4
+
+	superresNum      = 8
+	superresDenomMin = 9
+	allFrames        = 1<<numRefFrames - 1
+)
+
+// Transform modes.
+const (
+	TxModeOnly4x4 = 0
+	TxModeLargest = 1
+	TxModeSelect  = 2
+)
+
+// refFrame is what a Parser remembers of a reference frame: the values
+// that the frame headers referring to it read.
+type refFrame struct {
+	valid         bool
+	frameID       uint32
+	frameType     byte
+	orderHint     uint32
+	upscaledWidth uint32
+	frameWidth    uint32
+	frameHeight   uint32
+	renderWidth   uint32
+	renderHeight  uint32
+	segmentation  SegmentationParams
+	loopFilter    LoopFilterParams
+	globalMotion  [numRefFrames]GlobalMotion
+	filmGrain     FilmGrainParams
+}
+
+// defaultRefFrame returns the state that setup_past_independence() sets
+// up for frames without a primary reference frame.
+func defaultRefFrame() refFrame {
+	ref := refFrame{}
+	ref.loopFilter.RefDeltas = defaultLoopFilterRefDeltas
+	for i := range ref.globalMotion {
+		ref.globalMotion[i] = defaultGlobalMotion()
+	}
+	return ref
+}
+
+// FrameHeader is an uncompressed_header(). Values that are not coded are
+// inferred, and those taken from reference frames are filled in, so the
+// fields describe the frame whether or not they were present.
+type FrameHeader struct {
+	ShowExistingFrame       bool
+	FrameToShowMapIdx       byte
+	FramePresentationTime   uint32
+	DisplayFrameID          uint32
+	FrameType               byte
+	ShowFrame               bool
+	ShowableFrame           bool
+	ErrorResilientMode      bool
+	DisableCDFUpdate        bool
+	AllowScreenContentTools bool
+	ForceIntegerMV          bool
+	CurrentFrameID          uint32
+	FrameSizeOverrideFlag   bool
+	OrderHint               uint32
+	PrimaryRefFrame         byte
+
+	BufferRemovalTimePresentFlag bool
+	// BufferRemovalTime has an entry per operating point. Entries of
+	// operating points that do not include the frame are zero.
+	BufferRemovalTime []uint32
+	RefreshFrameFlags byte
+	// RefOrderHint holds ref_order_hint, which is only coded in error
+	// resilient mode with order hints enabled.
+	RefOrderHint []uint32
+
+	FrameWidth                  uint32
+	FrameHeight                 uint32
+	UpscaledWidth               uint32
+	UseSuperres                 bool
+	CodedDenom                  byte
+	RenderAndFrameSizeDifferent bool
+	RenderWidth                 uint32
+	RenderHeight                uint32
+	// FoundRef is the index in RefFrameIdx of the reference frame whose
+	// size the frame takes, or -1.
+	FoundRef int
+
+	AllowIntraBC             bool
+	FrameRefsShortSignaling  bool
+	LastFrameIdx             byte
+	GoldFrameIdx             byte
+	RefFrameIdx              [refsPerFrame]byte
+	DeltaFrameIDMinus1       [refsPerFrame]uint32
+	AllowHighPrecisionMV     bool
+	IsFilterSwitchable       bool
+	InterpolationFilter      byte
+	IsMotionModeSwitchable   bool
+	UseRefFrameMVs           bool
+	DisableFrameEndUpdateCDF bool
+
+	TileInfo       TileInfo
+	Quantization   QuantizationParams
+	Segmentation   SegmentationParams
+	DeltaQPresent  bool
+	DeltaQRes      byte
+	DeltaLFPresent bool
+	DeltaLFRes     byte
+	DeltaLFMulti   bool
+	CodedLossless  bool
+	AllLossless    bool
+
+	LoopFilter      LoopFilterParams
+	CDEF            CDEFParams
+	LoopRestoration LoopRestorationParams
+	TxMode          byte
+	ReferenceSelect bool
+	SkipModePresent bool
+	// SkipModeFrame holds the two reference frames used by skip mode.
+	SkipModeFrame     [2]byte
+	AllowWarpedMotion bool
+	ReducedTxSet      bool
+	// GlobalMotion is indexed by reference frame, from RefFrameLast to
+	// RefFrameAltRef.
+	GlobalMotion [numRefFrames]GlobalMotion
+	FilmGrain    FilmGrainParams
+
+	// HeaderLength is the length of the frame header in bytes, trailing
+	// bits or byte alignment included. In a frame OBU, the tile group
+	// follows.
+	HeaderLength int64
+}
+
+func (h *FrameHeader) FrameIsIntra() bool {
+	return h.FrameType == FrameTypeIntraOnly || h.FrameType == FrameTypeKey
+}
+
+func (h *FrameHeader) SuperresDenom() uint32 {
+	if h.UseSuperres {
+		return uint32(h.CodedDenom) + superresDenomMin
+	}
+	return superresNum
+}
+
+// MiCols returns the width of the frame in 4x4 mode info units.
+func (h *FrameHeader) MiCols() uint32 {
+	return 2 * ((h.FrameWidth + 7) >> 3)
+}
+
+// MiRows returns the height of the frame in 4x4 mode info units.
+func (h *FrameHeader) MiRows() uint32 {
+	return 2 * ((h.FrameHeight + 7) >> 3)
+}
+
+// Parser parses frame headers, keeping the state of the reference frames
+// that their syntax depends on. Sequence must be set, normally to the last
+// sequence header seen, before frame headers are parsed.
+type Parser struct {
+	Sequence *SequenceHeader
+	refs     [numRefFrames]refFrame
+}
+
+// ParseFrameHeader parses the frame header at the start of a frame header
+// or frame OBU and updates the reference frames as the decoding of the
+// frame would. Redundant frame headers must not be passed, as their
+// reference frames have already been updated. On error the state is left
+// unchanged.
+func (p *Parser) ParseFrameHeader(obu OBU) (*FrameHeader, error) {
+	if p.Sequence == nil {
+		return nil, &gobits.FieldError{Field: "sequence_header_obu", Err: gobits.ErrInvalidSyntax}
+	}
+	if obu.Header.Type != OBUTypeFrameHeader && obu.Header.Type != OBUTypeFrame {
+		return nil, &gobits.FieldError{Field: "obu_type", Err: gobits.ErrInvalidSyntax}
+	}
+
+	bs := gobits.NewBitStream(obu.Payload)
+	fp := &frameParser{
+		s:    gobits.NewReadingSyntax(bs),
+		seq:  p.Sequence,
+		obu:  obu.Header,
+		refs: p.refs,
+		h:    &FrameHeader{FoundRef: -1},
+	}
+	fp.uncompressedHeader()
+	if obu.Header.Type == OBUTypeFrame {
+		byteAlignment(fp.s)
+	} else {
+		trailingBits(fp.s)
+	}
+	if fp.s.Err() != nil {
+		return nil, fp.s.Err()
+	}
+	fp.h.HeaderLength = bs.Tell() / 8
+	fp.updateRefs()
+	p.refs = fp.refs
+	return fp.h, nil
+}
+
+// NewParser returns a Parser for frames that follow the sequence header sh.
+func NewParser(sh *SequenceHeader) *Parser {
+	return &Parser{Sequence: sh}
+}
+
+// frameParser holds the state of one frame header parse. It works on a
+// copy of the reference frames, which replaces the Parser's on success.
+type frameParser struct {
+	s    *gobits.Syntax
+	seq  *SequenceHeader
+	obu  OBUHeader
+	refs [numRefFrames]refFrame
+	h    *FrameHeader
+}
+
+// relativeDist implements get_relative_dist().
+func (fp *frameParser) relativeDist(a, b uint32) int32 {
+	if !fp.seq.EnableOrderHint {
+		return 0
+	}
+	diff := int32(a - b)
+	m := int32(1) << (fp.seq.OrderHintBits() - 1)
+	return diff&(m-1) - diff&m
+}
+
+func (fp *frameParser) uncompressedHeader() {
+	s, seq, h := fp.s, fp.seq, fp.h
+	idLen := byte(0)
+	if seq.FrameIDNumbersPresentFlag {
+		idLen = seq.AdditionalFrameIDLengthMinus1 + seq.DeltaFrameIDLengthMinus2 + 3
+	}
+
+	if seq.ReducedStillPictureHeader {
+		h.FrameType = FrameTypeKey
+		h.ShowFrame = true
+	} else {
+		s.Flag(&h.ShowExistingFrame, "show_existing_frame")
+		if h.ShowExistingFrame {
+			fp.showExistingFrame(idLen)
+			return
+		}
+		s.Byte(&h.FrameType, 2, "frame_type")
+		s.Flag(&h.ShowFrame, "show_frame")
+		if h.ShowFrame && seq.DecoderModelInfoPresentFlag && !seq.TimingInfo.EqualPictureInterval {
+			fp.temporalPointInfo()
+		}
+		if h.ShowFrame {
+			h.ShowableFrame = h.FrameType != FrameTypeKey
+		} else {
+			s.Flag(&h.ShowableFrame, "showable_frame")
+		}
+		if h.FrameType == FrameTypeSwitch || h.FrameType == FrameTypeKey && h.ShowFrame {
+			h.ErrorResilientMode = true
+		} else {
+			s.Flag(&h.ErrorResilientMode, "error_resilient_mode")
+		}
+	}
+	if h.FrameType == FrameTypeKey && h.ShowFrame {
+		for i := range fp.refs {
+			fp.refs[i].valid = false
+			fp.refs[i].orderHint = 0
+		}
+	}
+
+	s.Flag(&h.DisableCDFUpdate, "disable_cdf_update")
+	if seq.SeqForceScreenContentTools == SelectScreenContentTools {
+		s.Flag(&h.AllowScreenContentTools, "allow_screen_content_tools")
+	} else {
+		h.AllowScreenContentTools = seq.SeqForceScreenContentTools != 0
+	}
+	if h.AllowScreenContentTools {
+		if seq.SeqForceIntegerMV == SelectIntegerMV {
+			s.Flag(&h.ForceIntegerMV, "force_integer_mv")
+		} else {
+			h.ForceIntegerMV = seq.SeqForceIntegerMV != 0
+		}
+	}
+	if h.FrameIsIntra() {
+		h.ForceIntegerMV = true
+	}
+	if seq.FrameIDNumbersPresentFlag {
+		s.Bits(&h.CurrentFrameID, idLen, "current_frame_id")
+		fp.markRefFrames(idLen)
+	}
+	if h.FrameType == FrameTypeSwitch {
+		h.FrameSizeOverrideFlag = true
+	} else if !seq.ReducedStillPictureHeader {
+		s.Flag(&h.FrameSizeOverrideFlag, "frame_size_override_flag")
+	}
+	s.Bits(&h.OrderHint, seq.OrderHintBits(), "order_hint")
+	if h.FrameIsIntra() || h.ErrorResilientMode {
+		h.PrimaryRefFrame = primaryRefNone
+	} else {
+		s.Byte(&h.PrimaryRefFrame, 3, "primary_ref_frame")
+	}
+	if seq.DecoderModelInfoPresentFlag {
+		fp.bufferRemovalTimes()
+	}
+
+	if h.FrameType == FrameTypeSwitch || h.FrameType == FrameTypeKey && h.ShowFrame {
+		h.RefreshFrameFlags = allFrames
+	} else {
+		s.Byte(&h.RefreshFrameFlags, 8, "refresh_frame_flags")
+	}
+	if (!h.FrameIsIntra() || h.RefreshFrameFlags != allFrames) && h.ErrorResilientMode && seq.EnableOrderHint {
+		h.RefOrderHint = make([]uint32, numRefFrames)
+		for i := range h.RefOrderHint {
+			s.Bits(&h.RefOrderHint[i], seq.OrderHintBits(), "ref_order_hint")
+			if h.RefOrderHint[i] != fp.refs[i].orderHint {
+				fp.refs[i] = refFrame{orderHint: h.RefOrderHint[i]}
+			}
+		}
+	}
+
+	if h.FrameIsIntra() {
+		fp.frameSize()
+		fp.renderSize()
+		if h.AllowScreenContentTools && h.UpscaledWidth == h.FrameWidth {
+			s.Flag(&h.AllowIntraBC, "allow_intrabc")
+		}
+	} else {
+		fp.interFrame(idLen)
+	}
+	if seq.ReducedStillPictureHeader || h.DisableCDFUpdate {
+		h.DisableFrameEndUpdateCDF = true
+	} else {
+		s.Flag(&h.DisableFrameEndUpdateCDF, "disable_frame_end_update_cdf")
+	}
+	if s.Err() != nil {
+		return
+	}
+
+	prev := defaultRefFrame()
+	if h.PrimaryRefFrame != primaryRefNone {
+		prev = fp.refs[h.RefFrameIdx[h.PrimaryRefFrame]]
+	}
+	fp.tileInfo()
+	fp.quantizationParams()
+	fp.segmentationParams(&prev)
+	fp.deltaParams()
+	fp.lossless()
+	fp.loopFilterParams(&prev)
+	fp.cdefParams()
+	fp.lrParams()
+	if h.CodedLossless {
+		h.TxMode = TxModeOnly4x4
+	} else {
+		txModeSelect := false
+		s.Flag(&txModeSelect, "tx_mode_select")
+		h.TxMode = TxModeLargest
+		if txModeSelect {
+			h.TxMode = TxModeSelect
+		}
+	}
+	if !h.FrameIsIntra() {
+		s.Flag(&h.ReferenceSelect, "reference_select")
+	}
+	if fp.skipModeAllowed() {
+		s.Flag(&h.SkipModePresent, "skip_mode_present")
+	}
+	if !h.FrameIsIntra() && !h.ErrorResilientMode && seq.EnableWarpedMotion {
+		s.Flag(&h.AllowWarpedMotion, "allow_warped_motion")
+	}
+	s.Flag(&h.ReducedTxSet, "reduced_tx_set")
+	fp.globalMotionParams(&prev)
+	fp.filmGrainParams()
+}
+
+// showExistingFrame handles the rest of a header that shows a frame from
+// the reference frames, loading the values of that frame.
+func (fp *frameParser) showExistingFrame(idLen byte) {
+	s, seq, h := fp.s, fp.seq, fp.h
+	s.Byte(&h.FrameToShowMapIdx, 3, "frame_to_show_map_idx")
+	if seq.DecoderModelInfoPresentFlag && !seq.TimingInfo.EqualPictureInterval {
+		fp.temporalPointInfo()
+	}
+	if seq.FrameIDNumbersPresentFlag {
+		s.Bits(&h.DisplayFrameID, idLen, "display_frame_id")
+	}
+	ref := &fp.refs[h.FrameToShowMapIdx]
+	h.FrameType = ref.frameType
+	h.CurrentFrameID = ref.frameID
+	h.OrderHint = ref.orderHint
+	h.UpscaledWidth = ref.upscaledWidth
+	h.FrameWidth = ref.frameWidth
+	h.FrameHeight = ref.frameHeight
+	h.RenderWidth = ref.renderWidth
+	h.RenderHeight = ref.renderHeight
+	if h.FrameType == FrameTypeKey {
+		h.RefreshFrameFlags = allFrames
+	}
+	if seq.FilmGrainParamsPresent {
+		h.FilmGrain = ref.filmGrain
+	}
+}
+
+func (fp *frameParser) temporalPointInfo() {
+	n := fp.seq.DecoderModelInfo.FramePresentationTimeLengthMinus1 + 1
+	fp.s.Bits(&fp.h.FramePresentationTime, n, "frame_presentation_time")
+}
+
+func (fp *frameParser) bufferRemovalTimes() {
+	s, seq, h := fp.s, fp.seq, fp.h
+	s.Flag(&h.BufferRemovalTimePresentFlag, "buffer_removal_time_present_flag")
+	if !h.BufferRemovalTimePresentFlag {
+		return
+	}
+	h.BufferRemovalTime = make([]uint32, len(seq.OperatingPoints))
+	for i, op := range seq.OperatingPoints {
+		if !op.DecoderModelPresentForThisOp {
+			continue
+		}
+		inTemporalLayer := op.IDC>>fp.obu.TemporalID&1 != 0
+		inSpatialLayer := op.IDC>>(fp.obu.SpatialID+8)&1 != 0
+		if op.IDC == 0 || inTemporalLayer && inSpatialLayer {
+			n := seq.DecoderModelInfo.BufferRemovalTimeLengthMinus1 + 1
+			s.Bits(&h.BufferRemovalTime[i], n, "buffer_removal_time")
+		}
+	}
+}
+
+// markRefFrames implements mark_ref_frames(), invalidating reference
+// frames whose IDs are too far from the current one.
+func (fp *frameParser) markRefFrames(idLen byte) {
+	diff := uint32(1) << (fp.seq.DeltaFrameIDLengthMinus2 + 2)
+	current := fp.h.CurrentFrameID
+	for i := range fp.refs {
+		id := fp.refs[i].frameID
+		if current > diff {
+			if id > current || id < current-diff {
+				fp.refs[i].valid = false
+			}
+		} else if id > current && id < 1<<idLen+current-diff {
+			fp.refs[i].valid = false
+		}
+	}
+}
+
+func (fp *frameParser) interFrame(idLen byte) {
+	s, seq, h := fp.s, fp.seq, fp.h
+	if seq.EnableOrderHint {
+		s.Flag(&h.FrameRefsShortSignaling, "frame_refs_short_signaling")
+		if h.FrameRefsShortSignaling {
+			s.Byte(&h.LastFrameIdx, 3, "last_frame_idx")
+			s.Byte(&h.GoldFrameIdx, 3, "gold_frame_idx")
+			fp.setFrameRefs()
+		}
+	}
+	for i := range h.RefFrameIdx {
+		if !h.FrameRefsShortSignaling {
+			s.Byte(&h.RefFrameIdx[i], 3, "ref_frame_idx")
+		}
+		if seq.FrameIDNumbersPresentFlag {
+			s.Bits(&h.DeltaFrameIDMinus1[i], seq.DeltaFrameIDLengthMinus2+2, "delta_frame_id_minus_1")
+		}
+		if s.Err() == nil && !fp.refs[h.RefFrameIdx[i]].valid {
+			s.Fail("ref_frame_idx", gobits.ErrInvalidSyntax)
+		}
+	}
+	if h.FrameSizeOverrideFlag && !h.ErrorResilientMode {
+		fp.frameSizeWithRefs()
+	} else {
+		fp.frameSize()
+		fp.renderSize()
+	}
+	if !h.ForceIntegerMV {
+		s.Flag(&h.AllowHighPrecisionMV, "allow_high_precision_mv")
+	}
+	s.Flag(&h.IsFilterSwitchable, "is_filter_switchable")
+	if h.IsFilterSwitchable {
+		h.InterpolationFilter = InterpolationFilterSwitchable
+	} else {
+		s.Byte(&h.InterpolationFilter, 2, "interpolation_filter")
+	}
+	s.Flag(&h.IsMotionModeSwitchable, "is_motion_mode_switchable")
+	if !h.ErrorResilientMode && seq.EnableRefFrameMVs {
+		s.Flag(&h.UseRefFrameMVs, "use_ref_frame_mvs")
+	}
+}
+
+// setFrameRefs implements set_frame_refs(), which derives ref_frame_idx
+// from last_frame_idx, gold_frame_idx and the order hints of the reference
+// frames.
+func (fp *frameParser) setFrameRefs() {
+	h := fp.h
+	var idx [refsPerFrame]int
+	for i := range idx {
+		idx[i] = -1
+	}
+	idx[RefFrameLast-RefFrameLast] = int(h.LastFrameIdx)
+	idx[RefFrameGolden-RefFrameLast] = int(h.GoldFrameIdx)
+	var used [numRefFrames]bool
+	used[h.LastFrameIdx] = true
+	used[h.GoldFrameIdx] = true
+
+	curFrameHint := int32(1) << (fp.seq.OrderHintBits() - 1)
+	var shiftedOrderHints [numRefFrames]int32
+	for i := range fp.refs {
+		shiftedOrderHints[i] = curFrameHint + fp.relativeDist(fp.refs[i].orderHint, h.OrderHint)
+	}
+
+	// find returns the unused frame with the latest or earliest hint among
+	// the backward (at or after the current frame) or forward ones.
+	find := func(backward, latest bool) int {
+		ref, best := -1, int32(0)
+		for i, hint := range shiftedOrderHints {
+			if used[i] || (hint >= curFrameHint) != backward {
+				continue
+			}
+			if ref < 0 || latest && hint >= best || !latest && hint < best {
+				ref, best = i, hint
+			}
+		}
+		return ref
+	}
+	assign := func(refFrame, ref int) {
+		if ref >= 0 {
+			idx[refFrame-RefFrameLast] = ref
+			used[ref] = true
+		}
+	}
+	assign(RefFrameAltRef, find(true, true))
+	assign(RefFrameBwdRef, find(true, false))
+	assign(RefFrameAltRef2, find(true, false))
+	for _, refFrame := range []int{RefFrameLast2, RefFrameLast3, RefFrameBwdRef, RefFrameAltRef2, RefFrameAltRef} {
+		if idx[refFrame-RefFrameLast] < 0 {
+			assign(refFrame, find(false, true))
+		}
+	}
+
+	ref, earliest := -1, int32(0)
+	for i, hint := range shiftedOrderHints {
+		if ref < 0 || hint < earliest {
+			ref, earliest = i, hint
+		}
+	}
+	for i := range idx {
+		if idx[i] < 0 {
+			idx[i] = ref
+		}
+		h.RefFrameIdx[i] = byte(idx[i])
+	}
+}
+
+func (fp *frameParser) frameSize() {
+	s, seq, h := fp.s, fp.seq, fp.h
+	if h.FrameSizeOverrideFlag {
+		widthMinus1, heightMinus1 := uint32(0), uint32(0)
+		s.Bits(&widthMinus1, seq.FrameWidthBitsMinus1+1, "frame_width_minus_1")
+		s.Bits(&heightMinus1, seq.FrameHeightBitsMinus1+1, "frame_height_minus_1")
+		h.FrameWidth, h.FrameHeight = widthMinus1+1, heightMinus1+1
+	} else {
+		h.FrameWidth, h.FrameHeight = seq.MaxFrameWidth(), seq.MaxFrameHeight()
+	}
+	fp.superresParams()
+}
+
+func (fp *frameParser) superresParams() {
+	s, h := fp.s, fp.h
+	if fp.seq.EnableSuperres {
+		s.Flag(&h.UseSuperres, "use_superres")
+	}
+	if h.UseSuperres {
+		s.Byte(&h.CodedDenom, 3, "coded_denom")
+	}
+	h.UpscaledWidth = h.FrameWidth
+	denom := h.SuperresDenom()
+	h.FrameWidth = (h.UpscaledWidth*superresNum + denom/2) / denom
+}
+
+func (fp *frameParser) renderSize() {
+	s, h := fp.s, fp.h
+	s.Flag(&h.RenderAndFrameSizeDifferent, "render_and_frame_size_different")
+	if h.RenderAndFrameSizeDifferent {
+		widthMinus1, heightMinus1 := uint32(0), uint32(0)
+		s.Bits(&widthMinus1, 16, "render_width_minus_1")
+		s.Bits(&heightMinus1, 16, "render_height_minus_1")
+		h.RenderWidth, h.RenderHeight = widthMinus1+1, heightMinus1+1
+	} else {
+		h.RenderWidth, h.RenderHeight = h.UpscaledWidth, h.FrameHeight
+	}
+}
+
+func (fp *frameParser) frameSizeWithRefs() {
+	s, h := fp.s, fp.h
+	for i, idx := range h.RefFrameIdx {
+		foundRef := false
+		s.Flag(&foundRef, "found_ref")
+		if foundRef {
+			ref := &fp.refs[idx]
+			h.FoundRef = i
+			h.FrameWidth = ref.upscaledWidth
+			h.FrameHeight = ref.frameHeight
+			h.RenderWidth = ref.renderWidth
+			h.RenderHeight = ref.renderHeight
+			break
+		}
+	}
+	if h.FoundRef < 0 {
+		fp.frameSize()
+		fp.renderSize()
+	} else {
+		fp.superresParams()
+	}
+}
+
+// skipModeAllowed works out whether skip_mode_present is coded and, if so,
+// which frames skip mode uses.
+func (fp *frameParser) skipModeAllowed() bool {
+	h := fp.h
+	if h.FrameIsIntra() || !h.ReferenceSelect || !fp.seq.EnableOrderHint {
+		return false
+	}
+	forwardIdx, backwardIdx := -1, -1
+	forwardHint, backwardHint := uint32(0), uint32(0)
+	for i, idx := range h.RefFrameIdx {
+		refHint := fp.refs[idx].orderHint
+		if dist := fp.relativeDist(refHint, h.OrderHint); dist < 0 {
+			if forwardIdx < 0 || fp.relativeDist(refHint, forwardHint) > 0 {
+				forwardIdx, forwardHint = i, refHint
+			}
+		} else if dist > 0 {
+			if backwardIdx < 0 || fp.relativeDist(refHint, backwardHint) < 0 {
+				backwardIdx, backwardHint = i, refHint
+			}
+		}
+	}
+	if forwardIdx < 0 {
+		return false
+	}
+	if backwardIdx < 0 {
+		secondForwardHint := uint32(0)
+		for i, idx := range h.RefFrameIdx {
+			refHint := fp.refs[idx].orderHint
+			if fp.relativeDist(refHint, forwardHint) < 0 {
+				if backwardIdx < 0 || fp.relativeDist(refHint, secondForwardHint) > 0 {
+					backwardIdx, secondForwardHint = i, refHint
+				}
+			}
+		}
+		if backwardIdx < 0 {
+			return false
+		}
+	}
+	if backwardIdx < forwardIdx {
+		forwardIdx, backwardIdx = backwardIdx, forwardIdx
+	}
+	h.SkipModeFrame = [2]byte{byte(RefFrameLast + forwardIdx), byte(RefFrameLast + backwardIdx)}
+	return true
+}
+
+// updateRefs implements the reference frame update process for the
+// frames in refresh_frame_flags.
+func (fp *frameParser) updateRefs() {
+	h := fp.h
+	var ref refFrame
+	if h.ShowExistingFrame {
+		ref = fp.refs[h.FrameToShowMapIdx]
+	} else {
+		ref = refFrame{
+			valid:         true,
+			frameID:       h.CurrentFrameID,
+			frameType:     h.FrameType,
+			orderHint:     h.OrderHint,
+			upscaledWidth: h.UpscaledWidth,
+			frameWidth:    h.FrameWidth,
+			frameHeight:   h.FrameHeight,
+			renderWidth:   h.RenderWidth,
+			renderHeight:  h.RenderHeight,
+			segmentation:  h.Segmentation,
+			loopFilter:    h.LoopFilter,
+			globalMotion:  h.GlobalMotion,
+			filmGrain:     h.FilmGrain,
+		}
+	}
+	for i := range fp.refs {
+		if h.RefreshFrameFlags>>uint(i)&1 != 0 {
+			fp.refs[i] = ref
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package av1
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/stretchr/testify/assert"
+)
+
+func frameHeaderOBU(payload []byte) OBU {
+	return OBU{
+		Header:  OBUHeader{Type: OBUTypeFrameHeader, HasSizeField: true},
+		Payload: gobits.NewSectionByteAccessor(gobits.NewSliceByteAccessor(payload), 0, int64(len(payload))),
+	}
+}
+
+func TestParser_KeyFrame(t *testing.T) {
+	sc := NewScanner(gobits.NewSliceByteAccessor(temporalUnit()))
+	p := NewParser(nil)
+	var fh *FrameHeader
+	var tg *TileGroup
+	for sc.Next() {
+		obu := sc.OBU()
+		var err error
+		switch obu.Header.Type {
+		case OBUTypeSequenceHeader:
+			p.Sequence, err = ParseSequenceHeader(obu.Payload)
+		case OBUTypeFrameHeader:
+			fh, err = p.ParseFrameHeader(obu)
+		case OBUTypeTileGroup:
+			tg, err = ParseTileGroup(obu.Payload, fh)
+		}
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, sc.Err())
+
+	assert.Equal(t, byte(FrameTypeKey), fh.FrameType)
+	assert.True(t, fh.ShowFrame)
+	assert.True(t, fh.FrameIsIntra())
+	assert.Equal(t, byte(allFrames), fh.RefreshFrameFlags)
+	assert.Equal(t, byte(primaryRefNone), fh.PrimaryRefFrame)
+	assert.Equal(t, uint32(1920), fh.FrameWidth)
+	assert.Equal(t, uint32(1080), fh.FrameHeight)
+	assert.Equal(t, uint32(1920), fh.RenderWidth)
+	assert.Equal(t, uint32(480), fh.MiCols())
+	assert.Equal(t, uint32(270), fh.MiRows())
+	assert.Equal(t, TileInfo{
+		UniformTileSpacingFlag: true,
+		TileColsLog2:           1,
+		MiColStarts:            []uint32{0, 256, 480},
+		MiRowStarts:            []uint32{0, 270},
+		TileSizeBytesMinus1:    3,
+	}, fh.TileInfo)
+	assert.Equal(t, QuantizationParams{BaseQIdx: 100, DeltaQUAc: -3, DeltaQVAc: -3}, fh.Quantization)
+	assert.True(t, fh.DeltaQPresent)
+	assert.Equal(t, byte(1), fh.DeltaQRes)
+	assert.Equal(t, [4]byte{10, 8, 3, 4}, fh.LoopFilter.Level)
+	assert.True(t, fh.LoopFilter.DeltaEnabled)
+	assert.True(t, fh.LoopFilter.DeltaUpdate)
+	assert.Equal(t, [numRefFrames]int32{2, 0, 0, 0, -1, 0, -1, -1}, fh.LoopFilter.RefDeltas)
+	assert.Equal(t, CDEFParams{
+		DampingMinus3: 2,
+		Bits:          1,
+		YPriStrength:  []byte{5, 0},
+		YSecStrength:  []byte{4, 0},
+		UVPriStrength: []byte{2, 1},
+		UVSecStrength: []byte{1, 2},
+	}, fh.CDEF)
+	assert.Equal(t, LoopRestorationParams{
+		FrameRestorationType: [3]byte{RestoreSwitchable, RestoreNone, RestoreWiener},
+		UnitShift:            1,
+		UVShift:              1,
+		LoopRestorationSize:  [3]uint32{128, 64, 64},
+	}, fh.LoopRestoration)
+	assert.Equal(t, byte(TxModeSelect), fh.TxMode)
+	assert.Equal(t, int64(len(keyFrameHeader)), fh.HeaderLength)
+
+	assert.Equal(t, &TileGroup{TgEnd: 1, Tiles: []Tile{{Offset: 5, Size: 3}, {Offset: 8, Size: 2}}}, tg)
+
+	// An inter frame referring to the key frame through LAST inherits its
+	// loop filter deltas.
+	inter := []byte{0x30, 0x02, 0x00, 0x40, 0x00, 0x00, 0x72, 0x3c, 0x00, 0x00, 0x02, 0x01, 0x00, 0x01, 0xa8, 0x18, 0x10}
+	fh, err := p.ParseFrameHeader(frameHeaderOBU(inter))
+	assert.NoError(t, err)
+	assert.Equal(t, byte(FrameTypeInter), fh.FrameType)
+	assert.False(t, fh.FrameIsIntra())
+	assert.Equal(t, uint32(1), fh.OrderHint)
+	assert.Equal(t, byte(0), fh.PrimaryRefFrame)
+	assert.Equal(t, byte(0x01), fh.RefreshFrameFlags)
+	assert.Equal(t, [refsPerFrame]byte{}, fh.RefFrameIdx)
+	assert.Equal(t, -1, fh.FoundRef)
+	assert.Equal(t, uint32(1920), fh.FrameWidth)
+	assert.True(t, fh.AllowHighPrecisionMV)
+	assert.Equal(t, byte(InterpolationFilterSwitchable), fh.InterpolationFilter)
+	assert.True(t, fh.IsMotionModeSwitchable)
+	assert.Equal(t, byte(120), fh.Quantization.BaseQIdx)
+	assert.Equal(t, []uint32{0, 480}, fh.TileInfo.MiColStarts)
+	assert.Equal(t, [4]byte{}, fh.LoopFilter.Level)
+	assert.True(t, fh.LoopFilter.DeltaEnabled)
+	assert.False(t, fh.LoopFilter.DeltaUpdate)
+	assert.Equal(t, [numRefFrames]int32{2, 0, 0, 0, -1, 0, -1, -1}, fh.LoopFilter.RefDeltas)
+	assert.Equal(t, []byte{1}, fh.CDEF.YPriStrength)
+	assert.Equal(t, [3]byte{}, fh.LoopRestoration.FrameRestorationType)
+	assert.Equal(t, byte(TxModeLargest), fh.TxMode)
+	assert.True(t, fh.ReferenceSelect)
+	assert.False(t, fh.SkipModePresent)
+	assert.True(t, fh.AllowWarpedMotion)
+	assert.Equal(t, GlobalMotion{Type: GMTypeTranslation, Params: [6]int32{0, -16384, 1 << 16, 0, 0, 1 << 16}}, fh.GlobalMotion[RefFrameLast])
+	assert.Equal(t, defaultGlobalMotion(), fh.GlobalMotion[RefFrameGolden])
+
+	// show_existing_frame of the key frame, still held in slot 1, resets
+	// the refresh of all slots.
+	fh, err = p.ParseFrameHeader(frameHeaderOBU([]byte{0x98}))
+	assert.NoError(t, err)
+	assert.True(t, fh.ShowExistingFrame)
+	assert.Equal(t, byte(1), fh.FrameToShowMapIdx)
+	assert.Equal(t, byte(FrameTypeKey), fh.FrameType)
+	assert.Equal(t, byte(allFrames), fh.RefreshFrameFlags)
+	assert.Equal(t, uint32(1920), fh.FrameWidth)
+	assert.Equal(t, int64(1), fh.HeaderLength)
+}
+
+func TestParser_Errors(t *testing.T) {
+	_, err := NewParser(nil).ParseFrameHeader(frameHeaderOBU(keyFrameHeader))
+	var fe *gobits.FieldError
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "sequence_header_obu", fe.Field)
+
+	sh, err := ParseSequenceHeader(gobits.NewSliceByteAccessor(sequenceHeader1080p))
+	assert.NoError(t, err)
+	p := NewParser(sh)
+	obu := frameHeaderOBU(keyFrameHeader)
+	obu.Header.Type = OBUTypeTileGroup
+	_, err = p.ParseFrameHeader(obu)
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "obu_type", fe.Field)
+
+	_, err = p.ParseFrameHeader(frameHeaderOBU(keyFrameHeader[:8]))
+	assert.True(t, errors.Is(err, gobits.ErrUnexpectedEOF))
+
+	// Without a key frame the reference frames of an inter frame are
+	// invalid.
+	inter := []byte{0x30, 0x02, 0x00, 0x40, 0x00, 0x00, 0x72, 0x3c, 0x00, 0x00, 0x02, 0x01, 0x00, 0x01, 0xa8, 0x18, 0x10}
+	_, err = p.ParseFrameHeader(frameHeaderOBU(inter))
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "ref_frame_idx", fe.Field)
+	assert.True(t, errors.Is(err, gobits.ErrInvalidSyntax))
+}
@@ -0,0 +1,117 @@
+package av1
+
+import (
+	"encoding/binary"
+
+	"github.com/ibbbpbbbp/gobits"
+)
+
+const (
+	ivfFrameHeaderLength = 12
+)
+
+// IVFHeader is the file header of an IVF file.
+type IVFHeader struct {
+	Signature  [4]byte
+	Version    uint16
+	HeaderSize uint16
+	FourCC     [4]byte
+	Width      uint16
+	Height     uint16
+	// TimebaseDenominator and TimebaseNumerator give the unit of the frame
+	// timestamps, TimebaseNumerator/TimebaseDenominator seconds.
+	TimebaseDenominator uint32
+	TimebaseNumerator   uint32
+	FrameCount          uint32
+}
+
+// IVFFrame is a frame found by an IVFScanner. Data covers the frame
+// payload, the OBUs of one temporal unit for AV1, and shares the scanned
+// accessor.
+type IVFFrame struct {
+	Offset    int64
+	Timestamp uint64
+	Data      *gobits.SectionByteAccessor
+}
+
+// IVFScanner iterates over the frames of an IVF file. Call Next until it
+// returns false, then check Err.
+type IVFScanner struct {
+	ba     gobits.ByteAccessor
+	header IVFHeader
+	offset int64
+	frame  IVFFrame
+	err    error
+}
+
+func (sc *IVFScanner) readHeader() error {
+	r := gobits.NewSyntaxReader(gobits.NewBitStream(sc.ba))
+	h := &sc.header
+	for i := range h.Signature {
+		h.Signature[i] = r.ReadUint8("signature")
+	}
+	if r.Err() == nil && string(h.Signature[:]) != "DKIF" {
+		r.Fail("signature", gobits.ErrInvalidSyntax)
+	}
+	h.Version = r.ReadUint16(binary.LittleEndian, "version")
+	h.HeaderSize = r.ReadUint16(binary.LittleEndian, "header_size")
+	for i := range h.FourCC {
+		h.FourCC[i] = r.ReadUint8("fourcc")
+	}
+	h.Width = r.ReadUint16(binary.LittleEndian, "width")
+	h.Height = r.ReadUint16(binary.LittleEndian, "height")
+	h.TimebaseDenominator = r.ReadUint32(binary.LittleEndian, "timebase_denominator")
+	h.TimebaseNumerator = r.ReadUint32(binary.LittleEndian, "timebase_numerator")
+	h.FrameCount = r.ReadUint32(binary.LittleEndian, "frame_count")
+	if r.Err() == nil && sc.ba.Length() < int64(h.HeaderSize) {
+		r.Fail("header_size", gobits.ErrUnexpectedEOF)
+	}
+	sc.offset = int64(h.HeaderSize)
+	return r.Err()
+}
+
+// Next advances to the next frame. It returns false at the end of the
+// file or on error.
+func (sc *IVFScanner) Next() bool {
+	if sc.err != nil || sc.offset >= sc.ba.Length() {
+		return false
+	}
+	bs := gobits.NewBitStream(gobits.NewSectionByteAccessor(sc.ba, sc.offset, ivfFrameHeaderLength))
+	r := gobits.NewSyntaxReader(bs)
+	size := int64(r.ReadUint32(binary.LittleEndian, "frame_size"))
+	timestamp := r.ReadUint64(binary.LittleEndian, "timestamp")
+	if r.Err() == nil && sc.ba.Length()-sc.offset-ivfFrameHeaderLength < size {
+		r.Fail("frame_size", gobits.ErrUnexpectedEOF)
+	}
+	if r.Err() != nil {
+		sc.err = r.Err()
+		return false
+	}
+
+	data := gobits.NewSectionByteAccessor(sc.ba, sc.offset+ivfFrameHeaderLength, size)
+	sc.frame = IVFFrame{Offset: sc.offset, Timestamp: timestamp, Data: data}
+	sc.offset += ivfFrameHeaderLength + size
+	return true
+}
+
+func (sc *IVFScanner) Header() IVFHeader {
+	return sc.header
+}
+
+// Frame returns the frame found by the last successful call to Next.
+func (sc *IVFScanner) Frame() IVFFrame {
+	return sc.frame
+}
+
+func (sc *IVFScanner) Err() error {
+	return sc.err
+}
+
+// NewIVFScanner reads the file header at the start of ba and returns a
+// scanner for the frames that follow. A malformed header is reported by
+// Err.
+func NewIVFScanner(ba gobits.ByteAccessor) *IVFScanner {
+	sc := &IVFScanner{ba: ba}
+	sc.err = sc.readHeader()
+	return sc
+}
@@ -0,0 +1,122 @@
+package av1
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+)
+
+// OBUHeader is an obu_header(). TemporalID and SpatialID are only coded
+// when ExtensionFlag is set.
+type OBUHeader struct {
+	ForbiddenBit  bool
+	Type          byte
+	ExtensionFlag bool
+	HasSizeField  bool
+	ReservedBit   bool
+	TemporalID    byte
+	SpatialID     byte
+	// ExtensionReserved3Bits is extension_header_reserved_3bits.
+	ExtensionReserved3Bits byte
+}
+
+func (h *OBUHeader) syntax(s *gobits.Syntax) {
+	s.Flag(&h.ForbiddenBit, "obu_forbidden_bit")
+	s.Byte(&h.Type, 4, "obu_type")
+	s.Flag(&h.ExtensionFlag, "obu_extension_flag")
+	s.Flag(&h.HasSizeField, "obu_has_size_field")
+	s.Flag(&h.ReservedBit, "obu_reserved_1bit")
+	if h.ExtensionFlag {
+		s.Byte(&h.TemporalID, 3, "temporal_id")
+		s.Byte(&h.SpatialID, 2, "spatial_id")
+		s.Byte(&h.ExtensionReserved3Bits, 3, "extension_header_reserved_3bits")
+	}
+}
+
+// Marshal encodes an OBU with the header and payload. When HasSizeField is
+// set, obu_size is written in its shortest form.
+func (h OBUHeader) Marshal(payload []byte) ([]byte, error) {
+	ba := gobits.NewGrowableByteAccessor(nil)
+	s := gobits.NewWritingSyntax(gobits.NewBitStream(ba))
+	h.syntax(s)
+	if h.HasSizeField {
+		size := uint64(len(payload))
+		leb128(s, &size, "obu_size")
+	}
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	if len(payload) > 0 && !ba.Put(payload, ba.Length()) {
+		return nil, gobits.ErrInvalidOffset
+	}
+	return ba.Bytes(), nil
+}
+
+// OBU is an OBU found by a Scanner. Payload covers the obu_size bytes after
+// the header and size field and shares the scanned accessor.
+type OBU struct {
+	// Offset is the position of the first header byte in the stream.
+	Offset  int64
+	Header  OBUHeader
+	Payload *gobits.SectionByteAccessor
+}
+
+// Length returns the length of the whole OBU, header included.
+func (o OBU) Length() int64 {
+	return o.Payload.Offset() + o.Payload.Length() - o.Offset
+}
+
+// Scanner iterates over a sequence of OBUs in the low overhead bitstream
+// format, as stored in .obu files and in the samples of IVF, MP4 and
+// Matroska files. Call Next until it returns false, then check Err.
+type Scanner struct {
+	ba     gobits.ByteAccessor
+	offset int64
+	obu    OBU
+	err    error
+}
+
+// Next advances to the next OBU. It returns false at the end of the data
+// or on error. An OBU without obu_size extends to the end of the data.
+func (sc *Scanner) Next() bool {
+	if sc.err != nil || sc.offset >= sc.ba.Length() {
+		return false
+	}
+
+	rest := gobits.NewSectionByteAccessor(sc.ba, sc.offset, sc.ba.Length()-sc.offset)
+	bs := gobits.NewBitStream(rest)
+	s := gobits.NewReadingSyntax(bs)
+	header := OBUHeader{}
+	header.syntax(s)
+	size := uint64(0)
+	if header.HasSizeField {
+		leb128(s, &size, "obu_size")
+	}
+	if s.Err() != nil {
+		sc.err = s.Err()
+		return false
+	}
+	start := bs.Tell() / 8
+	if !header.HasSizeField {
+		size = uint64(rest.Length() - start)
+	} else if uint64(rest.Length()-start) < size {
+		sc.err = &gobits.FieldError{Field: "obu_size", Err: gobits.ErrUnexpectedEOF}
+		return false
+	}
+
+	payload := gobits.NewSectionByteAccessor(sc.ba, sc.offset+start, int64(size))
+	sc.obu = OBU{Offset: sc.offset, Header: header, Payload: payload}
+	sc.offset += start + int64(size)
+	return true
+}
+
+// OBU returns the OBU found by the last successful call to Next.
+func (sc *Scanner) OBU() OBU {
+	return sc.obu
+}
+
+func (sc *Scanner) Err() error {
+	return sc.err
+}
+
+func NewScanner(ba gobits.ByteAccessor) *Scanner {
+	return &Scanner{ba: ba}
+}
@@ -0,0 +1,577 @@
+package av1
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+)
+
+const (
+	maxSegments            = 8
+	segLvlMax              = 8
+	segLvlAltQ             = 0
+	maxLoopFilter          = 63
+	restorationTileSizeMax = 256
+
+	warpedModelPrecBits = 16
+	gmAbsTransBits      = 12
+	gmAbsTransOnlyBits  = 9
+	gmAbsAlphaBits      = 12
+	gmAlphaPrecBits     = 15
+	gmTransPrecBits     = 6
+	gmTransOnlyPrecBits = 3
+)
+
+// Global motion types.
+const (
+	GMTypeIdentity    = 0
+	GMTypeTranslation = 1
+	GMTypeRotZoom     = 2
+	GMTypeAffine      = 3
+)
+
+// Loop restoration types, the values of FrameRestorationType.
+const (
+	RestoreNone       = 0
+	RestoreWiener     = 1
+	RestoreSgrproj    = 2
+	RestoreSwitchable = 3
+)
+
+var (
+	segmentationFeatureBits   = [segLvlMax]byte{8, 6, 6, 6, 6, 3, 0, 0}
+	segmentationFeatureSigned = [segLvlMax]bool{true, true, true, true, true, false, false, false}
+	segmentationFeatureMax    = [segLvlMax]int32{255, maxLoopFilter, maxLoopFilter, maxLoopFilter, maxLoopFilter, 7, 0, 0}
+
+	defaultLoopFilterRefDeltas = [numRefFrames]int32{1, 0, 0, 0, -1, 0, -1, -1}
+
+	remapLRType = [4]byte{RestoreNone, RestoreSwitchable, RestoreWiener, RestoreSgrproj}
+)
+
+func clip3(low, high, v int32) int32 {
+	if v < low {
+		return low
+	}
+	if v > high {
+		return high
+	}
+	return v
+}
+
+// QuantizationParams is a quantization_params(). The V deltas equal the U
+// deltas unless DiffUVDelta is set.
+type QuantizationParams struct {
+	BaseQIdx     byte
+	DeltaQYDc    int32
+	DiffUVDelta  bool
+	DeltaQUDc    int32
+	DeltaQUAc    int32
+	DeltaQVDc    int32
+	DeltaQVAc    int32
+	UsingQmatrix bool
+	QmY          byte
+	QmU          byte
+	QmV          byte
+}
+
+func readDeltaQ(s *gobits.Syntax, delta *int32, field string) {
+	deltaCoded := false
+	s.Flag(&deltaCoded, "delta_coded")
+	if deltaCoded {
+		s.SignedBits(delta, 7, field)
+	}
+}
+
+func (fp *frameParser) quantizationParams() {
+	s, q, cc := fp.s, &fp.h.Quantization, &fp.seq.ColorConfig
+	s.Byte(&q.BaseQIdx, 8, "base_q_idx")
+	readDeltaQ(s, &q.DeltaQYDc, "delta_q_y_dc")
+	if cc.NumPlanes() > 1 {
+		if cc.SeparateUVDeltaQ {
+			s.Flag(&q.DiffUVDelta, "diff_uv_delta")
+		}
+		readDeltaQ(s, &q.DeltaQUDc, "delta_q_u_dc")
+		readDeltaQ(s, &q.DeltaQUAc, "delta_q_u_ac")
+		if q.DiffUVDelta {
+			readDeltaQ(s, &q.DeltaQVDc, "delta_q_v_dc")
+			readDeltaQ(s, &q.DeltaQVAc, "delta_q_v_ac")
+		} else {
+			q.DeltaQVDc, q.DeltaQVAc = q.DeltaQUDc, q.DeltaQUAc
+		}
+	}
+	s.Flag(&q.UsingQmatrix, "using_qmatrix")
+	if q.UsingQmatrix {
+		s.Byte(&q.QmY, 4, "qm_y")
+		s.Byte(&q.QmU, 4, "qm_u")
+		if cc.SeparateUVDeltaQ {
+			s.Byte(&q.QmV, 4, "qm_v")
+		} else {
+			q.QmV = q.QmU
+		}
+	}
+}
+
+// SegmentationParams is a segmentation_params(). When the feature values
+// are not updated they are those of the primary reference frame.
+type SegmentationParams struct {
+	SegmentationEnabled        bool
+	SegmentationUpdateMap      bool
+	SegmentationTemporalUpdate bool
+	SegmentationUpdateData     bool
+	FeatureEnabled             [maxSegments][segLvlMax]bool
+	FeatureData                [maxSegments][segLvlMax]int32
+}
+
+func (fp *frameParser) segmentationParams(prev *refFrame) {
+	s, g := fp.s, &fp.h.Segmentation
+	s.Flag(&g.SegmentationEnabled, "segmentation_enabled")
+	if !g.SegmentationEnabled {
+		return
+	}
+	if fp.h.PrimaryRefFrame == primaryRefNone {
+		g.SegmentationUpdateMap = true
+		g.SegmentationUpdateData = true
+	} else {
+		s.Flag(&g.SegmentationUpdateMap, "segmentation_update_map")
+		if g.SegmentationUpdateMap {
+			s.Flag(&g.SegmentationTemporalUpdate, "segmentation_temporal_update")
+		}
+		s.Flag(&g.SegmentationUpdateData, "segmentation_update_data")
+	}
+	if !g.SegmentationUpdateData {
+		g.FeatureEnabled = prev.segmentation.FeatureEnabled
+		g.FeatureData = prev.segmentation.FeatureData
+		return
+	}
+	for i := range g.FeatureEnabled {
+		for j := range g.FeatureEnabled[i] {
+			s.Flag(&g.FeatureEnabled[i][j], "feature_enabled")
+			if !g.FeatureEnabled[i][j] {
+				continue
+			}
+			limit := segmentationFeatureMax[j]
+			if segmentationFeatureSigned[j] {
+				value := int32(0)
+				s.SignedBits(&value, 1+segmentationFeatureBits[j], "feature_value")
+				g.FeatureData[i][j] = clip3(-limit, limit, value)
+			} else {
+				value := uint32(0)
+				s.Bits(&value, segmentationFeatureBits[j], "feature_value")
+				g.FeatureData[i][j] = clip3(0, limit, int32(value))
+			}
+		}
+	}
+}
+
+// qindex implements get_qindex() for the frame, ignoring delta_q.
+func (fp *frameParser) qindex(segmentID int) int32 {
+	g, base := &fp.h.Segmentation, int32(fp.h.Quantization.BaseQIdx)
+	if g.SegmentationEnabled && g.FeatureEnabled[segmentID][segLvlAltQ] {
+		return clip3(0, 255, base+g.FeatureData[segmentID][segLvlAltQ])
+	}
+	return base
+}
+
+func (fp *frameParser) deltaParams() {
+	s, h := fp.s, fp.h
+	if h.Quantization.BaseQIdx > 0 {
+		s.Flag(&h.DeltaQPresent, "delta_q_present")
+	}
+	if !h.DeltaQPresent {
+		return
+	}
+	s.Byte(&h.DeltaQRes, 2, "delta_q_res")
+	if !h.AllowIntraBC {
+		s.Flag(&h.DeltaLFPresent, "delta_lf_present")
+	}
+	if h.DeltaLFPresent {
+		s.Byte(&h.DeltaLFRes, 2, "delta_lf_res")
+		s.Flag(&h.DeltaLFMulti, "delta_lf_multi")
+	}
+}
+
+// lossless derives CodedLossless and AllLossless.
+func (fp *frameParser) lossless() {
+	h, q := fp.h, &fp.h.Quantization
+	h.CodedLossless = q.DeltaQYDc == 0 && q.DeltaQUDc == 0 && q.DeltaQUAc == 0 &&
+		q.DeltaQVDc == 0 && q.DeltaQVAc == 0
+	for segmentID := 0; segmentID < maxSegments && h.CodedLossless; segmentID++ {
+		h.CodedLossless = fp.qindex(segmentID) == 0
+	}
+	h.AllLossless = h.CodedLossless && h.FrameWidth == h.UpscaledWidth
+}
+
+// LoopFilterParams is a loop_filter_params(). Deltas that are not updated
+// are those of the primary reference frame.
+type LoopFilterParams struct {
+	// Level holds loop_filter_level[0] to [3]: vertical and horizontal
+	// luma, then U and V.
+	Level        [4]byte
+	Sharpness    byte
+	DeltaEnabled bool
+	DeltaUpdate  bool
+	RefDeltas    [numRefFrames]int32
+	ModeDeltas   [2]int32
+}
+
+func (fp *frameParser) loopFilterParams(prev *refFrame) {
+	s, h, lf := fp.s, fp.h, &fp.h.LoopFilter
+	if h.CodedLossless || h.AllowIntraBC {
+		lf.RefDeltas = defaultLoopFilterRefDeltas
+		return
+	}
+	lf.RefDeltas = prev.loopFilter.RefDeltas
+	lf.ModeDeltas = prev.loopFilter.ModeDeltas
+	s.Byte(&lf.Level[0], 6, "loop_filter_level")
+	s.Byte(&lf.Level[1], 6, "loop_filter_level")
+	if fp.seq.ColorConfig.NumPlanes() > 1 && (lf.Level[0] != 0 || lf.Level[1] != 0) {
+		s.Byte(&lf.Level[2], 6, "loop_filter_level")
+		s.Byte(&lf.Level[3], 6, "loop_filter_level")
+	}
+	s.Byte(&lf.Sharpness, 3, "loop_filter_sharpness")
+	s.Flag(&lf.DeltaEnabled, "loop_filter_delta_enabled")
+	if lf.DeltaEnabled {
+		s.Flag(&lf.DeltaUpdate, "loop_filter_delta_update")
+	}
+	if !lf.DeltaUpdate {
+		return
+	}
+	for i := range lf.RefDeltas {
+		update := false
+		s.Flag(&update, "update_ref_delta")
+		if update {
+			s.SignedBits(&lf.RefDeltas[i], 7, "loop_filter_ref_deltas")
+		}
+	}
+	for i := range lf.ModeDeltas {
+		update := false
+		s.Flag(&update, "update_mode_delta")
+		if update {
+			s.SignedBits(&lf.ModeDeltas[i], 7, "loop_filter_mode_deltas")
+		}
+	}
+}
+
+// CDEFParams is a cdef_params(). The strength lists have 1 << Bits
+// entries, and secondary strengths of 3 are stored as 4.
+type CDEFParams struct {
+	DampingMinus3 byte
+	Bits          byte
+	YPriStrength  []byte
+	YSecStrength  []byte
+	UVPriStrength []byte
+	UVSecStrength []byte
+}
+
+func (fp *frameParser) cdefParams() {
+	s, h, c := fp.s, fp.h, &fp.h.CDEF
+	coded := !h.CodedLossless && !h.AllowIntraBC && fp.seq.EnableCDEF
+	if coded {
+		s.Byte(&c.DampingMinus3, 2, "cdef_damping_minus_3")
+		s.Byte(&c.Bits, 2, "cdef_bits")
+	}
+	n := 1 << c.Bits
+	c.YPriStrength, c.YSecStrength = make([]byte, n), make([]byte, n)
+	c.UVPriStrength, c.UVSecStrength = make([]byte, n), make([]byte, n)
+	if !coded {
+		return
+	}
+	for i := 0; i < n; i++ {
+		s.Byte(&c.YPriStrength[i], 4, "cdef_y_pri_strength")
+		s.Byte(&c.YSecStrength[i], 2, "cdef_y_sec_strength")
+		if c.YSecStrength[i] == 3 {
+			c.YSecStrength[i]++
+		}
+		if fp.seq.ColorConfig.NumPlanes() > 1 {
+			s.Byte(&c.UVPriStrength[i], 4, "cdef_uv_pri_strength")
+			s.Byte(&c.UVSecStrength[i], 2, "cdef_uv_sec_strength")
+			if c.UVSecStrength[i] == 3 {
+				c.UVSecStrength[i]++
+			}
+		}
+	}
+}
+
+// LoopRestorationParams is an lr_params(). LoopRestorationSize is only
+// set when a plane uses loop restoration.
+type LoopRestorationParams struct {
+	FrameRestorationType [3]byte
+	UnitShift            byte
+	UVShift              byte
+	LoopRestorationSize  [3]uint32
+}
+
+func (fp *frameParser) lrParams() {
+	s, h, lr, cc := fp.s, fp.h, &fp.h.LoopRestoration, &fp.seq.ColorConfig
+	if h.AllLossless || h.AllowIntraBC || !fp.seq.EnableRestoration {
+		return
+	}
+	usesLR, usesChromaLR := false, false
+	for i := 0; i < cc.NumPlanes(); i++ {
+		lrType := byte(0)
+		s.Byte(&lrType, 2, "lr_type")
+		lr.FrameRestorationType[i] = remapLRType[lrType]
+		if lr.FrameRestorationType[i] != RestoreNone {
+			usesLR = true
+			usesChromaLR = usesChromaLR || i > 0
+		}
+	}
+	if !usesLR {
+		return
+	}
+	s.Byte(&lr.UnitShift, 1, "lr_unit_shift")
+	if fp.seq.Use128x128Superblock {
+		lr.UnitShift++
+	} else if lr.UnitShift != 0 {
+		extraShift := byte(0)
+		s.Byte(&extraShift, 1, "lr_unit_extra_shift")
+		lr.UnitShift += extraShift
+	}
+	if cc.SubsamplingX && cc.SubsamplingY && usesChromaLR {
+		s.Byte(&lr.UVShift, 1, "lr_uv_shift")
+	}
+	size := uint32(restorationTileSizeMax) >> (2 - lr.UnitShift)
+	lr.LoopRestorationSize = [3]uint32{size, size >> lr.UVShift, size >> lr.UVShift}
+}
+
+// GlobalMotion holds the global motion type and warp parameters of a
+// reference frame.
+type GlobalMotion struct {
+	Type   byte
+	Params [6]int32
+}
+
+func defaultGlobalMotion() GlobalMotion {
+	return GlobalMotion{Params: [6]int32{0, 0, 1 << warpedModelPrecBits, 0, 0, 1 << warpedModelPrecBits}}
+}
+
+func inverseRecenter(r, v int32) int32 {
+	switch {
+	case v > 2*r:
+		return v
+	case v&1 != 0:
+		return r - (v+1)>>1
+	}
+	return r + v>>1
+}
+
+// decodeSubexp implements decode_subexp().
+func (fp *frameParser) decodeSubexp(numSyms int32) int32 {
+	s := fp.s
+	i, mk, k := byte(0), int32(0), byte(3)
+	for s.Err() == nil {
+		b2 := k
+		if i > 0 {
+			b2 = k + i - 1
+		}
+		a := int32(1) << b2
+		if numSyms <= mk+3*a {
+			finalBits := uint32(0)
+			ns(s, &finalBits, uint32(numSyms-mk), "subexp_final_bits")
+			return int32(finalBits) + mk
+		}
+		moreBits := false
+		s.Flag(&moreBits, "subexp_more_bits")
+		if !moreBits {
+			bits := uint32(0)
+			s.Bits(&bits, b2, "subexp_bits")
+			return int32(bits) + mk
+		}
+		i++
+		mk += a
+	}
+	return 0
+}
+
+// decodeSignedSubexpWithRef implements decode_signed_subexp_with_ref().
+func (fp *frameParser) decodeSignedSubexpWithRef(low, high, r int32) int32 {
+	mx, r := high-low, r-low
+	v := fp.decodeSubexp(mx)
+	if r<<1 <= mx {
+		return inverseRecenter(r, v) + low
+	}
+	return mx - 1 - inverseRecenter(mx-1-r, v) + low
+}
+
+func (fp *frameParser) readGlobalParam(gm *GlobalMotion, prev *GlobalMotion, idx int) {
+	absBits, precBits := byte(gmAbsAlphaBits), byte(gmAlphaPrecBits)
+	if idx < 2 {
+		if gm.Type == GMTypeTranslation {
+			lowPrecision := byte(0)
+			if !fp.h.AllowHighPrecisionMV {
+				lowPrecision = 1
+			}
+			absBits, precBits = gmAbsTransOnlyBits-lowPrecision, gmTransOnlyPrecBits-lowPrecision
+		} else {
+			absBits, precBits = gmAbsTransBits, gmTransPrecBits
+		}
+	}
+	precDiff := warpedModelPrecBits - precBits
+	round, sub := int32(0), int32(0)
+	if idx%3 == 2 {
+		round, sub = 1<<warpedModelPrecBits, 1<<precBits
+	}
+	mx := int32(1) << absBits
+	r := prev.Params[idx]>>precDiff - sub
+	gm.Params[idx] = fp.decodeSignedSubexpWithRef(-mx, mx+1, r)<<precDiff + round
+}
+
+func (fp *frameParser) globalMotionParams(prev *refFrame) {
+	s, h := fp.s, fp.h
+	for ref := range h.GlobalMotion {
+		h.GlobalMotion[ref] = defaultGlobalMotion()
+	}
+	if h.FrameIsIntra() {
+		return
+	}
+	for ref := RefFrameLast; ref <= RefFrameAltRef; ref++ {
+		gm, prevGM := &h.GlobalMotion[ref], &prev.globalMotion[ref]
+		isGlobal := false
+		s.Flag(&isGlobal, "is_global")
+		if isGlobal {
+			isRotZoom := false
+			s.Flag(&isRotZoom, "is_rot_zoom")
+			if isRotZoom {
+				gm.Type = GMTypeRotZoom
+			} else {
+				isTranslation := false
+				s.Flag(&isTranslation, "is_translation")
+				gm.Type = GMTypeAffine
+				if isTranslation {
+					gm.Type = GMTypeTranslation
+				}
+			}
+		}
+		if gm.Type >= GMTypeRotZoom {
+			fp.readGlobalParam(gm, prevGM, 2)
+			fp.readGlobalParam(gm, prevGM, 3)
+			if gm.Type == GMTypeAffine {
+				fp.readGlobalParam(gm, prevGM, 4)
+				fp.readGlobalParam(gm, prevGM, 5)
+			} else {
+				gm.Params[4] = -gm.Params[3]
+				gm.Params[5] = gm.Params[2]
+			}
+		}
+		if gm.Type >= GMTypeTranslation {
+			fp.readGlobalParam(gm, prevGM, 0)
+			fp.readGlobalParam(gm, prevGM, 1)
+		}
+	}
+}
+
+// ScalingPoint is a point of a film grain scaling function.
+type ScalingPoint struct {
+	Value   byte
+	Scaling byte
+}
+
+// FilmGrainParams is a film_grain_params(). When UpdateGrain is clear the
+// parameters are those of reference frame FilmGrainParamsRefIdx, with
+// GrainSeed replaced.
+type FilmGrainParams struct {
+	ApplyGrain            bool
+	GrainSeed             uint32
+	UpdateGrain           bool
+	FilmGrainParamsRefIdx byte
+	PointY                []ScalingPoint
+	ChromaScalingFromLuma bool
+	PointCb               []ScalingPoint
+	PointCr               []ScalingPoint
+	GrainScalingMinus8    byte
+	ArCoeffLag            byte
+	ArCoeffsYPlus128      []byte
+	ArCoeffsCbPlus128     []byte
+	ArCoeffsCrPlus128     []byte
+	ArCoeffShiftMinus6    byte
+	GrainScaleShift       byte
+	CbMult                byte
+	CbLumaMult            byte
+	CbOffset              uint32
+	CrMult                byte
+	CrLumaMult            byte
+	CrOffset              uint32
+	OverlapFlag           bool
+	ClipToRestrictedRange bool
+}
+
+func scalingPoints(s *gobits.Syntax, points *[]ScalingPoint, component string) {
+	n := byte(0)
+	s.Byte(&n, 4, "num_"+component+"_points")
+	*points = make([]ScalingPoint, n)
+	for i := range *points {
+		s.Byte(&(*points)[i].Value, 8, "point_"+component+"_value")
+		s.Byte(&(*points)[i].Scaling, 8, "point_"+component+"_scaling")
+	}
+}
+
+func arCoeffs(s *gobits.Syntax, coeffs *[]byte, n int, field string) {
+	*coeffs = make([]byte, n)
+	for i := range *coeffs {
+		s.Byte(&(*coeffs)[i], 8, field)
+	}
+}
+
+func (fp *frameParser) filmGrainParams() {
+	s, h, g, cc := fp.s, fp.h, &fp.h.FilmGrain, &fp.seq.ColorConfig
+	if !fp.seq.FilmGrainParamsPresent || !h.ShowFrame && !h.ShowableFrame {
+		return
+	}
+	s.Flag(&g.ApplyGrain, "apply_grain")
+	if !g.ApplyGrain {
+		return
+	}
+	s.Bits(&g.GrainSeed, 16, "grain_seed")
+	if h.FrameType == FrameTypeInter {
+		s.Flag(&g.UpdateGrain, "update_grain")
+	} else {
+		g.UpdateGrain = true
+	}
+	if !g.UpdateGrain {
+		s.Byte(&g.FilmGrainParamsRefIdx, 3, "film_grain_params_ref_idx")
+		loaded := fp.refs[g.FilmGrainParamsRefIdx].filmGrain
+		loaded.ApplyGrain = true
+		loaded.GrainSeed = g.GrainSeed
+		loaded.UpdateGrain = false
+		loaded.FilmGrainParamsRefIdx = g.FilmGrainParamsRefIdx
+		*g = loaded
+		return
+	}
+
+	scalingPoints(s, &g.PointY, "y")
+	if !cc.MonoChrome {
+		s.Flag(&g.ChromaScalingFromLuma, "chroma_scaling_from_luma")
+	}
+	if cc.MonoChrome || g.ChromaScalingFromLuma || cc.SubsamplingX && cc.SubsamplingY && len(g.PointY) == 0 {
+		g.PointCb, g.PointCr = []ScalingPoint{}, []ScalingPoint{}
+	} else {
+		scalingPoints(s, &g.PointCb, "cb")
+		scalingPoints(s, &g.PointCr, "cr")
+	}
+	s.Byte(&g.GrainScalingMinus8, 2, "grain_scaling_minus_8")
+	s.Byte(&g.ArCoeffLag, 2, "ar_coeff_lag")
+	numPosLuma := 2 * int(g.ArCoeffLag) * (int(g.ArCoeffLag) + 1)
+	numPosChroma := numPosLuma
+	if len(g.PointY) > 0 {
+		numPosChroma++
+		arCoeffs(s, &g.ArCoeffsYPlus128, numPosLuma, "ar_coeffs_y_plus_128")
+	}
+	if g.ChromaScalingFromLuma || len(g.PointCb) > 0 {
+		arCoeffs(s, &g.ArCoeffsCbPlus128, numPosChroma, "ar_coeffs_cb_plus_128")
+	}
+	if g.ChromaScalingFromLuma || len(g.PointCr) > 0 {
+		arCoeffs(s, &g.ArCoeffsCrPlus128, numPosChroma, "ar_coeffs_cr_plus_128")
+	}
+	s.Byte(&g.ArCoeffShiftMinus6, 2, "ar_coeff_shift_minus_6")
+	s.Byte(&g.GrainScaleShift, 2, "grain_scale_shift")
+	if len(g.PointCb) > 0 {
+		s.Byte(&g.CbMult, 8, "cb_mult")
+		s.Byte(&g.CbLumaMult, 8, "cb_luma_mult")
+		s.Bits(&g.CbOffset, 9, "cb_offset")
+	}
+	if len(g.PointCr) > 0 {
+		s.Byte(&g.CrMult, 8, "cr_mult")
+		s.Byte(&g.CrLumaMult, 8, "cr_luma_mult")
+		s.Bits(&g.CrOffset, 9, "cr_offset")
+	}
+	s.Flag(&g.OverlapFlag, "overlap_flag")
+	s.Flag(&g.ClipToRestrictedRange, "clip_to_restricted_range")
+}
@@ -0,0 +1,355 @@
+package av1
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+)
+
+// TimingInfo is a timing_info().
+type TimingInfo struct {
+	NumUnitsInDisplayTick    uint32
+	TimeScale                uint32
+	EqualPictureInterval     bool
+	NumTicksPerPictureMinus1 uint32
+}
+
+func (t *TimingInfo) syntax(s *gobits.Syntax) {
+	s.Bits(&t.NumUnitsInDisplayTick, 32, "num_units_in_display_tick")
+	s.Bits(&t.TimeScale, 32, "time_scale")
+	s.Flag(&t.EqualPictureInterval, "equal_picture_interval")
+	if t.EqualPictureInterval {
+		uvlc(s, &t.NumTicksPerPictureMinus1, "num_ticks_per_picture_minus_1")
+	}
+}
+
+// DecoderModelInfo is a decoder_model_info().
+type DecoderModelInfo struct {
+	BufferDelayLengthMinus1           byte
+	NumUnitsInDecodingTick            uint32
+	BufferRemovalTimeLengthMinus1     byte
+	FramePresentationTimeLengthMinus1 byte
+}
+
+func (d *DecoderModelInfo) syntax(s *gobits.Syntax) {
+	s.Byte(&d.BufferDelayLengthMinus1, 5, "buffer_delay_length_minus_1")
+	s.Bits(&d.NumUnitsInDecodingTick, 32, "num_units_in_decoding_tick")
+	s.Byte(&d.BufferRemovalTimeLengthMinus1, 5, "buffer_removal_time_length_minus_1")
+	s.Byte(&d.FramePresentationTimeLengthMinus1, 5, "frame_presentation_time_length_minus_1")
+}
+
+// OperatingPoint holds the per operating point values of a sequence
+// header, operating_parameters_info() included.
+type OperatingPoint struct {
+	IDC                          uint32
+	SeqLevelIdx                  byte
+	SeqTier                      bool
+	DecoderModelPresentForThisOp bool
+	DecoderBufferDelay           uint32
+	EncoderBufferDelay           uint32
+	LowDelayModeFlag             bool
+	// InitialDisplayDelayPresentForThisOp is initial_display_delay_present_for_this_op.
+	InitialDisplayDelayPresentForThisOp bool
+	InitialDisplayDelayMinus1           byte
+}
+
+// ColorConfig is a color_config(). Inferred values are filled in when
+// parsing.
+type ColorConfig struct {
+	HighBitdepth                bool
+	TwelveBit                   bool
+	MonoChrome                  bool
+	ColorDescriptionPresentFlag bool
+	ColorPrimaries              byte
+	TransferCharacteristics     byte
+	MatrixCoefficients          byte
+	ColorRange                  bool
+	SubsamplingX                bool
+	SubsamplingY                bool
+	ChromaSamplePosition        byte
+	SeparateUVDeltaQ            bool
+}
+
+// BitDepth returns the sample bit depth.
+func (c *ColorConfig) BitDepth() byte {
+	switch {
+	case c.TwelveBit:
+		return 12
+	case c.HighBitdepth:
+		return 10
+	}
+	return 8
+}
+
+// NumPlanes returns the number of color planes, 1 for monochrome video.
+func (c *ColorConfig) NumPlanes() int {
+	if c.MonoChrome {
+		return 1
+	}
+	return 3
+}
+
+func (c *ColorConfig) syntax(s *gobits.Syntax, seqProfile byte) {
+	s.Flag(&c.HighBitdepth, "high_bitdepth")
+	if seqProfile == 2 && c.HighBitdepth {
+		s.Flag(&c.TwelveBit, "twelve_bit")
+	}
+	if seqProfile != 1 {
+		s.Flag(&c.MonoChrome, "mono_chrome")
+	}
+	s.Flag(&c.ColorDescriptionPresentFlag, "color_description_present_flag")
+	if c.ColorDescriptionPresentFlag {
+		s.Byte(&c.ColorPrimaries, 8, "color_primaries")
+		s.Byte(&c.TransferCharacteristics, 8, "transfer_characteristics")
+		s.Byte(&c.MatrixCoefficients, 8, "matrix_coefficients")
+	} else if s.Reading() {
+		c.ColorPrimaries = ColorPrimariesUnspecified
+		c.TransferCharacteristics = TransferCharacteristicsUnspecified
+		c.MatrixCoefficients = MatrixCoefficientsUnspecified
+	}
+
+	if c.MonoChrome {
+		s.Flag(&c.ColorRange, "color_range")
+		if s.Reading() {
+			c.SubsamplingX, c.SubsamplingY = true, true
+		}
+		return
+	}
+	if c.ColorPrimaries == ColorPrimariesBT709 &&
+		c.TransferCharacteristics == TransferCharacteristicsSRGB &&
+		c.MatrixCoefficients == MatrixCoefficientsIdentity {
+		if s.Reading() {
+			c.ColorRange = true
+		}
+	} else {
+		s.Flag(&c.ColorRange, "color_range")
+		switch {
+		case seqProfile == 0:
+			if s.Reading() {
+				c.SubsamplingX, c.SubsamplingY = true, true
+			}
+		case seqProfile == 1:
+		case c.BitDepth() == 12:
+			s.Flag(&c.SubsamplingX, "subsampling_x")
+			if c.SubsamplingX {
+				s.Flag(&c.SubsamplingY, "subsampling_y")
+			}
+		default:
+			if s.Reading() {
+				c.SubsamplingX = true
+			}
+		}
+		if c.SubsamplingX && c.SubsamplingY {
+			s.Byte(&c.ChromaSamplePosition, 2, "chroma_sample_position")
+		}
+	}
+	s.Flag(&c.SeparateUVDeltaQ, "separate_uv_delta_q")
+}
+
+// SequenceHeader is a sequence_header_obu(). Values that a reduced still
+// picture header does not code are filled in when parsing.
+type SequenceHeader struct {
+	SeqProfile                     byte
+	StillPicture                   bool
+	ReducedStillPictureHeader      bool
+	TimingInfoPresentFlag          bool
+	TimingInfo                     TimingInfo
+	DecoderModelInfoPresentFlag    bool
+	DecoderModelInfo               DecoderModelInfo
+	InitialDisplayDelayPresentFlag bool
+	// OperatingPoints has operating_points_cnt_minus_1 + 1 entries.
+	OperatingPoints []OperatingPoint
+
+	FrameWidthBitsMinus1          byte
+	FrameHeightBitsMinus1         byte
+	MaxFrameWidthMinus1           uint32
+	MaxFrameHeightMinus1          uint32
+	FrameIDNumbersPresentFlag     bool
+	DeltaFrameIDLengthMinus2      byte
+	AdditionalFrameIDLengthMinus1 byte
+	Use128x128Superblock          bool
+	EnableFilterIntra             bool
+	EnableIntraEdgeFilter         bool
+	EnableInterintraCompound      bool
+	EnableMaskedCompound          bool
+	EnableWarpedMotion            bool
+	EnableDualFilter              bool
+	EnableOrderHint               bool
+	EnableJntComp                 bool
+	EnableRefFrameMVs             bool
+	SeqChooseScreenContentTools   bool
+	SeqForceScreenContentTools    byte
+	SeqChooseIntegerMV            bool
+	SeqForceIntegerMV             byte
+	OrderHintBitsMinus1           byte
+	EnableSuperres                bool
+	EnableCDEF                    bool
+	EnableRestoration             bool
+	ColorConfig                   ColorConfig
+	FilmGrainParamsPresent        bool
+}
+
+func (sh *SequenceHeader) operatingPoints(s *gobits.Syntax) {
+	cntMinus1 := byte(len(sh.OperatingPoints) - 1)
+	s.Byte(&cntMinus1, 5, "operating_points_cnt_minus_1")
+	if s.Err() != nil {
+		return
+	}
+	if s.Reading() {
+		sh.OperatingPoints = make([]OperatingPoint, int(cntMinus1)+1)
+	} else if len(sh.OperatingPoints) == 0 || len(sh.OperatingPoints) > 32 {
+		s.Fail("operating_points_cnt_minus_1", gobits.ErrOutOfRange)
+		return
+	}
+	for i := range sh.OperatingPoints {
+		op := &sh.OperatingPoints[i]
+		s.Bits(&op.IDC, 12, "operating_point_idc")
+		s.Byte(&op.SeqLevelIdx, 5, "seq_level_idx")
+		if op.SeqLevelIdx > 7 {
+			s.Flag(&op.SeqTier, "seq_tier")
+		}
+		if sh.DecoderModelInfoPresentFlag {
+			s.Flag(&op.DecoderModelPresentForThisOp, "decoder_model_present_for_this_op")
+			if op.DecoderModelPresentForThisOp {
+				n := sh.DecoderModelInfo.BufferDelayLengthMinus1 + 1
+				s.Bits(&op.DecoderBufferDelay, n, "decoder_buffer_delay")
+				s.Bits(&op.EncoderBufferDelay, n, "encoder_buffer_delay")
+				s.Flag(&op.LowDelayModeFlag, "low_delay_mode_flag")
+			}
+		}
+		if sh.InitialDisplayDelayPresentFlag {
+			s.Flag(&op.InitialDisplayDelayPresentForThisOp, "initial_display_delay_present_for_this_op")
+			if op.InitialDisplayDelayPresentForThisOp {
+				s.Byte(&op.InitialDisplayDelayMinus1, 4, "initial_display_delay_minus_1")
+			}
+		}
+	}
+}
+
+func (sh *SequenceHeader) syntax(s *gobits.Syntax) {
+	s.Byte(&sh.SeqProfile, 3, "seq_profile")
+	s.Flag(&sh.StillPicture, "still_picture")
+	s.Flag(&sh.ReducedStillPictureHeader, "reduced_still_picture_header")
+	if sh.ReducedStillPictureHeader {
+		if s.Reading() {
+			sh.OperatingPoints = make([]OperatingPoint, 1)
+		} else if len(sh.OperatingPoints) != 1 {
+			s.Fail("seq_level_idx", gobits.ErrOutOfRange)
+			return
+		}
+		s.Byte(&sh.OperatingPoints[0].SeqLevelIdx, 5, "seq_level_idx")
+	} else {
+		s.Flag(&sh.TimingInfoPresentFlag, "timing_info_present_flag")
+		if sh.TimingInfoPresentFlag {
+			sh.TimingInfo.syntax(s)
+			s.Flag(&sh.DecoderModelInfoPresentFlag, "decoder_model_info_present_flag")
+			if sh.DecoderModelInfoPresentFlag {
+				sh.DecoderModelInfo.syntax(s)
+			}
+		}
+		s.Flag(&sh.InitialDisplayDelayPresentFlag, "initial_display_delay_present_flag")
+		sh.operatingPoints(s)
+	}
+
+	s.Byte(&sh.FrameWidthBitsMinus1, 4, "frame_width_bits_minus_1")
+	s.Byte(&sh.FrameHeightBitsMinus1, 4, "frame_height_bits_minus_1")
+	s.Bits(&sh.MaxFrameWidthMinus1, sh.FrameWidthBitsMinus1+1, "max_frame_width_minus_1")
+	s.Bits(&sh.MaxFrameHeightMinus1, sh.FrameHeightBitsMinus1+1, "max_frame_height_minus_1")
+	if !sh.ReducedStillPictureHeader {
+		s.Flag(&sh.FrameIDNumbersPresentFlag, "frame_id_numbers_present_flag")
+	}
+	if sh.FrameIDNumbersPresentFlag {
+		s.Byte(&sh.DeltaFrameIDLengthMinus2, 4, "delta_frame_id_length_minus_2")
+		s.Byte(&sh.AdditionalFrameIDLengthMinus1, 3, "additional_frame_id_length_minus_1")
+	}
+	s.Flag(&sh.Use128x128Superblock, "use_128x128_superblock")
+	s.Flag(&sh.EnableFilterIntra, "enable_filter_intra")
+	s.Flag(&sh.EnableIntraEdgeFilter, "enable_intra_edge_filter")
+
+	if sh.ReducedStillPictureHeader {
+		if s.Reading() {
+			sh.SeqForceScreenContentTools = SelectScreenContentTools
+			sh.SeqForceIntegerMV = SelectIntegerMV
+		}
+	} else {
+		s.Flag(&sh.EnableInterintraCompound, "enable_interintra_compound")
+		s.Flag(&sh.EnableMaskedCompound, "enable_masked_compound")
+		s.Flag(&sh.EnableWarpedMotion, "enable_warped_motion")
+		s.Flag(&sh.EnableDualFilter, "enable_dual_filter")
+		s.Flag(&sh.EnableOrderHint, "enable_order_hint")
+		if sh.EnableOrderHint {
+			s.Flag(&sh.EnableJntComp, "enable_jnt_comp")
+			s.Flag(&sh.EnableRefFrameMVs, "enable_ref_frame_mvs")
+		}
+		s.Flag(&sh.SeqChooseScreenContentTools, "seq_choose_screen_content_tools")
+		if sh.SeqChooseScreenContentTools {
+			if s.Reading() {
+				sh.SeqForceScreenContentTools = SelectScreenContentTools
+			}
+		} else {
+			s.Byte(&sh.SeqForceScreenContentTools, 1, "seq_force_screen_content_tools")
+		}
+		if sh.SeqForceScreenContentTools > 0 {
+			s.Flag(&sh.SeqChooseIntegerMV, "seq_choose_integer_mv")
+			if sh.SeqChooseIntegerMV {
+				if s.Reading() {
+					sh.SeqForceIntegerMV = SelectIntegerMV
+				}
+			} else {
+				s.Byte(&sh.SeqForceIntegerMV, 1, "seq_force_integer_mv")
+			}
+		} else if s.Reading() {
+			sh.SeqForceIntegerMV = SelectIntegerMV
+		}
+		if sh.EnableOrderHint {
+			s.Byte(&sh.OrderHintBitsMinus1, 3, "order_hint_bits_minus_1")
+		}
+	}
+
+	s.Flag(&sh.EnableSuperres, "enable_superres")
+	s.Flag(&sh.EnableCDEF, "enable_cdef")
+	s.Flag(&sh.EnableRestoration, "enable_restoration")
+	sh.ColorConfig.syntax(s, sh.SeqProfile)
+	s.Flag(&sh.FilmGrainParamsPresent, "film_grain_params_present")
+	trailingBits(s)
+}
+
+// OrderHintBits returns the length of order hints, zero when they are
+// disabled.
+func (sh *SequenceHeader) OrderHintBits() byte {
+	if !sh.EnableOrderHint {
+		return 0
+	}
+	return sh.OrderHintBitsMinus1 + 1
+}
+
+// MaxFrameWidth returns max_frame_width_minus_1 + 1.
+func (sh *SequenceHeader) MaxFrameWidth() uint32 {
+	return sh.MaxFrameWidthMinus1 + 1
+}
+
+// MaxFrameHeight returns max_frame_height_minus_1 + 1.
+func (sh *SequenceHeader) MaxFrameHeight() uint32 {
+	return sh.MaxFrameHeightMinus1 + 1
+}
+
+// ParseSequenceHeader parses the payload of a sequence header OBU.
+func ParseSequenceHeader(ba gobits.ByteAccessor) (*SequenceHeader, error) {
+	s := gobits.NewReadingSyntax(gobits.NewBitStream(ba))
+	sh := &SequenceHeader{}
+	sh.syntax(s)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	return sh, nil
+}
+
+// Marshal encodes the sequence header as an OBU payload, trailing bits
+// included.
+func (sh *SequenceHeader) Marshal() ([]byte, error) {
+	ba := gobits.NewGrowableByteAccessor(nil)
+	s := gobits.NewWritingSyntax(gobits.NewBitStream(ba))
+	sh.syntax(s)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	return ba.Bytes(), nil
+}
@@ -0,0 +1,121 @@
+package av1
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSequenceHeader(t *testing.T) {
+	sh, err := ParseSequenceHeader(gobits.NewSliceByteAccessor(sequenceHeader1080p))
+	assert.NoError(t, err)
+	assert.Equal(t, []OperatingPoint{{SeqLevelIdx: 8}}, sh.OperatingPoints)
+	assert.Equal(t, uint32(1920), sh.MaxFrameWidth())
+	assert.Equal(t, uint32(1080), sh.MaxFrameHeight())
+	assert.True(t, sh.Use128x128Superblock)
+	assert.True(t, sh.EnableOrderHint)
+	assert.Equal(t, byte(7), sh.OrderHintBits())
+	assert.Equal(t, byte(SelectScreenContentTools), sh.SeqForceScreenContentTools)
+	assert.Equal(t, byte(SelectIntegerMV), sh.SeqForceIntegerMV)
+	assert.False(t, sh.EnableSuperres)
+	assert.True(t, sh.EnableCDEF)
+	assert.Equal(t, ColorConfig{
+		ColorPrimaries:          ColorPrimariesUnspecified,
+		TransferCharacteristics: TransferCharacteristicsUnspecified,
+		MatrixCoefficients:      MatrixCoefficientsUnspecified,
+		SubsamplingX:            true,
+		SubsamplingY:            true,
+	}, sh.ColorConfig)
+	assert.Equal(t, byte(8), sh.ColorConfig.BitDepth())
+	assert.Equal(t, 3, sh.ColorConfig.NumPlanes())
+
+	payload, err := sh.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, sequenceHeader1080p, payload)
+
+	_, err = ParseSequenceHeader(gobits.NewSliceByteAccessor(sequenceHeader1080p[:9]))
+	var fe *gobits.FieldError
+	assert.True(t, errors.As(err, &fe))
+	assert.True(t, errors.Is(err, gobits.ErrUnexpectedEOF))
+}
+
+func TestSequenceHeader_RoundTrip(t *testing.T) {
+	sh := &SequenceHeader{
+		SeqProfile:                     2,
+		TimingInfoPresentFlag:          true,
+		TimingInfo:                     TimingInfo{NumUnitsInDisplayTick: 1001, TimeScale: 60000, EqualPictureInterval: true, NumTicksPerPictureMinus1: 1},
+		DecoderModelInfoPresentFlag:    true,
+		DecoderModelInfo:               DecoderModelInfo{BufferDelayLengthMinus1: 15, NumUnitsInDecodingTick: 1001, BufferRemovalTimeLengthMinus1: 9, FramePresentationTimeLengthMinus1: 7},
+		InitialDisplayDelayPresentFlag: true,
+		OperatingPoints: []OperatingPoint{
+			{IDC: 0x103, SeqLevelIdx: 12, SeqTier: true, DecoderModelPresentForThisOp: true, DecoderBufferDelay: 30000, EncoderBufferDelay: 20000, LowDelayModeFlag: true},
+			{IDC: 0x101, SeqLevelIdx: 5, InitialDisplayDelayPresentForThisOp: true, InitialDisplayDelayMinus1: 9},
+		},
+		FrameWidthBitsMinus1:          11,
+		FrameHeightBitsMinus1:         11,
+		MaxFrameWidthMinus1:           3839,
+		MaxFrameHeightMinus1:          2159,
+		FrameIDNumbersPresentFlag:     true,
+		DeltaFrameIDLengthMinus2:      12,
+		AdditionalFrameIDLengthMinus1: 2,
+		EnableOrderHint:               true,
+		SeqForceScreenContentTools:    1,
+		SeqForceIntegerMV:             0,
+		OrderHintBitsMinus1:           4,
+		EnableSuperres:                true,
+		ColorConfig: ColorConfig{
+			HighBitdepth:                true,
+			TwelveBit:                   true,
+			ColorDescriptionPresentFlag: true,
+			ColorPrimaries:              9,
+			TransferCharacteristics:     16,
+			MatrixCoefficients:          9,
+			ColorRange:                  true,
+			SubsamplingX:                true,
+			SubsamplingY:                false,
+			SeparateUVDeltaQ:            true,
+		},
+		FilmGrainParamsPresent: true,
+	}
+	payload, err := sh.Marshal()
+	assert.NoError(t, err)
+	parsed, err := ParseSequenceHeader(gobits.NewSliceByteAccessor(payload))
+	assert.NoError(t, err)
+	assert.Equal(t, sh, parsed)
+	assert.Equal(t, byte(12), parsed.ColorConfig.BitDepth())
+	rewritten, err := parsed.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, payload, rewritten)
+
+	obu, err := OBUHeader{Type: OBUTypeSequenceHeader, HasSizeField: true}.Marshal(payload)
+	assert.NoError(t, err)
+	sc := NewScanner(gobits.NewSliceByteAccessor(obu))
+	assert.True(t, sc.Next())
+	assert.Equal(t, payload, sc.OBU().Payload.Slice(0, int64(len(payload))))
+
+	// Reduced still picture headers code a single operating point.
+	still := &SequenceHeader{
+		StillPicture:              true,
+		ReducedStillPictureHeader: true,
+		OperatingPoints:           []OperatingPoint{{SeqLevelIdx: 4}},
+		FrameWidthBitsMinus1:      9,
+		FrameHeightBitsMinus1:     9,
+		MaxFrameWidthMinus1:       639,
+		MaxFrameHeightMinus1:      479,
+		ColorConfig:               ColorConfig{MonoChrome: true, ColorRange: true},
+	}
+	payload, err = still.Marshal()
+	assert.NoError(t, err)
+	parsed, err = ParseSequenceHeader(gobits.NewSliceByteAccessor(payload))
+	assert.NoError(t, err)
+	assert.Equal(t, byte(SelectScreenContentTools), parsed.SeqForceScreenContentTools)
+	assert.Equal(t, []OperatingPoint{{SeqLevelIdx: 4}}, parsed.OperatingPoints)
+	assert.True(t, parsed.ColorConfig.SubsamplingX)
+	assert.Equal(t, 1, parsed.ColorConfig.NumPlanes())
+
+	still.OperatingPoints = nil
+	_, err = still.Marshal()
+	assert.True(t, errors.Is(err, gobits.ErrOutOfRange))
+}
@@ -0,0 +1,215 @@
+package av1
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+)
+
+const (
+	maxTileWidth = 4096
+	maxTileArea  = 4096 * 2304
+	maxTileRows  = 64
+	maxTileCols  = 64
+)
+
+// TileInfo is a tile_info(). MiColStarts and MiRowStarts hold the first
+// mode info column and row of each tile followed by MiCols and MiRows, so
+// they have one entry more than there are tile columns and rows.
+type TileInfo struct {
+	UniformTileSpacingFlag bool
+	TileColsLog2           byte
+	TileRowsLog2           byte
+	MiColStarts            []uint32
+	MiRowStarts            []uint32
+	ContextUpdateTileID    uint32
+	TileSizeBytesMinus1    byte
+}
+
+func (t *TileInfo) TileCols() int {
+	return len(t.MiColStarts) - 1
+}
+
+func (t *TileInfo) TileRows() int {
+	return len(t.MiRowStarts) - 1
+}
+
+func tileLog2(blkSize, target int) byte {
+	k := byte(0)
+	for blkSize<<k < target {
+		k++
+	}
+	return k
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// incrementLog2 reads increment_tile_*_log2 flags until one is clear or
+// the maximum is reached.
+func incrementLog2(s *gobits.Syntax, log2 *byte, max byte, field string) {
+	for *log2 < max && s.Err() == nil {
+		increment := false
+		s.Flag(&increment, field)
+		if !increment {
+			return
+		}
+		*log2++
+	}
+}
+
+// uniformStarts lists the starts of tiles of tileSizeSb superblocks
+// followed by end.
+func uniformStarts(sbCount, tileSizeSb int, sbShift uint, end uint32) []uint32 {
+	starts := []uint32{}
+	for startSb := 0; startSb < sbCount; startSb += tileSizeSb {
+		starts = append(starts, uint32(startSb)<<sbShift)
+	}
+	return append(starts, end)
+}
+
+// explicitStarts reads the sizes of tiles coded with the given field and
+// returns their starts followed by end, and the largest size.
+func explicitStarts(s *gobits.Syntax, sbCount, maxTileSizeSb int, sbShift uint, end uint32, field string) ([]uint32, int) {
+	starts := []uint32{}
+	widest := 0
+	for startSb := 0; startSb < sbCount && s.Err() == nil; {
+		starts = append(starts, uint32(startSb)<<sbShift)
+		sizeSbMinus1 := uint32(0)
+		ns(s, &sizeSbMinus1, uint32(minInt(sbCount-startSb, maxTileSizeSb)), field)
+		sizeSb := int(sizeSbMinus1) + 1
+		widest = maxInt(widest, sizeSb)
+		startSb += sizeSb
+	}
+	return append(starts, end), widest
+}
+
+func (fp *frameParser) tileInfo() {
+	s, t := fp.s, &fp.h.TileInfo
+	miCols, miRows := fp.h.MiCols(), fp.h.MiRows()
+	sbShift := uint(4)
+	if fp.seq.Use128x128Superblock {
+		sbShift = 5
+	}
+	sbCols := int(miCols+1<<sbShift-1) >> sbShift
+	sbRows := int(miRows+1<<sbShift-1) >> sbShift
+	sbSize := sbShift + 2
+	maxTileWidthSb := maxTileWidth >> sbSize
+	maxTileAreaSb := maxTileArea >> (2 * sbSize)
+	minLog2TileCols := tileLog2(maxTileWidthSb, sbCols)
+	maxLog2TileCols := tileLog2(1, minInt(sbCols, maxTileCols))
+	maxLog2TileRows := tileLog2(1, minInt(sbRows, maxTileRows))
+	minLog2Tiles := tileLog2(maxTileAreaSb, sbRows*sbCols)
+	if minLog2Tiles < minLog2TileCols {
+		minLog2Tiles = minLog2TileCols
+	}
+
+	s.Flag(&t.UniformTileSpacingFlag, "uniform_tile_spacing_flag")
+	if t.UniformTileSpacingFlag {
+		t.TileColsLog2 = minLog2TileCols
+		incrementLog2(s, &t.TileColsLog2, maxLog2TileCols, "increment_tile_cols_log2")
+		tileWidthSb := (sbCols + 1<<t.TileColsLog2 - 1) >> t.TileColsLog2
+		t.MiColStarts = uniformStarts(sbCols, tileWidthSb, sbShift, miCols)
+
+		t.TileRowsLog2 = 0
+		if minLog2Tiles > t.TileColsLog2 {
+			t.TileRowsLog2 = minLog2Tiles - t.TileColsLog2
+		}
+		incrementLog2(s, &t.TileRowsLog2, maxLog2TileRows, "increment_tile_rows_log2")
+		tileHeightSb := (sbRows + 1<<t.TileRowsLog2 - 1) >> t.TileRowsLog2
+		t.MiRowStarts = uniformStarts(sbRows, tileHeightSb, sbShift, miRows)
+	} else {
+		var widestTileSb int
+		t.MiColStarts, widestTileSb = explicitStarts(s, sbCols, maxTileWidthSb, sbShift, miCols, "width_in_sbs_minus_1")
+		if s.Err() != nil {
+			return
+		}
+		t.TileColsLog2 = tileLog2(1, t.TileCols())
+		if minLog2Tiles > 0 {
+			maxTileAreaSb = sbRows * sbCols >> (minLog2Tiles + 1)
+		} else {
+			maxTileAreaSb = sbRows * sbCols
+		}
+		maxTileHeightSb := maxInt(maxTileAreaSb/widestTileSb, 1)
+		t.MiRowStarts, _ = explicitStarts(s, sbRows, maxTileHeightSb, sbShift, miRows, "height_in_sbs_minus_1")
+		t.TileRowsLog2 = tileLog2(1, t.TileRows())
+	}
+	if t.TileColsLog2 > 0 || t.TileRowsLog2 > 0 {
+		s.Bits(&t.ContextUpdateTileID, t.TileRowsLog2+t.TileColsLog2, "context_update_tile_id")
+		s.Byte(&t.TileSizeBytesMinus1, 2, "tile_size_bytes_minus_1")
+	}
+}
+
+// Tile locates the data of one tile in a tile group.
+type Tile struct {
+	Offset int64
+	Size   int64
+}
+
+// TileGroup is a tile_group_obu() with the tile data located but not
+// decoded.
+type TileGroup struct {
+	TileStartAndEndPresentFlag bool
+	TgStart                    uint32
+	TgEnd                      uint32
+	// Tiles has an entry for each tile from TgStart to TgEnd. Offsets are
+	// relative to the start of the tile group.
+	Tiles []Tile
+}
+
+// ParseTileGroup parses a tile group whose frame header is fh. For a tile
+// group OBU, ba is the OBU payload; for a frame OBU, it is the part of the
+// payload after fh.HeaderLength bytes.
+func ParseTileGroup(ba gobits.ByteAccessor, fh *FrameHeader) (*TileGroup, error) {
+	bs := gobits.NewBitStream(ba)
+	s := gobits.NewReadingSyntax(bs)
+	t := &fh.TileInfo
+	numTiles := uint32(t.TileCols() * t.TileRows())
+	g := &TileGroup{TgEnd: numTiles - 1}
+	if numTiles > 1 {
+		s.Flag(&g.TileStartAndEndPresentFlag, "tile_start_and_end_present_flag")
+	}
+	if g.TileStartAndEndPresentFlag {
+		tileBits := t.TileColsLog2 + t.TileRowsLog2
+		s.Bits(&g.TgStart, tileBits, "tg_start")
+		s.Bits(&g.TgEnd, tileBits, "tg_end")
+		if s.Err() == nil && (g.TgEnd < g.TgStart || numTiles <= g.TgEnd) {
+			s.Fail("tg_end", gobits.ErrOutOfRange)
+		}
+	}
+	byteAlignment(s)
+
+	g.Tiles = []Tile{}
+	offset := bs.Tell() / 8
+	for tileNum := g.TgStart; tileNum <= g.TgEnd && s.Err() == nil; tileNum++ {
+		size := ba.Length() - offset
+		if tileNum != g.TgEnd {
+			sizeMinus1 := uint64(0)
+			le(s, &sizeMinus1, int(t.TileSizeBytesMinus1)+1, "tile_size_minus_1")
+			offset = bs.Tell() / 8
+			size = int64(sizeMinus1) + 1
+			if s.Err() == nil && ba.Length()-offset < size {
+				s.Fail("tile_size_minus_1", gobits.ErrUnexpectedEOF)
+			}
+		}
+		if s.Err() != nil {
+			break
+		}
+		g.Tiles = append(g.Tiles, Tile{Offset: offset, Size: size})
+		offset += size
+		bs.Seek(offset, 0)
+	}
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	return g, nil
+}
@@ -2,7 +2,10 @@ package gobits
 
 import (
 	"encoding/binary"
+	"errors"
+	"io"
 	"math"
+	"math/bits"
 )
 
 type pos struct {
@@ -14,8 +17,42 @@ type PosWrapper struct {
 	pos
 }
 
+func (pw PosWrapper) ByteOffset() int64 {
+	return pw.byteOffset
+}
+
+func (pw PosWrapper) BitOffset() byte {
+	return pw.bitOffset
+}
+
+func (pw PosWrapper) Bits() int64 {
+	return pw.byteOffset*8 + int64(pw.bitOffset)
+}
+
+// Sub returns the distance in bits from other to pw.
+func (pw PosWrapper) Sub(other PosWrapper) int64 {
+	return pw.Bits() - other.Bits()
+}
+
+type BitOrder int
+
+const (
+	MSBFirst BitOrder = iota
+	LSBFirst
+)
+
+type Padding int
+
+const (
+	PadZeros Padding = iota
+	PadOnes
+	PadRBSPTrailingBits
+)
+
 type BitStream struct {
-	ba ByteAccessor
+	ba       ByteAccessor
+	bitOrder BitOrder
+	err      error
 	pos
 }
 
@@ -41,51 +78,92 @@ func countEffectiveBits(val uint64) byte {
 	return bitCount
 }
 
+func reverseBits(val uint64, count byte) uint64 {
+	if count == 0 {
+		return 0
+	}
+	return bits.Reverse64(val) >> (64 - count)
+}
+
 func writePartialByte(srcByte byte, srcBitCount byte, dstByte byte, dstBitOffset byte) byte {
 	mask := byte(0xff<<(8-srcBitCount)) >> dstBitOffset
 	return (dstByte &^ mask) | (srcByte >> dstBitOffset)
 }
 
-func (bs *BitStream) RemainingBits(bitCount int64) bool {
+func (bs *BitStream) remainingBits(bitCount int64) error {
+	if bitCount < 0 {
+		return ErrInvalidBitCount
+	}
 	bitCount += int64(bs.bitOffset)
 	byteOffset := bs.byteOffset
 	for bitCount > 0 {
-		_, ok := bs.ba.At(byteOffset)
-		if !ok {
-			return false
+		if _, err := byteAt(bs.ba, byteOffset); err != nil {
+			return err
 		}
 		byteOffset++
 		bitCount -= 8
 	}
+	return nil
+}
+
+func (bs *BitStream) RemainingBits(bitCount int64) bool {
+	return bs.remainingBits(bitCount) == nil
+}
+
+// Err returns the error of the last operation, or nil if it succeeded. A
+// successful operation clears the error of an earlier failure, so Err must
+// be checked right after the call that returned false. Use SyntaxReader for
+// an error that is kept until the end of a parse.
+func (bs *BitStream) Err() error {
+	return bs.err
+}
+
+func (bs *BitStream) record(op string, err error) bool {
+	if err != nil {
+		bs.err = &BitStreamError{
+			Op:         op,
+			ByteOffset: bs.byteOffset,
+			BitOffset:  bs.bitOffset,
+			Err:        err,
+		}
+		return false
+	}
+	bs.err = nil
 	return true
 }
 
-func (bs *BitStream) PeekBits(bitCount byte) (uint64, bool) {
+func (bs *BitStream) peekBits(bitCount byte) (uint64, error) {
 	if bitCount == 0 {
-		return 0, true
+		return 0, nil
 	}
-	if !bs.RemainingBits(int64(bitCount)) || bitCount > 64 {
-		return 0, false
+	if bitCount > 64 {
+		return 0, ErrInvalidBitCount
+	}
+	if err := bs.remainingBits(int64(bitCount)); err != nil {
+		return 0, err
+	}
+	if bs.bitOrder == LSBFirst {
+		return bs.peekBitsLSBFirst(bitCount)
 	}
 
 	byteOffset := bs.byteOffset
 	remainingBitsInCurrByte := 8 - bs.bitOffset
-	byt, ok := bs.ba.At(byteOffset)
-	if !ok {
-		return 0, false
+	byt, err := byteAt(bs.ba, byteOffset)
+	if err != nil {
+		return 0, err
 	}
 	bits_ := lowerBits(byt, remainingBitsInCurrByte)
 	byteOffset++
 	if bitCount < remainingBitsInCurrByte {
-		return uint64(higherBits(bits_, bs.bitOffset+bitCount)), true
+		return uint64(higherBits(bits_, bs.bitOffset+bitCount)), nil
 	}
 
 	bits := uint64(bits_)
 	bitCount -= remainingBitsInCurrByte
 	for bitCount >= 8 {
-		byt, ok := bs.ba.At(byteOffset)
-		if !ok {
-			return 0, false
+		byt, err := byteAt(bs.ba, byteOffset)
+		if err != nil {
+			return 0, err
 		}
 		bits = (bits << 8) | uint64(byt)
 		byteOffset++
@@ -93,83 +171,295 @@ func (bs *BitStream) PeekBits(bitCount byte) (uint64, bool) {
 	}
 
 	if bitCount > 0 {
-		byt, ok := bs.ba.At(byteOffset)
-		if !ok {
-			return 0, false
+		byt, err := byteAt(bs.ba, byteOffset)
+		if err != nil {
+			return 0, err
 		}
 		bits = (bits << bitCount) | uint64(higherBits(byt, bitCount))
 	}
 
-	return bits, true
+	return bits, nil
 }
 
-func (bs *BitStream) ConsumeBits(bitCount int64) bool {
-	if !bs.RemainingBits(bitCount) {
-		return false
+func (bs *BitStream) peekBitsLSBFirst(bitCount byte) (uint64, error) {
+	bits := uint64(0)
+	byteOffset := bs.byteOffset
+	bitOffset := bs.bitOffset
+	for peeked := byte(0); peeked < bitCount; {
+		byt, err := byteAt(bs.ba, byteOffset)
+		if err != nil {
+			return 0, err
+		}
+		count := 8 - bitOffset
+		if count > bitCount-peeked {
+			count = bitCount - peeked
+		}
+		bits |= uint64(lowerBits(byt>>bitOffset, count)) << peeked
+		peeked += count
+		bitOffset = 0
+		byteOffset++
+	}
+	return bits, nil
+}
+
+func (bs *BitStream) PeekBits(bitCount byte) (uint64, bool) {
+	bits, err := bs.peekBits(bitCount)
+	return bits, bs.record("PeekBits", err)
+}
+
+func (bs *BitStream) consumeBits(bitCount int64) error {
+	if err := bs.remainingBits(bitCount); err != nil {
+		return err
 	}
 	bs.byteOffset += (int64(bs.bitOffset) + bitCount) / 8
 	bs.bitOffset = byte((int64(bs.bitOffset) + bitCount) % 8)
-	return true
+	return nil
+}
+
+func (bs *BitStream) ConsumeBits(bitCount int64) bool {
+	return bs.record("ConsumeBits", bs.consumeBits(bitCount))
 }
 
 func (bs *BitStream) ConsumeBytes(byteCount int64) bool {
-	return bs.ConsumeBits(byteCount * 8)
+	return bs.record("ConsumeBytes", bs.consumeBits(byteCount*8))
 }
 
-func (bs *BitStream) ReadBits(bitCount byte) (uint64, bool) {
-	bits, ok := bs.PeekBits(bitCount)
-	if !ok {
-		return 0, false
+func (bs *BitStream) readBits(bitCount byte) (uint64, error) {
+	bits, err := bs.peekBits(bitCount)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := bs.consumeBits(int64(bitCount)); err != nil {
+		return 0, err
+	}
+
+	return bits, nil
+}
+
+// peekCode, readCode and writeCode treat the first bit in stream order as the
+// most significant bit regardless of the bit order. Variable-length codes are
+// built on them so that their bit sequence is identical in both orders.
+func (bs *BitStream) peekCode(bitCount byte) (uint64, error) {
+	bits, err := bs.peekBits(bitCount)
+	if err != nil || bs.bitOrder == MSBFirst {
+		return bits, err
+	}
+	return reverseBits(bits, bitCount), nil
+}
+
+func (bs *BitStream) readCode(bitCount byte) (uint64, error) {
+	bits, err := bs.readBits(bitCount)
+	if err != nil || bs.bitOrder == MSBFirst {
+		return bits, err
+	}
+	return reverseBits(bits, bitCount), nil
+}
+
+func (bs *BitStream) writeCode(val uint64, bitCount byte) error {
+	if bs.bitOrder == LSBFirst && bitCount <= 64 {
+		val = reverseBits(val, bitCount)
+	}
+	return bs.writeBits(val, bitCount)
+}
+
+// peekCodeUpTo behaves like peekCode but tolerates fewer than bitCount bits
+// remaining. The bits that are available are returned left-aligned and
+// padded with zeros, together with their count.
+func (bs *BitStream) peekCodeUpTo(bitCount byte) (uint64, byte, error) {
+	val, err := bs.peekCode(bitCount)
+	if err == nil {
+		return val, bitCount, nil
+	}
+	if !errors.Is(err, ErrUnexpectedEOF) {
+		return 0, 0, err
+	}
+
+	available := bs.ba.Length()*8 - bs.tell()
+	if available <= 0 || int64(bitCount) <= available {
+		return 0, 0, err
 	}
+	if val, err = bs.peekCode(byte(available)); err != nil {
+		return 0, 0, err
+	}
+	return val << (bitCount - byte(available)), byte(available), nil
+}
 
-	if !bs.ConsumeBits(int64(bitCount)) {
-		return 0, false
+// streamBytes and streamValue convert between a value read with readBits and
+// the bytes it occupies in stream order.
+func (bs *BitStream) streamBytes(val uint64, byteCount int) []byte {
+	bytes := make([]byte, byteCount)
+	for i := range bytes {
+		if bs.bitOrder == LSBFirst {
+			bytes[i] = byte(val >> (8 * i))
+		} else {
+			bytes[i] = byte(val >> (8 * (byteCount - 1 - i)))
+		}
 	}
+	return bytes
+}
 
-	return bits, true
+func (bs *BitStream) streamValue(bytes []byte) uint64 {
+	val := uint64(0)
+	for i, b := range bytes {
+		if bs.bitOrder == LSBFirst {
+			val |= uint64(b) << (8 * i)
+		} else {
+			val = (val << 8) | uint64(b)
+		}
+	}
+	return val
+}
+
+func (bs *BitStream) ReadBits(bitCount byte) (uint64, bool) {
+	bits, err := bs.readBits(bitCount)
+	return bits, bs.record("ReadBits", err)
 }
 
 func (bs *BitStream) ReadUint8() (uint8, bool) {
-	v, ok := bs.ReadBits(8)
-	return uint8(v), ok
+	v, err := bs.readBits(8)
+	return uint8(v), bs.record("ReadUint8", err)
 }
 
 func (bs *BitStream) ReadUint16(bo binary.ByteOrder) (uint16, bool) {
-	b, ok := bs.ReadBits(16)
-	v := []byte{byte((b >> 8) & 0xff), byte(b & 0xff)}
-	return bo.Uint16(v), ok
+	b, err := bs.readBits(16)
+	return bo.Uint16(bs.streamBytes(b, 2)), bs.record("ReadUint16", err)
 }
 
 func (bs *BitStream) ReadUint32(bo binary.ByteOrder) (uint32, bool) {
-	b, ok := bs.ReadBits(32)
-	v := []byte{byte((b >> 24) & 0xff), byte((b >> 16) & 0xff), byte((b >> 8) & 0xff), byte(b & 0xff)}
-	return bo.Uint32(v), ok
+	b, err := bs.readBits(32)
+	return bo.Uint32(bs.streamBytes(b, 4)), bs.record("ReadUint32", err)
 }
 
 func (bs *BitStream) ReadUint64(bo binary.ByteOrder) (uint64, bool) {
-	b, ok := bs.ReadBits(64)
-	v := []byte{
-		byte((b >> 56) & 0xff),
-		byte((b >> 48) & 0xff),
-		byte((b >> 40) & 0xff),
-		byte((b >> 32) & 0xff),
-		byte((b >> 24) & 0xff),
-		byte((b >> 16) & 0xff),
-		byte((b >> 8) & 0xff),
-		byte(b & 0xff),
+	b, err := bs.readBits(64)
+	return bo.Uint64(bs.streamBytes(b, 8)), bs.record("ReadUint64", err)
+}
+
+func signExtend(val uint64, bitCount byte) int64 {
+	shift := 64 - bitCount
+	return int64(val<<shift) >> shift
+}
+
+func (bs *BitStream) readSignedBits(bitCount byte) (int64, error) {
+	if bitCount == 0 {
+		return 0, nil
+	}
+	val, err := bs.readBits(bitCount)
+	if err != nil {
+		return 0, err
+	}
+	return signExtend(val, bitCount), nil
+}
+
+func (bs *BitStream) ReadSignedBits(bitCount byte) (int64, bool) {
+	val, err := bs.readSignedBits(bitCount)
+	return val, bs.record("ReadSignedBits", err)
+}
+
+func (bs *BitStream) readSignMagnitudeBits(bitCount byte) (int64, error) {
+	if bitCount == 0 {
+		return 0, ErrInvalidBitCount
 	}
-	return bo.Uint64(v), ok
+	val, err := bs.readBits(bitCount)
+	if err != nil {
+		return 0, err
+	}
+	magnitude := int64(val & (math.MaxUint64 >> (65 - bitCount)))
+	if val>>(bitCount-1) != 0 {
+		return -magnitude, nil
+	}
+	return magnitude, nil
+}
+
+func (bs *BitStream) ReadSignMagnitudeBits(bitCount byte) (int64, bool) {
+	val, err := bs.readSignMagnitudeBits(bitCount)
+	return val, bs.record("ReadSignMagnitudeBits", err)
+}
+
+func (bs *BitStream) ReadInt8() (int8, bool) {
+	v, err := bs.readBits(8)
+	return int8(v), bs.record("ReadInt8", err)
+}
+
+func (bs *BitStream) ReadInt16(bo binary.ByteOrder) (int16, bool) {
+	b, err := bs.readBits(16)
+	return int16(bo.Uint16(bs.streamBytes(b, 2))), bs.record("ReadInt16", err)
+}
+
+func (bs *BitStream) ReadInt32(bo binary.ByteOrder) (int32, bool) {
+	b, err := bs.readBits(32)
+	return int32(bo.Uint32(bs.streamBytes(b, 4))), bs.record("ReadInt32", err)
+}
+
+func (bs *BitStream) ReadInt64(bo binary.ByteOrder) (int64, bool) {
+	b, err := bs.readBits(64)
+	return int64(bo.Uint64(bs.streamBytes(b, 8))), bs.record("ReadInt64", err)
+}
+
+func (bs *BitStream) ReadFloat16(bo binary.ByteOrder) (float32, bool) {
+	b, err := bs.readBits(16)
+	return Float16ToFloat32(bo.Uint16(bs.streamBytes(b, 2))), bs.record("ReadFloat16", err)
+}
+
+func (bs *BitStream) ReadFloat32(bo binary.ByteOrder) (float32, bool) {
+	b, err := bs.readBits(32)
+	return math.Float32frombits(bo.Uint32(bs.streamBytes(b, 4))), bs.record("ReadFloat32", err)
+}
+
+func (bs *BitStream) ReadFloat64(bo binary.ByteOrder) (float64, bool) {
+	b, err := bs.readBits(64)
+	return math.Float64frombits(bo.Uint64(bs.streamBytes(b, 8))), bs.record("ReadFloat64", err)
+}
+
+func (bs *BitStream) seek(byteOffset int64, bitOffset byte) error {
+	if byteOffset < 0 || bitOffset >= 8 {
+		return ErrInvalidOffset
+	}
+	if bitOffset != 0 || byteOffset != bs.ba.Length() {
+		if _, err := byteAt(bs.ba, byteOffset); err != nil {
+			if errors.Is(err, ErrUnexpectedEOF) {
+				return ErrInvalidOffset
+			}
+			return err
+		}
+	}
+
+	bs.byteOffset = byteOffset
+	bs.bitOffset = bitOffset
+	return nil
 }
 
 func (bs *BitStream) Seek(byteOffset int64, bitOffset byte) bool {
-	_, ok := bs.ba.At(byteOffset)
-	if ok && bitOffset < 8 {
-		bs.byteOffset = byteOffset
-		bs.bitOffset = bitOffset
-		return true
+	return bs.record("Seek", bs.seek(byteOffset, bitOffset))
+}
+
+func (bs *BitStream) seekBits(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += bs.tell()
+	case io.SeekEnd:
+		offset += bs.ba.Length() * 8
+	default:
+		return 0, ErrInvalidOffset
+	}
+	if offset < 0 {
+		return 0, ErrInvalidOffset
 	}
+	if err := bs.seek(offset/8, byte(offset%8)); err != nil {
+		return 0, err
+	}
+	return offset, nil
+}
 
-	return false
+func (bs *BitStream) SeekBits(offset int64, whence int) (int64, bool) {
+	offset, err := bs.seekBits(offset, whence)
+	return offset, bs.record("SeekBits", err)
+}
+
+func (bs *BitStream) Tell() int64 {
+	return bs.tell()
 }
 
 func (bs *BitStream) SavePos() PosWrapper {
@@ -187,59 +477,149 @@ func (bs *BitStream) ResetPos() {
 	}
 }
 
-func (bs *BitStream) ReadExponentialGolomb() (uint64, bool) {
-	originalbyteOffset := bs.byteOffset
-	originalBitOffset := bs.bitOffset
-	zeroBitCount := 0
-	peekedBit := uint64(0)
-	ok := true
-
-	for {
-		if peekedBit, ok = bs.PeekBits(1); peekedBit != 0 || !ok {
+// countRun consumes up to maxBitCount consecutive bits equal to bit and
+// returns how many were consumed. The first differing bit is left unread.
+// Bits are examined up to 64 at a time rather than one by one.
+func (bs *BitStream) countRun(bit uint64, maxBitCount int64) (int64, error) {
+	count := int64(0)
+	chunk := byte(64)
+	for count < maxBitCount {
+		if int64(chunk) > maxBitCount-count {
+			chunk = byte(maxBitCount - count)
+		}
+		val, err := bs.peekCode(chunk)
+		if errors.Is(err, ErrUnexpectedEOF) && chunk > 1 {
+			chunk /= 2
+			continue
+		}
+		if err != nil {
+			return count, err
+		}
+		if bit != 0 {
+			val = ^val & (math.MaxUint64 >> (64 - chunk))
+		}
+		run := byte(bits.LeadingZeros64(val) - (64 - int(chunk)))
+		bs.consumeBits(int64(run))
+		count += int64(run)
+		if run < chunk {
 			break
 		}
-		zeroBitCount++
-		bs.ConsumeBits(1)
+	}
+	return count, nil
+}
+
+func (bs *BitStream) writeRun(bit uint64, bitCount int64) error {
+	code := uint64(0)
+	if bit != 0 {
+		code = math.MaxUint64
+	}
+	for ; bitCount > 0; bitCount -= 64 {
+		chunk := byte(64)
+		if bitCount < 64 {
+			chunk = byte(bitCount)
+		}
+		if err := bs.writeCode(code>>(64-chunk), chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (bs *BitStream) readExpGolombK(k byte) (uint64, error) {
+	if k > 63 {
+		return 0, ErrInvalidBitCount
 	}
 
+	original := bs.pos
+	zeroBitCount, err := bs.countRun(0, 64)
+
 	val := uint64(0)
-	valueBitCount := zeroBitCount + 1
+	valueBitCount := zeroBitCount + int64(k) + 1
 
-	if !ok {
+	if err != nil {
 		goto failed
 	} else if valueBitCount > 64 {
+		err = ErrMalformedCode
 		goto failed
-	} else if val, ok = bs.ReadBits(byte(valueBitCount)); !ok {
+	} else if val, err = bs.readCode(byte(valueBitCount)); err != nil {
 		goto failed
 	}
 
-	return val - 1, true
+	return val - 1<<k, nil
 
 failed:
-	bs.Seek(originalbyteOffset, originalBitOffset)
-	return 0, false
+	bs.pos = original
+	return 0, err
 }
 
-func (bs *BitStream) ReadSignedExponentialGolomb() (int64, bool) {
-	val := uint64(0)
-	ok := true
+func (bs *BitStream) ReadExpGolombK(k byte) (uint64, bool) {
+	val, err := bs.readExpGolombK(k)
+	return val, bs.record("ReadExpGolombK", err)
+}
 
-	if val, ok = bs.ReadExponentialGolomb(); !ok {
-		return 0, false
-	}
+func (bs *BitStream) ReadExponentialGolomb() (uint64, bool) {
+	val, err := bs.readExpGolombK(0)
+	return val, bs.record("ReadExponentialGolomb", err)
+}
 
+func unsignedToSigned(val uint64) int64 {
 	if val&1 == 0 {
-		return -int64(val / 2), true
+		return -int64(val / 2)
 	}
-	return int64(val+1) / 2, true
+	return int64(val/2) + 1
 }
 
-func (bs *BitStream) WriteBits(val uint64, bitCount byte) bool {
+func signedToUnsigned(val int64) (uint64, error) {
+	if val > 0 {
+		return uint64(val)*2 - 1, nil
+	}
+	if val == math.MinInt64 {
+		return 0, ErrOutOfRange
+	}
+	return uint64(-val) * 2, nil
+}
+
+func (bs *BitStream) readSignedExpGolombK(k byte) (int64, error) {
+	val, err := bs.readExpGolombK(k)
+	if err != nil {
+		return 0, err
+	}
+	return unsignedToSigned(val), nil
+}
+
+func (bs *BitStream) ReadSignedExpGolombK(k byte) (int64, bool) {
+	val, err := bs.readSignedExpGolombK(k)
+	return val, bs.record("ReadSignedExpGolombK", err)
+}
+
+func (bs *BitStream) ReadSignedExponentialGolomb() (int64, bool) {
+	val, err := bs.readSignedExpGolombK(0)
+	return val, bs.record("ReadSignedExponentialGolomb", err)
+}
+
+// writableBits extends an ExtendableByteAccessor as needed and reports
+// whether bitCount bits can be written at the current position.
+func (bs *BitStream) writableBits(bitCount int64) error {
+	if eba, ok := bs.ba.(ExtendableByteAccessor); ok && bitCount > 0 {
+		if err := eba.Extend(bs.byteOffset + (int64(bs.bitOffset)+bitCount+7)/8); err != nil {
+			return err
+		}
+	}
+	return bs.remainingBits(bitCount)
+}
+
+func (bs *BitStream) writeBits(val uint64, bitCount byte) error {
 	if bitCount == 0 {
-		return true
+		return nil
 	}
-	if !bs.RemainingBits(int64(bitCount)) || bitCount > 64 {
-		return false
+	if bitCount > 64 {
+		return ErrInvalidBitCount
+	}
+	if err := bs.writableBits(int64(bitCount)); err != nil {
+		return err
+	}
+	if bs.bitOrder == LSBFirst {
+		return bs.writeBitsLSBFirst(val, bitCount)
 	}
 
 	consumeBits := int64(bitCount)
@@ -251,9 +631,9 @@ func (bs *BitStream) WriteBits(val uint64, bitCount byte) bool {
 	}
 
 	dstByteOffset := bs.byteOffset
-	dstByte, ok := bs.ba.At(dstByteOffset)
-	if !ok {
-		return false
+	dstByte, err := byteAt(bs.ba, dstByteOffset)
+	if err != nil {
+		return err
 	}
 
 	bytes := make([]byte, (bs.bitOffset+bitCount+7)/8)
@@ -274,73 +654,341 @@ func (bs *BitStream) WriteBits(val uint64, bitCount byte) bool {
 
 	if bitCount > 0 {
 		dstByteOffset++
-		dstByte, ok := bs.ba.At(dstByteOffset)
-		if !ok {
-			return false
+		dstByte, err := byteAt(bs.ba, dstByteOffset)
+		if err != nil {
+			return err
 		}
 		bytes[dstByteOffset-bs.byteOffset] = writePartialByte(highestByte(val), bitCount, dstByte, 0)
 	}
 
 fin:
-	if !bs.ba.Put(bytes, bs.byteOffset) {
-		return false
+	if err := putBytes(bs.ba, bytes, bs.byteOffset); err != nil {
+		return err
 	}
 
-	return bs.ConsumeBits(consumeBits)
+	return bs.consumeBits(consumeBits)
+}
+
+func (bs *BitStream) writeBitsLSBFirst(val uint64, bitCount byte) error {
+	bytes := make([]byte, (bs.bitOffset+bitCount+7)/8)
+	bitOffset := bs.bitOffset
+	written := byte(0)
+	for i := range bytes {
+		count := 8 - bitOffset
+		if count > bitCount-written {
+			count = bitCount - written
+		}
+		dstByte := byte(0)
+		if count < 8 {
+			var err error
+			if dstByte, err = byteAt(bs.ba, bs.byteOffset+int64(i)); err != nil {
+				return err
+			}
+		}
+		mask := lowerBits(0xff, count) << bitOffset
+		bytes[i] = (dstByte &^ mask) | ((byte(val>>written) << bitOffset) & mask)
+		written += count
+		bitOffset = 0
+	}
+
+	if err := putBytes(bs.ba, bytes, bs.byteOffset); err != nil {
+		return err
+	}
+
+	return bs.consumeBits(int64(bitCount))
+}
+
+func (bs *BitStream) WriteBits(val uint64, bitCount byte) bool {
+	return bs.record("WriteBits", bs.writeBits(val, bitCount))
 }
 
 func (bs *BitStream) WriteUint8(val uint8) bool {
-	return bs.WriteBits(uint64(val), 8)
+	return bs.record("WriteUint8", bs.writeBits(uint64(val), 8))
 }
 
 func (bs *BitStream) WriteUint16(val uint16, bo binary.ByteOrder) bool {
-	v := []byte{byte((val >> 8) & 0xff), byte(val & 0xff)}
-	return bs.WriteBits(uint64(bo.Uint16(v)), 16)
+	v := make([]byte, 2)
+	bo.PutUint16(v, val)
+	return bs.record("WriteUint16", bs.writeBits(bs.streamValue(v), 16))
 }
 
 func (bs *BitStream) WriteUint32(val uint32, bo binary.ByteOrder) bool {
-	v := []byte{byte((val >> 24) & 0xff), byte((val >> 16) & 0xff), byte((val >> 8) & 0xff), byte(val & 0xff)}
-	return bs.WriteBits(uint64(bo.Uint32(v)), 32)
+	v := make([]byte, 4)
+	bo.PutUint32(v, val)
+	return bs.record("WriteUint32", bs.writeBits(bs.streamValue(v), 32))
 }
 
 func (bs *BitStream) WriteUint64(val uint64, bo binary.ByteOrder) bool {
-	v := []byte{
-		byte((val >> 56) & 0xff),
-		byte((val >> 48) & 0xff),
-		byte((val >> 40) & 0xff),
-		byte((val >> 32) & 0xff),
-		byte((val >> 24) & 0xff),
-		byte((val >> 16) & 0xff),
-		byte((val >> 8) & 0xff),
-		byte(val & 0xff),
+	v := make([]byte, 8)
+	bo.PutUint64(v, val)
+	return bs.record("WriteUint64", bs.writeBits(bs.streamValue(v), 64))
+}
+
+func (bs *BitStream) writeSignedBits(val int64, bitCount byte) error {
+	if bitCount == 0 {
+		if val != 0 {
+			return ErrOutOfRange
+		}
+		return nil
+	}
+	if bitCount > 64 {
+		return ErrInvalidBitCount
+	}
+	if signExtend(uint64(val), bitCount) != val {
+		return ErrOutOfRange
+	}
+	return bs.writeBits(uint64(val), bitCount)
+}
+
+func (bs *BitStream) WriteSignedBits(val int64, bitCount byte) bool {
+	return bs.record("WriteSignedBits", bs.writeSignedBits(val, bitCount))
+}
+
+func (bs *BitStream) writeSignMagnitudeBits(val int64, bitCount byte) error {
+	if bitCount == 0 || bitCount > 64 {
+		return ErrInvalidBitCount
+	}
+	magnitude := uint64(val)
+	sign := uint64(0)
+	if val < 0 {
+		magnitude = -magnitude
+		sign = 1
+	}
+	if magnitude>>(bitCount-1) != 0 {
+		return ErrOutOfRange
+	}
+	return bs.writeBits(sign<<(bitCount-1)|magnitude, bitCount)
+}
+
+func (bs *BitStream) WriteSignMagnitudeBits(val int64, bitCount byte) bool {
+	return bs.record("WriteSignMagnitudeBits", bs.writeSignMagnitudeBits(val, bitCount))
+}
+
+func (bs *BitStream) WriteInt8(val int8) bool {
+	return bs.record("WriteInt8", bs.writeBits(uint64(uint8(val)), 8))
+}
+
+func (bs *BitStream) WriteInt16(val int16, bo binary.ByteOrder) bool {
+	v := make([]byte, 2)
+	bo.PutUint16(v, uint16(val))
+	return bs.record("WriteInt16", bs.writeBits(bs.streamValue(v), 16))
+}
+
+func (bs *BitStream) WriteInt32(val int32, bo binary.ByteOrder) bool {
+	v := make([]byte, 4)
+	bo.PutUint32(v, uint32(val))
+	return bs.record("WriteInt32", bs.writeBits(bs.streamValue(v), 32))
+}
+
+func (bs *BitStream) WriteInt64(val int64, bo binary.ByteOrder) bool {
+	v := make([]byte, 8)
+	bo.PutUint64(v, uint64(val))
+	return bs.record("WriteInt64", bs.writeBits(bs.streamValue(v), 64))
+}
+
+func (bs *BitStream) WriteFloat16(val float32, bo binary.ByteOrder) bool {
+	v := make([]byte, 2)
+	bo.PutUint16(v, Float32ToFloat16(val))
+	return bs.record("WriteFloat16", bs.writeBits(bs.streamValue(v), 16))
+}
+
+func (bs *BitStream) WriteFloat32(val float32, bo binary.ByteOrder) bool {
+	v := make([]byte, 4)
+	bo.PutUint32(v, math.Float32bits(val))
+	return bs.record("WriteFloat32", bs.writeBits(bs.streamValue(v), 32))
+}
+
+func (bs *BitStream) WriteFloat64(val float64, bo binary.ByteOrder) bool {
+	v := make([]byte, 8)
+	bo.PutUint64(v, math.Float64bits(val))
+	return bs.record("WriteFloat64", bs.writeBits(bs.streamValue(v), 64))
+}
+
+func (bs *BitStream) writeExpGolombK(val uint64, k byte) error {
+	if k > 63 {
+		return ErrInvalidBitCount
+	}
+	if val > math.MaxUint64-1<<k {
+		return ErrOutOfRange
 	}
-	return bs.WriteBits(uint64(bo.Uint64(v)), 64)
+	val += 1 << k
+	valueBitCount := countEffectiveBits(val)
+	if err := bs.writableBits(int64(valueBitCount)*2 - int64(k) - 1); err != nil {
+		return err
+	}
+
+	original := bs.pos
+	if err := bs.writeCode(0, valueBitCount-k-1); err != nil {
+		bs.pos = original
+		return err
+	}
+	if err := bs.writeCode(val, valueBitCount); err != nil {
+		bs.pos = original
+		return err
+	}
+	return nil
+}
+
+func (bs *BitStream) WriteExpGolombK(val uint64, k byte) bool {
+	return bs.record("WriteExpGolombK", bs.writeExpGolombK(val, k))
 }
 
 func (bs *BitStream) WriteExponentialGolomb(val uint64) bool {
-	if val == math.MaxUint64 {
-		return false
+	return bs.record("WriteExponentialGolomb", bs.writeExpGolombK(val, 0))
+}
+
+func (bs *BitStream) writeSignedExpGolombK(val int64, k byte) error {
+	v, err := signedToUnsigned(val)
+	if err != nil {
+		return err
 	}
-	val++
-	return bs.WriteBits(val, countEffectiveBits(val)*2-1)
+	return bs.writeExpGolombK(v, k)
+}
+
+func (bs *BitStream) WriteSignedExpGolombK(val int64, k byte) bool {
+	return bs.record("WriteSignedExpGolombK", bs.writeSignedExpGolombK(val, k))
 }
 
 func (bs *BitStream) WriteSignedExponentialGolomb(val int64) bool {
-	if val == 0 {
-		return bs.WriteExponentialGolomb(0)
-	} else if val > 0 {
-		return bs.WriteExponentialGolomb(uint64(val)*2 - 1)
-	} else {
-		if val == math.MinInt64 {
-			return false
+	return bs.record("WriteSignedExponentialGolomb", bs.writeSignedExpGolombK(val, 0))
+}
+
+func (bs *BitStream) tell() int64 {
+	return bs.byteOffset*8 + int64(bs.bitOffset)
+}
+
+func (bs *BitStream) BitsToNextBoundary(alignBits int64) int64 {
+	if alignBits <= 1 {
+		return 0
+	}
+	return (alignBits - bs.tell()%alignBits) % alignBits
+}
+
+func (bs *BitStream) BitsToNextByte() int64 {
+	return bs.BitsToNextBoundary(8)
+}
+
+func (bs *BitStream) IsAligned(alignBits int64) bool {
+	return bs.BitsToNextBoundary(alignBits) == 0
+}
+
+func (bs *BitStream) IsByteAligned() bool {
+	return bs.bitOffset == 0
+}
+
+func (bs *BitStream) paddingBitCount(alignBits int64, padding Padding) (int64, error) {
+	if alignBits <= 0 {
+		return 0, ErrInvalidBitCount
+	}
+	if padding < PadZeros || PadRBSPTrailingBits < padding {
+		return 0, ErrOutOfRange
+	}
+	bitCount := bs.BitsToNextBoundary(alignBits)
+	if bitCount == 0 && padding == PadRBSPTrailingBits {
+		bitCount = alignBits
+	}
+	return bitCount, nil
+}
+
+// paddingCode returns the next chunk of padding in stream order. The first
+// chunk of PadRBSPTrailingBits begins with the stop bit.
+func paddingCode(padding Padding, first bool, bitCount byte) uint64 {
+	switch padding {
+	case PadOnes:
+		return math.MaxUint64 >> (64 - bitCount)
+	case PadRBSPTrailingBits:
+		if first {
+			return 1 << (bitCount - 1)
 		}
-		return bs.WriteExponentialGolomb(uint64(-val) * 2)
 	}
+	return 0
+}
+
+func (bs *BitStream) alignRead(alignBits int64) error {
+	if alignBits <= 0 {
+		return ErrInvalidBitCount
+	}
+	return bs.consumeBits(bs.BitsToNextBoundary(alignBits))
+}
+
+func (bs *BitStream) AlignRead(alignBits int64) bool {
+	return bs.record("AlignRead", bs.alignRead(alignBits))
+}
+
+func (bs *BitStream) alignReadPadding(alignBits int64, padding Padding) error {
+	bitCount, err := bs.paddingBitCount(alignBits, padding)
+	if err != nil {
+		return err
+	}
+	if err := bs.remainingBits(bitCount); err != nil {
+		return err
+	}
+
+	original := bs.pos
+	for first := true; bitCount > 0; first = false {
+		chunk := byte(64)
+		if bitCount < 64 {
+			chunk = byte(bitCount)
+		}
+		val, err := bs.readCode(chunk)
+		if err != nil {
+			bs.pos = original
+			return err
+		}
+		if val != paddingCode(padding, first, chunk) {
+			bs.pos = original
+			return ErrInvalidPadding
+		}
+		bitCount -= int64(chunk)
+	}
+	return nil
+}
+
+func (bs *BitStream) AlignReadPadding(alignBits int64, padding Padding) bool {
+	return bs.record("AlignReadPadding", bs.alignReadPadding(alignBits, padding))
+}
+
+func (bs *BitStream) alignWrite(alignBits int64, padding Padding) error {
+	bitCount, err := bs.paddingBitCount(alignBits, padding)
+	if err != nil {
+		return err
+	}
+
+	original := bs.pos
+	for first := true; bitCount > 0; first = false {
+		chunk := byte(64)
+		if bitCount < 64 {
+			chunk = byte(bitCount)
+		}
+		if err := bs.writeCode(paddingCode(padding, first, chunk), chunk); err != nil {
+			bs.pos = original
+			return err
+		}
+		bitCount -= int64(chunk)
+	}
+	return nil
+}
+
+func (bs *BitStream) AlignWrite(alignBits int64, padding Padding) bool {
+	return bs.record("AlignWrite", bs.alignWrite(alignBits, padding))
+}
+
+func (bs *BitStream) PadToByteBoundary(padding Padding) bool {
+	return bs.record("PadToByteBoundary", bs.alignWrite(8, padding))
+}
+
+func (bs *BitStream) BitOrder() BitOrder {
+	return bs.bitOrder
 }
 
 func NewBitStream(ba ByteAccessor) *BitStream {
+	return NewBitStreamWithBitOrder(ba, MSBFirst)
+}
+
+func NewBitStreamWithBitOrder(ba ByteAccessor, bitOrder BitOrder) *BitStream {
 	return &BitStream{
-		ba: ba,
+		ba:       ba,
+		bitOrder: bitOrder,
 		pos: pos{
 			byteOffset: 0,
 			bitOffset:  0,
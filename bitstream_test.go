@@ -2,6 +2,9 @@ package gobits
 
 import (
 	"encoding/binary"
+	"errors"
+	"io"
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -662,3 +665,598 @@ func TestBitStream_WriteSignedExponentialGolomb(t *testing.T) {
 	assert.False(t, ok)
 	assert.Equal(t, int64(0), sexpg)
 }
+
+func TestBitStream_WriteGrowable(t *testing.T) {
+	ba := NewGrowableByteAccessor(nil)
+	bs := NewBitStream(ba)
+
+	assert.True(t, bs.WriteBits(0x5, 3))
+	assert.Equal(t, []byte{0xa0}, ba.Bytes())
+	assert.True(t, bs.WriteBits(0x1234, 16))
+	assert.Equal(t, []byte{0xa2, 0x46, 0x80}, ba.Bytes())
+	assert.True(t, bs.WriteExponentialGolomb(3))
+	assert.Equal(t, []byte{0xa2, 0x46, 0x84}, ba.Bytes())
+
+	assert.True(t, bs.Seek(0, 0))
+	b, ok := bs.ReadBits(3)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(0x5), b)
+}
+
+func TestBitStream_PadToByteBoundary(t *testing.T) {
+	t.Run("zeros", func(t *testing.T) {
+		ba := NewGrowableByteAccessor(nil)
+		bs := NewBitStream(ba)
+		assert.True(t, bs.WriteBits(0x7, 3))
+		assert.True(t, bs.PadToByteBoundary(PadZeros))
+		assert.Equal(t, []byte{0xe0}, ba.Bytes())
+		assert.True(t, bs.PadToByteBoundary(PadZeros))
+		assert.Equal(t, []byte{0xe0}, ba.Bytes())
+	})
+	t.Run("ones", func(t *testing.T) {
+		ba := NewGrowableByteAccessor(nil)
+		bs := NewBitStream(ba)
+		assert.True(t, bs.WriteBits(0, 3))
+		assert.True(t, bs.PadToByteBoundary(PadOnes))
+		assert.Equal(t, []byte{0x1f}, ba.Bytes())
+		assert.True(t, bs.PadToByteBoundary(PadOnes))
+		assert.Equal(t, []byte{0x1f}, ba.Bytes())
+	})
+	t.Run("rbsp_trailing_bits", func(t *testing.T) {
+		ba := NewGrowableByteAccessor(nil)
+		bs := NewBitStream(ba)
+		assert.True(t, bs.WriteBits(0x7, 3))
+		assert.True(t, bs.PadToByteBoundary(PadRBSPTrailingBits))
+		assert.Equal(t, []byte{0xf0}, ba.Bytes())
+		assert.True(t, bs.PadToByteBoundary(PadRBSPTrailingBits))
+		assert.Equal(t, []byte{0xf0, 0x80}, ba.Bytes())
+	})
+	t.Run("fixed_size", func(t *testing.T) {
+		bs := NewBitStream(NewSliceByteAccessor([]byte{0xff}))
+		assert.True(t, bs.ConsumeBits(8))
+		assert.False(t, bs.PadToByteBoundary(PadRBSPTrailingBits))
+	})
+}
+
+func TestNewBitStreamWithBitOrder(t *testing.T) {
+	bs := NewBitStreamWithBitOrder(NewSliceByteAccessor([]byte{1}), LSBFirst)
+	assert.NotNil(t, bs)
+	assert.Equal(t, LSBFirst, bs.BitOrder())
+	assert.Equal(t, MSBFirst, NewBitStream(NewSliceByteAccessor([]byte{1})).BitOrder())
+}
+
+func TestBitStream_LSBFirst(t *testing.T) {
+	t.Run("read", func(t *testing.T) {
+		bs := NewBitStreamWithBitOrder(NewSliceByteAccessor([]byte{0x8d, 0x5a, 0xa5, 0xff}), LSBFirst)
+
+		bits, ok := bs.PeekBits(3)
+		assert.True(t, ok)
+		assert.Equal(t, uint64(0x5), bits)
+
+		bits, ok = bs.ReadBits(3)
+		assert.True(t, ok)
+		assert.Equal(t, uint64(0x5), bits)
+
+		bits, ok = bs.ReadBits(9)
+		assert.True(t, ok)
+		assert.Equal(t, uint64(0x151), bits)
+
+		bits, ok = bs.ReadBits(20)
+		assert.True(t, ok)
+		assert.Equal(t, uint64(0xffa55), bits)
+
+		_, ok = bs.ReadBits(1)
+		assert.False(t, ok)
+	})
+	t.Run("write", func(t *testing.T) {
+		ba := NewGrowableByteAccessor(nil)
+		bs := NewBitStreamWithBitOrder(ba, LSBFirst)
+
+		assert.True(t, bs.WriteBits(0x5, 3))
+		assert.True(t, bs.WriteBits(0x151, 9))
+		assert.True(t, bs.WriteBits(0xffa55, 20))
+		assert.Equal(t, []byte{0x8d, 0x5a, 0xa5, 0xff}, ba.Bytes())
+
+		assert.True(t, bs.Seek(0, 3))
+		assert.True(t, bs.WriteBits(0, 2))
+		assert.Equal(t, []byte{0x85, 0x5a, 0xa5, 0xff}, ba.Bytes())
+
+		assert.True(t, bs.Seek(0, 0))
+		for _, v := range []uint64{0x1, 0x0, 0x1234567890abcdef} {
+			assert.True(t, bs.WriteBits(v, 64))
+		}
+		assert.True(t, bs.Seek(0, 0))
+		for _, v := range []uint64{0x1, 0x0, 0x1234567890abcdef} {
+			bits, ok := bs.ReadBits(64)
+			assert.True(t, ok)
+			assert.Equal(t, v, bits)
+		}
+	})
+	t.Run("uint", func(t *testing.T) {
+		bs := NewBitStreamWithBitOrder(NewSliceByteAccessor(make([]byte, 8)), LSBFirst)
+
+		assert.True(t, bs.WriteUint16(0x1122, binary.LittleEndian))
+		assert.True(t, bs.WriteUint16(0x3344, binary.BigEndian))
+		assert.True(t, bs.WriteUint32(0x55667788, binary.LittleEndian))
+		assert.Equal(t, []byte{0x22, 0x11, 0x33, 0x44, 0x88, 0x77, 0x66, 0x55}, bs.ba.Slice(0, 8))
+
+		assert.True(t, bs.Seek(0, 0))
+		u16, ok := bs.ReadUint16(binary.LittleEndian)
+		assert.True(t, ok)
+		assert.Equal(t, uint16(0x1122), u16)
+		u16, ok = bs.ReadUint16(binary.BigEndian)
+		assert.True(t, ok)
+		assert.Equal(t, uint16(0x3344), u16)
+		u32, ok := bs.ReadUint32(binary.LittleEndian)
+		assert.True(t, ok)
+		assert.Equal(t, uint32(0x55667788), u32)
+
+		assert.True(t, bs.Seek(0, 0))
+		u64, ok := bs.ReadUint64(binary.LittleEndian)
+		assert.True(t, ok)
+		assert.Equal(t, uint64(0x5566778844331122), u64)
+	})
+	t.Run("exponential_golomb", func(t *testing.T) {
+		ba := NewGrowableByteAccessor(nil)
+		bs := NewBitStreamWithBitOrder(ba, LSBFirst)
+
+		for i := int64(-5); i <= 5; i++ {
+			assert.True(t, bs.WriteSignedExponentialGolomb(i))
+		}
+		assert.True(t, bs.PadToByteBoundary(PadRBSPTrailingBits))
+
+		assert.True(t, bs.Seek(0, 0))
+		for i := int64(-5); i <= 5; i++ {
+			v, ok := bs.ReadSignedExponentialGolomb()
+			assert.True(t, ok)
+			assert.Equal(t, i, v)
+		}
+
+		// The codes 1, 010 and 011 occupy bit 0 through bit 6 of 0x65.
+		bs = NewBitStreamWithBitOrder(NewSliceByteAccessor([]byte{0x65}), LSBFirst)
+		for _, expect := range []uint64{0, 1, 2} {
+			v, ok := bs.ReadExponentialGolomb()
+			assert.True(t, ok)
+			assert.Equal(t, expect, v)
+		}
+	})
+}
+
+func TestBitStream_ReadSignedBits(t *testing.T) {
+	bs := NewBitStream(NewSliceByteAccessor([]byte{0x80, 0x17, 0xff, 0x80, 0, 0, 0, 0, 0, 0, 0}))
+
+	v, ok := bs.ReadSignedBits(12)
+	assert.True(t, ok)
+	assert.Equal(t, int64(-2047), v)
+
+	v, ok = bs.ReadSignedBits(4)
+	assert.True(t, ok)
+	assert.Equal(t, int64(7), v)
+
+	v, ok = bs.ReadSignedBits(0)
+	assert.True(t, ok)
+	assert.Equal(t, int64(0), v)
+
+	v, ok = bs.ReadSignedBits(1)
+	assert.True(t, ok)
+	assert.Equal(t, int64(-1), v)
+
+	assert.True(t, bs.Seek(3, 0))
+	v, ok = bs.ReadSignedBits(64)
+	assert.True(t, ok)
+	assert.Equal(t, int64(-1<<63), v)
+
+	_, ok = bs.ReadSignedBits(65)
+	assert.False(t, ok)
+}
+
+func TestBitStream_WriteSignedBits(t *testing.T) {
+	bs := NewBitStream(NewGrowableByteAccessor(nil))
+
+	assert.True(t, bs.WriteSignedBits(-2048, 12))
+	assert.True(t, bs.WriteSignedBits(2047, 12))
+	assert.True(t, bs.WriteSignedBits(-1, 1))
+	assert.True(t, bs.WriteSignedBits(0, 0))
+	assert.True(t, bs.WriteSignedBits(-1<<63, 64))
+
+	assert.False(t, bs.WriteSignedBits(-2049, 12))
+	assert.True(t, errors.Is(bs.Err(), ErrOutOfRange))
+	assert.False(t, bs.WriteSignedBits(2048, 12))
+	assert.False(t, bs.WriteSignedBits(1, 1))
+	assert.False(t, bs.WriteSignedBits(1, 0))
+	assert.False(t, bs.WriteSignedBits(0, 65))
+
+	assert.True(t, bs.Seek(0, 0))
+	for _, c := range []struct {
+		expect   int64
+		bitCount byte
+	}{{-2048, 12}, {2047, 12}, {-1, 1}, {-1 << 63, 64}} {
+		v, ok := bs.ReadSignedBits(c.bitCount)
+		assert.True(t, ok)
+		assert.Equal(t, c.expect, v)
+	}
+}
+
+func TestBitStream_SignMagnitudeBits(t *testing.T) {
+	bs := NewBitStream(NewGrowableByteAccessor(nil))
+
+	assert.True(t, bs.WriteSignMagnitudeBits(-5, 4))
+	assert.True(t, bs.WriteSignMagnitudeBits(7, 4))
+	assert.True(t, bs.WriteSignMagnitudeBits(0, 1))
+	assert.True(t, bs.WriteSignMagnitudeBits(-(1<<63-1), 64))
+	assert.False(t, bs.WriteSignMagnitudeBits(8, 4))
+	assert.True(t, errors.Is(bs.Err(), ErrOutOfRange))
+	assert.False(t, bs.WriteSignMagnitudeBits(-8, 4))
+	assert.False(t, bs.WriteSignMagnitudeBits(-1<<63, 64))
+	assert.False(t, bs.WriteSignMagnitudeBits(0, 0))
+	assert.True(t, errors.Is(bs.Err(), ErrInvalidBitCount))
+
+	assert.True(t, bs.Seek(0, 0))
+	b, ok := bs.PeekBits(8)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(0xd7), b)
+
+	for _, c := range []struct {
+		expect   int64
+		bitCount byte
+	}{{-5, 4}, {7, 4}, {0, 1}, {-(1<<63 - 1), 64}} {
+		v, ok := bs.ReadSignMagnitudeBits(c.bitCount)
+		assert.True(t, ok)
+		assert.Equal(t, c.expect, v)
+	}
+
+	bs = NewBitStream(NewSliceByteAccessor([]byte{0x80}))
+	v, ok := bs.ReadSignMagnitudeBits(8)
+	assert.True(t, ok)
+	assert.Equal(t, int64(0), v)
+}
+
+func TestBitStream_Int(t *testing.T) {
+	bs := NewBitStream(NewSliceByteAccessor(make([]byte, 15)))
+
+	assert.True(t, bs.WriteInt8(-2))
+	assert.True(t, bs.WriteInt16(-2, binary.LittleEndian))
+	assert.True(t, bs.WriteInt32(-2, binary.BigEndian))
+	assert.True(t, bs.WriteInt64(-0x1122334455667788, binary.LittleEndian))
+	assert.Equal(t, []byte{0xfe, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xfe}, bs.ba.Slice(0, 7))
+
+	assert.True(t, bs.Seek(0, 0))
+	i8, ok := bs.ReadInt8()
+	assert.True(t, ok)
+	assert.Equal(t, int8(-2), i8)
+	i16, ok := bs.ReadInt16(binary.LittleEndian)
+	assert.True(t, ok)
+	assert.Equal(t, int16(-2), i16)
+	i32, ok := bs.ReadInt32(binary.BigEndian)
+	assert.True(t, ok)
+	assert.Equal(t, int32(-2), i32)
+	i64, ok := bs.ReadInt64(binary.LittleEndian)
+	assert.True(t, ok)
+	assert.Equal(t, int64(-0x1122334455667788), i64)
+
+	_, ok = bs.ReadInt8()
+	assert.False(t, ok)
+}
+
+func TestBitStream_Float(t *testing.T) {
+	bs := NewBitStream(NewGrowableByteAccessor(nil))
+
+	assert.True(t, bs.WriteBits(0x5, 3))
+	assert.True(t, bs.WriteFloat16(-1.5, binary.BigEndian))
+	assert.True(t, bs.WriteFloat32(3.25, binary.LittleEndian))
+	assert.True(t, bs.WriteFloat64(math.Pi, binary.BigEndian))
+	assert.True(t, bs.WriteFloat16(float32(math.Inf(1)), binary.LittleEndian))
+
+	assert.True(t, bs.Seek(0, 3))
+	f16, ok := bs.ReadFloat16(binary.BigEndian)
+	assert.True(t, ok)
+	assert.Equal(t, float32(-1.5), f16)
+	f32, ok := bs.ReadFloat32(binary.LittleEndian)
+	assert.True(t, ok)
+	assert.Equal(t, float32(3.25), f32)
+	f64, ok := bs.ReadFloat64(binary.BigEndian)
+	assert.True(t, ok)
+	assert.Equal(t, math.Pi, f64)
+	f16, ok = bs.ReadFloat16(binary.LittleEndian)
+	assert.True(t, ok)
+	assert.True(t, math.IsInf(float64(f16), 1))
+
+	_, ok = bs.ReadFloat32(binary.BigEndian)
+	assert.False(t, ok)
+
+	bs = NewBitStream(NewSliceByteAccessor([]byte{0x3f, 0x80, 0x00, 0x00}))
+	f32, ok = bs.ReadFloat32(binary.BigEndian)
+	assert.True(t, ok)
+	assert.Equal(t, float32(1), f32)
+}
+
+func TestBitStream_Alignment(t *testing.T) {
+	bs := NewBitStream(NewSliceByteAccessor(make([]byte, 8)))
+
+	assert.True(t, bs.IsByteAligned())
+	assert.True(t, bs.IsAligned(32))
+	assert.Equal(t, int64(0), bs.BitsToNextByte())
+
+	assert.True(t, bs.ConsumeBits(3))
+	assert.False(t, bs.IsByteAligned())
+	assert.False(t, bs.IsAligned(32))
+	assert.True(t, bs.IsAligned(1))
+	assert.True(t, bs.IsAligned(0))
+	assert.Equal(t, int64(5), bs.BitsToNextByte())
+	assert.Equal(t, int64(29), bs.BitsToNextBoundary(32))
+
+	assert.True(t, bs.AlignRead(8))
+	assert.Equal(t, int64(1), bs.byteOffset)
+	assert.Equal(t, byte(0), bs.bitOffset)
+	assert.True(t, bs.AlignRead(8))
+	assert.Equal(t, int64(1), bs.byteOffset)
+
+	assert.True(t, bs.AlignRead(32))
+	assert.Equal(t, int64(4), bs.byteOffset)
+
+	assert.True(t, bs.ConsumeBits(1))
+	assert.False(t, bs.AlignRead(128))
+	assert.True(t, errors.Is(bs.Err(), ErrUnexpectedEOF))
+	assert.False(t, bs.AlignRead(0))
+	assert.True(t, errors.Is(bs.Err(), ErrInvalidBitCount))
+}
+
+func TestBitStream_AlignReadPadding(t *testing.T) {
+	t.Run("zeros", func(t *testing.T) {
+		bs := NewBitStream(NewSliceByteAccessor([]byte{0xe0, 0xe1}))
+		assert.True(t, bs.ConsumeBits(3))
+		assert.True(t, bs.AlignReadPadding(8, PadZeros))
+		assert.Equal(t, int64(1), bs.byteOffset)
+
+		assert.True(t, bs.ConsumeBits(3))
+		assert.False(t, bs.AlignReadPadding(8, PadZeros))
+		assert.True(t, errors.Is(bs.Err(), ErrInvalidPadding))
+		assert.Equal(t, int64(1), bs.byteOffset)
+		assert.Equal(t, byte(3), bs.bitOffset)
+	})
+	t.Run("ones", func(t *testing.T) {
+		bs := NewBitStream(NewSliceByteAccessor([]byte{0x1f}))
+		assert.True(t, bs.ConsumeBits(3))
+		assert.True(t, bs.AlignReadPadding(8, PadOnes))
+	})
+	t.Run("rbsp_trailing_bits", func(t *testing.T) {
+		bs := NewBitStream(NewSliceByteAccessor([]byte{0xf0, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01}))
+		assert.True(t, bs.ConsumeBits(3))
+		assert.True(t, bs.AlignReadPadding(8, PadRBSPTrailingBits))
+		assert.Equal(t, int64(1), bs.byteOffset)
+
+		assert.True(t, bs.AlignReadPadding(8, PadRBSPTrailingBits))
+		assert.Equal(t, int64(2), bs.byteOffset)
+
+		assert.False(t, bs.AlignReadPadding(8, PadRBSPTrailingBits))
+		assert.True(t, errors.Is(bs.Err(), ErrInvalidPadding))
+
+		assert.True(t, bs.Seek(1, 0))
+		assert.False(t, bs.AlignReadPadding(88, PadRBSPTrailingBits))
+		assert.True(t, errors.Is(bs.Err(), ErrInvalidPadding))
+		assert.True(t, bs.Seek(1, 0))
+		assert.True(t, bs.AlignReadPadding(80, PadRBSPTrailingBits))
+		assert.Equal(t, int64(10), bs.byteOffset)
+	})
+	t.Run("lsb_first", func(t *testing.T) {
+		bs := NewBitStreamWithBitOrder(NewSliceByteAccessor([]byte{0x0f}), LSBFirst)
+		assert.True(t, bs.ConsumeBits(3))
+		assert.False(t, bs.AlignReadPadding(8, PadZeros))
+		assert.True(t, bs.AlignReadPadding(8, PadRBSPTrailingBits))
+	})
+}
+
+func TestBitStream_AlignWrite(t *testing.T) {
+	ba := NewGrowableByteAccessor(nil)
+	bs := NewBitStream(ba)
+
+	assert.True(t, bs.WriteBits(1, 1))
+	assert.True(t, bs.AlignWrite(16, PadOnes))
+	assert.Equal(t, []byte{0xff, 0xff}, ba.Bytes())
+	assert.True(t, bs.AlignWrite(16, PadZeros))
+	assert.Equal(t, []byte{0xff, 0xff}, ba.Bytes())
+
+	assert.True(t, bs.WriteBits(0, 1))
+	assert.True(t, bs.AlignWrite(32, PadRBSPTrailingBits))
+	assert.Equal(t, []byte{0xff, 0xff, 0x40, 0x00}, ba.Bytes())
+	assert.True(t, bs.AlignWrite(32, PadRBSPTrailingBits))
+	assert.Equal(t, []byte{0xff, 0xff, 0x40, 0x00, 0x80, 0x00, 0x00, 0x00}, ba.Bytes())
+
+	assert.True(t, bs.AlignWrite(128, PadOnes))
+	assert.Equal(t, int64(16), ba.Length())
+	assert.Equal(t, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, ba.Slice(8, 8))
+
+	assert.False(t, bs.AlignWrite(0, PadZeros))
+	assert.True(t, errors.Is(bs.Err(), ErrInvalidBitCount))
+	assert.False(t, bs.AlignWrite(8, Padding(-1)))
+	assert.True(t, errors.Is(bs.Err(), ErrOutOfRange))
+
+	bs = NewBitStream(NewSliceByteAccessor([]byte{0}))
+	assert.True(t, bs.ConsumeBits(4))
+	assert.False(t, bs.AlignWrite(16, PadOnes))
+	assert.Equal(t, int64(0), bs.byteOffset)
+	assert.Equal(t, byte(4), bs.bitOffset)
+}
+
+func TestBitStream_Tell(t *testing.T) {
+	bs := NewBitStream(NewSliceByteAccessor([]byte{1, 2, 3}))
+	assert.Equal(t, int64(0), bs.Tell())
+	assert.True(t, bs.ConsumeBits(13))
+	assert.Equal(t, int64(13), bs.Tell())
+	assert.True(t, bs.ConsumeBits(11))
+	assert.Equal(t, int64(24), bs.Tell())
+}
+
+func TestBitStream_SeekBits(t *testing.T) {
+	bs := NewBitStream(NewSliceByteAccessor([]byte{1, 2, 3}))
+
+	offset, ok := bs.SeekBits(13, io.SeekStart)
+	assert.True(t, ok)
+	assert.Equal(t, int64(13), offset)
+	assert.Equal(t, int64(1), bs.byteOffset)
+	assert.Equal(t, byte(5), bs.bitOffset)
+
+	offset, ok = bs.SeekBits(-6, io.SeekCurrent)
+	assert.True(t, ok)
+	assert.Equal(t, int64(7), offset)
+
+	offset, ok = bs.SeekBits(-1, io.SeekEnd)
+	assert.True(t, ok)
+	assert.Equal(t, int64(23), offset)
+
+	offset, ok = bs.SeekBits(0, io.SeekEnd)
+	assert.True(t, ok)
+	assert.Equal(t, int64(24), offset)
+	_, ok = bs.ReadBits(1)
+	assert.False(t, ok)
+
+	_, ok = bs.SeekBits(1, io.SeekEnd)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrInvalidOffset))
+	_, ok = bs.SeekBits(-1, io.SeekStart)
+	assert.False(t, ok)
+	_, ok = bs.SeekBits(0, 3)
+	assert.False(t, ok)
+	assert.Equal(t, int64(24), bs.Tell())
+}
+
+func TestBitStream_SeekEnd(t *testing.T) {
+	t.Run("slice_byteaccessor", func(t *testing.T) {
+		bs := NewBitStream(NewSliceByteAccessor([]byte{1, 2, 3, 4, 5}))
+		assert.True(t, bs.Seek(5, 0))
+		assert.False(t, bs.Seek(5, 1))
+		assert.False(t, bs.Seek(6, 0))
+		assert.False(t, bs.WriteBits(1, 1))
+	})
+	t.Run("io_byteaccessor", func(t *testing.T) {
+		rwseeker, teardown := setupTestDataFile(t)
+		defer teardown()
+		bs := NewBitStream(NewIOByteAccessor(rwseeker))
+		assert.True(t, bs.Seek(7248, 0))
+		assert.False(t, bs.Seek(7248, 1))
+		assert.True(t, bs.RemainingBits(0))
+		assert.False(t, bs.RemainingBits(1))
+	})
+	t.Run("growable_byteaccessor", func(t *testing.T) {
+		ba := NewGrowableByteAccessor([]byte{0xaa})
+		bs := NewBitStream(ba)
+		_, ok := bs.SeekBits(0, io.SeekEnd)
+		assert.True(t, ok)
+		assert.True(t, bs.WriteUint8(0xbb))
+		assert.Equal(t, []byte{0xaa, 0xbb}, ba.Bytes())
+	})
+}
+
+func TestPosWrapper(t *testing.T) {
+	bs := NewBitStream(NewSliceByteAccessor([]byte{1, 2, 3}))
+	assert.True(t, bs.ConsumeBits(3))
+	start := bs.SavePos()
+	assert.True(t, bs.ConsumeBits(14))
+	end := bs.SavePos()
+
+	assert.Equal(t, int64(0), start.ByteOffset())
+	assert.Equal(t, byte(3), start.BitOffset())
+	assert.Equal(t, int64(3), start.Bits())
+	assert.Equal(t, int64(2), end.ByteOffset())
+	assert.Equal(t, byte(1), end.BitOffset())
+	assert.Equal(t, int64(17), end.Bits())
+	assert.Equal(t, int64(14), end.Sub(start))
+	assert.Equal(t, int64(-14), start.Sub(end))
+}
+
+func TestBitStream_ReadExpGolombK(t *testing.T) {
+	bs := NewBitStream(NewSliceByteAccessor([]byte{0xb4, 0x52, 0x00}))
+	for _, expect := range []uint64{0, 1, 2, 3} {
+		v, ok := bs.ReadExpGolombK(1)
+		assert.True(t, ok)
+		assert.Equal(t, expect, v)
+	}
+
+	// 00 10000 with k = 2 is 16 - 4 = 12.
+	v, ok := bs.ReadExpGolombK(2)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(12), v)
+
+	pos := bs.SavePos()
+	_, ok = bs.ReadExpGolombK(3)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrUnexpectedEOF))
+	assert.Equal(t, pos, bs.SavePos())
+
+	_, ok = bs.ReadExpGolombK(64)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrInvalidBitCount))
+}
+
+func TestBitStream_WriteExpGolombK(t *testing.T) {
+	ba := NewGrowableByteAccessor(nil)
+	bs := NewBitStream(ba)
+	for _, v := range []uint64{0, 1, 2, 3} {
+		assert.True(t, bs.WriteExpGolombK(v, 1))
+	}
+	assert.True(t, bs.PadToByteBoundary(PadZeros))
+	assert.Equal(t, []byte{0xb4, 0x50}, ba.Bytes())
+
+	values := []uint64{0, 1, 7, 8, 1000, 1<<32 + 5, 1<<63 - 1, math.MaxUint64 - 1<<5}
+	for k := byte(0); k < 6; k++ {
+		ba = NewGrowableByteAccessor(nil)
+		bs = NewBitStream(ba)
+		for _, v := range values {
+			if v > math.MaxUint64-1<<k {
+				continue
+			}
+			assert.True(t, bs.WriteExpGolombK(v, k))
+		}
+		assert.True(t, bs.Seek(0, 0))
+		for _, expect := range values {
+			if expect > math.MaxUint64-1<<k {
+				continue
+			}
+			v, ok := bs.ReadExpGolombK(k)
+			assert.True(t, ok)
+			assert.Equal(t, expect, v)
+		}
+	}
+
+	assert.False(t, bs.WriteExpGolombK(math.MaxUint64-1<<4, 5))
+	assert.True(t, errors.Is(bs.Err(), ErrOutOfRange))
+	assert.False(t, bs.WriteExpGolombK(0, 64))
+	assert.True(t, errors.Is(bs.Err(), ErrInvalidBitCount))
+
+	bs = NewBitStream(NewSliceByteAccessor([]byte{0xff}))
+	assert.True(t, bs.ConsumeBits(2))
+	assert.False(t, bs.WriteExpGolombK(100, 0))
+	assert.Equal(t, int64(2), bs.Tell())
+	assert.Equal(t, []byte{0xff}, bs.ba.Slice(0, 1))
+}
+
+func TestBitStream_SignedExpGolombK(t *testing.T) {
+	ba := NewGrowableByteAccessor(nil)
+	bs := NewBitStream(ba)
+	values := []int64{0, 1, -1, 2, -2, 100, -100, math.MaxInt64 / 8, math.MinInt64/8 + 1}
+	for _, v := range values {
+		assert.True(t, bs.WriteSignedExpGolombK(v, 3))
+	}
+	assert.True(t, bs.WriteSignedExpGolombK(math.MaxInt64, 0))
+	assert.True(t, bs.WriteSignedExpGolombK(math.MinInt64+1, 0))
+	assert.False(t, bs.WriteSignedExpGolombK(math.MaxInt64, 3))
+	assert.True(t, errors.Is(bs.Err(), ErrOutOfRange))
+	assert.False(t, bs.WriteSignedExpGolombK(math.MinInt64, 0))
+	assert.True(t, errors.Is(bs.Err(), ErrOutOfRange))
+
+	assert.True(t, bs.Seek(0, 0))
+	for _, expect := range values {
+		v, ok := bs.ReadSignedExpGolombK(3)
+		assert.True(t, ok)
+		assert.Equal(t, expect, v)
+	}
+	v, ok := bs.ReadSignedExpGolombK(0)
+	assert.True(t, ok)
+	assert.Equal(t, int64(math.MaxInt64), v)
+	v, ok = bs.ReadSignedExponentialGolomb()
+	assert.True(t, ok)
+	assert.Equal(t, int64(math.MinInt64+1), v)
+
+	_, ok = bs.ReadSignedExpGolombK(3)
+	assert.False(t, ok)
+}
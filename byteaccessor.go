@@ -6,3 +6,46 @@ type ByteAccessor interface {
 	Put(bytes []byte, byteOffset int64) bool
 	Length() int64
 }
+
+// CheckedByteAccessor is implemented by accessors that can explain why At or
+// Put failed. BitStream uses it when available to report descriptive errors.
+type CheckedByteAccessor interface {
+	ByteAccessor
+	ByteAt(byteOffset int64) (byte, error)
+	PutBytes(bytes []byte, byteOffset int64) error
+}
+
+// ExtendableByteAccessor is implemented by accessors whose length grows on
+// demand. BitStream extends them before writing past the end of data.
+type ExtendableByteAccessor interface {
+	ByteAccessor
+	Extend(length int64) error
+}
+
+// TruncatableByteAccessor is implemented by accessors whose length can be
+// reduced, so that rewriting data in a shorter form leaves no stale bytes.
+type TruncatableByteAccessor interface {
+	ByteAccessor
+	Truncate(length int64) error
+}
+
+func byteAt(ba ByteAccessor, byteOffset int64) (byte, error) {
+	if cba, ok := ba.(CheckedByteAccessor); ok {
+		return cba.ByteAt(byteOffset)
+	}
+	b, ok := ba.At(byteOffset)
+	if !ok {
+		return 0, ErrUnexpectedEOF
+	}
+	return b, nil
+}
+
+func putBytes(ba ByteAccessor, bytes []byte, byteOffset int64) error {
+	if cba, ok := ba.(CheckedByteAccessor); ok {
+		return cba.PutBytes(bytes, byteOffset)
+	}
+	if !ba.Put(bytes, byteOffset) {
+		return ErrInvalidOffset
+	}
+	return nil
+}
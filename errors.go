@@ -0,0 +1,57 @@
+package gobits
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnexpectedEOF   = errors.New("gobits: unexpected end of data")
+	ErrInvalidBitCount = errors.New("gobits: invalid bit count")
+	ErrInvalidOffset   = errors.New("gobits: invalid offset")
+	ErrMalformedCode   = errors.New("gobits: malformed variable-length code")
+	ErrOutOfRange      = errors.New("gobits: value out of range")
+	ErrInvalidPadding  = errors.New("gobits: invalid padding bits")
+	ErrInvalidTable    = errors.New("gobits: invalid code table")
+	ErrInvalidSyntax   = errors.New("gobits: invalid syntax")
+	ErrMarker          = errors.New("gobits: marker in entropy-coded data")
+	ErrIO              = errors.New("gobits: i/o error")
+	ErrChecksum        = errors.New("gobits: checksum mismatch")
+)
+
+// BitStreamError describes a failed BitStream operation and the position at
+// which it was attempted.
+type BitStreamError struct {
+	Op         string
+	ByteOffset int64
+	BitOffset  byte
+	Err        error
+}
+
+func (e *BitStreamError) Error() string {
+	return fmt.Sprintf("gobits: %s at byte %d bit %d: %v", e.Op, e.ByteOffset, e.BitOffset, e.Err)
+}
+
+func (e *BitStreamError) Unwrap() error {
+	return e.Err
+}
+
+// IOError wraps an error returned by the io.ReadWriteSeeker behind an
+// IOByteAccessor. It matches ErrIO as well as the original error.
+type IOError struct {
+	Op     string
+	Offset int64
+	Err    error
+}
+
+func (e *IOError) Error() string {
+	return fmt.Sprintf("gobits: %s at offset %d: %v", e.Op, e.Offset, e.Err)
+}
+
+func (e *IOError) Unwrap() error {
+	return e.Err
+}
+
+func (e *IOError) Is(target error) bool {
+	return target == ErrIO
+}
@@ -0,0 +1,112 @@
+package gobits
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errBrokenDevice = errors.New("broken device")
+
+type brokenReadWriteSeeker struct{}
+
+func (brokenReadWriteSeeker) Read(p []byte) (int, error) {
+	return 0, errBrokenDevice
+}
+
+func (brokenReadWriteSeeker) Write(p []byte) (int, error) {
+	return 0, errBrokenDevice
+}
+
+func (brokenReadWriteSeeker) Seek(offset int64, whence int) (int64, error) {
+	return offset, nil
+}
+
+func TestBitStreamError(t *testing.T) {
+	err := &BitStreamError{Op: "ReadBits", ByteOffset: 3, BitOffset: 5, Err: ErrUnexpectedEOF}
+	assert.Equal(t, "gobits: ReadBits at byte 3 bit 5: gobits: unexpected end of data", err.Error())
+	assert.True(t, errors.Is(err, ErrUnexpectedEOF))
+	assert.False(t, errors.Is(err, ErrInvalidBitCount))
+}
+
+func TestIOError(t *testing.T) {
+	err := &IOError{Op: "read", Offset: 10, Err: io.ErrClosedPipe}
+	assert.True(t, errors.Is(err, ErrIO))
+	assert.True(t, errors.Is(err, io.ErrClosedPipe))
+	assert.False(t, errors.Is(err, ErrUnexpectedEOF))
+}
+
+func TestBitStream_Err(t *testing.T) {
+	t.Run("unexpected_eof", func(t *testing.T) {
+		bs := NewBitStream(NewSliceByteAccessor([]byte{0xff}))
+		assert.Nil(t, bs.Err())
+
+		assert.True(t, bs.ConsumeBits(3))
+		_, ok := bs.ReadBits(6)
+		assert.False(t, ok)
+
+		var bsErr *BitStreamError
+		assert.True(t, errors.As(bs.Err(), &bsErr))
+		assert.Equal(t, "ReadBits", bsErr.Op)
+		assert.Equal(t, int64(0), bsErr.ByteOffset)
+		assert.Equal(t, byte(3), bsErr.BitOffset)
+		assert.True(t, errors.Is(bs.Err(), ErrUnexpectedEOF))
+
+		_, ok = bs.ReadBits(5)
+		assert.True(t, ok)
+		assert.Nil(t, bs.Err())
+	})
+	t.Run("invalid_bit_count", func(t *testing.T) {
+		bs := NewBitStream(NewSliceByteAccessor(make([]byte, 16)))
+		_, ok := bs.PeekBits(65)
+		assert.False(t, ok)
+		assert.True(t, errors.Is(bs.Err(), ErrInvalidBitCount))
+
+		assert.False(t, bs.WriteBits(0, 65))
+		assert.True(t, errors.Is(bs.Err(), ErrInvalidBitCount))
+	})
+	t.Run("invalid_offset", func(t *testing.T) {
+		bs := NewBitStream(NewSliceByteAccessor([]byte{1, 2}))
+		assert.False(t, bs.Seek(1, 8))
+		assert.True(t, errors.Is(bs.Err(), ErrInvalidOffset))
+		assert.False(t, bs.Seek(3, 0))
+		assert.True(t, errors.Is(bs.Err(), ErrInvalidOffset))
+		assert.False(t, bs.Seek(-1, 0))
+		assert.True(t, errors.Is(bs.Err(), ErrInvalidOffset))
+	})
+	t.Run("malformed_exponential_golomb", func(t *testing.T) {
+		bytes := make([]byte, 17)
+		bytes[8] = 0x80
+		bs := NewBitStream(NewSliceByteAccessor(bytes))
+		_, ok := bs.ReadExponentialGolomb()
+		assert.False(t, ok)
+		assert.True(t, errors.Is(bs.Err(), ErrMalformedCode))
+		assert.Equal(t, int64(0), bs.byteOffset)
+		assert.Equal(t, byte(0), bs.bitOffset)
+
+		bs = NewBitStream(NewSliceByteAccessor([]byte{0x00}))
+		_, ok = bs.ReadExponentialGolomb()
+		assert.False(t, ok)
+		assert.True(t, errors.Is(bs.Err(), ErrUnexpectedEOF))
+	})
+	t.Run("out_of_range", func(t *testing.T) {
+		bs := NewBitStream(NewSliceByteAccessor(make([]byte, 16)))
+		assert.False(t, bs.WriteExponentialGolomb(1<<64-1))
+		assert.True(t, errors.Is(bs.Err(), ErrOutOfRange))
+		assert.False(t, bs.WriteSignedExponentialGolomb(-1<<63))
+		assert.True(t, errors.Is(bs.Err(), ErrOutOfRange))
+	})
+	t.Run("io_error", func(t *testing.T) {
+		bs := NewBitStream(NewIOByteAccessor(brokenReadWriteSeeker{}))
+		_, ok := bs.ReadUint8()
+		assert.False(t, ok)
+		assert.True(t, errors.Is(bs.Err(), ErrIO))
+		assert.True(t, errors.Is(bs.Err(), errBrokenDevice))
+
+		var ioErr *IOError
+		assert.True(t, errors.As(bs.Err(), &ioErr))
+		assert.Equal(t, "read", ioErr.Op)
+	})
+}
@@ -0,0 +1,77 @@
+package gobits
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFloat16ToFloat32(t *testing.T) {
+	cases := []struct {
+		h      uint16
+		expect float32
+	}{
+		{0x0000, 0},
+		{0x3c00, 1},
+		{0xc000, -2},
+		{0x7bff, 65504},
+		{0x3555, 0.333251953125},
+		{0x0400, 6.103515625e-05},
+		{0x03ff, 6.097555160522461e-05},
+		{0x0001, 5.960464477539063e-08},
+		{0x7c00, float32(math.Inf(1))},
+		{0xfc00, float32(math.Inf(-1))},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expect, Float16ToFloat32(c.h))
+	}
+
+	assert.Equal(t, uint32(0x80000000), math.Float32bits(Float16ToFloat32(0x8000)))
+	assert.Equal(t, uint32(0x7fc02000), math.Float32bits(Float16ToFloat32(0x7e01)))
+	assert.Equal(t, uint32(0xff802000), math.Float32bits(Float16ToFloat32(0xfc01)))
+}
+
+func TestFloat32ToFloat16(t *testing.T) {
+	cases := []struct {
+		f      float32
+		expect uint16
+	}{
+		{0, 0x0000},
+		{1, 0x3c00},
+		{-2, 0xc000},
+		{65504, 0x7bff},
+		{65519, 0x7bff},
+		{65520, 0x7c00},
+		{1e10, 0x7c00},
+		{-1e10, 0xfc00},
+		{0.333333333, 0x3555},
+		{6.103515625e-05, 0x0400},
+		{6.097555160522461e-05, 0x03ff},
+		{5.960464477539063e-08, 0x0001},
+		{2.98023223876953125e-08, 0x0000},
+		{2.99e-08, 0x0001},
+		{8.940696716308594e-08, 0x0002},
+		{1e-10, 0x0000},
+		{1.0009765625, 0x3c01},
+		{1.00048828125, 0x3c00},
+		{1.00146484375, 0x3c02},
+		{float32(math.Inf(1)), 0x7c00},
+		{float32(math.Inf(-1)), 0xfc00},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expect, Float32ToFloat16(c.f), "%v", c.f)
+	}
+
+	assert.Equal(t, uint16(0x8000), Float32ToFloat16(float32(math.Copysign(0, -1))))
+	assert.Equal(t, uint16(0x7e01), Float32ToFloat16(math.Float32frombits(0x7fc02000)))
+	assert.Equal(t, uint16(0x7e00), Float32ToFloat16(math.Float32frombits(0x7fc00001)))
+	assert.Equal(t, uint16(0x7e00), Float32ToFloat16(math.Float32frombits(0x7f800001)))
+
+	for h := 0; h <= 0xffff; h++ {
+		if h&0x7c00 == 0x7c00 && h&0x3ff != 0 {
+			continue
+		}
+		assert.Equal(t, uint16(h), Float32ToFloat16(Float16ToFloat32(uint16(h))))
+	}
+}
@@ -0,0 +1,127 @@
+package gobits
+
+// FrameHeaderFunc parses the frame header at byteOffset, if there is a
+// valid one, and returns the length of the frame, header included, and a
+// comparable value that frames of the same stream share.
+type FrameHeaderFunc func(byteOffset int64) (length int64, stream interface{}, ok bool)
+
+// FrameSync locates the frames of a stream in which each frame starts with
+// a syncword and a header that gives the frame length, as in MPEG audio and
+// ADTS. It locks on to a position only when checks frames of one stream
+// follow each other from there, which rules out most false syncwords in
+// tags and corrupt data. Once locked, each frame need only be followed by
+// another of the same stream; otherwise it searches again.
+type FrameSync struct {
+	ba        ByteAccessor
+	pattern   []byte
+	mask      []byte
+	checks    int
+	header    FrameHeaderFunc
+	offset    int64
+	length    int64
+	discarded int64
+	locked    bool
+	stream    interface{}
+	err       error
+}
+
+// follows reports whether checks frames follow each other from byteOffset
+// on, each within the data, unless the data ends first. The frames must
+// belong to stream if known is set, and to a single stream in any case.
+func (fs *FrameSync) follows(byteOffset int64, checks int, stream interface{}, known bool) bool {
+	for i := 0; i < checks; i++ {
+		if i > 0 && byteOffset == fs.ba.Length() {
+			return true
+		}
+		length, s, ok := fs.header(byteOffset)
+		if !ok || length <= 0 || (known && s != stream) {
+			return false
+		}
+		stream, known = s, true
+		byteOffset += length
+		if byteOffset > fs.ba.Length() {
+			return false
+		}
+	}
+	return true
+}
+
+// resync returns the offset of the first frame at or after byteOffset that
+// can be locked on to, or -1 if there is none.
+func (fs *FrameSync) resync(byteOffset int64) int64 {
+	for {
+		byteOffset = FindSyncword(fs.ba, byteOffset, fs.pattern, fs.mask)
+		if byteOffset < 0 || fs.follows(byteOffset, fs.checks, nil, false) {
+			return byteOffset
+		}
+		byteOffset++
+	}
+}
+
+// Next advances to the next frame. It returns false at the end of the data
+// or on error. A valid header of the current stream where no frame can be
+// locked on to starts a frame that the data cuts short, which fails with
+// ErrUnexpectedEOF.
+func (fs *FrameSync) Next() bool {
+	if fs.err != nil {
+		return false
+	}
+	fs.offset += fs.length
+	fs.length = 0
+	if fs.offset >= fs.ba.Length() {
+		return false
+	}
+
+	if !fs.locked || !fs.follows(fs.offset, 1, fs.stream, true) {
+		start := fs.resync(fs.offset)
+		if start < 0 {
+			if _, stream, ok := fs.header(fs.offset); ok && (!fs.locked || stream == fs.stream) {
+				fs.err = ErrUnexpectedEOF
+				return false
+			}
+			fs.discarded += fs.ba.Length() - fs.offset
+			fs.offset = fs.ba.Length()
+			return false
+		}
+		fs.discarded += start - fs.offset
+		fs.offset = start
+	}
+	fs.length, fs.stream, _ = fs.header(fs.offset)
+	fs.locked = true
+	return true
+}
+
+// Skip skips n bytes of data that cannot hold frames, such as a tag at the
+// start of a file, and counts them as discarded.
+func (fs *FrameSync) Skip(n int64) {
+	fs.offset += n
+	fs.discarded += n
+}
+
+// Offset returns the offset of the frame found by the last successful call
+// to Next.
+func (fs *FrameSync) Offset() int64 {
+	return fs.offset
+}
+
+// Length returns the length of the frame found by the last successful call
+// to Next.
+func (fs *FrameSync) Length() int64 {
+	return fs.length
+}
+
+// Discarded returns the number of bytes skipped so far while searching for
+// frames.
+func (fs *FrameSync) Discarded() int64 {
+	return fs.discarded
+}
+
+func (fs *FrameSync) Err() error {
+	return fs.err
+}
+
+// NewFrameSync returns a FrameSync for the frames in ba, which start with
+// pattern under mask as for FindSyncword and whose headers header parses.
+func NewFrameSync(ba ByteAccessor, pattern, mask []byte, checks int, header FrameHeaderFunc) *FrameSync {
+	return &FrameSync{ba: ba, pattern: pattern, mask: mask, checks: checks, header: header}
+}
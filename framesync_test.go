@@ -0,0 +1,65 @@
+package gobits
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testFrame returns a frame of a format whose header is a5, then c0 ORed
+// with the stream, then the frame length.
+func testFrame(stream byte, length int) []byte {
+	frame := make([]byte, length)
+	frame[0], frame[1], frame[2] = 0xa5, 0xc0|stream, byte(length)
+	return frame
+}
+
+func TestFrameSync(t *testing.T) {
+	var data []byte
+	offsets := []int64{}
+	appendFrames := func(stream byte, lengths ...int) {
+		for _, length := range lengths {
+			offsets = append(offsets, int64(len(data)))
+			data = append(data, testFrame(stream, length)...)
+		}
+	}
+	// A false syncword, frames, junk, then frames of another stream, which
+	// is followed once it can be locked on to.
+	data = append(data, 0x00, 0xa5, 0xc1, 0x03, 0x00)
+	appendFrames(1, 5, 6, 7, 8)
+	data = append(data, 0xa5, 0xc1, 0x00)
+	appendFrames(2, 4, 4, 4)
+	ba := NewSliceByteAccessor(data)
+
+	header := func(byteOffset int64) (int64, interface{}, bool) {
+		h := ba.Slice(byteOffset, 3)
+		if len(h) < 3 || h[0] != 0xa5 || h[1]&0xf0 != 0xc0 || h[2] < 3 {
+			return 0, nil, false
+		}
+		return int64(h[2]), h[1] & 0x0f, true
+	}
+	fs := NewFrameSync(ba, []byte{0xa5, 0xc0}, []byte{0xff, 0xf0}, 3, header)
+	found := []int64{}
+	for fs.Next() {
+		found = append(found, fs.Offset())
+		length, _, _ := header(fs.Offset())
+		assert.Equal(t, length, fs.Length())
+	}
+	assert.NoError(t, fs.Err())
+	assert.Equal(t, offsets, found)
+	assert.Equal(t, int64(5+3), fs.Discarded())
+
+	// A frame cut short after frames of its stream.
+	data = append(data[5:31], testFrame(1, 8)[:7]...)
+	ba = NewSliceByteAccessor(append([]byte{0x00, 0x00}, data...))
+	fs = NewFrameSync(ba, []byte{0xa5, 0xc0}, []byte{0xff, 0xf0}, 3, header)
+	fs.Skip(2)
+	count := 0
+	for fs.Next() {
+		count++
+	}
+	assert.Equal(t, 4, count)
+	assert.True(t, errors.Is(fs.Err(), ErrUnexpectedEOF))
+	assert.Equal(t, int64(2), fs.Discarded())
+}
@@ -0,0 +1,250 @@
+package gobits
+
+import (
+	"math"
+)
+
+const (
+	// maxUnaryLength bounds the unary prefix of Golomb and unary codes so
+	// that corrupt input or an ill-chosen divisor cannot run away.
+	maxUnaryLength = int64(1 << 24)
+)
+
+// GolombEscape describes an escape code for limited-length Golomb codes such
+// as JPEG-LS. A unary quotient equal to Quotient is followed by a value coded
+// by Read and Write instead of the regular remainder. Values whose quotient
+// reaches Quotient are always escaped when writing. The error Read or Write
+// returns is reported as the error of the Golomb read or write.
+type GolombEscape struct {
+	Quotient uint64
+	Read     func(bs *BitStream) (uint64, error)
+	Write    func(bs *BitStream, val uint64) error
+}
+
+func zigzagEncode(val int64) uint64 {
+	return uint64(val<<1) ^ uint64(val>>63)
+}
+
+func zigzagDecode(val uint64) int64 {
+	return int64(val>>1) ^ -int64(val&1)
+}
+
+func truncatedBinaryParams(n uint64) (bitCount byte, cutoff uint64) {
+	bitCount = countEffectiveBits(n - 1)
+	cutoff = uint64(1)<<bitCount - n
+	return bitCount, cutoff
+}
+
+func truncatedBinaryBitCount(val, n uint64) int64 {
+	bitCount, cutoff := truncatedBinaryParams(n)
+	if val < cutoff {
+		return int64(bitCount) - 1
+	}
+	return int64(bitCount)
+}
+
+func (bs *BitStream) readTruncatedBinary(n uint64) (uint64, error) {
+	if n == 0 {
+		return 0, ErrOutOfRange
+	}
+	if n == 1 {
+		return 0, nil
+	}
+
+	bitCount, cutoff := truncatedBinaryParams(n)
+	original := bs.pos
+	val, err := bs.readCode(bitCount - 1)
+	if err != nil {
+		return 0, err
+	}
+	if val < cutoff {
+		return val, nil
+	}
+	bit, err := bs.readCode(1)
+	if err != nil {
+		bs.pos = original
+		return 0, err
+	}
+	return (val<<1 | bit) - cutoff, nil
+}
+
+func (bs *BitStream) writeTruncatedBinary(val, n uint64) error {
+	if val >= n {
+		return ErrOutOfRange
+	}
+	if n == 1 {
+		return nil
+	}
+
+	bitCount, cutoff := truncatedBinaryParams(n)
+	if val < cutoff {
+		return bs.writeCode(val, bitCount-1)
+	}
+	return bs.writeCode(val+cutoff, bitCount)
+}
+
+func (bs *BitStream) readGolombQuotient() (uint64, error) {
+	q, err := bs.countRun(0, maxUnaryLength)
+	if err != nil {
+		return 0, err
+	}
+	if q == maxUnaryLength {
+		return 0, ErrMalformedCode
+	}
+	return uint64(q), bs.consumeBits(1)
+}
+
+func (bs *BitStream) writeGolombQuotient(q uint64) error {
+	if err := bs.writeRun(0, int64(q)); err != nil {
+		return err
+	}
+	return bs.writeCode(1, 1)
+}
+
+func (bs *BitStream) readGolomb(m uint64, escape *GolombEscape) (uint64, error) {
+	if m == 0 {
+		return 0, ErrOutOfRange
+	}
+
+	original := bs.pos
+	val := uint64(0)
+	r := uint64(0)
+	q, err := bs.readGolombQuotient()
+
+	if err != nil {
+		goto failed
+	} else if escape != nil && q == escape.Quotient {
+		if val, err = escape.Read(bs); err == nil {
+			return val, nil
+		}
+		goto failed
+	} else if escape != nil && q > escape.Quotient {
+		err = ErrMalformedCode
+		goto failed
+	} else if q > math.MaxUint64/m {
+		err = ErrMalformedCode
+		goto failed
+	} else if r, err = bs.readTruncatedBinary(m); err != nil {
+		goto failed
+	}
+
+	val = q * m
+	if val > math.MaxUint64-r {
+		err = ErrMalformedCode
+		goto failed
+	}
+	return val + r, nil
+
+failed:
+	bs.pos = original
+	return 0, err
+}
+
+func (bs *BitStream) writeGolomb(val, m uint64, escape *GolombEscape) error {
+	if m == 0 {
+		return ErrOutOfRange
+	}
+
+	q := val / m
+	r := val % m
+	original := bs.pos
+
+	if escape != nil && q >= escape.Quotient {
+		if escape.Quotient >= uint64(maxUnaryLength) {
+			return ErrOutOfRange
+		}
+		if err := bs.writeGolombQuotient(escape.Quotient); err != nil {
+			bs.pos = original
+			return err
+		}
+		if err := escape.Write(bs, val); err != nil {
+			bs.pos = original
+			return err
+		}
+		return nil
+	}
+
+	if q >= uint64(maxUnaryLength) {
+		return ErrOutOfRange
+	}
+	if err := bs.writableBits(int64(q) + 1 + truncatedBinaryBitCount(r, m)); err != nil {
+		return err
+	}
+	if err := bs.writeGolombQuotient(q); err != nil {
+		bs.pos = original
+		return err
+	}
+	if err := bs.writeTruncatedBinary(r, m); err != nil {
+		bs.pos = original
+		return err
+	}
+	return nil
+}
+
+func (bs *BitStream) ReadGolomb(m uint64) (uint64, bool) {
+	val, err := bs.readGolomb(m, nil)
+	return val, bs.record("ReadGolomb", err)
+}
+
+func (bs *BitStream) WriteGolomb(val, m uint64) bool {
+	return bs.record("WriteGolomb", bs.writeGolomb(val, m, nil))
+}
+
+func (bs *BitStream) ReadGolombWithEscape(m uint64, escape GolombEscape) (uint64, bool) {
+	val, err := bs.readGolomb(m, &escape)
+	return val, bs.record("ReadGolombWithEscape", err)
+}
+
+func (bs *BitStream) WriteGolombWithEscape(val, m uint64, escape GolombEscape) bool {
+	return bs.record("WriteGolombWithEscape", bs.writeGolomb(val, m, &escape))
+}
+
+func (bs *BitStream) ReadSignedGolomb(m uint64) (int64, bool) {
+	val, err := bs.readGolomb(m, nil)
+	return zigzagDecode(val), bs.record("ReadSignedGolomb", err)
+}
+
+func (bs *BitStream) WriteSignedGolomb(val int64, m uint64) bool {
+	return bs.record("WriteSignedGolomb", bs.writeGolomb(zigzagEncode(val), m, nil))
+}
+
+func riceDivisor(k byte) (uint64, error) {
+	if k > 63 {
+		return 0, ErrInvalidBitCount
+	}
+	return uint64(1) << k, nil
+}
+
+func (bs *BitStream) readRice(k byte) (uint64, error) {
+	m, err := riceDivisor(k)
+	if err != nil {
+		return 0, err
+	}
+	return bs.readGolomb(m, nil)
+}
+
+func (bs *BitStream) writeRice(val uint64, k byte) error {
+	m, err := riceDivisor(k)
+	if err != nil {
+		return err
+	}
+	return bs.writeGolomb(val, m, nil)
+}
+
+func (bs *BitStream) ReadRice(k byte) (uint64, bool) {
+	val, err := bs.readRice(k)
+	return val, bs.record("ReadRice", err)
+}
+
+func (bs *BitStream) WriteRice(val uint64, k byte) bool {
+	return bs.record("WriteRice", bs.writeRice(val, k))
+}
+
+func (bs *BitStream) ReadSignedRice(k byte) (int64, bool) {
+	val, err := bs.readRice(k)
+	return zigzagDecode(val), bs.record("ReadSignedRice", err)
+}
+
+func (bs *BitStream) WriteSignedRice(val int64, k byte) bool {
+	return bs.record("WriteSignedRice", bs.writeRice(zigzagEncode(val), k))
+}
@@ -0,0 +1,181 @@
+package gobits
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBitStream_ReadRice(t *testing.T) {
+	bs := NewBitStream(NewSliceByteAccessor([]byte{0x8a, 0x60}))
+	for _, expect := range []uint64{0, 5, 10} {
+		v, ok := bs.ReadRice(2)
+		assert.True(t, ok)
+		assert.Equal(t, expect, v)
+	}
+
+	pos := bs.SavePos()
+	_, ok := bs.ReadRice(2)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrUnexpectedEOF))
+	assert.Equal(t, pos, bs.SavePos())
+
+	_, ok = bs.ReadRice(64)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrInvalidBitCount))
+}
+
+func TestBitStream_WriteRice(t *testing.T) {
+	ba := NewGrowableByteAccessor(nil)
+	bs := NewBitStream(ba)
+	for _, v := range []uint64{0, 5, 10} {
+		assert.True(t, bs.WriteRice(v, 2))
+	}
+	assert.True(t, bs.PadToByteBoundary(PadZeros))
+	assert.Equal(t, []byte{0x8a, 0x60}, ba.Bytes())
+
+	values := []uint64{0, 1, 63, 64, 65, 1000, 12345}
+	for k := byte(0); k < 12; k++ {
+		ba = NewGrowableByteAccessor(nil)
+		bs = NewBitStream(ba)
+		for _, v := range values {
+			assert.True(t, bs.WriteRice(v, k))
+		}
+		assert.True(t, bs.Seek(0, 0))
+		for _, expect := range values {
+			v, ok := bs.ReadRice(k)
+			assert.True(t, ok)
+			assert.Equal(t, expect, v)
+		}
+	}
+
+	assert.True(t, bs.WriteRice(math.MaxUint64, 63))
+	assert.False(t, bs.WriteRice(math.MaxUint64, 0))
+	assert.True(t, errors.Is(bs.Err(), ErrOutOfRange))
+
+	bs = NewBitStream(NewSliceByteAccessor([]byte{0xff}))
+	assert.False(t, bs.WriteRice(100, 1))
+	assert.True(t, errors.Is(bs.Err(), ErrUnexpectedEOF))
+	assert.Equal(t, []byte{0xff}, bs.ba.Slice(0, 1))
+	assert.Equal(t, int64(0), bs.Tell())
+}
+
+func TestBitStream_SignedRice(t *testing.T) {
+	bs := NewBitStream(NewGrowableByteAccessor(nil))
+	values := []int64{0, -1, 1, -2, 2, -100, 100}
+	for _, v := range values {
+		assert.True(t, bs.WriteSignedRice(v, 3))
+	}
+	assert.True(t, bs.Seek(0, 0))
+	for _, expect := range values {
+		v, ok := bs.ReadSignedRice(3)
+		assert.True(t, ok)
+		assert.Equal(t, expect, v)
+	}
+
+	// -1 folds to 1: 1 01 with k = 2.
+	bs = NewBitStream(NewSliceByteAccessor([]byte{0xa0}))
+	v, ok := bs.ReadSignedRice(2)
+	assert.True(t, ok)
+	assert.Equal(t, int64(-1), v)
+}
+
+func TestBitStream_Golomb(t *testing.T) {
+	ba := NewGrowableByteAccessor(nil)
+	bs := NewBitStream(ba)
+	for _, v := range []uint64{0, 4, 8} {
+		assert.True(t, bs.WriteGolomb(v, 3))
+	}
+	assert.True(t, bs.PadToByteBoundary(PadZeros))
+	assert.Equal(t, []byte{0x98, 0xe0}, ba.Bytes())
+
+	assert.True(t, bs.Seek(0, 0))
+	for _, expect := range []uint64{0, 4, 8} {
+		v, ok := bs.ReadGolomb(3)
+		assert.True(t, ok)
+		assert.Equal(t, expect, v)
+	}
+
+	values := []uint64{0, 1, 2, 6, 7, 99, 1000}
+	for _, m := range []uint64{1, 2, 3, 5, 7, 10, 100} {
+		bs = NewBitStream(NewGrowableByteAccessor(nil))
+		for _, v := range values {
+			assert.True(t, bs.WriteGolomb(v, m))
+			assert.True(t, bs.WriteSignedGolomb(-int64(v), m))
+		}
+		assert.True(t, bs.Seek(0, 0))
+		for _, expect := range values {
+			v, ok := bs.ReadGolomb(m)
+			assert.True(t, ok)
+			assert.Equal(t, expect, v)
+			s, ok := bs.ReadSignedGolomb(m)
+			assert.True(t, ok)
+			assert.Equal(t, -int64(expect), s)
+		}
+	}
+
+	_, ok := bs.ReadGolomb(0)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrOutOfRange))
+	assert.False(t, bs.WriteGolomb(1, 0))
+	assert.True(t, errors.Is(bs.Err(), ErrOutOfRange))
+}
+
+func TestBitStream_GolombWithEscape(t *testing.T) {
+	escape := GolombEscape{
+		Quotient: 4,
+		Read: func(bs *BitStream) (uint64, error) {
+			v, _ := bs.ReadBits(8)
+			return v + 1, bs.Err()
+		},
+		Write: func(bs *BitStream, val uint64) error {
+			bs.WriteBits(val-1, 8)
+			return bs.Err()
+		},
+	}
+
+	ba := NewGrowableByteAccessor(nil)
+	bs := NewBitStream(ba)
+	assert.True(t, bs.WriteGolombWithEscape(3, 4, escape))
+	assert.True(t, bs.WriteGolombWithEscape(100, 4, escape))
+	assert.True(t, bs.WriteGolombWithEscape(15, 4, escape))
+	assert.True(t, bs.PadToByteBoundary(PadZeros))
+	assert.Equal(t, []byte{0xe1, 0x63, 0x1c}, ba.Bytes())
+
+	assert.True(t, bs.Seek(0, 0))
+	for _, expect := range []uint64{3, 100, 15} {
+		v, ok := bs.ReadGolombWithEscape(4, escape)
+		assert.True(t, ok)
+		assert.Equal(t, expect, v)
+	}
+
+	bs = NewBitStream(NewSliceByteAccessor([]byte{0x04, 0x00}))
+	_, ok := bs.ReadGolombWithEscape(4, escape)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrMalformedCode))
+	assert.Equal(t, int64(0), bs.Tell())
+
+	bs = NewBitStream(NewSliceByteAccessor([]byte{0x08}))
+	_, ok = bs.ReadGolombWithEscape(4, escape)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrUnexpectedEOF))
+	assert.Equal(t, int64(0), bs.Tell())
+
+	errEscape := errors.New("escape")
+	escape.Read = func(bs *BitStream) (uint64, error) { return 0, errEscape }
+	bs = NewBitStream(NewSliceByteAccessor([]byte{0x08, 0x00}))
+	_, ok = bs.ReadGolombWithEscape(4, escape)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), errEscape))
+	assert.Equal(t, int64(0), bs.Tell())
+}
+
+func TestBitStream_GolombMalformed(t *testing.T) {
+	bs := NewBitStream(NewSliceByteAccessor(make([]byte, maxUnaryLength/8+1)))
+	_, ok := bs.ReadRice(0)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrMalformedCode))
+	assert.Equal(t, int64(0), bs.Tell())
+}
@@ -0,0 +1,63 @@
+package gobits
+
+type GrowableByteAccessor struct {
+	SliceByteAccessor
+}
+
+func (ba *GrowableByteAccessor) Extend(length int64) error {
+	if length < 0 {
+		return ErrInvalidOffset
+	}
+	if length <= int64(len(ba.bytes)) {
+		return nil
+	}
+	if length <= int64(cap(ba.bytes)) {
+		oldLength := len(ba.bytes)
+		ba.bytes = ba.bytes[:length]
+		for i := oldLength; i < len(ba.bytes); i++ {
+			ba.bytes[i] = 0
+		}
+		return nil
+	}
+
+	newCap := int64(cap(ba.bytes)) * 2
+	if newCap < length {
+		newCap = length
+	}
+	bytes := make([]byte, length, newCap)
+	copy(bytes, ba.bytes)
+	ba.bytes = bytes
+	return nil
+}
+
+func (ba *GrowableByteAccessor) Truncate(length int64) error {
+	if length < 0 || int64(len(ba.bytes)) < length {
+		return ErrInvalidOffset
+	}
+	ba.bytes = ba.bytes[:length]
+	return nil
+}
+
+func (ba *GrowableByteAccessor) PutBytes(bytes []byte, byteOffset int64) error {
+	if byteOffset < 0 || bytes == nil {
+		return ErrInvalidOffset
+	}
+	if err := ba.Extend(byteOffset + int64(len(bytes))); err != nil {
+		return err
+	}
+
+	copy(ba.bytes[byteOffset:], bytes)
+	return nil
+}
+
+func (ba *GrowableByteAccessor) Put(bytes []byte, byteOffset int64) bool {
+	return ba.PutBytes(bytes, byteOffset) == nil
+}
+
+func (ba *GrowableByteAccessor) Bytes() []byte {
+	return ba.bytes
+}
+
+func NewGrowableByteAccessor(bytes []byte) *GrowableByteAccessor {
+	return &GrowableByteAccessor{SliceByteAccessor{bytes: bytes}}
+}
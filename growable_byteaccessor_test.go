@@ -0,0 +1,61 @@
+package gobits
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewGrowableByteAccessor(t *testing.T) {
+	ba := NewGrowableByteAccessor(nil)
+	assert.NotNil(t, ba)
+	assert.Equal(t, int64(0), ba.Length())
+}
+
+func TestGrowableByteAccessor_Extend(t *testing.T) {
+	ba := NewGrowableByteAccessor([]byte{1, 2})
+
+	assert.Nil(t, ba.Extend(1))
+	assert.Equal(t, []byte{1, 2}, ba.Bytes())
+
+	assert.Nil(t, ba.Extend(5))
+	assert.Equal(t, []byte{1, 2, 0, 0, 0}, ba.Bytes())
+
+	assert.Equal(t, ErrInvalidOffset, ba.Extend(-1))
+}
+
+func TestGrowableByteAccessor_Truncate(t *testing.T) {
+	ba := NewGrowableByteAccessor([]byte{1, 2, 3})
+
+	assert.Nil(t, ba.Truncate(1))
+	assert.Equal(t, []byte{1}, ba.Bytes())
+
+	assert.Nil(t, ba.Extend(2))
+	assert.Equal(t, []byte{1, 0}, ba.Bytes())
+
+	assert.Equal(t, ErrInvalidOffset, ba.Truncate(3))
+	assert.Equal(t, ErrInvalidOffset, ba.Truncate(-1))
+}
+
+func TestGrowableByteAccessor_Put(t *testing.T) {
+	ba := NewGrowableByteAccessor(nil)
+
+	assert.True(t, ba.Put([]byte{1, 2, 3}, 0))
+	assert.Equal(t, []byte{1, 2, 3}, ba.Bytes())
+
+	assert.True(t, ba.Put([]byte{4, 5}, 2))
+	assert.Equal(t, []byte{1, 2, 4, 5}, ba.Bytes())
+
+	assert.True(t, ba.Put([]byte{6}, 6))
+	assert.Equal(t, []byte{1, 2, 4, 5, 0, 0, 6}, ba.Bytes())
+	assert.Equal(t, int64(7), ba.Length())
+
+	assert.False(t, ba.Put([]byte{1}, -1))
+	assert.False(t, ba.Put(nil, 0))
+
+	at, ok := ba.At(6)
+	assert.True(t, ok)
+	assert.Equal(t, byte(6), at)
+	_, ok = ba.At(7)
+	assert.False(t, ok)
+}
@@ -0,0 +1,43 @@
+// Package h264 parses and writes H.264 sequence parameter sets, picture
+// parameter sets and slice headers.
+//
+// Each structure is described once as a gobits.Syntax, so parsing a NAL
+// unit and marshalling the result reproduces it bit for bit. Fields are
+// named after the syntax elements of ITU-T H.264 section 7.3.
+package h264
+
+// NAL unit types handled by this package.
+const (
+	NALTypeSlice    = 1
+	NALTypeSliceIDR = 5
+	NALTypeSPS      = 7
+	NALTypePPS      = 8
+)
+
+// Slice types, modulo 5.
+const (
+	SliceTypeP  = 0
+	SliceTypeB  = 1
+	SliceTypeI  = 2
+	SliceTypeSP = 3
+	SliceTypeSI = 4
+)
+
+// ParameterSets holds the parameter sets that PPS and slice header syntax
+// depends on, keyed by their IDs.
+type ParameterSets struct {
+	SPS map[uint32]*SPS
+	PPS map[uint32]*PPS
+}
+
+func (ps *ParameterSets) AddSPS(sps *SPS) {
+	ps.SPS[sps.ID] = sps
+}
+
+func (ps *ParameterSets) AddPPS(pps *PPS) {
+	ps.PPS[pps.ID] = pps
+}
+
+func NewParameterSets() *ParameterSets {
+	return &ParameterSets{SPS: map[uint32]*SPS{}, PPS: map[uint32]*PPS{}}
+}
@@ -0,0 +1,210 @@
+package h264
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/ibbbpbbbp/gobits/nal"
+)
+
+// Slice group map types.
+const (
+	SliceGroupMapInterleaved = 0
+	SliceGroupMapDispersed   = 1
+	SliceGroupMapForeground  = 2
+	SliceGroupMapBoxOut      = 3
+	SliceGroupMapRasterScan  = 4
+	SliceGroupMapWipe        = 5
+	SliceGroupMapExplicit    = 6
+)
+
+// maxPicSizeInMapUnits bounds pic_size_in_map_units_minus1 by the largest
+// frame size of any level.
+const maxPicSizeInMapUnits = 139264
+
+// PPS is a pic_parameter_set_rbsp().
+type PPS struct {
+	Header nal.Header
+
+	ID                                    uint32
+	SPSID                                 uint32
+	EntropyCodingModeFlag                 bool
+	BottomFieldPicOrderInFramePresentFlag bool
+
+	NumSliceGroupsMinus1          uint32
+	SliceGroupMapType             uint32
+	RunLengthMinus1               []uint32
+	TopLeft                       []uint32
+	BottomRight                   []uint32
+	SliceGroupChangeDirectionFlag bool
+	SliceGroupChangeRateMinus1    uint32
+	// SliceGroupID has pic_size_in_map_units_minus1 + 1 entries.
+	SliceGroupID []uint32
+
+	NumRefIdxL0DefaultActiveMinus1     uint32
+	NumRefIdxL1DefaultActiveMinus1     uint32
+	WeightedPredFlag                   bool
+	WeightedBipredIdc                  byte
+	PicInitQpMinus26                   int32
+	PicInitQsMinus26                   int32
+	ChromaQpIndexOffset                int32
+	DeblockingFilterControlPresentFlag bool
+	ConstrainedIntraPredFlag           bool
+	RedundantPicCntPresentFlag         bool
+
+	// MoreRBSPData reports whether the fields below are present.
+	MoreRBSPData                bool
+	Transform8x8ModeFlag        bool
+	PicScalingMatrixPresentFlag bool
+	ScalingLists                []ScalingList
+	SecondChromaQpIndexOffset   int32
+}
+
+func (pps *PPS) sliceGroups(s *gobits.Syntax) {
+	if !s.Count(&pps.NumSliceGroupsMinus1, 7, "num_slice_groups_minus1") {
+		return
+	}
+	if pps.NumSliceGroupsMinus1 == 0 {
+		return
+	}
+	n := int(pps.NumSliceGroupsMinus1)
+	if !s.Count(&pps.SliceGroupMapType, SliceGroupMapExplicit, "slice_group_map_type") {
+		return
+	}
+	switch pps.SliceGroupMapType {
+	case SliceGroupMapInterleaved:
+		if s.Reading() {
+			pps.RunLengthMinus1 = make([]uint32, n+1)
+		}
+		if !s.ListLength(len(pps.RunLengthMinus1), n+1, "run_length_minus1") {
+			return
+		}
+		for i := range pps.RunLengthMinus1 {
+			s.ExponentialGolomb(&pps.RunLengthMinus1[i], "run_length_minus1")
+		}
+	case SliceGroupMapForeground:
+		if s.Reading() {
+			pps.TopLeft = make([]uint32, n)
+			pps.BottomRight = make([]uint32, n)
+		}
+		if !s.ListLength(len(pps.TopLeft), n, "top_left") ||
+			!s.ListLength(len(pps.BottomRight), n, "bottom_right") {
+			return
+		}
+		for i := range pps.TopLeft {
+			s.ExponentialGolomb(&pps.TopLeft[i], "top_left")
+			s.ExponentialGolomb(&pps.BottomRight[i], "bottom_right")
+		}
+	case SliceGroupMapBoxOut, SliceGroupMapRasterScan, SliceGroupMapWipe:
+		s.Flag(&pps.SliceGroupChangeDirectionFlag, "slice_group_change_direction_flag")
+		s.ExponentialGolomb(&pps.SliceGroupChangeRateMinus1, "slice_group_change_rate_minus1")
+	case SliceGroupMapExplicit:
+		size := uint32(len(pps.SliceGroupID)) - 1
+		if !s.Count(&size, maxPicSizeInMapUnits-1, "pic_size_in_map_units_minus1") {
+			return
+		}
+		if s.Reading() {
+			pps.SliceGroupID = make([]uint32, size+1)
+		}
+		bits := gobits.CeilLog2(pps.NumSliceGroupsMinus1 + 1)
+		for i := range pps.SliceGroupID {
+			s.Bits(&pps.SliceGroupID[i], bits, "slice_group_id")
+		}
+	}
+}
+
+// syntax handles the PPS. more implements more_rbsp_data() when reading;
+// sps looks up the SPS the scaling matrix depends on.
+func (pps *PPS) syntax(s *gobits.Syntax, more func() bool, sps func() (*SPS, bool)) {
+	s.ExponentialGolomb(&pps.ID, "pic_parameter_set_id")
+	if pps.ID > 255 {
+		s.Fail("pic_parameter_set_id", gobits.ErrOutOfRange)
+	}
+	s.ExponentialGolomb(&pps.SPSID, "seq_parameter_set_id")
+	if pps.SPSID > 31 {
+		s.Fail("seq_parameter_set_id", gobits.ErrOutOfRange)
+	}
+	s.Flag(&pps.EntropyCodingModeFlag, "entropy_coding_mode_flag")
+	s.Flag(&pps.BottomFieldPicOrderInFramePresentFlag, "bottom_field_pic_order_in_frame_present_flag")
+	pps.sliceGroups(s)
+	if !s.Count(&pps.NumRefIdxL0DefaultActiveMinus1, 31, "num_ref_idx_l0_default_active_minus1") ||
+		!s.Count(&pps.NumRefIdxL1DefaultActiveMinus1, 31, "num_ref_idx_l1_default_active_minus1") {
+		return
+	}
+	s.Flag(&pps.WeightedPredFlag, "weighted_pred_flag")
+	s.Byte(&pps.WeightedBipredIdc, 2, "weighted_bipred_idc")
+	s.SignedExponentialGolomb(&pps.PicInitQpMinus26, "pic_init_qp_minus26")
+	s.SignedExponentialGolomb(&pps.PicInitQsMinus26, "pic_init_qs_minus26")
+	s.SignedExponentialGolomb(&pps.ChromaQpIndexOffset, "chroma_qp_index_offset")
+	s.Flag(&pps.DeblockingFilterControlPresentFlag, "deblocking_filter_control_present_flag")
+	s.Flag(&pps.ConstrainedIntraPredFlag, "constrained_intra_pred_flag")
+	s.Flag(&pps.RedundantPicCntPresentFlag, "redundant_pic_cnt_present_flag")
+	if s.Err() != nil {
+		return
+	}
+
+	if s.Reading() {
+		pps.MoreRBSPData = more()
+	}
+	if pps.MoreRBSPData {
+		s.Flag(&pps.Transform8x8ModeFlag, "transform_8x8_mode_flag")
+		s.Flag(&pps.PicScalingMatrixPresentFlag, "pic_scaling_matrix_present_flag")
+		if pps.PicScalingMatrixPresentFlag && s.Err() == nil {
+			n := 6
+			if pps.Transform8x8ModeFlag {
+				if !s.Reading() {
+					n = len(pps.ScalingLists)
+				} else if sps, ok := sps(); !ok {
+					s.Fail("seq_parameter_set_id", gobits.ErrInvalidSyntax)
+					return
+				} else if sps.ChromaFormatIdc == 3 {
+					n = 12
+				} else {
+					n = 8
+				}
+				if n != 8 && n != 12 {
+					s.Fail("pic_scaling_list_present_flag", gobits.ErrOutOfRange)
+					return
+				}
+			}
+			scalingLists(s, &pps.ScalingLists, n)
+		}
+		s.SignedExponentialGolomb(&pps.SecondChromaQpIndexOffset, "second_chroma_qp_index_offset")
+	} else if s.Reading() {
+		pps.SecondChromaQpIndexOffset = pps.ChromaQpIndexOffset
+	}
+	s.RBSPTrailingBits()
+}
+
+// ParsePPS parses a PPS NAL unit, header included, with emulation
+// prevention bytes still in place. ps is only consulted for the
+// chroma_format_idc of the referenced SPS when the PPS carries 8x8 scaling
+// lists, and may be nil otherwise.
+func ParsePPS(ba gobits.ByteAccessor, ps *ParameterSets) (*PPS, error) {
+	header, rbsp, s, err := nal.ReadRBSP(ba, nal.H264, nal.IsType(NALTypePPS))
+	if err != nil {
+		return nil, err
+	}
+	pps := &PPS{Header: header}
+	more := func() bool {
+		return rbsp.MoreRBSPData(s.BitStream().Tell())
+	}
+	sps := func() (*SPS, bool) {
+		if ps == nil {
+			return nil, false
+		}
+		sps, ok := ps.SPS[pps.SPSID]
+		return sps, ok
+	}
+	pps.syntax(s, more, sps)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	return pps, nil
+}
+
+// Marshal encodes the PPS as a NAL unit. The number of scaling lists written
+// is taken from ScalingLists.
+func (pps *PPS) Marshal() ([]byte, error) {
+	return nal.WriteRBSP(pps.Header, nal.H264, func(s *gobits.Syntax) {
+		pps.syntax(s, nil, nil)
+	})
+}
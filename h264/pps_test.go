@@ -0,0 +1,87 @@
+package h264
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/ibbbpbbbp/gobits/nal"
+	"github.com/stretchr/testify/assert"
+)
+
+// ppsHigh is the PPS that accompanies spsHigh.
+var ppsHigh = []byte{0x68, 0xeb, 0xe3, 0xcb, 0x22, 0xc0}
+
+func TestParsePPS(t *testing.T) {
+	pps, err := ParsePPS(gobits.NewSliceByteAccessor(ppsHigh), nil)
+	assert.NoError(t, err)
+	assert.True(t, pps.EntropyCodingModeFlag)
+	assert.Equal(t, uint32(2), pps.NumRefIdxL0DefaultActiveMinus1)
+	assert.True(t, pps.WeightedPredFlag)
+	assert.Equal(t, byte(2), pps.WeightedBipredIdc)
+	assert.Equal(t, int32(-3), pps.PicInitQpMinus26)
+	assert.Equal(t, int32(-2), pps.ChromaQpIndexOffset)
+	assert.True(t, pps.MoreRBSPData)
+	assert.True(t, pps.Transform8x8ModeFlag)
+	assert.Equal(t, int32(-2), pps.SecondChromaQpIndexOffset)
+
+	raw, err := pps.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, ppsHigh, raw)
+}
+
+func TestPPS_RoundTrip(t *testing.T) {
+	ps := NewParameterSets()
+	sps, err := ParseSPS(gobits.NewSliceByteAccessor(spsHigh))
+	assert.NoError(t, err)
+	ps.AddSPS(sps)
+
+	for _, pps := range []*PPS{
+		{
+			Header:                         nal.Header{RefIdc: 3, Type: NALTypePPS},
+			ID:                             3,
+			NumSliceGroupsMinus1:           2,
+			SliceGroupMapType:              SliceGroupMapInterleaved,
+			RunLengthMinus1:                []uint32{10, 20, 30},
+			NumRefIdxL0DefaultActiveMinus1: 1,
+			PicInitQsMinus26:               -4,
+			ChromaQpIndexOffset:            3,
+			SecondChromaQpIndexOffset:      3,
+		},
+		{
+			Header:                     nal.Header{RefIdc: 3, Type: NALTypePPS},
+			NumSliceGroupsMinus1:       3,
+			SliceGroupMapType:          SliceGroupMapExplicit,
+			SliceGroupID:               []uint32{0, 1, 2, 3, 3, 2, 1, 0},
+			RedundantPicCntPresentFlag: true,
+		},
+		{
+			Header:                      nal.Header{RefIdc: 3, Type: NALTypePPS},
+			NumSliceGroupsMinus1:        1,
+			SliceGroupMapType:           SliceGroupMapForeground,
+			TopLeft:                     []uint32{5},
+			BottomRight:                 []uint32{90},
+			MoreRBSPData:                true,
+			Transform8x8ModeFlag:        true,
+			PicScalingMatrixPresentFlag: true,
+			ScalingLists:                []ScalingList{{}, {}, {Present: true, DeltaScales: []int32{-8}}, {}, {}, {}, {}, {}},
+			SecondChromaQpIndexOffset:   -1,
+		},
+	} {
+		raw, err := pps.Marshal()
+		assert.NoError(t, err)
+		parsed, err := ParsePPS(gobits.NewSliceByteAccessor(raw), ps)
+		assert.NoError(t, err)
+		assert.Equal(t, pps, parsed)
+
+		if pps.PicScalingMatrixPresentFlag {
+			_, err = ParsePPS(gobits.NewSliceByteAccessor(raw), nil)
+			var fieldErr *gobits.FieldError
+			assert.True(t, errors.As(err, &fieldErr))
+			assert.Equal(t, "seq_parameter_set_id", fieldErr.Field)
+		}
+	}
+
+	_, err = (&PPS{NumSliceGroupsMinus1: 1, RunLengthMinus1: []uint32{1}}).Marshal()
+	assert.True(t, errors.Is(err, gobits.ErrOutOfRange))
+}
@@ -0,0 +1,429 @@
+package h264
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/ibbbpbbbp/gobits/nal"
+)
+
+// RefPicListModification is one iteration of the loop in
+// ref_pic_list_modification(). The terminating
+// modification_of_pic_nums_idc equal to 3 is not stored.
+type RefPicListModification struct {
+	ModificationOfPicNumsIdc uint32
+	AbsDiffPicNumMinus1      uint32
+	LongTermPicNum           uint32
+}
+
+func refPicListModifications(s *gobits.Syntax, flag *bool, list *[]RefPicListModification, suffix string) {
+	s.Flag(flag, "ref_pic_list_modification_flag_"+suffix)
+	if !*flag {
+		return
+	}
+	if s.Reading() {
+		*list = nil
+	}
+	for i := 0; s.Err() == nil; i++ {
+		m := RefPicListModification{ModificationOfPicNumsIdc: 3}
+		if !s.Reading() && i < len(*list) {
+			m = (*list)[i]
+			if m.ModificationOfPicNumsIdc > 2 {
+				s.Fail("modification_of_pic_nums_idc", gobits.ErrOutOfRange)
+				return
+			}
+		}
+		if !s.Count(&m.ModificationOfPicNumsIdc, 5, "modification_of_pic_nums_idc") {
+			return
+		}
+		switch m.ModificationOfPicNumsIdc {
+		case 0, 1:
+			s.ExponentialGolomb(&m.AbsDiffPicNumMinus1, "abs_diff_pic_num_minus1")
+		case 2:
+			s.ExponentialGolomb(&m.LongTermPicNum, "long_term_pic_num")
+		case 3:
+			return
+		default:
+			s.Fail("modification_of_pic_nums_idc", gobits.ErrOutOfRange)
+			return
+		}
+		if s.Reading() {
+			*list = append(*list, m)
+		}
+	}
+}
+
+// PredWeight holds the weights of one reference index in
+// pred_weight_table().
+type PredWeight struct {
+	LumaWeightFlag   bool
+	LumaWeight       int32
+	LumaOffset       int32
+	ChromaWeightFlag bool
+	ChromaWeight     [2]int32
+	ChromaOffset     [2]int32
+}
+
+type PredWeightTable struct {
+	LumaLog2WeightDenom   uint32
+	ChromaLog2WeightDenom uint32
+	// L0 and L1 have num_ref_idx_lX_active_minus1 + 1 entries each; L1 is
+	// only present in B slices.
+	L0 []PredWeight
+	L1 []PredWeight
+}
+
+func predWeights(s *gobits.Syntax, list *[]PredWeight, n int, chroma bool, suffix string) {
+	if s.Reading() {
+		*list = make([]PredWeight, n)
+	}
+	if !s.ListLength(len(*list), n, "luma_weight_"+suffix+"_flag") {
+		return
+	}
+	for i := range *list {
+		w := &(*list)[i]
+		s.Flag(&w.LumaWeightFlag, "luma_weight_"+suffix+"_flag")
+		if w.LumaWeightFlag {
+			s.SignedExponentialGolomb(&w.LumaWeight, "luma_weight_"+suffix)
+			s.SignedExponentialGolomb(&w.LumaOffset, "luma_offset_"+suffix)
+		}
+		if chroma {
+			s.Flag(&w.ChromaWeightFlag, "chroma_weight_"+suffix+"_flag")
+			if w.ChromaWeightFlag {
+				for j := 0; j < 2; j++ {
+					s.SignedExponentialGolomb(&w.ChromaWeight[j], "chroma_weight_"+suffix)
+					s.SignedExponentialGolomb(&w.ChromaOffset[j], "chroma_offset_"+suffix)
+				}
+			}
+		}
+	}
+}
+
+// MMCO is one memory_management_control_operation of
+// dec_ref_pic_marking(). The terminating operation 0 is not stored.
+type MMCO struct {
+	Operation                 uint32
+	DifferenceOfPicNumsMinus1 uint32
+	LongTermPicNum            uint32
+	LongTermFrameIdx          uint32
+	MaxLongTermFrameIdxPlus1  uint32
+}
+
+type DecRefPicMarking struct {
+	NoOutputOfPriorPicsFlag       bool
+	LongTermReferenceFlag         bool
+	AdaptiveRefPicMarkingModeFlag bool
+	Operations                    []MMCO
+}
+
+func (m *DecRefPicMarking) syntax(s *gobits.Syntax, idr bool) {
+	if idr {
+		s.Flag(&m.NoOutputOfPriorPicsFlag, "no_output_of_prior_pics_flag")
+		s.Flag(&m.LongTermReferenceFlag, "long_term_reference_flag")
+		return
+	}
+	s.Flag(&m.AdaptiveRefPicMarkingModeFlag, "adaptive_ref_pic_marking_mode_flag")
+	if !m.AdaptiveRefPicMarkingModeFlag {
+		return
+	}
+	if s.Reading() {
+		m.Operations = nil
+	}
+	for i := 0; s.Err() == nil; i++ {
+		op := MMCO{}
+		if !s.Reading() && i < len(m.Operations) {
+			op = m.Operations[i]
+			if op.Operation == 0 {
+				s.Fail("memory_management_control_operation", gobits.ErrOutOfRange)
+				return
+			}
+		}
+		if !s.Count(&op.Operation, 6, "memory_management_control_operation") {
+			return
+		}
+		if op.Operation == 0 {
+			return
+		}
+		if op.Operation == 1 || op.Operation == 3 {
+			s.ExponentialGolomb(&op.DifferenceOfPicNumsMinus1, "difference_of_pic_nums_minus1")
+		}
+		if op.Operation == 2 {
+			s.ExponentialGolomb(&op.LongTermPicNum, "long_term_pic_num")
+		}
+		if op.Operation == 3 || op.Operation == 6 {
+			s.ExponentialGolomb(&op.LongTermFrameIdx, "long_term_frame_idx")
+		}
+		if op.Operation == 4 {
+			s.ExponentialGolomb(&op.MaxLongTermFrameIdxPlus1, "max_long_term_frame_idx_plus1")
+		}
+		if s.Reading() {
+			m.Operations = append(m.Operations, op)
+		}
+	}
+}
+
+// SliceHeader is a slice_header() of a coded slice NAL unit.
+type SliceHeader struct {
+	Header nal.Header
+
+	FirstMbInSlice          uint32
+	SliceType               uint32
+	PPSID                   uint32
+	ColourPlaneID           byte
+	FrameNum                uint32
+	FieldPicFlag            bool
+	BottomFieldFlag         bool
+	IdrPicID                uint32
+	PicOrderCntLsb          uint32
+	DeltaPicOrderCntBottom  int32
+	DeltaPicOrderCnt        [2]int32
+	RedundantPicCnt         uint32
+	DirectSpatialMvPredFlag bool
+
+	// NumRefIdxL0ActiveMinus1 and NumRefIdxL1ActiveMinus1 are set from the
+	// PPS defaults when NumRefIdxActiveOverrideFlag is false.
+	NumRefIdxActiveOverrideFlag bool
+	NumRefIdxL0ActiveMinus1     uint32
+	NumRefIdxL1ActiveMinus1     uint32
+
+	RefPicListModificationFlagL0 bool
+	RefPicListModificationL0     []RefPicListModification
+	RefPicListModificationFlagL1 bool
+	RefPicListModificationL1     []RefPicListModification
+
+	PredWeightTable  PredWeightTable
+	DecRefPicMarking DecRefPicMarking
+
+	CabacInitIdc               uint32
+	SliceQpDelta               int32
+	SpForSwitchFlag            bool
+	SliceQsDelta               int32
+	DisableDeblockingFilterIdc uint32
+	SliceAlphaC0OffsetDiv2     int32
+	SliceBetaOffsetDiv2        int32
+	SliceGroupChangeCycle      uint32
+
+	// DataBitOffset is the RBSP bit position following the parsed header.
+	DataBitOffset int64
+}
+
+func (h *SliceHeader) IsIDR() bool {
+	return h.Header.Type == NALTypeSliceIDR
+}
+
+func (h *SliceHeader) isType(types ...uint32) bool {
+	for _, t := range types {
+		if h.SliceType%5 == t {
+			return true
+		}
+	}
+	return false
+}
+
+// sliceGroupChangeCycleBits returns the length of slice_group_change_cycle,
+// Ceil(Log2(PicSizeInMapUnits ÷ SliceGroupChangeRate + 1)).
+func sliceGroupChangeCycleBits(sps *SPS, pps *PPS) byte {
+	size := uint64(sps.PicWidthInMbsMinus1+1) * uint64(sps.PicHeightInMapUnitsMinus1+1)
+	rate := uint64(pps.SliceGroupChangeRateMinus1) + 1
+	bits := byte(0)
+	for rate<<bits < size+rate {
+		bits++
+	}
+	return bits
+}
+
+func (h *SliceHeader) syntax(s *gobits.Syntax, ps *ParameterSets) {
+	s.ExponentialGolomb(&h.FirstMbInSlice, "first_mb_in_slice")
+	if !s.Count(&h.SliceType, 9, "slice_type") {
+		return
+	}
+	s.ExponentialGolomb(&h.PPSID, "pic_parameter_set_id")
+	if s.Err() != nil {
+		return
+	}
+	if ps == nil {
+		s.Fail("pic_parameter_set_id", gobits.ErrInvalidSyntax)
+		return
+	}
+	pps, ok := ps.PPS[h.PPSID]
+	if !ok {
+		s.Fail("pic_parameter_set_id", gobits.ErrInvalidSyntax)
+		return
+	}
+	sps, ok := ps.SPS[pps.SPSID]
+	if !ok {
+		s.Fail("seq_parameter_set_id", gobits.ErrInvalidSyntax)
+		return
+	}
+
+	if sps.SeparateColourPlaneFlag {
+		s.Byte(&h.ColourPlaneID, 2, "colour_plane_id")
+	}
+	s.Bits(&h.FrameNum, byte(sps.Log2MaxFrameNumMinus4+4), "frame_num")
+	if !sps.FrameMbsOnlyFlag {
+		s.Flag(&h.FieldPicFlag, "field_pic_flag")
+		if h.FieldPicFlag {
+			s.Flag(&h.BottomFieldFlag, "bottom_field_flag")
+		}
+	}
+	if h.IsIDR() {
+		s.ExponentialGolomb(&h.IdrPicID, "idr_pic_id")
+	}
+	bottom := pps.BottomFieldPicOrderInFramePresentFlag && !h.FieldPicFlag
+	if sps.PicOrderCntType == 0 {
+		s.Bits(&h.PicOrderCntLsb, byte(sps.Log2MaxPicOrderCntLsbMinus4+4), "pic_order_cnt_lsb")
+		if bottom {
+			s.SignedExponentialGolomb(&h.DeltaPicOrderCntBottom, "delta_pic_order_cnt_bottom")
+		}
+	}
+	if sps.PicOrderCntType == 1 && !sps.DeltaPicOrderAlwaysZeroFlag {
+		s.SignedExponentialGolomb(&h.DeltaPicOrderCnt[0], "delta_pic_order_cnt")
+		if bottom {
+			s.SignedExponentialGolomb(&h.DeltaPicOrderCnt[1], "delta_pic_order_cnt")
+		}
+	}
+	if pps.RedundantPicCntPresentFlag {
+		s.ExponentialGolomb(&h.RedundantPicCnt, "redundant_pic_cnt")
+	}
+	if h.isType(SliceTypeB) {
+		s.Flag(&h.DirectSpatialMvPredFlag, "direct_spatial_mv_pred_flag")
+	}
+
+	if h.isType(SliceTypeP, SliceTypeSP, SliceTypeB) {
+		s.Flag(&h.NumRefIdxActiveOverrideFlag, "num_ref_idx_active_override_flag")
+	} else if s.Reading() {
+		h.NumRefIdxActiveOverrideFlag = false
+	}
+	if h.NumRefIdxActiveOverrideFlag {
+		if !s.Count(&h.NumRefIdxL0ActiveMinus1, 31, "num_ref_idx_l0_active_minus1") {
+			return
+		}
+		if h.isType(SliceTypeB) && !s.Count(&h.NumRefIdxL1ActiveMinus1, 31, "num_ref_idx_l1_active_minus1") {
+			return
+		}
+	}
+	l0, l1 := h.NumRefIdxL0ActiveMinus1, h.NumRefIdxL1ActiveMinus1
+	if !h.NumRefIdxActiveOverrideFlag {
+		l0, l1 = pps.NumRefIdxL0DefaultActiveMinus1, pps.NumRefIdxL1DefaultActiveMinus1
+	}
+	if s.Reading() {
+		h.NumRefIdxL0ActiveMinus1, h.NumRefIdxL1ActiveMinus1 = l0, l1
+	}
+
+	if !h.isType(SliceTypeI, SliceTypeSI) {
+		refPicListModifications(s, &h.RefPicListModificationFlagL0, &h.RefPicListModificationL0, "l0")
+	}
+	if h.isType(SliceTypeB) {
+		refPicListModifications(s, &h.RefPicListModificationFlagL1, &h.RefPicListModificationL1, "l1")
+	}
+
+	if (pps.WeightedPredFlag && h.isType(SliceTypeP, SliceTypeSP)) ||
+		(pps.WeightedBipredIdc == 1 && h.isType(SliceTypeB)) {
+		t := &h.PredWeightTable
+		chroma := sps.ChromaArrayType() != 0
+		s.ExponentialGolomb(&t.LumaLog2WeightDenom, "luma_log2_weight_denom")
+		if chroma {
+			s.ExponentialGolomb(&t.ChromaLog2WeightDenom, "chroma_log2_weight_denom")
+		}
+		predWeights(s, &t.L0, int(l0)+1, chroma, "l0")
+		if h.isType(SliceTypeB) {
+			predWeights(s, &t.L1, int(l1)+1, chroma, "l1")
+		}
+	}
+	if h.Header.RefIdc != 0 {
+		h.DecRefPicMarking.syntax(s, h.IsIDR())
+	}
+	if pps.EntropyCodingModeFlag && !h.isType(SliceTypeI, SliceTypeSI) {
+		s.ExponentialGolomb(&h.CabacInitIdc, "cabac_init_idc")
+	}
+	s.SignedExponentialGolomb(&h.SliceQpDelta, "slice_qp_delta")
+	if h.isType(SliceTypeSP, SliceTypeSI) {
+		if h.isType(SliceTypeSP) {
+			s.Flag(&h.SpForSwitchFlag, "sp_for_switch_flag")
+		}
+		s.SignedExponentialGolomb(&h.SliceQsDelta, "slice_qs_delta")
+	}
+	if pps.DeblockingFilterControlPresentFlag {
+		s.ExponentialGolomb(&h.DisableDeblockingFilterIdc, "disable_deblocking_filter_idc")
+		if h.DisableDeblockingFilterIdc != 1 {
+			s.SignedExponentialGolomb(&h.SliceAlphaC0OffsetDiv2, "slice_alpha_c0_offset_div2")
+			s.SignedExponentialGolomb(&h.SliceBetaOffsetDiv2, "slice_beta_offset_div2")
+		}
+	}
+	if pps.NumSliceGroupsMinus1 > 0 &&
+		SliceGroupMapBoxOut <= pps.SliceGroupMapType && pps.SliceGroupMapType <= SliceGroupMapWipe {
+		s.Bits(&h.SliceGroupChangeCycle, sliceGroupChangeCycleBits(sps, pps), "slice_group_change_cycle")
+	}
+	if s.Reading() {
+		h.DataBitOffset = s.BitStream().Tell()
+	}
+}
+
+// ParseSliceHeader parses the header of a coded slice NAL unit, NAL unit
+// header included. The PPS and SPS it refers to must be in ps.
+func ParseSliceHeader(ba gobits.ByteAccessor, ps *ParameterSets) (*SliceHeader, error) {
+	header, _, s, err := nal.ReadRBSP(ba, nal.H264, nal.IsType(NALTypeSlice, NALTypeSliceIDR))
+	if err != nil {
+		return nil, err
+	}
+	h := &SliceHeader{Header: header}
+	h.syntax(s, ps)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	return h, nil
+}
+
+// ReplaceSliceHeader re-encodes the coded slice NAL unit in ba with its
+// header replaced by h. The slice data is copied unchanged; for CABAC slices
+// the cabac_alignment_one_bits are regenerated for the new header length.
+func ReplaceSliceHeader(ba gobits.ByteAccessor, ps *ParameterSets, h *SliceHeader) ([]byte, error) {
+	header, rbsp, s, err := nal.ReadRBSP(ba, nal.H264, nal.IsType(NALTypeSlice, NALTypeSliceIDR))
+	if err != nil {
+		return nil, err
+	}
+	original := &SliceHeader{Header: header}
+	original.syntax(s, ps)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	in := s.BitStream()
+	cabac := ps.PPS[original.PPSID].EntropyCodingModeFlag
+	if cabac {
+		s.Align(8, gobits.PadOnes, "cabac_alignment_one_bit")
+		if s.Err() != nil {
+			return nil, s.Err()
+		}
+	}
+	end, ok := rbsp.TrailingBitsOffset()
+	if !ok || end < in.Tell() {
+		return nil, &gobits.FieldError{Field: "rbsp_stop_one_bit", Err: gobits.ErrInvalidSyntax}
+	}
+
+	return nal.WriteRBSP(h.Header, nal.H264, func(out *gobits.Syntax) {
+		h.syntax(out, ps)
+		if cabac {
+			out.Align(8, gobits.PadOnes, "cabac_alignment_one_bit")
+		}
+		for remaining := end - in.Tell(); remaining > 0 && out.Err() == nil; {
+			n := byte(64)
+			if remaining < 64 {
+				n = byte(remaining)
+			}
+			val, ok := in.ReadBits(n)
+			if !ok {
+				out.Fail("slice_data", in.Err())
+				return
+			}
+			out.Bits64(&val, n, "slice_data")
+			remaining -= int64(n)
+		}
+		out.RBSPTrailingBits()
+	})
+}
+
+// Marshal encodes the slice header as the start of a NAL unit, without slice
+// data or trailing bits. Use ReplaceSliceHeader to rewrite a complete slice.
+func (h *SliceHeader) Marshal(ps *ParameterSets) ([]byte, error) {
+	return nal.WriteRBSP(h.Header, nal.H264, func(s *gobits.Syntax) {
+		h.syntax(s, ps)
+	})
+}
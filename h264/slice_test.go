@@ -0,0 +1,119 @@
+package h264
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/ibbbpbbbp/gobits/nal"
+	"github.com/stretchr/testify/assert"
+)
+
+func highParameterSets(t *testing.T) *ParameterSets {
+	ps := NewParameterSets()
+	sps, err := ParseSPS(gobits.NewSliceByteAccessor(spsHigh))
+	assert.NoError(t, err)
+	ps.AddSPS(sps)
+	pps, err := ParsePPS(gobits.NewSliceByteAccessor(ppsHigh), ps)
+	assert.NoError(t, err)
+	ps.AddPPS(pps)
+	return ps
+}
+
+// sliceData is slice data that needs an emulation prevention byte.
+var sliceData = []byte{0x12, 0x34, 0x00, 0x00, 0x01, 0xff, 0x80}
+
+// writeSlice encodes a CABAC slice with h as its header.
+func writeSlice(t *testing.T, ps *ParameterSets, h *SliceHeader) []byte {
+	raw, err := nal.WriteRBSP(h.Header, nal.H264, func(s *gobits.Syntax) {
+		h.syntax(s, ps)
+		s.Align(8, gobits.PadOnes, "cabac_alignment_one_bit")
+		for i := range sliceData {
+			s.Byte(&sliceData[i], 8, "slice_data")
+		}
+		s.RBSPTrailingBits()
+	})
+	assert.NoError(t, err)
+	return raw
+}
+
+func TestParseSliceHeader(t *testing.T) {
+	ps := highParameterSets(t)
+	h := &SliceHeader{
+		Header:                       nal.Header{RefIdc: 2, Type: NALTypeSlice},
+		SliceType:                    SliceTypeP + 5,
+		FrameNum:                     3,
+		PicOrderCntLsb:               6,
+		NumRefIdxActiveOverrideFlag:  true,
+		NumRefIdxL0ActiveMinus1:      1,
+		RefPicListModificationFlagL0: true,
+		RefPicListModificationL0: []RefPicListModification{
+			{ModificationOfPicNumsIdc: 0, AbsDiffPicNumMinus1: 1},
+			{ModificationOfPicNumsIdc: 2, LongTermPicNum: 4},
+		},
+		PredWeightTable: PredWeightTable{
+			LumaLog2WeightDenom:   6,
+			ChromaLog2WeightDenom: 6,
+			L0: []PredWeight{
+				{LumaWeightFlag: true, LumaWeight: 60, LumaOffset: -3},
+				{ChromaWeightFlag: true, ChromaWeight: [2]int32{64, 62}, ChromaOffset: [2]int32{1, -1}},
+			},
+		},
+		DecRefPicMarking: DecRefPicMarking{
+			AdaptiveRefPicMarkingModeFlag: true,
+			Operations: []MMCO{
+				{Operation: 1, DifferenceOfPicNumsMinus1: 2},
+				{Operation: 3, DifferenceOfPicNumsMinus1: 0, LongTermFrameIdx: 1},
+				{Operation: 4, MaxLongTermFrameIdxPlus1: 2},
+			},
+		},
+		CabacInitIdc:           1,
+		SliceQpDelta:           -2,
+		SliceAlphaC0OffsetDiv2: -1,
+		SliceBetaOffsetDiv2:    1,
+	}
+	raw := writeSlice(t, ps, h)
+
+	parsed, err := ParseSliceHeader(gobits.NewSliceByteAccessor(raw), ps)
+	assert.NoError(t, err)
+	h.DataBitOffset = parsed.DataBitOffset
+	assert.Equal(t, h, parsed)
+
+	// The defaults of the PPS apply without an override.
+	h.NumRefIdxActiveOverrideFlag = false
+	h.PredWeightTable.L0 = append(h.PredWeightTable.L0, PredWeight{})
+	parsed, err = ParseSliceHeader(gobits.NewSliceByteAccessor(writeSlice(t, ps, h)), ps)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), parsed.NumRefIdxL0ActiveMinus1)
+	assert.Len(t, parsed.PredWeightTable.L0, 3)
+
+	_, err = ParseSliceHeader(gobits.NewSliceByteAccessor(raw), NewParameterSets())
+	assert.Error(t, err)
+	_, err = ParseSliceHeader(gobits.NewSliceByteAccessor(raw), nil)
+	assert.True(t, errors.Is(err, gobits.ErrInvalidSyntax))
+	_, err = ReplaceSliceHeader(gobits.NewSliceByteAccessor(raw), nil, h)
+	assert.True(t, errors.Is(err, gobits.ErrInvalidSyntax))
+}
+
+func TestReplaceSliceHeader(t *testing.T) {
+	ps := highParameterSets(t)
+	h := &SliceHeader{
+		Header:           nal.Header{RefIdc: 3, Type: NALTypeSliceIDR},
+		SliceType:        SliceTypeI + 5,
+		IdrPicID:         1,
+		SliceQpDelta:     4,
+		DecRefPicMarking: DecRefPicMarking{LongTermReferenceFlag: true},
+	}
+	raw := writeSlice(t, ps, h)
+
+	for _, idrPicID := range []uint32{0, 1, 1000, 65535} {
+		h.IdrPicID = idrPicID
+		replaced, err := ReplaceSliceHeader(gobits.NewSliceByteAccessor(raw), ps, h)
+		assert.NoError(t, err)
+		assert.Equal(t, writeSlice(t, ps, h), replaced)
+
+		parsed, err := ParseSliceHeader(gobits.NewSliceByteAccessor(replaced), ps)
+		assert.NoError(t, err)
+		assert.Equal(t, idrPicID, parsed.IdrPicID)
+	}
+}
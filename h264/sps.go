@@ -0,0 +1,434 @@
+package h264
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/ibbbpbbbp/gobits/nal"
+)
+
+// ScalingList holds a scaling_list() as coded: the delta_scale values in
+// the order they appear. Present is the corresponding
+// seq_scaling_list_present_flag or pic_scaling_list_present_flag.
+type ScalingList struct {
+	Present     bool
+	DeltaScales []int32
+}
+
+func (l *ScalingList) syntax(s *gobits.Syntax, size int) {
+	s.Flag(&l.Present, "scaling_list_present_flag")
+	if !l.Present {
+		return
+	}
+	if s.Reading() {
+		l.DeltaScales = nil
+	}
+
+	last, next := int32(8), int32(8)
+	k := 0
+	for j := 0; j < size && s.Err() == nil; j++ {
+		if next != 0 {
+			if s.Reading() {
+				l.DeltaScales = append(l.DeltaScales, 0)
+			} else if k == len(l.DeltaScales) {
+				s.Fail("delta_scale", gobits.ErrOutOfRange)
+				return
+			}
+			s.SignedExponentialGolomb(&l.DeltaScales[k], "delta_scale")
+			next = ((last+l.DeltaScales[k])%256 + 256) % 256
+			k++
+		}
+		if next != 0 {
+			last = next
+		}
+	}
+	s.ListLength(len(l.DeltaScales), k, "delta_scale")
+}
+
+// Values expands the list to size scaling factors. useDefault reports that
+// the list signals the default scaling matrix instead.
+func (l ScalingList) Values(size int) (values []int32, useDefault bool) {
+	values = make([]int32, size)
+	last, next := int32(8), int32(8)
+	k := 0
+	for j := range values {
+		if next != 0 && k < len(l.DeltaScales) {
+			next = ((last+l.DeltaScales[k])%256 + 256) % 256
+			k++
+			useDefault = j == 0 && next == 0
+		}
+		if next != 0 {
+			values[j] = next
+		} else {
+			values[j] = last
+		}
+		last = values[j]
+	}
+	return values, useDefault
+}
+
+// scalingLists handles the lists of an SPS or PPS scaling matrix: six 4x4
+// lists followed by n-6 8x8 lists.
+func scalingLists(s *gobits.Syntax, lists *[]ScalingList, n int) {
+	if s.Reading() {
+		*lists = make([]ScalingList, n)
+	}
+	if !s.ListLength(len(*lists), n, "scaling_list_present_flag") {
+		return
+	}
+	for i := range *lists {
+		size := 64
+		if i < 6 {
+			size = 16
+		}
+		(*lists)[i].syntax(s, size)
+	}
+}
+
+// CPB holds the parameters of one coded picture buffer specification in
+// hrd_parameters().
+type CPB struct {
+	BitRateValueMinus1 uint32
+	CpbSizeValueMinus1 uint32
+	CbrFlag            bool
+}
+
+type HRD struct {
+	BitRateScale                       byte
+	CpbSizeScale                       byte
+	CPBs                               []CPB
+	InitialCpbRemovalDelayLengthMinus1 byte
+	CpbRemovalDelayLengthMinus1        byte
+	DpbOutputDelayLengthMinus1         byte
+	TimeOffsetLength                   byte
+}
+
+func (h *HRD) syntax(s *gobits.Syntax) {
+	cpbCntMinus1 := uint32(len(h.CPBs)) - 1
+	if !s.Count(&cpbCntMinus1, 31, "cpb_cnt_minus1") {
+		return
+	}
+	s.Byte(&h.BitRateScale, 4, "bit_rate_scale")
+	s.Byte(&h.CpbSizeScale, 4, "cpb_size_scale")
+	if s.Reading() {
+		h.CPBs = make([]CPB, cpbCntMinus1+1)
+	}
+	for i := range h.CPBs {
+		s.ExponentialGolomb(&h.CPBs[i].BitRateValueMinus1, "bit_rate_value_minus1")
+		s.ExponentialGolomb(&h.CPBs[i].CpbSizeValueMinus1, "cpb_size_value_minus1")
+		s.Flag(&h.CPBs[i].CbrFlag, "cbr_flag")
+	}
+	s.Byte(&h.InitialCpbRemovalDelayLengthMinus1, 5, "initial_cpb_removal_delay_length_minus1")
+	s.Byte(&h.CpbRemovalDelayLengthMinus1, 5, "cpb_removal_delay_length_minus1")
+	s.Byte(&h.DpbOutputDelayLengthMinus1, 5, "dpb_output_delay_length_minus1")
+	s.Byte(&h.TimeOffsetLength, 5, "time_offset_length")
+}
+
+// AspectRatioExtendedSAR is the aspect_ratio_idc that signals an explicit
+// sample aspect ratio.
+const AspectRatioExtendedSAR = 255
+
+type VUI struct {
+	AspectRatioInfoPresentFlag bool
+	AspectRatioIdc             byte
+	SarWidth                   uint32
+	SarHeight                  uint32
+
+	OverscanInfoPresentFlag bool
+	OverscanAppropriateFlag bool
+
+	VideoSignalTypePresentFlag   bool
+	VideoFormat                  byte
+	VideoFullRangeFlag           bool
+	ColourDescriptionPresentFlag bool
+	ColourPrimaries              byte
+	TransferCharacteristics      byte
+	MatrixCoefficients           byte
+
+	ChromaLocInfoPresentFlag       bool
+	ChromaSampleLocTypeTopField    uint32
+	ChromaSampleLocTypeBottomField uint32
+	TimingInfoPresentFlag          bool
+	NumUnitsInTick                 uint32
+	TimeScale                      uint32
+	FixedFrameRateFlag             bool
+	NalHrdParametersPresentFlag    bool
+	NalHRD                         HRD
+	VclHrdParametersPresentFlag    bool
+	VclHRD                         HRD
+	LowDelayHrdFlag                bool
+	PicStructPresentFlag           bool
+	BitstreamRestrictionFlag       bool
+	MotionVectorsOverPicBoundaries bool
+	MaxBytesPerPicDenom            uint32
+	MaxBitsPerMbDenom              uint32
+	Log2MaxMvLengthHorizontal      uint32
+	Log2MaxMvLengthVertical        uint32
+	MaxNumReorderFrames            uint32
+	MaxDecFrameBuffering           uint32
+}
+
+func (v *VUI) syntax(s *gobits.Syntax) {
+	s.Flag(&v.AspectRatioInfoPresentFlag, "aspect_ratio_info_present_flag")
+	if v.AspectRatioInfoPresentFlag {
+		s.Byte(&v.AspectRatioIdc, 8, "aspect_ratio_idc")
+		if v.AspectRatioIdc == AspectRatioExtendedSAR {
+			s.Bits(&v.SarWidth, 16, "sar_width")
+			s.Bits(&v.SarHeight, 16, "sar_height")
+		}
+	}
+	s.Flag(&v.OverscanInfoPresentFlag, "overscan_info_present_flag")
+	if v.OverscanInfoPresentFlag {
+		s.Flag(&v.OverscanAppropriateFlag, "overscan_appropriate_flag")
+	}
+	s.Flag(&v.VideoSignalTypePresentFlag, "video_signal_type_present_flag")
+	if v.VideoSignalTypePresentFlag {
+		s.Byte(&v.VideoFormat, 3, "video_format")
+		s.Flag(&v.VideoFullRangeFlag, "video_full_range_flag")
+		s.Flag(&v.ColourDescriptionPresentFlag, "colour_description_present_flag")
+		if v.ColourDescriptionPresentFlag {
+			s.Byte(&v.ColourPrimaries, 8, "colour_primaries")
+			s.Byte(&v.TransferCharacteristics, 8, "transfer_characteristics")
+			s.Byte(&v.MatrixCoefficients, 8, "matrix_coefficients")
+		}
+	}
+	s.Flag(&v.ChromaLocInfoPresentFlag, "chroma_loc_info_present_flag")
+	if v.ChromaLocInfoPresentFlag {
+		s.ExponentialGolomb(&v.ChromaSampleLocTypeTopField, "chroma_sample_loc_type_top_field")
+		s.ExponentialGolomb(&v.ChromaSampleLocTypeBottomField, "chroma_sample_loc_type_bottom_field")
+	}
+	s.Flag(&v.TimingInfoPresentFlag, "timing_info_present_flag")
+	if v.TimingInfoPresentFlag {
+		s.Bits(&v.NumUnitsInTick, 32, "num_units_in_tick")
+		s.Bits(&v.TimeScale, 32, "time_scale")
+		s.Flag(&v.FixedFrameRateFlag, "fixed_frame_rate_flag")
+	}
+	s.Flag(&v.NalHrdParametersPresentFlag, "nal_hrd_parameters_present_flag")
+	if v.NalHrdParametersPresentFlag {
+		v.NalHRD.syntax(s)
+	}
+	s.Flag(&v.VclHrdParametersPresentFlag, "vcl_hrd_parameters_present_flag")
+	if v.VclHrdParametersPresentFlag {
+		v.VclHRD.syntax(s)
+	}
+	if v.NalHrdParametersPresentFlag || v.VclHrdParametersPresentFlag {
+		s.Flag(&v.LowDelayHrdFlag, "low_delay_hrd_flag")
+	}
+	s.Flag(&v.PicStructPresentFlag, "pic_struct_present_flag")
+	s.Flag(&v.BitstreamRestrictionFlag, "bitstream_restriction_flag")
+	if v.BitstreamRestrictionFlag {
+		s.Flag(&v.MotionVectorsOverPicBoundaries, "motion_vectors_over_pic_boundaries_flag")
+		s.ExponentialGolomb(&v.MaxBytesPerPicDenom, "max_bytes_per_pic_denom")
+		s.ExponentialGolomb(&v.MaxBitsPerMbDenom, "max_bits_per_mb_denom")
+		s.ExponentialGolomb(&v.Log2MaxMvLengthHorizontal, "log2_max_mv_length_horizontal")
+		s.ExponentialGolomb(&v.Log2MaxMvLengthVertical, "log2_max_mv_length_vertical")
+		s.ExponentialGolomb(&v.MaxNumReorderFrames, "max_num_reorder_frames")
+		s.ExponentialGolomb(&v.MaxDecFrameBuffering, "max_dec_frame_buffering")
+	}
+}
+
+// FrameRate returns the frame rate implied by the timing information of a
+// progressive stream, or zero if there is none.
+func (v *VUI) FrameRate() float64 {
+	if !v.TimingInfoPresentFlag || v.NumUnitsInTick == 0 {
+		return 0
+	}
+	return float64(v.TimeScale) / float64(2*v.NumUnitsInTick)
+}
+
+// SPS is a seq_parameter_set_rbsp().
+type SPS struct {
+	Header nal.Header
+
+	ProfileIdc byte
+	// ConstraintFlags holds constraint_set0_flag to constraint_set5_flag
+	// and reserved_zero_2bits, first flag in the most significant bit.
+	ConstraintFlags byte
+	LevelIdc        byte
+	ID              uint32
+
+	ChromaFormatIdc                 uint32
+	SeparateColourPlaneFlag         bool
+	BitDepthLumaMinus8              uint32
+	BitDepthChromaMinus8            uint32
+	QpprimeYZeroTransformBypassFlag bool
+	SeqScalingMatrixPresentFlag     bool
+	ScalingLists                    []ScalingList
+
+	Log2MaxFrameNumMinus4          uint32
+	PicOrderCntType                uint32
+	Log2MaxPicOrderCntLsbMinus4    uint32
+	DeltaPicOrderAlwaysZeroFlag    bool
+	OffsetForNonRefPic             int32
+	OffsetForTopToBottomField      int32
+	OffsetForRefFrame              []int32
+	MaxNumRefFrames                uint32
+	GapsInFrameNumValueAllowedFlag bool
+	PicWidthInMbsMinus1            uint32
+	PicHeightInMapUnitsMinus1      uint32
+	FrameMbsOnlyFlag               bool
+	MbAdaptiveFrameFieldFlag       bool
+	Direct8x8InferenceFlag         bool
+
+	FrameCroppingFlag     bool
+	FrameCropLeftOffset   uint32
+	FrameCropRightOffset  uint32
+	FrameCropTopOffset    uint32
+	FrameCropBottomOffset uint32
+
+	VUIParametersPresentFlag bool
+	VUI                      VUI
+}
+
+// HasChromaFormat reports whether profile_idc selects the syntax that
+// carries chroma_format_idc and the related fields.
+func (sps *SPS) HasChromaFormat() bool {
+	switch sps.ProfileIdc {
+	case 100, 110, 122, 244, 44, 83, 86, 118, 128, 138, 139, 134, 135:
+		return true
+	}
+	return false
+}
+
+func (sps *SPS) syntax(s *gobits.Syntax) {
+	s.Byte(&sps.ProfileIdc, 8, "profile_idc")
+	s.Byte(&sps.ConstraintFlags, 8, "constraint_set_flags")
+	s.Byte(&sps.LevelIdc, 8, "level_idc")
+	s.ExponentialGolomb(&sps.ID, "seq_parameter_set_id")
+	if sps.ID > 31 {
+		s.Fail("seq_parameter_set_id", gobits.ErrOutOfRange)
+	}
+
+	if s.Reading() {
+		sps.ChromaFormatIdc = 1
+	}
+	if sps.HasChromaFormat() {
+		s.ExponentialGolomb(&sps.ChromaFormatIdc, "chroma_format_idc")
+		if sps.ChromaFormatIdc == 3 {
+			s.Flag(&sps.SeparateColourPlaneFlag, "separate_colour_plane_flag")
+		}
+		s.ExponentialGolomb(&sps.BitDepthLumaMinus8, "bit_depth_luma_minus8")
+		s.ExponentialGolomb(&sps.BitDepthChromaMinus8, "bit_depth_chroma_minus8")
+		s.Flag(&sps.QpprimeYZeroTransformBypassFlag, "qpprime_y_zero_transform_bypass_flag")
+		s.Flag(&sps.SeqScalingMatrixPresentFlag, "seq_scaling_matrix_present_flag")
+		if sps.SeqScalingMatrixPresentFlag {
+			n := 8
+			if sps.ChromaFormatIdc == 3 {
+				n = 12
+			}
+			scalingLists(s, &sps.ScalingLists, n)
+		}
+	}
+
+	s.ExponentialGolomb(&sps.Log2MaxFrameNumMinus4, "log2_max_frame_num_minus4")
+	if sps.Log2MaxFrameNumMinus4 > 12 {
+		s.Fail("log2_max_frame_num_minus4", gobits.ErrOutOfRange)
+	}
+	s.ExponentialGolomb(&sps.PicOrderCntType, "pic_order_cnt_type")
+	switch sps.PicOrderCntType {
+	case 0:
+		s.ExponentialGolomb(&sps.Log2MaxPicOrderCntLsbMinus4, "log2_max_pic_order_cnt_lsb_minus4")
+		if sps.Log2MaxPicOrderCntLsbMinus4 > 12 {
+			s.Fail("log2_max_pic_order_cnt_lsb_minus4", gobits.ErrOutOfRange)
+		}
+	case 1:
+		s.Flag(&sps.DeltaPicOrderAlwaysZeroFlag, "delta_pic_order_always_zero_flag")
+		s.SignedExponentialGolomb(&sps.OffsetForNonRefPic, "offset_for_non_ref_pic")
+		s.SignedExponentialGolomb(&sps.OffsetForTopToBottomField, "offset_for_top_to_bottom_field")
+		n := uint32(len(sps.OffsetForRefFrame))
+		if !s.Count(&n, 255, "num_ref_frames_in_pic_order_cnt_cycle") {
+			return
+		}
+		if s.Reading() {
+			sps.OffsetForRefFrame = make([]int32, n)
+		}
+		for i := range sps.OffsetForRefFrame {
+			s.SignedExponentialGolomb(&sps.OffsetForRefFrame[i], "offset_for_ref_frame")
+		}
+	}
+	s.ExponentialGolomb(&sps.MaxNumRefFrames, "max_num_ref_frames")
+	s.Flag(&sps.GapsInFrameNumValueAllowedFlag, "gaps_in_frame_num_value_allowed_flag")
+	s.ExponentialGolomb(&sps.PicWidthInMbsMinus1, "pic_width_in_mbs_minus1")
+	s.ExponentialGolomb(&sps.PicHeightInMapUnitsMinus1, "pic_height_in_map_units_minus1")
+	s.Flag(&sps.FrameMbsOnlyFlag, "frame_mbs_only_flag")
+	if !sps.FrameMbsOnlyFlag {
+		s.Flag(&sps.MbAdaptiveFrameFieldFlag, "mb_adaptive_frame_field_flag")
+	}
+	s.Flag(&sps.Direct8x8InferenceFlag, "direct_8x8_inference_flag")
+	s.Flag(&sps.FrameCroppingFlag, "frame_cropping_flag")
+	if sps.FrameCroppingFlag {
+		s.ExponentialGolomb(&sps.FrameCropLeftOffset, "frame_crop_left_offset")
+		s.ExponentialGolomb(&sps.FrameCropRightOffset, "frame_crop_right_offset")
+		s.ExponentialGolomb(&sps.FrameCropTopOffset, "frame_crop_top_offset")
+		s.ExponentialGolomb(&sps.FrameCropBottomOffset, "frame_crop_bottom_offset")
+	}
+	s.Flag(&sps.VUIParametersPresentFlag, "vui_parameters_present_flag")
+	if sps.VUIParametersPresentFlag {
+		sps.VUI.syntax(s)
+	}
+	s.RBSPTrailingBits()
+}
+
+// ChromaArrayType returns the ChromaArrayType variable.
+func (sps *SPS) ChromaArrayType() uint32 {
+	if sps.SeparateColourPlaneFlag {
+		return 0
+	}
+	return sps.ChromaFormatIdc
+}
+
+// cropUnits returns CropUnitX and CropUnitY.
+func (sps *SPS) cropUnits() (uint32, uint32) {
+	x, y := uint32(1), uint32(1)
+	switch sps.ChromaArrayType() {
+	case 1:
+		x, y = 2, 2
+	case 2:
+		x = 2
+	}
+	if !sps.FrameMbsOnlyFlag {
+		y *= 2
+	}
+	return x, y
+}
+
+// Width returns the width of the decoded frames after cropping.
+func (sps *SPS) Width() int {
+	x, _ := sps.cropUnits()
+	width := (sps.PicWidthInMbsMinus1 + 1) * 16
+	if sps.FrameCroppingFlag {
+		width -= x * (sps.FrameCropLeftOffset + sps.FrameCropRightOffset)
+	}
+	return int(width)
+}
+
+// Height returns the height of the decoded frames after cropping.
+func (sps *SPS) Height() int {
+	_, y := sps.cropUnits()
+	height := (sps.PicHeightInMapUnitsMinus1 + 1) * 16
+	if !sps.FrameMbsOnlyFlag {
+		height *= 2
+	}
+	if sps.FrameCroppingFlag {
+		height -= y * (sps.FrameCropTopOffset + sps.FrameCropBottomOffset)
+	}
+	return int(height)
+}
+
+// ParseSPS parses an SPS NAL unit, header included, with emulation
+// prevention bytes still in place.
+func ParseSPS(ba gobits.ByteAccessor) (*SPS, error) {
+	header, _, s, err := nal.ReadRBSP(ba, nal.H264, nal.IsType(NALTypeSPS))
+	if err != nil {
+		return nil, err
+	}
+	sps := &SPS{Header: header}
+	sps.syntax(s)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	return sps, nil
+}
+
+// Marshal encodes the SPS as a NAL unit.
+func (sps *SPS) Marshal() ([]byte, error) {
+	return nal.WriteRBSP(sps.Header, nal.H264, sps.syntax)
+}
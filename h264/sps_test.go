@@ -0,0 +1,141 @@
+package h264
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/ibbbpbbbp/gobits/nal"
+	"github.com/stretchr/testify/assert"
+)
+
+// spsHigh is a 1280x720 High profile SPS as produced by x264.
+var spsHigh = []byte{
+	0x67, 0x64, 0x00, 0x1f, 0xac, 0xd9, 0x40, 0x50, 0x05, 0xbb, 0x01, 0x10, 0x00,
+	0x00, 0x03, 0x00, 0x10, 0x00, 0x00, 0x03, 0x03, 0xc0, 0xf1, 0x83, 0x19, 0x60,
+}
+
+func TestParseSPS(t *testing.T) {
+	sps, err := ParseSPS(gobits.NewSliceByteAccessor(spsHigh))
+	assert.NoError(t, err)
+	assert.Equal(t, nal.Header{RefIdc: 3, Type: NALTypeSPS}, sps.Header)
+	assert.Equal(t, byte(100), sps.ProfileIdc)
+	assert.Equal(t, byte(31), sps.LevelIdc)
+	assert.Equal(t, uint32(1), sps.ChromaFormatIdc)
+	assert.Equal(t, uint32(2), sps.Log2MaxPicOrderCntLsbMinus4)
+	assert.Equal(t, uint32(4), sps.MaxNumRefFrames)
+	assert.True(t, sps.VUIParametersPresentFlag)
+	assert.Equal(t, uint32(60), sps.VUI.TimeScale)
+	assert.Equal(t, uint32(2), sps.VUI.MaxNumReorderFrames)
+	assert.Equal(t, 1280, sps.Width())
+	assert.Equal(t, 720, sps.Height())
+	assert.Equal(t, 30.0, sps.VUI.FrameRate())
+
+	raw, err := sps.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, spsHigh, raw)
+
+	_, err = ParseSPS(gobits.NewSliceByteAccessor([]byte{0x68, 0xeb, 0xe3, 0xcb, 0x22, 0xc0}))
+	var fieldErr *gobits.FieldError
+	assert.True(t, errors.As(err, &fieldErr))
+	assert.Equal(t, "nal_unit_type", fieldErr.Field)
+	assert.True(t, errors.Is(err, gobits.ErrInvalidSyntax))
+
+	_, err = ParseSPS(gobits.NewSliceByteAccessor(spsHigh[:12]))
+	assert.True(t, errors.Is(err, gobits.ErrUnexpectedEOF))
+}
+
+func TestSPS_Rewrite(t *testing.T) {
+	sps, err := ParseSPS(gobits.NewSliceByteAccessor(spsHigh))
+	assert.NoError(t, err)
+
+	sps.LevelIdc = 40
+	sps.VUI.NumUnitsInTick = 1001
+	sps.VUI.TimeScale = 60000
+	raw, err := sps.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x67, 0x64, 0x00, 0x28}, raw[:4])
+
+	rewritten, err := ParseSPS(gobits.NewSliceByteAccessor(raw))
+	assert.NoError(t, err)
+	assert.Equal(t, sps, rewritten)
+	assert.InDelta(t, 29.97, rewritten.VUI.FrameRate(), 0.01)
+}
+
+func TestSPS_RoundTrip(t *testing.T) {
+	sps := &SPS{
+		Header:                      nal.Header{RefIdc: 3, Type: NALTypeSPS},
+		ProfileIdc:                  100,
+		ConstraintFlags:             0x0c,
+		LevelIdc:                    40,
+		ID:                          1,
+		ChromaFormatIdc:             1,
+		SeqScalingMatrixPresentFlag: true,
+		ScalingLists: []ScalingList{
+			{Present: true, DeltaScales: []int32{-8}},
+			{Present: true, DeltaScales: []int32{1, 2, -3, 0, 5, 6, 7, 8, 0, 0, 0, 0, 0, 0, 0, -128}},
+			{}, {}, {}, {}, {},
+			{Present: true, DeltaScales: []int32{8, -16}},
+		},
+		PicOrderCntType:           1,
+		OffsetForNonRefPic:        -2,
+		OffsetForTopToBottomField: 1,
+		OffsetForRefFrame:         []int32{2, -2, 4},
+		MaxNumRefFrames:           2,
+		PicWidthInMbsMinus1:       119,
+		PicHeightInMapUnitsMinus1: 33,
+		MbAdaptiveFrameFieldFlag:  true,
+		Direct8x8InferenceFlag:    true,
+		FrameCroppingFlag:         true,
+		FrameCropBottomOffset:     2,
+		VUIParametersPresentFlag:  true,
+		VUI: VUI{
+			AspectRatioInfoPresentFlag:   true,
+			AspectRatioIdc:               AspectRatioExtendedSAR,
+			SarWidth:                     4,
+			SarHeight:                    3,
+			VideoSignalTypePresentFlag:   true,
+			VideoFormat:                  5,
+			ColourDescriptionPresentFlag: true,
+			ColourPrimaries:              1,
+			TransferCharacteristics:      1,
+			MatrixCoefficients:           1,
+			TimingInfoPresentFlag:        true,
+			NumUnitsInTick:               1001,
+			TimeScale:                    60000,
+			FixedFrameRateFlag:           true,
+			NalHrdParametersPresentFlag:  true,
+			NalHRD: HRD{
+				BitRateScale: 4,
+				CpbSizeScale: 3,
+				CPBs: []CPB{
+					{BitRateValueMinus1: 15624, CpbSizeValueMinus1: 62499, CbrFlag: true},
+					{BitRateValueMinus1: 31249, CpbSizeValueMinus1: 124999},
+				},
+				InitialCpbRemovalDelayLengthMinus1: 23,
+				CpbRemovalDelayLengthMinus1:        23,
+				DpbOutputDelayLengthMinus1:         23,
+				TimeOffsetLength:                   24,
+			},
+			PicStructPresentFlag: true,
+		},
+	}
+	raw, err := sps.Marshal()
+	assert.NoError(t, err)
+	parsed, err := ParseSPS(gobits.NewSliceByteAccessor(raw))
+	assert.NoError(t, err)
+	assert.Equal(t, sps, parsed)
+	assert.Equal(t, 1920, parsed.Width())
+	assert.Equal(t, 1080, parsed.Height())
+
+	values, useDefault := parsed.ScalingLists[0].Values(16)
+	assert.True(t, useDefault)
+	assert.Equal(t, int32(8), values[15])
+	values, useDefault = parsed.ScalingLists[7].Values(64)
+	assert.False(t, useDefault)
+	assert.Equal(t, []int32{16, 16, 16}, values[:3])
+
+	sps.ScalingLists = sps.ScalingLists[:7]
+	_, err = sps.Marshal()
+	assert.True(t, errors.Is(err, gobits.ErrOutOfRange))
+}
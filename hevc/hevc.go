@@ -0,0 +1,71 @@
+// Package hevc parses and writes HEVC video, sequence and picture parameter
+// sets and slice segment headers.
+//
+// As in package h264, each structure is described once as a gobits.Syntax,
+// so parsing a NAL unit and marshalling the result reproduces it bit for
+// bit. Fields are named after the syntax elements of ITU-T H.265 section
+// 7.3. Multilayer, 3D and screen content coding extensions are not
+// interpreted; their bits are kept as extension data.
+package hevc
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+)
+
+// NAL unit types handled by this package.
+const (
+	NALTypeBLAWLP   = 16
+	NALTypeIDRWRADL = 19
+	NALTypeIDRNLP   = 20
+	NALTypeRsvIRAP  = 23
+	NALTypeVPS      = 32
+	NALTypeSPS      = 33
+	NALTypePPS      = 34
+)
+
+const (
+	SliceTypeB = 0
+	SliceTypeP = 1
+	SliceTypeI = 2
+)
+
+// ParameterSets holds the parameter sets that later syntax depends on,
+// keyed by their IDs.
+type ParameterSets struct {
+	VPS map[uint32]*VPS
+	SPS map[uint32]*SPS
+	PPS map[uint32]*PPS
+}
+
+func (ps *ParameterSets) AddVPS(vps *VPS) {
+	ps.VPS[vps.ID] = vps
+}
+
+func (ps *ParameterSets) AddSPS(sps *SPS) {
+	ps.SPS[sps.ID] = sps
+}
+
+func (ps *ParameterSets) AddPPS(pps *PPS) {
+	ps.PPS[pps.ID] = pps
+}
+
+func NewParameterSets() *ParameterSets {
+	return &ParameterSets{VPS: map[uint32]*VPS{}, SPS: map[uint32]*SPS{}, PPS: map[uint32]*PPS{}}
+}
+
+// extensionData handles the *_extension_data_flag bits that run up to the
+// RBSP trailing bits.
+func extensionData(s *gobits.Syntax, data *[]bool, more func() bool, field string) {
+	if s.Reading() {
+		*data = nil
+		for s.Err() == nil && more() {
+			flag := false
+			s.Flag(&flag, field)
+			*data = append(*data, flag)
+		}
+		return
+	}
+	for i := range *data {
+		s.Flag(&(*data)[i], field)
+	}
+}
@@ -0,0 +1,106 @@
+package hevc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/stretchr/testify/assert"
+)
+
+// Main profile parameter sets of a 1280x720 stream as produced by x265.
+var (
+	vpsMain = []byte{
+		0x40, 0x01, 0x0c, 0x01, 0xff, 0xff, 0x01, 0x60, 0x00, 0x00, 0x03, 0x00,
+		0x90, 0x00, 0x00, 0x03, 0x00, 0x00, 0x03, 0x00, 0x5d, 0x95, 0x98, 0x09,
+	}
+	spsMain = []byte{
+		0x42, 0x01, 0x01, 0x01, 0x60, 0x00, 0x00, 0x03, 0x00, 0x90, 0x00, 0x00,
+		0x03, 0x00, 0x00, 0x03, 0x00, 0x5d, 0xa0, 0x02, 0x80, 0x80, 0x2d, 0x16,
+		0x59, 0x59, 0xa4, 0x93, 0x2b, 0xc0, 0x5a, 0x70, 0x80, 0x00, 0x01, 0xf4,
+		0x80, 0x00, 0x3a, 0x98, 0x04,
+	}
+	ppsMain = []byte{0x44, 0x01, 0xc1, 0x72, 0xb4, 0x62, 0x40}
+)
+
+func mainParameterSets(t *testing.T) *ParameterSets {
+	ps := NewParameterSets()
+	vps, err := ParseVPS(gobits.NewSliceByteAccessor(vpsMain))
+	assert.NoError(t, err)
+	ps.AddVPS(vps)
+	sps, err := ParseSPS(gobits.NewSliceByteAccessor(spsMain))
+	assert.NoError(t, err)
+	ps.AddSPS(sps)
+	pps, err := ParsePPS(gobits.NewSliceByteAccessor(ppsMain))
+	assert.NoError(t, err)
+	ps.AddPPS(pps)
+	return ps
+}
+
+func TestParameterSets_RoundTrip(t *testing.T) {
+	ps := mainParameterSets(t)
+
+	raw, err := ps.VPS[0].Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, vpsMain, raw)
+	assert.Equal(t, []SubLayerOrderingInfo{{MaxDecPicBufferingMinus1: 4, MaxNumReorderPics: 2, MaxLatencyIncreasePlus1: 5}},
+		ps.VPS[0].SubLayerOrderingInfo)
+
+	sps := ps.SPS[0]
+	raw, err = sps.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, spsMain, raw)
+	assert.Equal(t, 1280, sps.Width())
+	assert.Equal(t, 720, sps.Height())
+	assert.Equal(t, uint32(240), sps.PicSizeInCtbsY())
+	assert.InDelta(t, 29.97, sps.VUI.FrameRate(), 0.01)
+
+	pps := ps.PPS[0]
+	raw, err = pps.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, ppsMain, raw)
+	assert.True(t, pps.EntropyCodingSyncEnabledFlag)
+	assert.Equal(t, uint32(1), pps.DiffCuQpDeltaDepth)
+
+	_, err = ParseSPS(gobits.NewSliceByteAccessor(vpsMain))
+	var fieldErr *gobits.FieldError
+	assert.True(t, errors.As(err, &fieldErr))
+	assert.Equal(t, "nal_unit_type", fieldErr.Field)
+
+	_, err = ParseSPS(gobits.NewSliceByteAccessor(spsMain[:30]))
+	assert.True(t, errors.Is(err, gobits.ErrUnexpectedEOF))
+}
+
+func TestProfileTierLevel_CodecString(t *testing.T) {
+	ps := mainParameterSets(t)
+	sps := ps.SPS[0]
+	assert.Equal(t, "hvc1.1.6.L93.90", sps.CodecString("hvc1"))
+	assert.True(t, sps.ProfileTierLevel.General.ProgressiveSourceFlag())
+	assert.False(t, sps.ProfileTierLevel.General.InterlacedSourceFlag())
+
+	sps.ProfileTierLevel.General.ConstraintIndicatorFlags = 0xb0 << 40
+	assert.Equal(t, "hvc1.1.6.L93.B0", sps.CodecString("hvc1"))
+
+	// Rewriting the level keeps the rest of the SPS intact.
+	sps.ProfileTierLevel.GeneralLevelIdc = 120
+	raw, err := sps.Marshal()
+	assert.NoError(t, err)
+	rewritten, err := ParseSPS(gobits.NewSliceByteAccessor(raw))
+	assert.NoError(t, err)
+	assert.Equal(t, sps, rewritten)
+	assert.Equal(t, "hev1.1.6.L120.B0", rewritten.CodecString("hev1"))
+
+	ptl := ProfileTierLevel{
+		General: Profile{
+			ProfileSpace:             1,
+			TierFlag:                 true,
+			ProfileIdc:               2,
+			CompatibilityFlags:       0x20000000,
+			ConstraintIndicatorFlags: 0x900000000100,
+		},
+		GeneralLevelIdc: 153,
+	}
+	assert.Equal(t, "hvc1.A2.4.H153.90.0.0.0.1", ptl.CodecString("hvc1"))
+	ptl.General.ConstraintIndicatorFlags = 0
+	assert.Equal(t, "hvc1.A2.4.H153", ptl.CodecString("hvc1"))
+}
@@ -0,0 +1,225 @@
+package hevc
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/ibbbpbbbp/gobits/nal"
+)
+
+// PPSRangeExtension is a pps_range_extension(). CbQpOffsetList and
+// CrQpOffsetList have chroma_qp_offset_list_len_minus1 + 1 entries.
+type PPSRangeExtension struct {
+	Log2MaxTransformSkipBlockSizeMinus2 uint32
+	CrossComponentPredictionEnabledFlag bool
+	ChromaQpOffsetListEnabledFlag       bool
+	DiffCuChromaQpOffsetDepth           uint32
+	CbQpOffsetList                      []int32
+	CrQpOffsetList                      []int32
+	Log2SaoOffsetScaleLuma              uint32
+	Log2SaoOffsetScaleChroma            uint32
+}
+
+func (e *PPSRangeExtension) syntax(s *gobits.Syntax, transformSkipEnabledFlag bool) {
+	if transformSkipEnabledFlag {
+		s.ExponentialGolomb(&e.Log2MaxTransformSkipBlockSizeMinus2, "log2_max_transform_skip_block_size_minus2")
+	}
+	s.Flag(&e.CrossComponentPredictionEnabledFlag, "cross_component_prediction_enabled_flag")
+	s.Flag(&e.ChromaQpOffsetListEnabledFlag, "chroma_qp_offset_list_enabled_flag")
+	if e.ChromaQpOffsetListEnabledFlag {
+		s.ExponentialGolomb(&e.DiffCuChromaQpOffsetDepth, "diff_cu_chroma_qp_offset_depth")
+		lenMinus1 := uint32(len(e.CbQpOffsetList)) - 1
+		if !s.Count(&lenMinus1, 5, "chroma_qp_offset_list_len_minus1") {
+			return
+		}
+		if s.Reading() {
+			e.CbQpOffsetList = make([]int32, lenMinus1+1)
+			e.CrQpOffsetList = make([]int32, lenMinus1+1)
+		}
+		if !s.ListLength(len(e.CrQpOffsetList), len(e.CbQpOffsetList), "cr_qp_offset_list") {
+			return
+		}
+		for i := range e.CbQpOffsetList {
+			s.SignedExponentialGolomb(&e.CbQpOffsetList[i], "cb_qp_offset_list")
+			s.SignedExponentialGolomb(&e.CrQpOffsetList[i], "cr_qp_offset_list")
+		}
+	}
+	s.ExponentialGolomb(&e.Log2SaoOffsetScaleLuma, "log2_sao_offset_scale_luma")
+	s.ExponentialGolomb(&e.Log2SaoOffsetScaleChroma, "log2_sao_offset_scale_chroma")
+}
+
+// PPS is a pic_parameter_set_rbsp().
+type PPS struct {
+	Header nal.Header
+
+	ID                                uint32
+	SPSID                             uint32
+	DependentSliceSegmentsEnabledFlag bool
+	OutputFlagPresentFlag             bool
+	NumExtraSliceHeaderBits           byte
+	SignDataHidingEnabledFlag         bool
+	CabacInitPresentFlag              bool
+	NumRefIdxL0DefaultActiveMinus1    uint32
+	NumRefIdxL1DefaultActiveMinus1    uint32
+	InitQpMinus26                     int32
+	ConstrainedIntraPredFlag          bool
+	TransformSkipEnabledFlag          bool
+	CuQpDeltaEnabledFlag              bool
+	DiffCuQpDeltaDepth                uint32
+	CbQpOffset                        int32
+	CrQpOffset                        int32
+	SliceChromaQpOffsetsPresentFlag   bool
+	WeightedPredFlag                  bool
+	WeightedBipredFlag                bool
+	TransquantBypassEnabledFlag       bool
+	TilesEnabledFlag                  bool
+	EntropyCodingSyncEnabledFlag      bool
+
+	NumTileColumnsMinus1 uint32
+	NumTileRowsMinus1    uint32
+	UniformSpacingFlag   bool
+	// ColumnWidthMinus1 and RowHeightMinus1 are only coded without uniform
+	// spacing and have NumTileColumnsMinus1 and NumTileRowsMinus1 entries.
+	ColumnWidthMinus1                []uint32
+	RowHeightMinus1                  []uint32
+	LoopFilterAcrossTilesEnabledFlag bool
+
+	LoopFilterAcrossSlicesEnabledFlag   bool
+	DeblockingFilterControlPresentFlag  bool
+	DeblockingFilterOverrideEnabledFlag bool
+	DeblockingFilterDisabledFlag        bool
+	BetaOffsetDiv2                      int32
+	TcOffsetDiv2                        int32
+
+	ScalingListDataPresentFlag             bool
+	ScalingListData                        ScalingListData
+	ListsModificationPresentFlag           bool
+	Log2ParallelMergeLevelMinus2           uint32
+	SliceSegmentHeaderExtensionPresentFlag bool
+
+	ExtensionPresentFlag    bool
+	RangeExtensionFlag      bool
+	MultilayerExtensionFlag bool
+	Extension3DFlag         bool
+	SCCExtensionFlag        bool
+	Extension4Bits          byte
+	RangeExtension          PPSRangeExtension
+	// ExtensionData holds the bits of the extensions after the range
+	// extension, which are not interpreted.
+	ExtensionData []bool
+}
+
+func (pps *PPS) tiles(s *gobits.Syntax) {
+	if !s.Count(&pps.NumTileColumnsMinus1, 19, "num_tile_columns_minus1") ||
+		!s.Count(&pps.NumTileRowsMinus1, 21, "num_tile_rows_minus1") {
+		return
+	}
+	s.Flag(&pps.UniformSpacingFlag, "uniform_spacing_flag")
+	if !pps.UniformSpacingFlag {
+		if s.Reading() {
+			pps.ColumnWidthMinus1 = make([]uint32, pps.NumTileColumnsMinus1)
+			pps.RowHeightMinus1 = make([]uint32, pps.NumTileRowsMinus1)
+		}
+		if !s.ListLength(len(pps.ColumnWidthMinus1), int(pps.NumTileColumnsMinus1), "column_width_minus1") ||
+			!s.ListLength(len(pps.RowHeightMinus1), int(pps.NumTileRowsMinus1), "row_height_minus1") {
+			return
+		}
+		for i := range pps.ColumnWidthMinus1 {
+			s.ExponentialGolomb(&pps.ColumnWidthMinus1[i], "column_width_minus1")
+		}
+		for i := range pps.RowHeightMinus1 {
+			s.ExponentialGolomb(&pps.RowHeightMinus1[i], "row_height_minus1")
+		}
+	}
+	s.Flag(&pps.LoopFilterAcrossTilesEnabledFlag, "loop_filter_across_tiles_enabled_flag")
+}
+
+func (pps *PPS) syntax(s *gobits.Syntax, more func() bool) {
+	if !s.Count(&pps.ID, 63, "pps_pic_parameter_set_id") ||
+		!s.Count(&pps.SPSID, 15, "pps_seq_parameter_set_id") {
+		return
+	}
+	s.Flag(&pps.DependentSliceSegmentsEnabledFlag, "dependent_slice_segments_enabled_flag")
+	s.Flag(&pps.OutputFlagPresentFlag, "output_flag_present_flag")
+	s.Byte(&pps.NumExtraSliceHeaderBits, 3, "num_extra_slice_header_bits")
+	s.Flag(&pps.SignDataHidingEnabledFlag, "sign_data_hiding_enabled_flag")
+	s.Flag(&pps.CabacInitPresentFlag, "cabac_init_present_flag")
+	if !s.Count(&pps.NumRefIdxL0DefaultActiveMinus1, 14, "num_ref_idx_l0_default_active_minus1") ||
+		!s.Count(&pps.NumRefIdxL1DefaultActiveMinus1, 14, "num_ref_idx_l1_default_active_minus1") {
+		return
+	}
+	s.SignedExponentialGolomb(&pps.InitQpMinus26, "init_qp_minus26")
+	s.Flag(&pps.ConstrainedIntraPredFlag, "constrained_intra_pred_flag")
+	s.Flag(&pps.TransformSkipEnabledFlag, "transform_skip_enabled_flag")
+	s.Flag(&pps.CuQpDeltaEnabledFlag, "cu_qp_delta_enabled_flag")
+	if pps.CuQpDeltaEnabledFlag {
+		s.ExponentialGolomb(&pps.DiffCuQpDeltaDepth, "diff_cu_qp_delta_depth")
+	}
+	s.SignedExponentialGolomb(&pps.CbQpOffset, "pps_cb_qp_offset")
+	s.SignedExponentialGolomb(&pps.CrQpOffset, "pps_cr_qp_offset")
+	s.Flag(&pps.SliceChromaQpOffsetsPresentFlag, "pps_slice_chroma_qp_offsets_present_flag")
+	s.Flag(&pps.WeightedPredFlag, "weighted_pred_flag")
+	s.Flag(&pps.WeightedBipredFlag, "weighted_bipred_flag")
+	s.Flag(&pps.TransquantBypassEnabledFlag, "transquant_bypass_enabled_flag")
+	s.Flag(&pps.TilesEnabledFlag, "tiles_enabled_flag")
+	s.Flag(&pps.EntropyCodingSyncEnabledFlag, "entropy_coding_sync_enabled_flag")
+	if pps.TilesEnabledFlag {
+		pps.tiles(s)
+	}
+	s.Flag(&pps.LoopFilterAcrossSlicesEnabledFlag, "pps_loop_filter_across_slices_enabled_flag")
+	s.Flag(&pps.DeblockingFilterControlPresentFlag, "deblocking_filter_control_present_flag")
+	if pps.DeblockingFilterControlPresentFlag {
+		s.Flag(&pps.DeblockingFilterOverrideEnabledFlag, "deblocking_filter_override_enabled_flag")
+		s.Flag(&pps.DeblockingFilterDisabledFlag, "pps_deblocking_filter_disabled_flag")
+		if !pps.DeblockingFilterDisabledFlag {
+			s.SignedExponentialGolomb(&pps.BetaOffsetDiv2, "pps_beta_offset_div2")
+			s.SignedExponentialGolomb(&pps.TcOffsetDiv2, "pps_tc_offset_div2")
+		}
+	}
+	s.Flag(&pps.ScalingListDataPresentFlag, "pps_scaling_list_data_present_flag")
+	if pps.ScalingListDataPresentFlag {
+		pps.ScalingListData.syntax(s)
+	}
+	s.Flag(&pps.ListsModificationPresentFlag, "lists_modification_present_flag")
+	s.ExponentialGolomb(&pps.Log2ParallelMergeLevelMinus2, "log2_parallel_merge_level_minus2")
+	s.Flag(&pps.SliceSegmentHeaderExtensionPresentFlag, "slice_segment_header_extension_present_flag")
+
+	s.Flag(&pps.ExtensionPresentFlag, "pps_extension_present_flag")
+	if pps.ExtensionPresentFlag {
+		s.Flag(&pps.RangeExtensionFlag, "pps_range_extension_flag")
+		s.Flag(&pps.MultilayerExtensionFlag, "pps_multilayer_extension_flag")
+		s.Flag(&pps.Extension3DFlag, "pps_3d_extension_flag")
+		s.Flag(&pps.SCCExtensionFlag, "pps_scc_extension_flag")
+		s.Byte(&pps.Extension4Bits, 4, "pps_extension_4bits")
+		if pps.RangeExtensionFlag {
+			pps.RangeExtension.syntax(s, pps.TransformSkipEnabledFlag)
+		}
+		if pps.MultilayerExtensionFlag || pps.Extension3DFlag || pps.SCCExtensionFlag || pps.Extension4Bits != 0 {
+			extensionData(s, &pps.ExtensionData, more, "pps_extension_data_flag")
+		}
+	}
+	s.RBSPTrailingBits()
+}
+
+// ParsePPS parses a PPS NAL unit, header included, with emulation
+// prevention bytes still in place.
+func ParsePPS(ba gobits.ByteAccessor) (*PPS, error) {
+	header, rbsp, s, err := nal.ReadRBSP(ba, nal.HEVC, nal.IsType(NALTypePPS))
+	if err != nil {
+		return nil, err
+	}
+	more := func() bool {
+		return rbsp.MoreRBSPData(s.BitStream().Tell())
+	}
+	pps := &PPS{Header: header}
+	pps.syntax(s, more)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	return pps, nil
+}
+
+// Marshal encodes the PPS as a NAL unit.
+func (pps *PPS) Marshal() ([]byte, error) {
+	return nal.WriteRBSP(pps.Header, nal.HEVC, func(s *gobits.Syntax) {
+		pps.syntax(s, nil)
+	})
+}
@@ -0,0 +1,119 @@
+package hevc
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+
+	"github.com/ibbbpbbbp/gobits"
+)
+
+// Profile holds the profile part of profile_tier_level() for the general
+// layer or a sub-layer.
+type Profile struct {
+	ProfileSpace byte
+	TierFlag     bool
+	ProfileIdc   byte
+	// CompatibilityFlags holds profile_compatibility_flag[j] in bit 31-j,
+	// as coded.
+	CompatibilityFlags uint32
+	// ConstraintIndicatorFlags holds the 48 bits from progressive_source_flag
+	// to the end of the profile, as coded.
+	ConstraintIndicatorFlags uint64
+}
+
+func (p *Profile) syntax(s *gobits.Syntax) {
+	s.Byte(&p.ProfileSpace, 2, "profile_space")
+	s.Flag(&p.TierFlag, "tier_flag")
+	s.Byte(&p.ProfileIdc, 5, "profile_idc")
+	s.Bits(&p.CompatibilityFlags, 32, "profile_compatibility_flag")
+	s.Bits64(&p.ConstraintIndicatorFlags, 48, "constraint_indicator_flags")
+}
+
+func (p *Profile) ProgressiveSourceFlag() bool {
+	return p.ConstraintIndicatorFlags>>47&1 != 0
+}
+
+func (p *Profile) InterlacedSourceFlag() bool {
+	return p.ConstraintIndicatorFlags>>46&1 != 0
+}
+
+type SubLayer struct {
+	ProfilePresentFlag bool
+	LevelPresentFlag   bool
+	Profile            Profile
+	LevelIdc           byte
+}
+
+// ProfileTierLevel is a profile_tier_level() with profilePresentFlag equal
+// to 1, as used by VPS and SPS. It has one SubLayer per sub-layer above the
+// lowest.
+type ProfileTierLevel struct {
+	General         Profile
+	GeneralLevelIdc byte
+	SubLayers       []SubLayer
+}
+
+func (ptl *ProfileTierLevel) syntax(s *gobits.Syntax, maxNumSubLayersMinus1 byte) {
+	ptl.General.syntax(s)
+	s.Byte(&ptl.GeneralLevelIdc, 8, "general_level_idc")
+
+	n := int(maxNumSubLayersMinus1)
+	if s.Reading() {
+		ptl.SubLayers = make([]SubLayer, n)
+	}
+	if !s.ListLength(len(ptl.SubLayers), n, "sub_layer_profile_present_flag") {
+		return
+	}
+	for i := range ptl.SubLayers {
+		s.Flag(&ptl.SubLayers[i].ProfilePresentFlag, "sub_layer_profile_present_flag")
+		s.Flag(&ptl.SubLayers[i].LevelPresentFlag, "sub_layer_level_present_flag")
+	}
+	if n > 0 {
+		for i := n; i < 8; i++ {
+			var reserved byte
+			s.Byte(&reserved, 2, "reserved_zero_2bits")
+		}
+	}
+	for i := range ptl.SubLayers {
+		l := &ptl.SubLayers[i]
+		if l.ProfilePresentFlag {
+			l.Profile.syntax(s)
+		}
+		if l.LevelPresentFlag {
+			s.Byte(&l.LevelIdc, 8, "sub_layer_level_idc")
+		}
+	}
+}
+
+// CodecString returns the RFC 6381 codecs parameter for the general profile,
+// tier and level, as defined in ISO/IEC 14496-15 annex E, for example
+// "hvc1.1.6.L93.B0". sampleEntry is the sample entry type, "hvc1" or "hev1".
+func (ptl *ProfileTierLevel) CodecString(sampleEntry string) string {
+	g := &ptl.General
+	var b strings.Builder
+	b.WriteString(sampleEntry)
+	b.WriteByte('.')
+	if g.ProfileSpace > 0 {
+		b.WriteByte('A' + g.ProfileSpace - 1)
+	}
+	fmt.Fprintf(&b, "%d.%X.", g.ProfileIdc, bits.Reverse32(g.CompatibilityFlags))
+	if g.TierFlag {
+		b.WriteByte('H')
+	} else {
+		b.WriteByte('L')
+	}
+	fmt.Fprintf(&b, "%d", ptl.GeneralLevelIdc)
+
+	constraints := make([]byte, 6)
+	for i := range constraints {
+		constraints[i] = byte(g.ConstraintIndicatorFlags >> (40 - 8*i))
+	}
+	for len(constraints) > 0 && constraints[len(constraints)-1] == 0 {
+		constraints = constraints[:len(constraints)-1]
+	}
+	for _, c := range constraints {
+		fmt.Fprintf(&b, ".%X", c)
+	}
+	return b.String()
+}
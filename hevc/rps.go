@@ -0,0 +1,199 @@
+package hevc
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+)
+
+// RefPic is one explicitly coded entry of a short-term reference picture
+// set.
+type RefPic struct {
+	DeltaPocMinus1    uint32
+	UsedByCurrPicFlag bool
+}
+
+// ShortTermRefPicSet is an st_ref_pic_set(). It is either coded explicitly
+// through NegativePics and PositivePics, or predicted from an earlier set
+// when InterRefPicSetPredictionFlag is set.
+type ShortTermRefPicSet struct {
+	InterRefPicSetPredictionFlag bool
+	// DeltaIdxMinus1 is only coded for the set in a slice header; it must be
+	// 0 in the SPS.
+	DeltaIdxMinus1    uint32
+	DeltaRpsSign      bool
+	AbsDeltaRpsMinus1 uint32
+	UsedByCurrPicFlag []bool
+	UseDeltaFlag      []bool
+
+	NegativePics []RefPic
+	PositivePics []RefPic
+
+	deltaPocS0 []int32
+	usedS0     []bool
+	deltaPocS1 []int32
+	usedS1     []bool
+}
+
+// DeltaPocS0 returns DeltaPocS0 and UsedByCurrPicS0, the pictures preceding
+// the current one in output order, closest first.
+func (rps *ShortTermRefPicSet) DeltaPocS0() ([]int32, []bool) {
+	return rps.deltaPocS0, rps.usedS0
+}
+
+// DeltaPocS1 returns DeltaPocS1 and UsedByCurrPicS1, the pictures following
+// the current one in output order, closest first.
+func (rps *ShortTermRefPicSet) DeltaPocS1() ([]int32, []bool) {
+	return rps.deltaPocS1, rps.usedS1
+}
+
+func (rps *ShortTermRefPicSet) NumDeltaPocs() int {
+	return len(rps.deltaPocS0) + len(rps.deltaPocS1)
+}
+
+// numUsed returns the number of pictures used by the current picture.
+func (rps *ShortTermRefPicSet) numUsed() int {
+	n := 0
+	for _, list := range [][]bool{rps.usedS0, rps.usedS1} {
+		for _, used := range list {
+			if used {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// syntax handles set idx of sets, the sets of the SPS. A set in a slice
+// header has idx equal to len(sets).
+func (rps *ShortTermRefPicSet) syntax(s *gobits.Syntax, idx int, sets []ShortTermRefPicSet) {
+	if idx != 0 {
+		s.Flag(&rps.InterRefPicSetPredictionFlag, "inter_ref_pic_set_prediction_flag")
+	} else if rps.InterRefPicSetPredictionFlag {
+		// The first set has no set to be predicted from.
+		s.Fail("inter_ref_pic_set_prediction_flag", gobits.ErrOutOfRange)
+		return
+	}
+	if rps.InterRefPicSetPredictionFlag {
+		if idx == len(sets) {
+			if !s.Count(&rps.DeltaIdxMinus1, uint32(idx-1), "delta_idx_minus1") {
+				return
+			}
+		} else if rps.DeltaIdxMinus1 != 0 {
+			// delta_idx_minus1 is inferred to be 0 in the SPS.
+			s.Fail("delta_idx_minus1", gobits.ErrOutOfRange)
+			return
+		}
+		s.Flag(&rps.DeltaRpsSign, "delta_rps_sign")
+		if !s.Count(&rps.AbsDeltaRpsMinus1, 1<<15-1, "abs_delta_rps_minus1") {
+			return
+		}
+		refIdx := idx - int(rps.DeltaIdxMinus1) - 1
+		if refIdx < 0 || refIdx >= len(sets) {
+			s.Fail("delta_idx_minus1", gobits.ErrOutOfRange)
+			return
+		}
+		ref := &sets[refIdx]
+		n := ref.NumDeltaPocs() + 1
+		if s.Reading() {
+			rps.UsedByCurrPicFlag = make([]bool, n)
+			rps.UseDeltaFlag = make([]bool, n)
+		}
+		if !s.ListLength(len(rps.UsedByCurrPicFlag), n, "used_by_curr_pic_flag") ||
+			!s.ListLength(len(rps.UseDeltaFlag), n, "use_delta_flag") {
+			return
+		}
+		for j := 0; j < n; j++ {
+			s.Flag(&rps.UsedByCurrPicFlag[j], "used_by_curr_pic_flag")
+			if !rps.UsedByCurrPicFlag[j] {
+				s.Flag(&rps.UseDeltaFlag[j], "use_delta_flag")
+			} else if s.Reading() {
+				rps.UseDeltaFlag[j] = true
+			}
+		}
+		if s.Err() == nil {
+			rps.predict(ref)
+		}
+		return
+	}
+
+	if !refPics(s, &rps.NegativePics, "num_negative_pics", "s0") ||
+		!refPics(s, &rps.PositivePics, "num_positive_pics", "s1") {
+		return
+	}
+	rps.deltaPocS0, rps.usedS0 = nil, nil
+	poc := int32(0)
+	for _, p := range rps.NegativePics {
+		poc -= int32(p.DeltaPocMinus1) + 1
+		rps.deltaPocS0 = append(rps.deltaPocS0, poc)
+		rps.usedS0 = append(rps.usedS0, p.UsedByCurrPicFlag)
+	}
+	rps.deltaPocS1, rps.usedS1 = nil, nil
+	poc = 0
+	for _, p := range rps.PositivePics {
+		poc += int32(p.DeltaPocMinus1) + 1
+		rps.deltaPocS1 = append(rps.deltaPocS1, poc)
+		rps.usedS1 = append(rps.usedS1, p.UsedByCurrPicFlag)
+	}
+}
+
+// refPics handles the num_negative_pics or num_positive_pics loop, whose
+// entries are coded in two passes.
+func refPics(s *gobits.Syntax, pics *[]RefPic, field, suffix string) bool {
+	n := uint32(len(*pics))
+	if !s.Count(&n, 16, field) {
+		return false
+	}
+	if s.Reading() {
+		*pics = make([]RefPic, n)
+	}
+	for i := range *pics {
+		s.ExponentialGolomb(&(*pics)[i].DeltaPocMinus1, "delta_poc_"+suffix+"_minus1")
+		s.Flag(&(*pics)[i].UsedByCurrPicFlag, "used_by_curr_pic_"+suffix+"_flag")
+	}
+	return s.Err() == nil
+}
+
+// predict derives the set from ref as in equations 7-61 and 7-62.
+func (rps *ShortTermRefPicSet) predict(ref *ShortTermRefPicSet) {
+	deltaRps := int32(rps.AbsDeltaRpsMinus1) + 1
+	if rps.DeltaRpsSign {
+		deltaRps = -deltaRps
+	}
+	numNegative := len(ref.deltaPocS0)
+	numDeltaPocs := ref.NumDeltaPocs()
+
+	rps.deltaPocS0, rps.usedS0 = nil, nil
+	for j := len(ref.deltaPocS1) - 1; j >= 0; j-- {
+		if dPoc := ref.deltaPocS1[j] + deltaRps; dPoc < 0 && rps.UseDeltaFlag[numNegative+j] {
+			rps.deltaPocS0 = append(rps.deltaPocS0, dPoc)
+			rps.usedS0 = append(rps.usedS0, rps.UsedByCurrPicFlag[numNegative+j])
+		}
+	}
+	if deltaRps < 0 && rps.UseDeltaFlag[numDeltaPocs] {
+		rps.deltaPocS0 = append(rps.deltaPocS0, deltaRps)
+		rps.usedS0 = append(rps.usedS0, rps.UsedByCurrPicFlag[numDeltaPocs])
+	}
+	for j := 0; j < numNegative; j++ {
+		if dPoc := ref.deltaPocS0[j] + deltaRps; dPoc < 0 && rps.UseDeltaFlag[j] {
+			rps.deltaPocS0 = append(rps.deltaPocS0, dPoc)
+			rps.usedS0 = append(rps.usedS0, rps.UsedByCurrPicFlag[j])
+		}
+	}
+
+	rps.deltaPocS1, rps.usedS1 = nil, nil
+	for j := numNegative - 1; j >= 0; j-- {
+		if dPoc := ref.deltaPocS0[j] + deltaRps; dPoc > 0 && rps.UseDeltaFlag[j] {
+			rps.deltaPocS1 = append(rps.deltaPocS1, dPoc)
+			rps.usedS1 = append(rps.usedS1, rps.UsedByCurrPicFlag[j])
+		}
+	}
+	if deltaRps > 0 && rps.UseDeltaFlag[numDeltaPocs] {
+		rps.deltaPocS1 = append(rps.deltaPocS1, deltaRps)
+		rps.usedS1 = append(rps.usedS1, rps.UsedByCurrPicFlag[numDeltaPocs])
+	}
+	for j := 0; j < len(ref.deltaPocS1); j++ {
+		if dPoc := ref.deltaPocS1[j] + deltaRps; dPoc > 0 && rps.UseDeltaFlag[numNegative+j] {
+			rps.deltaPocS1 = append(rps.deltaPocS1, dPoc)
+			rps.usedS1 = append(rps.usedS1, rps.UsedByCurrPicFlag[numNegative+j])
+		}
+	}
+}
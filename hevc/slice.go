@@ -0,0 +1,457 @@
+package hevc
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/ibbbpbbbp/gobits/nal"
+)
+
+// LongTermPic is one entry of the long-term reference pictures of a slice
+// header. The first SliceSegmentHeader.NumLongTermSPS entries select a
+// candidate of the SPS through LtIdxSPS; the others are coded explicitly.
+type LongTermPic struct {
+	LtIdxSPS               uint32
+	PocLsbLt               uint32
+	UsedByCurrPicLtFlag    bool
+	DeltaPocMsbPresentFlag bool
+	DeltaPocMsbCycleLt     uint32
+}
+
+// PredWeight holds the weights of one reference index in
+// pred_weight_table().
+type PredWeight struct {
+	LumaWeightFlag    bool
+	ChromaWeightFlag  bool
+	DeltaLumaWeight   int32
+	LumaOffset        int32
+	DeltaChromaWeight [2]int32
+	DeltaChromaOffset [2]int32
+}
+
+// PredWeightTable is a pred_weight_table(). Every reference picture is
+// assumed to differ from the current picture, which holds unless the
+// current picture can be its own reference (screen content coding).
+type PredWeightTable struct {
+	LumaLog2WeightDenom        uint32
+	DeltaChromaLog2WeightDenom int32
+	// L0 and L1 have num_ref_idx_lX_active_minus1 + 1 entries each; L1 is
+	// only present in B slices.
+	L0 []PredWeight
+	L1 []PredWeight
+}
+
+func predWeights(s *gobits.Syntax, list *[]PredWeight, n int, chroma bool, suffix string) {
+	if s.Reading() {
+		*list = make([]PredWeight, n)
+	}
+	if !s.ListLength(len(*list), n, "luma_weight_"+suffix+"_flag") {
+		return
+	}
+	for i := range *list {
+		s.Flag(&(*list)[i].LumaWeightFlag, "luma_weight_"+suffix+"_flag")
+	}
+	if chroma {
+		for i := range *list {
+			s.Flag(&(*list)[i].ChromaWeightFlag, "chroma_weight_"+suffix+"_flag")
+		}
+	}
+	for i := range *list {
+		w := &(*list)[i]
+		if w.LumaWeightFlag {
+			s.SignedExponentialGolomb(&w.DeltaLumaWeight, "delta_luma_weight_"+suffix)
+			s.SignedExponentialGolomb(&w.LumaOffset, "luma_offset_"+suffix)
+		}
+		if w.ChromaWeightFlag {
+			for j := 0; j < 2; j++ {
+				s.SignedExponentialGolomb(&w.DeltaChromaWeight[j], "delta_chroma_weight_"+suffix)
+				s.SignedExponentialGolomb(&w.DeltaChromaOffset[j], "delta_chroma_offset_"+suffix)
+			}
+		}
+	}
+}
+
+// SliceSegmentHeader is a slice_segment_header(). Fields that are not coded
+// but inferred, such as PicOutputFlag or the deblocking parameters, are set
+// to their inferred values when parsing.
+type SliceSegmentHeader struct {
+	Header nal.Header
+
+	FirstSliceSegmentInPicFlag bool
+	NoOutputOfPriorPicsFlag    bool
+	PPSID                      uint32
+	DependentSliceSegmentFlag  bool
+	SliceSegmentAddress        uint32
+
+	SliceReservedFlags []bool
+	SliceType          uint32
+	PicOutputFlag      bool
+	ColourPlaneID      byte
+
+	PicOrderCntLsb            uint32
+	ShortTermRefPicSetSPSFlag bool
+	ShortTermRefPicSet        ShortTermRefPicSet
+	ShortTermRefPicSetIdx     uint32
+	NumLongTermSPS            uint32
+	LongTermPics              []LongTermPic
+	TemporalMvpEnabledFlag    bool
+
+	SaoLumaFlag   bool
+	SaoChromaFlag bool
+
+	NumRefIdxActiveOverrideFlag  bool
+	NumRefIdxL0ActiveMinus1      uint32
+	NumRefIdxL1ActiveMinus1      uint32
+	RefPicListModificationFlagL0 bool
+	ListEntryL0                  []uint32
+	RefPicListModificationFlagL1 bool
+	ListEntryL1                  []uint32
+	MvdL1ZeroFlag                bool
+	CabacInitFlag                bool
+	CollocatedFromL0Flag         bool
+	CollocatedRefIdx             uint32
+	PredWeightTable              PredWeightTable
+	FiveMinusMaxNumMergeCand     uint32
+
+	SliceQpDelta                      int32
+	SliceCbQpOffset                   int32
+	SliceCrQpOffset                   int32
+	CuChromaQpOffsetEnabledFlag       bool
+	DeblockingFilterOverrideFlag      bool
+	SliceDeblockingFilterDisabledFlag bool
+	SliceBetaOffsetDiv2               int32
+	SliceTcOffsetDiv2                 int32
+	LoopFilterAcrossSlicesEnabledFlag bool
+
+	OffsetLenMinus1        uint32
+	EntryPointOffsetMinus1 []uint32
+	ExtensionData          []byte
+
+	// DataOffset is the RBSP byte offset of the slice data that follows the
+	// parsed header.
+	DataOffset int64
+}
+
+func (h *SliceSegmentHeader) IsIRAP() bool {
+	return NALTypeBLAWLP <= h.Header.Type && h.Header.Type <= NALTypeRsvIRAP
+}
+
+func (h *SliceSegmentHeader) IsIDR() bool {
+	return h.Header.Type == NALTypeIDRWRADL || h.Header.Type == NALTypeIDRNLP
+}
+
+// currentRPS returns the short-term reference picture set the slice uses.
+func (h *SliceSegmentHeader) currentRPS(sps *SPS) *ShortTermRefPicSet {
+	if !h.ShortTermRefPicSetSPSFlag {
+		return &h.ShortTermRefPicSet
+	}
+	return &sps.ShortTermRefPicSets[h.ShortTermRefPicSetIdx]
+}
+
+// NumPicTotalCurr returns the number of reference pictures usable by the
+// current picture, as in equation 7-55.
+func (h *SliceSegmentHeader) NumPicTotalCurr(sps *SPS) int {
+	if h.IsIDR() {
+		return 0
+	}
+	n := h.currentRPS(sps).numUsed()
+	for i, lt := range h.LongTermPics {
+		if i < int(h.NumLongTermSPS) {
+			if int(lt.LtIdxSPS) < len(sps.LongTermRefPics) && sps.LongTermRefPics[lt.LtIdxSPS].UsedByCurrPicFlag {
+				n++
+			}
+		} else if lt.UsedByCurrPicLtFlag {
+			n++
+		}
+	}
+	return n
+}
+
+func (h *SliceSegmentHeader) longTermPics(s *gobits.Syntax, sps *SPS) {
+	if len(sps.LongTermRefPics) > 0 {
+		if !s.Count(&h.NumLongTermSPS, uint32(len(sps.LongTermRefPics)), "num_long_term_sps") {
+			return
+		}
+	} else if s.Reading() {
+		h.NumLongTermSPS = 0
+	}
+	numLongTermPics := uint32(len(h.LongTermPics)) - h.NumLongTermSPS
+	if !s.Count(&numLongTermPics, 32, "num_long_term_pics") {
+		return
+	}
+	if s.Reading() {
+		h.LongTermPics = make([]LongTermPic, h.NumLongTermSPS+numLongTermPics)
+	}
+	for i := range h.LongTermPics {
+		lt := &h.LongTermPics[i]
+		if i < int(h.NumLongTermSPS) {
+			if len(sps.LongTermRefPics) > 1 {
+				s.Bits(&lt.LtIdxSPS, gobits.CeilLog2(uint32(len(sps.LongTermRefPics))), "lt_idx_sps")
+			}
+		} else {
+			s.Bits(&lt.PocLsbLt, sps.log2MaxPicOrderCntLsb(), "poc_lsb_lt")
+			s.Flag(&lt.UsedByCurrPicLtFlag, "used_by_curr_pic_lt_flag")
+		}
+		s.Flag(&lt.DeltaPocMsbPresentFlag, "delta_poc_msb_present_flag")
+		if lt.DeltaPocMsbPresentFlag {
+			s.ExponentialGolomb(&lt.DeltaPocMsbCycleLt, "delta_poc_msb_cycle_lt")
+		}
+	}
+}
+
+func listEntries(s *gobits.Syntax, flag *bool, entries *[]uint32, n int, bits byte, suffix string) {
+	s.Flag(flag, "ref_pic_list_modification_flag_"+suffix)
+	if !*flag {
+		return
+	}
+	if s.Reading() {
+		*entries = make([]uint32, n)
+	}
+	if !s.ListLength(len(*entries), n, "list_entry_"+suffix) {
+		return
+	}
+	for i := range *entries {
+		s.Bits(&(*entries)[i], bits, "list_entry_"+suffix)
+	}
+}
+
+func (h *SliceSegmentHeader) refs(s *gobits.Syntax, sps *SPS, pps *PPS) {
+	b := h.SliceType == SliceTypeB
+	s.Flag(&h.NumRefIdxActiveOverrideFlag, "num_ref_idx_active_override_flag")
+	if h.NumRefIdxActiveOverrideFlag {
+		if !s.Count(&h.NumRefIdxL0ActiveMinus1, 14, "num_ref_idx_l0_active_minus1") {
+			return
+		}
+		if b && !s.Count(&h.NumRefIdxL1ActiveMinus1, 14, "num_ref_idx_l1_active_minus1") {
+			return
+		}
+	}
+	l0, l1 := h.NumRefIdxL0ActiveMinus1, h.NumRefIdxL1ActiveMinus1
+	if !h.NumRefIdxActiveOverrideFlag {
+		l0, l1 = pps.NumRefIdxL0DefaultActiveMinus1, pps.NumRefIdxL1DefaultActiveMinus1
+	}
+	if s.Reading() {
+		h.NumRefIdxL0ActiveMinus1, h.NumRefIdxL1ActiveMinus1 = l0, l1
+	}
+
+	if numPicTotalCurr := h.NumPicTotalCurr(sps); pps.ListsModificationPresentFlag && numPicTotalCurr > 1 {
+		bits := gobits.CeilLog2(uint32(numPicTotalCurr))
+		listEntries(s, &h.RefPicListModificationFlagL0, &h.ListEntryL0, int(l0)+1, bits, "l0")
+		if b {
+			listEntries(s, &h.RefPicListModificationFlagL1, &h.ListEntryL1, int(l1)+1, bits, "l1")
+		}
+	}
+	if b {
+		s.Flag(&h.MvdL1ZeroFlag, "mvd_l1_zero_flag")
+	}
+	if pps.CabacInitPresentFlag {
+		s.Flag(&h.CabacInitFlag, "cabac_init_flag")
+	}
+	if h.TemporalMvpEnabledFlag {
+		if b {
+			s.Flag(&h.CollocatedFromL0Flag, "collocated_from_l0_flag")
+		} else if s.Reading() {
+			h.CollocatedFromL0Flag = true
+		}
+		if (h.CollocatedFromL0Flag && l0 > 0) || (!h.CollocatedFromL0Flag && l1 > 0) {
+			s.ExponentialGolomb(&h.CollocatedRefIdx, "collocated_ref_idx")
+		}
+	}
+	if (pps.WeightedPredFlag && h.SliceType == SliceTypeP) || (pps.WeightedBipredFlag && b) {
+		t := &h.PredWeightTable
+		chroma := sps.ChromaArrayType() != 0
+		s.ExponentialGolomb(&t.LumaLog2WeightDenom, "luma_log2_weight_denom")
+		if chroma {
+			s.SignedExponentialGolomb(&t.DeltaChromaLog2WeightDenom, "delta_chroma_log2_weight_denom")
+		}
+		predWeights(s, &t.L0, int(l0)+1, chroma, "l0")
+		if b {
+			predWeights(s, &t.L1, int(l1)+1, chroma, "l1")
+		}
+	}
+	s.ExponentialGolomb(&h.FiveMinusMaxNumMergeCand, "five_minus_max_num_merge_cand")
+}
+
+func (h *SliceSegmentHeader) independent(s *gobits.Syntax, sps *SPS, pps *PPS) {
+	n := int(pps.NumExtraSliceHeaderBits)
+	if s.Reading() {
+		h.SliceReservedFlags = make([]bool, n)
+	}
+	if !s.ListLength(len(h.SliceReservedFlags), n, "slice_reserved_flag") {
+		return
+	}
+	for i := range h.SliceReservedFlags {
+		s.Flag(&h.SliceReservedFlags[i], "slice_reserved_flag")
+	}
+	if !s.Count(&h.SliceType, SliceTypeI, "slice_type") {
+		return
+	}
+	if pps.OutputFlagPresentFlag {
+		s.Flag(&h.PicOutputFlag, "pic_output_flag")
+	} else if s.Reading() {
+		h.PicOutputFlag = true
+	}
+	if sps.SeparateColourPlaneFlag {
+		s.Byte(&h.ColourPlaneID, 2, "colour_plane_id")
+	}
+
+	if !h.IsIDR() {
+		s.Bits(&h.PicOrderCntLsb, sps.log2MaxPicOrderCntLsb(), "slice_pic_order_cnt_lsb")
+		s.Flag(&h.ShortTermRefPicSetSPSFlag, "short_term_ref_pic_set_sps_flag")
+		numSets := len(sps.ShortTermRefPicSets)
+		if !h.ShortTermRefPicSetSPSFlag {
+			h.ShortTermRefPicSet.syntax(s, numSets, sps.ShortTermRefPicSets)
+		} else if numSets > 1 {
+			s.Bits(&h.ShortTermRefPicSetIdx, gobits.CeilLog2(uint32(numSets)), "short_term_ref_pic_set_idx")
+		} else if s.Reading() {
+			h.ShortTermRefPicSetIdx = 0
+		}
+		if h.ShortTermRefPicSetSPSFlag && int(h.ShortTermRefPicSetIdx) >= numSets {
+			s.Fail("short_term_ref_pic_set_idx", gobits.ErrOutOfRange)
+			return
+		}
+		if sps.LongTermRefPicsPresentFlag {
+			h.longTermPics(s, sps)
+		}
+		if sps.TemporalMvpEnabledFlag {
+			s.Flag(&h.TemporalMvpEnabledFlag, "slice_temporal_mvp_enabled_flag")
+		}
+	}
+	if sps.SampleAdaptiveOffsetEnabledFlag {
+		s.Flag(&h.SaoLumaFlag, "slice_sao_luma_flag")
+		if sps.ChromaArrayType() != 0 {
+			s.Flag(&h.SaoChromaFlag, "slice_sao_chroma_flag")
+		}
+	}
+	if s.Err() != nil {
+		return
+	}
+	if h.SliceType == SliceTypeP || h.SliceType == SliceTypeB {
+		h.refs(s, sps, pps)
+	}
+
+	s.SignedExponentialGolomb(&h.SliceQpDelta, "slice_qp_delta")
+	if pps.SliceChromaQpOffsetsPresentFlag {
+		s.SignedExponentialGolomb(&h.SliceCbQpOffset, "slice_cb_qp_offset")
+		s.SignedExponentialGolomb(&h.SliceCrQpOffset, "slice_cr_qp_offset")
+	}
+	if pps.RangeExtension.ChromaQpOffsetListEnabledFlag {
+		s.Flag(&h.CuChromaQpOffsetEnabledFlag, "cu_chroma_qp_offset_enabled_flag")
+	}
+	if pps.DeblockingFilterOverrideEnabledFlag {
+		s.Flag(&h.DeblockingFilterOverrideFlag, "deblocking_filter_override_flag")
+	}
+	if h.DeblockingFilterOverrideFlag {
+		s.Flag(&h.SliceDeblockingFilterDisabledFlag, "slice_deblocking_filter_disabled_flag")
+		if !h.SliceDeblockingFilterDisabledFlag {
+			s.SignedExponentialGolomb(&h.SliceBetaOffsetDiv2, "slice_beta_offset_div2")
+			s.SignedExponentialGolomb(&h.SliceTcOffsetDiv2, "slice_tc_offset_div2")
+		}
+	} else if s.Reading() {
+		h.SliceDeblockingFilterDisabledFlag = pps.DeblockingFilterDisabledFlag
+		h.SliceBetaOffsetDiv2 = pps.BetaOffsetDiv2
+		h.SliceTcOffsetDiv2 = pps.TcOffsetDiv2
+	}
+	disabled := h.SliceDeblockingFilterDisabledFlag
+	if !h.DeblockingFilterOverrideFlag {
+		disabled = pps.DeblockingFilterDisabledFlag
+	}
+	if pps.LoopFilterAcrossSlicesEnabledFlag && (h.SaoLumaFlag || h.SaoChromaFlag || !disabled) {
+		s.Flag(&h.LoopFilterAcrossSlicesEnabledFlag, "slice_loop_filter_across_slices_enabled_flag")
+	} else if s.Reading() {
+		h.LoopFilterAcrossSlicesEnabledFlag = pps.LoopFilterAcrossSlicesEnabledFlag
+	}
+}
+
+func (h *SliceSegmentHeader) syntax(s *gobits.Syntax, ps *ParameterSets) {
+	s.Flag(&h.FirstSliceSegmentInPicFlag, "first_slice_segment_in_pic_flag")
+	if h.IsIRAP() {
+		s.Flag(&h.NoOutputOfPriorPicsFlag, "no_output_of_prior_pics_flag")
+	}
+	if !s.Count(&h.PPSID, 63, "slice_pic_parameter_set_id") {
+		return
+	}
+	pps, ok := ps.PPS[h.PPSID]
+	if !ok {
+		s.Fail("slice_pic_parameter_set_id", gobits.ErrInvalidSyntax)
+		return
+	}
+	sps, ok := ps.SPS[pps.SPSID]
+	if !ok {
+		s.Fail("pps_seq_parameter_set_id", gobits.ErrInvalidSyntax)
+		return
+	}
+
+	if !h.FirstSliceSegmentInPicFlag {
+		if pps.DependentSliceSegmentsEnabledFlag {
+			s.Flag(&h.DependentSliceSegmentFlag, "dependent_slice_segment_flag")
+		}
+		s.Bits(&h.SliceSegmentAddress, gobits.CeilLog2(sps.PicSizeInCtbsY()), "slice_segment_address")
+	} else if s.Reading() {
+		h.DependentSliceSegmentFlag = false
+	}
+	if !h.DependentSliceSegmentFlag {
+		h.independent(s, sps, pps)
+	}
+
+	if pps.TilesEnabledFlag || pps.EntropyCodingSyncEnabledFlag {
+		n := uint32(len(h.EntryPointOffsetMinus1))
+		if !s.Count(&n, sps.PicSizeInCtbsY(), "num_entry_point_offsets") {
+			return
+		}
+		if n > 0 {
+			if !s.Count(&h.OffsetLenMinus1, 31, "offset_len_minus1") {
+				return
+			}
+			if s.Reading() {
+				h.EntryPointOffsetMinus1 = make([]uint32, n)
+			}
+			for i := range h.EntryPointOffsetMinus1 {
+				s.Bits(&h.EntryPointOffsetMinus1[i], byte(h.OffsetLenMinus1+1), "entry_point_offset_minus1")
+			}
+		}
+	}
+	if pps.SliceSegmentHeaderExtensionPresentFlag {
+		n := uint32(len(h.ExtensionData))
+		if !s.Count(&n, 256, "slice_segment_header_extension_length") {
+			return
+		}
+		if s.Reading() {
+			h.ExtensionData = make([]byte, n)
+		}
+		for i := range h.ExtensionData {
+			s.Byte(&h.ExtensionData[i], 8, "slice_segment_header_extension_data_byte")
+		}
+	}
+	s.Align(8, gobits.PadRBSPTrailingBits, "byte_alignment")
+	if s.Reading() {
+		h.DataOffset = s.BitStream().Tell() / 8
+	}
+}
+
+func isSlice(t byte) bool {
+	return t <= NALTypeRsvIRAP && (t < 10 || 15 < t)
+}
+
+// ParseSliceSegmentHeader parses the header of a coded slice segment NAL
+// unit, NAL unit header included. The PPS and SPS it refers to must be in
+// ps.
+func ParseSliceSegmentHeader(ba gobits.ByteAccessor, ps *ParameterSets) (*SliceSegmentHeader, error) {
+	header, _, s, err := nal.ReadRBSP(ba, nal.HEVC, isSlice)
+	if err != nil {
+		return nil, err
+	}
+	h := &SliceSegmentHeader{Header: header}
+	h.syntax(s, ps)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	return h, nil
+}
+
+// Marshal encodes the slice segment header, up to and including
+// byte_alignment(), as the start of a NAL unit. Slice data can be appended
+// to the result as is.
+func (h *SliceSegmentHeader) Marshal(ps *ParameterSets) ([]byte, error) {
+	return nal.WriteRBSP(h.Header, nal.HEVC, func(s *gobits.Syntax) {
+		h.syntax(s, ps)
+	})
+}
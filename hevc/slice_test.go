@@ -0,0 +1,76 @@
+package hevc
+
+import (
+	"testing"
+
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/ibbbpbbbp/gobits/nal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSliceSegmentHeader(t *testing.T) {
+	ps := mainParameterSets(t)
+	h := &SliceSegmentHeader{
+		Header:                     nal.Header{Type: 1, TemporalIDPlus1: 1},
+		FirstSliceSegmentInPicFlag: true,
+		SliceReservedFlags:         []bool{},
+		SliceType:                  SliceTypeP,
+		PicOutputFlag:              true,
+		PicOrderCntLsb:             5,
+		ShortTermRefPicSet: ShortTermRefPicSet{
+			NegativePics: []RefPic{{0, true}, {1, true}},
+			PositivePics: []RefPic{},
+		},
+		TemporalMvpEnabledFlag:      true,
+		SaoLumaFlag:                 true,
+		NumRefIdxActiveOverrideFlag: true,
+		NumRefIdxL0ActiveMinus1:     1,
+		CollocatedFromL0Flag:        true,
+		CollocatedRefIdx:            1,
+		PredWeightTable: PredWeightTable{
+			LumaLog2WeightDenom:        6,
+			DeltaChromaLog2WeightDenom: -1,
+			L0: []PredWeight{
+				{LumaWeightFlag: true, DeltaLumaWeight: -4, LumaOffset: 2},
+				{ChromaWeightFlag: true, DeltaChromaWeight: [2]int32{1, -1}, DeltaChromaOffset: [2]int32{3, 0}},
+			},
+		},
+		FiveMinusMaxNumMergeCand:          2,
+		SliceQpDelta:                      -3,
+		LoopFilterAcrossSlicesEnabledFlag: true,
+		OffsetLenMinus1:                   9,
+		EntryPointOffsetMinus1:            []uint32{100, 1023},
+	}
+	raw, err := h.Marshal(ps)
+	assert.NoError(t, err)
+
+	parsed, err := ParseSliceSegmentHeader(gobits.NewSliceByteAccessor(append(raw, 0xaf, 0x80)), ps)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(raw)), parsed.DataOffset)
+	h.DataOffset = parsed.DataOffset
+	assert.Equal(t, h, parsed)
+	assert.Equal(t, 2, parsed.NumPicTotalCurr(ps.SPS[0]))
+
+	idr := &SliceSegmentHeader{
+		Header:                     nal.Header{Type: NALTypeIDRWRADL, TemporalIDPlus1: 1},
+		FirstSliceSegmentInPicFlag: true,
+		NoOutputOfPriorPicsFlag:    true,
+		SliceReservedFlags:         []bool{},
+		SliceType:                  SliceTypeI,
+		PicOutputFlag:              true,
+		SaoLumaFlag:                true,
+		SaoChromaFlag:              true,
+		SliceQpDelta:               4,
+	}
+	raw, err = idr.Marshal(ps)
+	assert.NoError(t, err)
+	parsed, err = ParseSliceSegmentHeader(gobits.NewSliceByteAccessor(raw), ps)
+	assert.NoError(t, err)
+	idr.DataOffset = parsed.DataOffset
+	assert.Equal(t, idr, parsed)
+
+	_, err = ParseSliceSegmentHeader(gobits.NewSliceByteAccessor(vpsMain), ps)
+	assert.Error(t, err)
+	_, err = ParseSliceSegmentHeader(gobits.NewSliceByteAccessor(raw), NewParameterSets())
+	assert.Error(t, err)
+}
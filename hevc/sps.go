@@ -0,0 +1,334 @@
+package hevc
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/ibbbpbbbp/gobits/nal"
+)
+
+// ScalingList is one list of scaling_list_data(). It is either predicted
+// from a reference list, when PredModeFlag is clear, or coded as deltas.
+type ScalingList struct {
+	PredModeFlag      bool
+	PredMatrixIDDelta uint32
+	// DCCoefMinus8 is only coded for 16x16 and 32x32 lists.
+	DCCoefMinus8 int32
+	DeltaCoefs   []int32
+}
+
+// ScalingListData is a scaling_list_data(), indexed by sizeId and
+// matrixId. For sizeId 3 only matrixId 0 and 3 are coded.
+type ScalingListData struct {
+	Lists [4][6]ScalingList
+}
+
+func (d *ScalingListData) syntax(s *gobits.Syntax) {
+	for sizeID := 0; sizeID < 4; sizeID++ {
+		step := 1
+		if sizeID == 3 {
+			step = 3
+		}
+		for matrixID := 0; matrixID < 6 && s.Err() == nil; matrixID += step {
+			l := &d.Lists[sizeID][matrixID]
+			s.Flag(&l.PredModeFlag, "scaling_list_pred_mode_flag")
+			if !l.PredModeFlag {
+				if !s.Count(&l.PredMatrixIDDelta, uint32(matrixID/step), "scaling_list_pred_matrix_id_delta") {
+					return
+				}
+				continue
+			}
+			coefNum := 64
+			if sizeID == 0 {
+				coefNum = 16
+			}
+			if sizeID > 1 {
+				s.SignedExponentialGolomb(&l.DCCoefMinus8, "scaling_list_dc_coef_minus8")
+			}
+			if s.Reading() {
+				l.DeltaCoefs = make([]int32, coefNum)
+			}
+			if !s.ListLength(len(l.DeltaCoefs), coefNum, "scaling_list_delta_coef") {
+				return
+			}
+			for i := range l.DeltaCoefs {
+				s.SignedExponentialGolomb(&l.DeltaCoefs[i], "scaling_list_delta_coef")
+			}
+		}
+	}
+}
+
+// LongTermRefPic is a long-term reference picture candidate of the SPS.
+type LongTermRefPic struct {
+	PocLsb            uint32
+	UsedByCurrPicFlag bool
+}
+
+// SPSRangeExtension is an sps_range_extension().
+type SPSRangeExtension struct {
+	TransformSkipRotationEnabledFlag    bool
+	TransformSkipContextEnabledFlag     bool
+	ImplicitRdpcmEnabledFlag            bool
+	ExplicitRdpcmEnabledFlag            bool
+	ExtendedPrecisionProcessingFlag     bool
+	IntraSmoothingDisabledFlag          bool
+	HighPrecisionOffsetsEnabledFlag     bool
+	PersistentRiceAdaptationEnabledFlag bool
+	CabacBypassAlignmentEnabledFlag     bool
+}
+
+func (e *SPSRangeExtension) syntax(s *gobits.Syntax) {
+	s.Flag(&e.TransformSkipRotationEnabledFlag, "transform_skip_rotation_enabled_flag")
+	s.Flag(&e.TransformSkipContextEnabledFlag, "transform_skip_context_enabled_flag")
+	s.Flag(&e.ImplicitRdpcmEnabledFlag, "implicit_rdpcm_enabled_flag")
+	s.Flag(&e.ExplicitRdpcmEnabledFlag, "explicit_rdpcm_enabled_flag")
+	s.Flag(&e.ExtendedPrecisionProcessingFlag, "extended_precision_processing_flag")
+	s.Flag(&e.IntraSmoothingDisabledFlag, "intra_smoothing_disabled_flag")
+	s.Flag(&e.HighPrecisionOffsetsEnabledFlag, "high_precision_offsets_enabled_flag")
+	s.Flag(&e.PersistentRiceAdaptationEnabledFlag, "persistent_rice_adaptation_enabled_flag")
+	s.Flag(&e.CabacBypassAlignmentEnabledFlag, "cabac_bypass_alignment_enabled_flag")
+}
+
+// SPS is a seq_parameter_set_rbsp().
+type SPS struct {
+	Header nal.Header
+
+	VPSID                 uint32
+	MaxSubLayersMinus1    byte
+	TemporalIDNestingFlag bool
+	ProfileTierLevel      ProfileTierLevel
+	ID                    uint32
+
+	ChromaFormatIdc         uint32
+	SeparateColourPlaneFlag bool
+	PicWidthInLumaSamples   uint32
+	PicHeightInLumaSamples  uint32
+	ConformanceWindowFlag   bool
+	ConformanceWindow       Window
+	BitDepthLumaMinus8      uint32
+	BitDepthChromaMinus8    uint32
+
+	Log2MaxPicOrderCntLsbMinus4     uint32
+	SubLayerOrderingInfoPresentFlag bool
+	SubLayerOrderingInfo            []SubLayerOrderingInfo
+
+	Log2MinLumaCodingBlockSizeMinus3     uint32
+	Log2DiffMaxMinLumaCodingBlockSize    uint32
+	Log2MinLumaTransformBlockSizeMinus2  uint32
+	Log2DiffMaxMinLumaTransformBlockSize uint32
+	MaxTransformHierarchyDepthInter      uint32
+	MaxTransformHierarchyDepthIntra      uint32
+
+	ScalingListEnabledFlag     bool
+	ScalingListDataPresentFlag bool
+	ScalingListData            ScalingListData
+
+	AmpEnabledFlag                       bool
+	SampleAdaptiveOffsetEnabledFlag      bool
+	PCMEnabledFlag                       bool
+	PCMSampleBitDepthLumaMinus1          byte
+	PCMSampleBitDepthChromaMinus1        byte
+	Log2MinPCMLumaCodingBlockSizeMinus3  uint32
+	Log2DiffMaxMinPCMLumaCodingBlockSize uint32
+	PCMLoopFilterDisabledFlag            bool
+
+	ShortTermRefPicSets             []ShortTermRefPicSet
+	LongTermRefPicsPresentFlag      bool
+	LongTermRefPics                 []LongTermRefPic
+	TemporalMvpEnabledFlag          bool
+	StrongIntraSmoothingEnabledFlag bool
+
+	VUIParametersPresentFlag bool
+	VUI                      VUI
+
+	ExtensionPresentFlag    bool
+	RangeExtensionFlag      bool
+	MultilayerExtensionFlag bool
+	Extension3DFlag         bool
+	SCCExtensionFlag        bool
+	Extension4Bits          byte
+	RangeExtension          SPSRangeExtension
+	// ExtensionData holds the bits of the extensions after the range
+	// extension, which are not interpreted.
+	ExtensionData []bool
+}
+
+func (sps *SPS) syntax(s *gobits.Syntax, more func() bool) {
+	s.Bits(&sps.VPSID, 4, "sps_video_parameter_set_id")
+	s.Byte(&sps.MaxSubLayersMinus1, 3, "sps_max_sub_layers_minus1")
+	s.Flag(&sps.TemporalIDNestingFlag, "sps_temporal_id_nesting_flag")
+	sps.ProfileTierLevel.syntax(s, sps.MaxSubLayersMinus1)
+	if !s.Count(&sps.ID, 15, "sps_seq_parameter_set_id") ||
+		!s.Count(&sps.ChromaFormatIdc, 3, "chroma_format_idc") {
+		return
+	}
+	if sps.ChromaFormatIdc == 3 {
+		s.Flag(&sps.SeparateColourPlaneFlag, "separate_colour_plane_flag")
+	}
+	s.ExponentialGolomb(&sps.PicWidthInLumaSamples, "pic_width_in_luma_samples")
+	s.ExponentialGolomb(&sps.PicHeightInLumaSamples, "pic_height_in_luma_samples")
+	s.Flag(&sps.ConformanceWindowFlag, "conformance_window_flag")
+	if sps.ConformanceWindowFlag {
+		sps.ConformanceWindow.syntax(s, "conf_win")
+	}
+	s.ExponentialGolomb(&sps.BitDepthLumaMinus8, "bit_depth_luma_minus8")
+	s.ExponentialGolomb(&sps.BitDepthChromaMinus8, "bit_depth_chroma_minus8")
+	if !s.Count(&sps.Log2MaxPicOrderCntLsbMinus4, 12, "log2_max_pic_order_cnt_lsb_minus4") {
+		return
+	}
+	subLayerOrderingInfo(s, &sps.SubLayerOrderingInfoPresentFlag, &sps.SubLayerOrderingInfo, sps.MaxSubLayersMinus1, "sps")
+
+	s.ExponentialGolomb(&sps.Log2MinLumaCodingBlockSizeMinus3, "log2_min_luma_coding_block_size_minus3")
+	s.ExponentialGolomb(&sps.Log2DiffMaxMinLumaCodingBlockSize, "log2_diff_max_min_luma_coding_block_size")
+	s.ExponentialGolomb(&sps.Log2MinLumaTransformBlockSizeMinus2, "log2_min_luma_transform_block_size_minus2")
+	s.ExponentialGolomb(&sps.Log2DiffMaxMinLumaTransformBlockSize, "log2_diff_max_min_luma_transform_block_size")
+	s.ExponentialGolomb(&sps.MaxTransformHierarchyDepthInter, "max_transform_hierarchy_depth_inter")
+	s.ExponentialGolomb(&sps.MaxTransformHierarchyDepthIntra, "max_transform_hierarchy_depth_intra")
+	if sps.Log2MinLumaCodingBlockSizeMinus3+sps.Log2DiffMaxMinLumaCodingBlockSize > 3 {
+		s.Fail("log2_diff_max_min_luma_coding_block_size", gobits.ErrOutOfRange)
+	}
+	s.Flag(&sps.ScalingListEnabledFlag, "scaling_list_enabled_flag")
+	if sps.ScalingListEnabledFlag {
+		s.Flag(&sps.ScalingListDataPresentFlag, "sps_scaling_list_data_present_flag")
+		if sps.ScalingListDataPresentFlag {
+			sps.ScalingListData.syntax(s)
+		}
+	}
+	s.Flag(&sps.AmpEnabledFlag, "amp_enabled_flag")
+	s.Flag(&sps.SampleAdaptiveOffsetEnabledFlag, "sample_adaptive_offset_enabled_flag")
+	s.Flag(&sps.PCMEnabledFlag, "pcm_enabled_flag")
+	if sps.PCMEnabledFlag {
+		s.Byte(&sps.PCMSampleBitDepthLumaMinus1, 4, "pcm_sample_bit_depth_luma_minus1")
+		s.Byte(&sps.PCMSampleBitDepthChromaMinus1, 4, "pcm_sample_bit_depth_chroma_minus1")
+		s.ExponentialGolomb(&sps.Log2MinPCMLumaCodingBlockSizeMinus3, "log2_min_pcm_luma_coding_block_size_minus3")
+		s.ExponentialGolomb(&sps.Log2DiffMaxMinPCMLumaCodingBlockSize, "log2_diff_max_min_pcm_luma_coding_block_size")
+		s.Flag(&sps.PCMLoopFilterDisabledFlag, "pcm_loop_filter_disabled_flag")
+	}
+
+	numSets := uint32(len(sps.ShortTermRefPicSets))
+	if !s.Count(&numSets, 64, "num_short_term_ref_pic_sets") {
+		return
+	}
+	if s.Reading() {
+		sps.ShortTermRefPicSets = make([]ShortTermRefPicSet, numSets)
+	}
+	for i := range sps.ShortTermRefPicSets {
+		sps.ShortTermRefPicSets[i].syntax(s, i, sps.ShortTermRefPicSets)
+	}
+	s.Flag(&sps.LongTermRefPicsPresentFlag, "long_term_ref_pics_present_flag")
+	if sps.LongTermRefPicsPresentFlag {
+		n := uint32(len(sps.LongTermRefPics))
+		if !s.Count(&n, 32, "num_long_term_ref_pics_sps") {
+			return
+		}
+		if s.Reading() {
+			sps.LongTermRefPics = make([]LongTermRefPic, n)
+		}
+		for i := range sps.LongTermRefPics {
+			s.Bits(&sps.LongTermRefPics[i].PocLsb, sps.log2MaxPicOrderCntLsb(), "lt_ref_pic_poc_lsb_sps")
+			s.Flag(&sps.LongTermRefPics[i].UsedByCurrPicFlag, "used_by_curr_pic_lt_sps_flag")
+		}
+	}
+	s.Flag(&sps.TemporalMvpEnabledFlag, "sps_temporal_mvp_enabled_flag")
+	s.Flag(&sps.StrongIntraSmoothingEnabledFlag, "strong_intra_smoothing_enabled_flag")
+	s.Flag(&sps.VUIParametersPresentFlag, "vui_parameters_present_flag")
+	if sps.VUIParametersPresentFlag {
+		sps.VUI.syntax(s, sps.MaxSubLayersMinus1)
+	}
+
+	s.Flag(&sps.ExtensionPresentFlag, "sps_extension_present_flag")
+	if sps.ExtensionPresentFlag {
+		s.Flag(&sps.RangeExtensionFlag, "sps_range_extension_flag")
+		s.Flag(&sps.MultilayerExtensionFlag, "sps_multilayer_extension_flag")
+		s.Flag(&sps.Extension3DFlag, "sps_3d_extension_flag")
+		s.Flag(&sps.SCCExtensionFlag, "sps_scc_extension_flag")
+		s.Byte(&sps.Extension4Bits, 4, "sps_extension_4bits")
+		if sps.RangeExtensionFlag {
+			sps.RangeExtension.syntax(s)
+		}
+		if sps.MultilayerExtensionFlag || sps.Extension3DFlag || sps.SCCExtensionFlag || sps.Extension4Bits != 0 {
+			extensionData(s, &sps.ExtensionData, more, "sps_extension_data_flag")
+		}
+	}
+	s.RBSPTrailingBits()
+}
+
+func (sps *SPS) log2MaxPicOrderCntLsb() byte {
+	return byte(sps.Log2MaxPicOrderCntLsbMinus4 + 4)
+}
+
+// ChromaArrayType returns the ChromaArrayType variable.
+func (sps *SPS) ChromaArrayType() uint32 {
+	if sps.SeparateColourPlaneFlag {
+		return 0
+	}
+	return sps.ChromaFormatIdc
+}
+
+// PicSizeInCtbsY returns the number of coding tree blocks in a picture.
+func (sps *SPS) PicSizeInCtbsY() uint32 {
+	ctbLog2 := sps.Log2MinLumaCodingBlockSizeMinus3 + 3 + sps.Log2DiffMaxMinLumaCodingBlockSize
+	ctb := uint32(1) << ctbLog2
+	width := (sps.PicWidthInLumaSamples + ctb - 1) >> ctbLog2
+	height := (sps.PicHeightInLumaSamples + ctb - 1) >> ctbLog2
+	return width * height
+}
+
+func (sps *SPS) subSampling() (uint32, uint32) {
+	switch sps.ChromaArrayType() {
+	case 1:
+		return 2, 2
+	case 2:
+		return 2, 1
+	}
+	return 1, 1
+}
+
+// Width returns the width of the conformance cropping window.
+func (sps *SPS) Width() int {
+	x, _ := sps.subSampling()
+	width := sps.PicWidthInLumaSamples
+	if sps.ConformanceWindowFlag {
+		width -= x * (sps.ConformanceWindow.LeftOffset + sps.ConformanceWindow.RightOffset)
+	}
+	return int(width)
+}
+
+// Height returns the height of the conformance cropping window.
+func (sps *SPS) Height() int {
+	_, y := sps.subSampling()
+	height := sps.PicHeightInLumaSamples
+	if sps.ConformanceWindowFlag {
+		height -= y * (sps.ConformanceWindow.TopOffset + sps.ConformanceWindow.BottomOffset)
+	}
+	return int(height)
+}
+
+// CodecString returns the RFC 6381 codecs parameter of the stream, see
+// ProfileTierLevel.CodecString.
+func (sps *SPS) CodecString(sampleEntry string) string {
+	return sps.ProfileTierLevel.CodecString(sampleEntry)
+}
+
+// ParseSPS parses an SPS NAL unit, header included, with emulation
+// prevention bytes still in place.
+func ParseSPS(ba gobits.ByteAccessor) (*SPS, error) {
+	header, rbsp, s, err := nal.ReadRBSP(ba, nal.HEVC, nal.IsType(NALTypeSPS))
+	if err != nil {
+		return nil, err
+	}
+	more := func() bool {
+		return rbsp.MoreRBSPData(s.BitStream().Tell())
+	}
+	sps := &SPS{Header: header}
+	sps.syntax(s, more)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	return sps, nil
+}
+
+// Marshal encodes the SPS as a NAL unit.
+func (sps *SPS) Marshal() ([]byte, error) {
+	return nal.WriteRBSP(sps.Header, nal.HEVC, func(s *gobits.Syntax) {
+		sps.syntax(s, nil)
+	})
+}
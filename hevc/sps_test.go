@@ -0,0 +1,121 @@
+package hevc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/ibbbpbbbp/gobits/nal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSPS_RoundTrip(t *testing.T) {
+	coefs := make([]int32, 64)
+	coefs[0], coefs[63] = 8, -3
+	sps := &SPS{
+		Header:             nal.Header{Type: NALTypeSPS, TemporalIDPlus1: 1},
+		MaxSubLayersMinus1: 2,
+		ProfileTierLevel: ProfileTierLevel{
+			General:         Profile{ProfileIdc: 2, CompatibilityFlags: 0x20000000, ConstraintIndicatorFlags: 0xb0 << 40},
+			GeneralLevelIdc: 123,
+			SubLayers: []SubLayer{
+				{LevelPresentFlag: true, LevelIdc: 90},
+				{ProfilePresentFlag: true, Profile: Profile{ProfileIdc: 1}},
+			},
+		},
+		ID:                                2,
+		ChromaFormatIdc:                   1,
+		PicWidthInLumaSamples:             1920,
+		PicHeightInLumaSamples:            1088,
+		ConformanceWindowFlag:             true,
+		ConformanceWindow:                 Window{BottomOffset: 4},
+		BitDepthLumaMinus8:                2,
+		BitDepthChromaMinus8:              2,
+		Log2MaxPicOrderCntLsbMinus4:       4,
+		SubLayerOrderingInfoPresentFlag:   true,
+		SubLayerOrderingInfo:              []SubLayerOrderingInfo{{}, {1, 0, 0}, {4, 2, 0}},
+		Log2DiffMaxMinLumaCodingBlockSize: 2,
+		ScalingListEnabledFlag:            true,
+		ScalingListDataPresentFlag:        true,
+		PCMEnabledFlag:                    true,
+		PCMSampleBitDepthLumaMinus1:       7,
+		PCMSampleBitDepthChromaMinus1:     7,
+		ShortTermRefPicSets: []ShortTermRefPicSet{
+			{NegativePics: []RefPic{{0, true}, {1, true}}, PositivePics: []RefPic{{3, false}}},
+			{
+				InterRefPicSetPredictionFlag: true,
+				DeltaRpsSign:                 true,
+				UsedByCurrPicFlag:            []bool{true, false, true, true},
+				UseDeltaFlag:                 []bool{true, true, true, true},
+			},
+		},
+		LongTermRefPicsPresentFlag: true,
+		LongTermRefPics:            []LongTermRefPic{{PocLsb: 17, UsedByCurrPicFlag: true}, {PocLsb: 200}},
+		TemporalMvpEnabledFlag:     true,
+		VUIParametersPresentFlag:   true,
+		VUI: VUI{
+			DefaultDisplayWindowFlag: true,
+			DefaultDisplayWindow:     Window{LeftOffset: 8, RightOffset: 8},
+			TimingInfoPresentFlag:    true,
+			NumUnitsInTick:           1,
+			TimeScale:                50,
+			HrdParametersPresentFlag: true,
+			HRD: HRD{
+				NalHrdParametersPresentFlag: true,
+				SubPicHrdParamsPresentFlag:  true,
+				TickDivisorMinus2:           98,
+				BitRateScale:                2,
+				SubLayers: []SubLayerHRD{
+					{FixedPicRateGeneralFlag: true, FixedPicRateWithinCvsFlag: true, CpbCntMinus1: 1,
+						NAL: []CPB{{BitRateValueMinus1: 1000, CbrFlag: true}, {CpbSizeDuValueMinus1: 7}}},
+					{LowDelayHrdFlag: true, NAL: []CPB{{BitRateValueMinus1: 500}}},
+					{NAL: []CPB{{BitRateDuValueMinus1: 3}}},
+				},
+			},
+		},
+		ExtensionPresentFlag: true,
+		RangeExtensionFlag:   true,
+		RangeExtension:       SPSRangeExtension{ImplicitRdpcmEnabledFlag: true, CabacBypassAlignmentEnabledFlag: true},
+		Extension4Bits:       1,
+		ExtensionData:        []bool{true, false, true},
+	}
+	sps.ScalingListData.Lists[1][2] = ScalingList{PredMatrixIDDelta: 2}
+	sps.ScalingListData.Lists[2][0] = ScalingList{PredModeFlag: true, DCCoefMinus8: 8, DeltaCoefs: coefs}
+	sps.ScalingListData.Lists[3][3] = ScalingList{PredMatrixIDDelta: 1}
+
+	raw, err := sps.Marshal()
+	assert.NoError(t, err)
+	parsed, err := ParseSPS(gobits.NewSliceByteAccessor(raw))
+	assert.NoError(t, err)
+	assert.Equal(t, sps, parsed)
+	assert.Equal(t, 1080, parsed.Height())
+
+	// Set 1 is predicted from set 0 with deltaRps -1.
+	deltaPocs, used := parsed.ShortTermRefPicSets[1].DeltaPocS0()
+	assert.Equal(t, []int32{-1, -2, -4}, deltaPocs)
+	assert.Equal(t, []bool{true, true, false}, used)
+	deltaPocs, used = parsed.ShortTermRefPicSets[1].DeltaPocS1()
+	assert.Equal(t, []int32{3}, deltaPocs)
+	assert.Equal(t, []bool{true}, used)
+
+	sps.ShortTermRefPicSets[1].UseDeltaFlag = nil
+	_, err = sps.Marshal()
+	assert.True(t, errors.Is(err, gobits.ErrOutOfRange))
+
+	// delta_idx_minus1 is not coded in the SPS, so only 0 can be written.
+	sps.ShortTermRefPicSets[1].UseDeltaFlag = []bool{true, true, true, true}
+	sps.ShortTermRefPicSets[1].DeltaIdxMinus1 = 1
+	_, err = sps.Marshal()
+	var fe *gobits.FieldError
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "delta_idx_minus1", fe.Field)
+	assert.True(t, errors.Is(err, gobits.ErrOutOfRange))
+
+	// Neither is inter_ref_pic_set_prediction_flag for the first set.
+	sps.ShortTermRefPicSets[1].DeltaIdxMinus1 = 0
+	sps.ShortTermRefPicSets[0].InterRefPicSetPredictionFlag = true
+	_, err = sps.Marshal()
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "inter_ref_pic_set_prediction_flag", fe.Field)
+	assert.True(t, errors.Is(err, gobits.ErrOutOfRange))
+}
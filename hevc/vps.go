@@ -0,0 +1,163 @@
+package hevc
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/ibbbpbbbp/gobits/nal"
+)
+
+// SubLayerOrderingInfo holds the DPB sizing of one sub-layer in a VPS or
+// SPS.
+type SubLayerOrderingInfo struct {
+	MaxDecPicBufferingMinus1 uint32
+	MaxNumReorderPics        uint32
+	MaxLatencyIncreasePlus1  uint32
+}
+
+// subLayerOrderingInfo handles the sub-layer ordering loop. When the present
+// flag is clear only the values of the highest sub-layer are coded, and the
+// list has a single entry.
+func subLayerOrderingInfo(s *gobits.Syntax, present *bool, infos *[]SubLayerOrderingInfo, maxSubLayersMinus1 byte, prefix string) {
+	s.Flag(present, prefix+"_sub_layer_ordering_info_present_flag")
+	n := 1
+	if *present {
+		n = int(maxSubLayersMinus1) + 1
+	}
+	if s.Reading() {
+		*infos = make([]SubLayerOrderingInfo, n)
+	}
+	if !s.ListLength(len(*infos), n, prefix+"_max_dec_pic_buffering_minus1") {
+		return
+	}
+	for i := range *infos {
+		info := &(*infos)[i]
+		s.ExponentialGolomb(&info.MaxDecPicBufferingMinus1, prefix+"_max_dec_pic_buffering_minus1")
+		s.ExponentialGolomb(&info.MaxNumReorderPics, prefix+"_max_num_reorder_pics")
+		s.ExponentialGolomb(&info.MaxLatencyIncreasePlus1, prefix+"_max_latency_increase_plus1")
+	}
+}
+
+// VPSHRD is one entry of the hrd_parameters() loop of a VPS.
+type VPSHRD struct {
+	LayerSetIdx      uint32
+	CprmsPresentFlag bool
+	HRD              HRD
+}
+
+// VPS is a video_parameter_set_rbsp().
+type VPS struct {
+	Header nal.Header
+
+	ID                              uint32
+	BaseLayerInternalFlag           bool
+	BaseLayerAvailableFlag          bool
+	MaxLayersMinus1                 byte
+	MaxSubLayersMinus1              byte
+	TemporalIDNestingFlag           bool
+	Reserved0xffff16Bits            uint32
+	ProfileTierLevel                ProfileTierLevel
+	SubLayerOrderingInfoPresentFlag bool
+	SubLayerOrderingInfo            []SubLayerOrderingInfo
+	MaxLayerID                      byte
+	// LayerIDIncludedFlag has one entry per layer set after the first, each
+	// with MaxLayerID + 1 flags.
+	LayerIDIncludedFlag [][]bool
+
+	TimingInfoPresentFlag       bool
+	NumUnitsInTick              uint32
+	TimeScale                   uint32
+	PocProportionalToTimingFlag bool
+	NumTicksPocDiffOneMinus1    uint32
+	HRDs                        []VPSHRD
+
+	ExtensionFlag bool
+	ExtensionData []bool
+}
+
+func (vps *VPS) syntax(s *gobits.Syntax, more func() bool) {
+	s.Bits(&vps.ID, 4, "vps_video_parameter_set_id")
+	s.Flag(&vps.BaseLayerInternalFlag, "vps_base_layer_internal_flag")
+	s.Flag(&vps.BaseLayerAvailableFlag, "vps_base_layer_available_flag")
+	s.Byte(&vps.MaxLayersMinus1, 6, "vps_max_layers_minus1")
+	s.Byte(&vps.MaxSubLayersMinus1, 3, "vps_max_sub_layers_minus1")
+	s.Flag(&vps.TemporalIDNestingFlag, "vps_temporal_id_nesting_flag")
+	s.Bits(&vps.Reserved0xffff16Bits, 16, "vps_reserved_0xffff_16bits")
+	vps.ProfileTierLevel.syntax(s, vps.MaxSubLayersMinus1)
+	subLayerOrderingInfo(s, &vps.SubLayerOrderingInfoPresentFlag, &vps.SubLayerOrderingInfo, vps.MaxSubLayersMinus1, "vps")
+
+	s.Byte(&vps.MaxLayerID, 6, "vps_max_layer_id")
+	numLayerSetsMinus1 := uint32(len(vps.LayerIDIncludedFlag))
+	if !s.Count(&numLayerSetsMinus1, 1023, "vps_num_layer_sets_minus1") {
+		return
+	}
+	if s.Reading() {
+		vps.LayerIDIncludedFlag = make([][]bool, numLayerSetsMinus1)
+	}
+	for i := range vps.LayerIDIncludedFlag {
+		if s.Reading() {
+			vps.LayerIDIncludedFlag[i] = make([]bool, int(vps.MaxLayerID)+1)
+		}
+		if !s.ListLength(len(vps.LayerIDIncludedFlag[i]), int(vps.MaxLayerID)+1, "layer_id_included_flag") {
+			return
+		}
+		for j := range vps.LayerIDIncludedFlag[i] {
+			s.Flag(&vps.LayerIDIncludedFlag[i][j], "layer_id_included_flag")
+		}
+	}
+
+	s.Flag(&vps.TimingInfoPresentFlag, "vps_timing_info_present_flag")
+	if vps.TimingInfoPresentFlag {
+		s.Bits(&vps.NumUnitsInTick, 32, "vps_num_units_in_tick")
+		s.Bits(&vps.TimeScale, 32, "vps_time_scale")
+		s.Flag(&vps.PocProportionalToTimingFlag, "vps_poc_proportional_to_timing_flag")
+		if vps.PocProportionalToTimingFlag {
+			s.ExponentialGolomb(&vps.NumTicksPocDiffOneMinus1, "vps_num_ticks_poc_diff_one_minus1")
+		}
+		numHRDParameters := uint32(len(vps.HRDs))
+		if !s.Count(&numHRDParameters, numLayerSetsMinus1+1, "vps_num_hrd_parameters") {
+			return
+		}
+		if s.Reading() {
+			vps.HRDs = make([]VPSHRD, numHRDParameters)
+		}
+		for i := range vps.HRDs {
+			h := &vps.HRDs[i]
+			s.ExponentialGolomb(&h.LayerSetIdx, "hrd_layer_set_idx")
+			if i > 0 {
+				s.Flag(&h.CprmsPresentFlag, "cprms_present_flag")
+			} else if s.Reading() {
+				h.CprmsPresentFlag = true
+			}
+			h.HRD.syntax(s, h.CprmsPresentFlag, vps.MaxSubLayersMinus1)
+		}
+	}
+	s.Flag(&vps.ExtensionFlag, "vps_extension_flag")
+	if vps.ExtensionFlag {
+		extensionData(s, &vps.ExtensionData, more, "vps_extension_data_flag")
+	}
+	s.RBSPTrailingBits()
+}
+
+// ParseVPS parses a VPS NAL unit, header included, with emulation prevention
+// bytes still in place.
+func ParseVPS(ba gobits.ByteAccessor) (*VPS, error) {
+	header, rbsp, s, err := nal.ReadRBSP(ba, nal.HEVC, nal.IsType(NALTypeVPS))
+	if err != nil {
+		return nil, err
+	}
+	more := func() bool {
+		return rbsp.MoreRBSPData(s.BitStream().Tell())
+	}
+	vps := &VPS{Header: header}
+	vps.syntax(s, more)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	return vps, nil
+}
+
+// Marshal encodes the VPS as a NAL unit.
+func (vps *VPS) Marshal() ([]byte, error) {
+	return nal.WriteRBSP(vps.Header, nal.HEVC, func(s *gobits.Syntax) {
+		vps.syntax(s, nil)
+	})
+}
@@ -0,0 +1,257 @@
+package hevc
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+)
+
+// CPB holds the parameters of one coded picture buffer specification in
+// sub_layer_hrd_parameters(). The DU values are only present when
+// SubPicHrdParamsPresentFlag is set.
+type CPB struct {
+	BitRateValueMinus1   uint32
+	CpbSizeValueMinus1   uint32
+	CpbSizeDuValueMinus1 uint32
+	BitRateDuValueMinus1 uint32
+	CbrFlag              bool
+}
+
+// SubLayerHRD holds the per sub-layer part of hrd_parameters(). NAL and VCL
+// have CpbCntMinus1 + 1 entries when the corresponding parameters are
+// present.
+type SubLayerHRD struct {
+	FixedPicRateGeneralFlag     bool
+	FixedPicRateWithinCvsFlag   bool
+	ElementalDurationInTcMinus1 uint32
+	LowDelayHrdFlag             bool
+	CpbCntMinus1                uint32
+	NAL                         []CPB
+	VCL                         []CPB
+}
+
+type HRD struct {
+	NalHrdParametersPresentFlag            bool
+	VclHrdParametersPresentFlag            bool
+	SubPicHrdParamsPresentFlag             bool
+	TickDivisorMinus2                      byte
+	DuCpbRemovalDelayIncrementLengthMinus1 byte
+	SubPicCpbParamsInPicTimingSeiFlag      bool
+	DpbOutputDelayDuLengthMinus1           byte
+	BitRateScale                           byte
+	CpbSizeScale                           byte
+	CpbSizeDuScale                         byte
+	InitialCpbRemovalDelayLengthMinus1     byte
+	AuCpbRemovalDelayLengthMinus1          byte
+	DpbOutputDelayLengthMinus1             byte
+	SubLayers                              []SubLayerHRD
+}
+
+func (h *HRD) cpbs(s *gobits.Syntax, cpbs *[]CPB, n int) {
+	if s.Reading() {
+		*cpbs = make([]CPB, n)
+	}
+	if !s.ListLength(len(*cpbs), n, "bit_rate_value_minus1") {
+		return
+	}
+	for i := range *cpbs {
+		c := &(*cpbs)[i]
+		s.ExponentialGolomb(&c.BitRateValueMinus1, "bit_rate_value_minus1")
+		s.ExponentialGolomb(&c.CpbSizeValueMinus1, "cpb_size_value_minus1")
+		if h.SubPicHrdParamsPresentFlag {
+			s.ExponentialGolomb(&c.CpbSizeDuValueMinus1, "cpb_size_du_value_minus1")
+			s.ExponentialGolomb(&c.BitRateDuValueMinus1, "bit_rate_du_value_minus1")
+		}
+		s.Flag(&c.CbrFlag, "cbr_flag")
+	}
+}
+
+func (h *HRD) syntax(s *gobits.Syntax, commonInfPresentFlag bool, maxNumSubLayersMinus1 byte) {
+	if commonInfPresentFlag {
+		s.Flag(&h.NalHrdParametersPresentFlag, "nal_hrd_parameters_present_flag")
+		s.Flag(&h.VclHrdParametersPresentFlag, "vcl_hrd_parameters_present_flag")
+		if h.NalHrdParametersPresentFlag || h.VclHrdParametersPresentFlag {
+			s.Flag(&h.SubPicHrdParamsPresentFlag, "sub_pic_hrd_params_present_flag")
+			if h.SubPicHrdParamsPresentFlag {
+				s.Byte(&h.TickDivisorMinus2, 8, "tick_divisor_minus2")
+				s.Byte(&h.DuCpbRemovalDelayIncrementLengthMinus1, 5, "du_cpb_removal_delay_increment_length_minus1")
+				s.Flag(&h.SubPicCpbParamsInPicTimingSeiFlag, "sub_pic_cpb_params_in_pic_timing_sei_flag")
+				s.Byte(&h.DpbOutputDelayDuLengthMinus1, 5, "dpb_output_delay_du_length_minus1")
+			}
+			s.Byte(&h.BitRateScale, 4, "bit_rate_scale")
+			s.Byte(&h.CpbSizeScale, 4, "cpb_size_scale")
+			if h.SubPicHrdParamsPresentFlag {
+				s.Byte(&h.CpbSizeDuScale, 4, "cpb_size_du_scale")
+			}
+			s.Byte(&h.InitialCpbRemovalDelayLengthMinus1, 5, "initial_cpb_removal_delay_length_minus1")
+			s.Byte(&h.AuCpbRemovalDelayLengthMinus1, 5, "au_cpb_removal_delay_length_minus1")
+			s.Byte(&h.DpbOutputDelayLengthMinus1, 5, "dpb_output_delay_length_minus1")
+		}
+	}
+
+	n := int(maxNumSubLayersMinus1) + 1
+	if s.Reading() {
+		h.SubLayers = make([]SubLayerHRD, n)
+	}
+	if !s.ListLength(len(h.SubLayers), n, "fixed_pic_rate_general_flag") {
+		return
+	}
+	for i := range h.SubLayers {
+		l := &h.SubLayers[i]
+		s.Flag(&l.FixedPicRateGeneralFlag, "fixed_pic_rate_general_flag")
+		if !l.FixedPicRateGeneralFlag {
+			s.Flag(&l.FixedPicRateWithinCvsFlag, "fixed_pic_rate_within_cvs_flag")
+		} else if s.Reading() {
+			l.FixedPicRateWithinCvsFlag = true
+		}
+		if l.FixedPicRateWithinCvsFlag {
+			s.ExponentialGolomb(&l.ElementalDurationInTcMinus1, "elemental_duration_in_tc_minus1")
+		} else {
+			s.Flag(&l.LowDelayHrdFlag, "low_delay_hrd_flag")
+		}
+		if !l.LowDelayHrdFlag && !s.Count(&l.CpbCntMinus1, 31, "cpb_cnt_minus1") {
+			return
+		}
+		if h.NalHrdParametersPresentFlag {
+			h.cpbs(s, &l.NAL, int(l.CpbCntMinus1)+1)
+		}
+		if h.VclHrdParametersPresentFlag {
+			h.cpbs(s, &l.VCL, int(l.CpbCntMinus1)+1)
+		}
+	}
+}
+
+// Window is a cropping window in units of chroma samples, such as the
+// conformance window or the default display window.
+type Window struct {
+	LeftOffset   uint32
+	RightOffset  uint32
+	TopOffset    uint32
+	BottomOffset uint32
+}
+
+func (w *Window) syntax(s *gobits.Syntax, prefix string) {
+	s.ExponentialGolomb(&w.LeftOffset, prefix+"_left_offset")
+	s.ExponentialGolomb(&w.RightOffset, prefix+"_right_offset")
+	s.ExponentialGolomb(&w.TopOffset, prefix+"_top_offset")
+	s.ExponentialGolomb(&w.BottomOffset, prefix+"_bottom_offset")
+}
+
+// AspectRatioExtendedSAR is the aspect_ratio_idc that signals an explicit
+// sample aspect ratio.
+const AspectRatioExtendedSAR = 255
+
+type VUI struct {
+	AspectRatioInfoPresentFlag bool
+	AspectRatioIdc             byte
+	SarWidth                   uint32
+	SarHeight                  uint32
+
+	OverscanInfoPresentFlag bool
+	OverscanAppropriateFlag bool
+
+	VideoSignalTypePresentFlag   bool
+	VideoFormat                  byte
+	VideoFullRangeFlag           bool
+	ColourDescriptionPresentFlag bool
+	ColourPrimaries              byte
+	TransferCharacteristics      byte
+	MatrixCoeffs                 byte
+
+	ChromaLocInfoPresentFlag       bool
+	ChromaSampleLocTypeTopField    uint32
+	ChromaSampleLocTypeBottomField uint32
+
+	NeutralChromaIndicationFlag bool
+	FieldSeqFlag                bool
+	FrameFieldInfoPresentFlag   bool
+	DefaultDisplayWindowFlag    bool
+	DefaultDisplayWindow        Window
+
+	TimingInfoPresentFlag       bool
+	NumUnitsInTick              uint32
+	TimeScale                   uint32
+	PocProportionalToTimingFlag bool
+	NumTicksPocDiffOneMinus1    uint32
+	HrdParametersPresentFlag    bool
+	HRD                         HRD
+
+	BitstreamRestrictionFlag       bool
+	TilesFixedStructureFlag        bool
+	MotionVectorsOverPicBoundaries bool
+	RestrictedRefPicListsFlag      bool
+	MinSpatialSegmentationIdc      uint32
+	MaxBytesPerPicDenom            uint32
+	MaxBitsPerMinCuDenom           uint32
+	Log2MaxMvLengthHorizontal      uint32
+	Log2MaxMvLengthVertical        uint32
+}
+
+func (v *VUI) syntax(s *gobits.Syntax, maxSubLayersMinus1 byte) {
+	s.Flag(&v.AspectRatioInfoPresentFlag, "aspect_ratio_info_present_flag")
+	if v.AspectRatioInfoPresentFlag {
+		s.Byte(&v.AspectRatioIdc, 8, "aspect_ratio_idc")
+		if v.AspectRatioIdc == AspectRatioExtendedSAR {
+			s.Bits(&v.SarWidth, 16, "sar_width")
+			s.Bits(&v.SarHeight, 16, "sar_height")
+		}
+	}
+	s.Flag(&v.OverscanInfoPresentFlag, "overscan_info_present_flag")
+	if v.OverscanInfoPresentFlag {
+		s.Flag(&v.OverscanAppropriateFlag, "overscan_appropriate_flag")
+	}
+	s.Flag(&v.VideoSignalTypePresentFlag, "video_signal_type_present_flag")
+	if v.VideoSignalTypePresentFlag {
+		s.Byte(&v.VideoFormat, 3, "video_format")
+		s.Flag(&v.VideoFullRangeFlag, "video_full_range_flag")
+		s.Flag(&v.ColourDescriptionPresentFlag, "colour_description_present_flag")
+		if v.ColourDescriptionPresentFlag {
+			s.Byte(&v.ColourPrimaries, 8, "colour_primaries")
+			s.Byte(&v.TransferCharacteristics, 8, "transfer_characteristics")
+			s.Byte(&v.MatrixCoeffs, 8, "matrix_coeffs")
+		}
+	}
+	s.Flag(&v.ChromaLocInfoPresentFlag, "chroma_loc_info_present_flag")
+	if v.ChromaLocInfoPresentFlag {
+		s.ExponentialGolomb(&v.ChromaSampleLocTypeTopField, "chroma_sample_loc_type_top_field")
+		s.ExponentialGolomb(&v.ChromaSampleLocTypeBottomField, "chroma_sample_loc_type_bottom_field")
+	}
+	s.Flag(&v.NeutralChromaIndicationFlag, "neutral_chroma_indication_flag")
+	s.Flag(&v.FieldSeqFlag, "field_seq_flag")
+	s.Flag(&v.FrameFieldInfoPresentFlag, "frame_field_info_present_flag")
+	s.Flag(&v.DefaultDisplayWindowFlag, "default_display_window_flag")
+	if v.DefaultDisplayWindowFlag {
+		v.DefaultDisplayWindow.syntax(s, "def_disp_win")
+	}
+	s.Flag(&v.TimingInfoPresentFlag, "vui_timing_info_present_flag")
+	if v.TimingInfoPresentFlag {
+		s.Bits(&v.NumUnitsInTick, 32, "vui_num_units_in_tick")
+		s.Bits(&v.TimeScale, 32, "vui_time_scale")
+		s.Flag(&v.PocProportionalToTimingFlag, "vui_poc_proportional_to_timing_flag")
+		if v.PocProportionalToTimingFlag {
+			s.ExponentialGolomb(&v.NumTicksPocDiffOneMinus1, "vui_num_ticks_poc_diff_one_minus1")
+		}
+		s.Flag(&v.HrdParametersPresentFlag, "vui_hrd_parameters_present_flag")
+		if v.HrdParametersPresentFlag {
+			v.HRD.syntax(s, true, maxSubLayersMinus1)
+		}
+	}
+	s.Flag(&v.BitstreamRestrictionFlag, "bitstream_restriction_flag")
+	if v.BitstreamRestrictionFlag {
+		s.Flag(&v.TilesFixedStructureFlag, "tiles_fixed_structure_flag")
+		s.Flag(&v.MotionVectorsOverPicBoundaries, "motion_vectors_over_pic_boundaries_flag")
+		s.Flag(&v.RestrictedRefPicListsFlag, "restricted_ref_pic_lists_flag")
+		s.ExponentialGolomb(&v.MinSpatialSegmentationIdc, "min_spatial_segmentation_idc")
+		s.ExponentialGolomb(&v.MaxBytesPerPicDenom, "max_bytes_per_pic_denom")
+		s.ExponentialGolomb(&v.MaxBitsPerMinCuDenom, "max_bits_per_min_cu_denom")
+		s.ExponentialGolomb(&v.Log2MaxMvLengthHorizontal, "log2_max_mv_length_horizontal")
+		s.ExponentialGolomb(&v.Log2MaxMvLengthVertical, "log2_max_mv_length_vertical")
+	}
+}
+
+// FrameRate returns the picture rate implied by the timing information, or
+// zero if there is none.
+func (v *VUI) FrameRate() float64 {
+	if !v.TimingInfoPresentFlag || v.NumUnitsInTick == 0 {
+		return 0
+	}
+	return float64(v.TimeScale) / float64(v.NumUnitsInTick)
+}
@@ -0,0 +1,169 @@
+package gobits
+
+// HuffmanCode is a single entry of a Huffman code book. Code holds the
+// Length code bits right-aligned, first bit most significant.
+type HuffmanCode struct {
+	Symbol int
+	Code   uint64
+	Length byte
+}
+
+type huffmanKey struct {
+	code   uint64
+	length byte
+}
+
+// HuffmanTable decodes and encodes symbols of a prefix-free code. Decoding
+// goes through a VLCTable whose values are the symbols, so long codes are
+// resolved through its sub-tables. Tables are immutable once built and may
+// be shared between goroutines.
+type HuffmanTable struct {
+	codes   []HuffmanCode
+	symbols map[int]HuffmanCode
+	vlc     *VLCTable
+}
+
+// checkPrefixFree verifies that every code fits its length and that no code
+// is a prefix of, or equal to, another.
+func checkPrefixFree(keys []huffmanKey) error {
+	seen := map[huffmanKey]bool{}
+	for _, key := range keys {
+		if key.length == 0 || key.length > 64 || key.code>>key.length != 0 || seen[key] {
+			return ErrInvalidTable
+		}
+		seen[key] = true
+	}
+	for _, key := range keys {
+		for length := byte(1); length < key.length; length++ {
+			if seen[huffmanKey{key.code >> (key.length - length), length}] {
+				return ErrInvalidTable
+			}
+		}
+	}
+	return nil
+}
+
+// NewCanonicalHuffmanTable builds the canonical code in which symbol i has
+// code length lengths[i], as DEFLATE and many other formats transmit it.
+// A length of zero means the symbol is unused.
+func NewCanonicalHuffmanTable(lengths []byte) (*HuffmanTable, error) {
+	for _, l := range lengths {
+		if l > 64 {
+			return nil, ErrInvalidTable
+		}
+	}
+
+	counts := make([]int, 64)
+	symbols := []int{}
+	for length := byte(1); length <= 64; length++ {
+		for symbol, l := range lengths {
+			if l == length {
+				counts[length-1]++
+				symbols = append(symbols, symbol)
+			}
+		}
+	}
+	return NewHuffmanTableFromCounts(counts, symbols)
+}
+
+// NewHuffmanTableFromCounts builds a canonical code from the number of codes
+// of each length, starting at length 1, and the symbols in code order, which
+// is how JPEG DHT segments and MPEG-style tables describe them.
+func NewHuffmanTableFromCounts(counts []int, symbols []int) (*HuffmanTable, error) {
+	if len(counts) > 64 {
+		return nil, ErrInvalidTable
+	}
+
+	codes := []HuffmanCode{}
+	code := uint64(0)
+	for i, count := range counts {
+		length := byte(i + 1)
+		if count < 0 {
+			return nil, ErrInvalidTable
+		}
+		for ; count > 0; count-- {
+			if len(codes) == len(symbols) || code>>length != 0 {
+				return nil, ErrInvalidTable
+			}
+			codes = append(codes, HuffmanCode{Symbol: symbols[len(codes)], Code: code, Length: length})
+			code++
+		}
+		code <<= 1
+	}
+	if len(codes) != len(symbols) {
+		return nil, ErrInvalidTable
+	}
+	return NewHuffmanTable(codes)
+}
+
+// NewHuffmanTable builds a table from an explicit code book. The codes must
+// be prefix-free and each symbol may appear only once; the code does not
+// need to be complete.
+func NewHuffmanTable(codes []HuffmanCode) (*HuffmanTable, error) {
+	if len(codes) == 0 {
+		return nil, ErrInvalidTable
+	}
+
+	t := &HuffmanTable{symbols: map[int]HuffmanCode{}}
+	entries := make([]VLCEntry, len(codes))
+	for i, c := range codes {
+		if _, ok := t.symbols[c.Symbol]; ok {
+			return nil, ErrInvalidTable
+		}
+		t.symbols[c.Symbol] = c
+		entries[i] = VLCEntry{Code: c.Code, Length: c.Length, Value: c.Symbol}
+	}
+	vlc, err := NewVLCTable(entries)
+	if err != nil {
+		return nil, err
+	}
+	t.vlc = vlc
+	t.codes = make([]HuffmanCode, len(vlc.entries))
+	for i, e := range vlc.entries {
+		t.codes[i] = HuffmanCode{Symbol: e.Value.(int), Code: e.Code, Length: e.Length}
+	}
+	return t, nil
+}
+
+// Codes returns the code book ordered by length and then by code.
+func (t *HuffmanTable) Codes() []HuffmanCode {
+	return append([]HuffmanCode{}, t.codes...)
+}
+
+// Code returns the code assigned to symbol.
+func (t *HuffmanTable) Code(symbol int) (HuffmanCode, bool) {
+	c, ok := t.symbols[symbol]
+	return c, ok
+}
+
+func (t *HuffmanTable) MaxLength() byte {
+	return t.vlc.maxLength
+}
+
+func (bs *BitStream) readHuffman(t *HuffmanTable) (int, error) {
+	symbol, _, err := bs.readVLC(t.vlc)
+	if err != nil {
+		return 0, err
+	}
+	return symbol.(int), nil
+}
+
+func (bs *BitStream) writeHuffman(t *HuffmanTable, symbol int) error {
+	c, ok := t.symbols[symbol]
+	if !ok {
+		return ErrOutOfRange
+	}
+	return bs.writeCode(c.Code, c.Length)
+}
+
+// ReadHuffman decodes one symbol coded with t. Code bits are taken in stream
+// order, so the same table serves MSB-first formats like JPEG and LSB-first
+// formats like DEFLATE.
+func (bs *BitStream) ReadHuffman(t *HuffmanTable) (int, bool) {
+	symbol, err := bs.readHuffman(t)
+	return symbol, bs.record("ReadHuffman", err)
+}
+
+func (bs *BitStream) WriteHuffman(t *HuffmanTable, symbol int) bool {
+	return bs.record("WriteHuffman", bs.writeHuffman(t, symbol))
+}
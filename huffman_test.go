@@ -0,0 +1,189 @@
+package gobits
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// jpegLuminanceDC is the example DC luminance table from ITU-T T.81 K.3.
+func jpegLuminanceDC(t *testing.T) *HuffmanTable {
+	table, err := NewHuffmanTableFromCounts(
+		[]int{0, 1, 5, 1, 1, 1, 1, 1, 1},
+		[]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11})
+	assert.NoError(t, err)
+	return table
+}
+
+func deflateFixedLiterals(t *testing.T) *HuffmanTable {
+	lengths := make([]byte, 288)
+	for i := range lengths {
+		switch {
+		case i < 144:
+			lengths[i] = 8
+		case i < 256:
+			lengths[i] = 9
+		case i < 280:
+			lengths[i] = 7
+		default:
+			lengths[i] = 8
+		}
+	}
+	table, err := NewCanonicalHuffmanTable(lengths)
+	assert.NoError(t, err)
+	return table
+}
+
+func TestHuffmanTable_Construction(t *testing.T) {
+	table, err := NewCanonicalHuffmanTable([]byte{2, 1, 3, 3, 0})
+	assert.NoError(t, err)
+	assert.Equal(t, []HuffmanCode{
+		{Symbol: 1, Code: 0, Length: 1},
+		{Symbol: 0, Code: 2, Length: 2},
+		{Symbol: 2, Code: 6, Length: 3},
+		{Symbol: 3, Code: 7, Length: 3},
+	}, table.Codes())
+	assert.Equal(t, byte(3), table.MaxLength())
+	_, ok := table.Code(4)
+	assert.False(t, ok)
+
+	dc := jpegLuminanceDC(t)
+	c, ok := dc.Code(11)
+	assert.True(t, ok)
+	assert.Equal(t, HuffmanCode{Symbol: 11, Code: 0x1fe, Length: 9}, c)
+
+	invalid := map[string]func() (*HuffmanTable, error){
+		"empty":           func() (*HuffmanTable, error) { return NewHuffmanTable(nil) },
+		"oversubscribed":  func() (*HuffmanTable, error) { return NewCanonicalHuffmanTable([]byte{1, 1, 1}) },
+		"too_long":        func() (*HuffmanTable, error) { return NewCanonicalHuffmanTable([]byte{65}) },
+		"missing_symbols": func() (*HuffmanTable, error) { return NewHuffmanTableFromCounts([]int{0, 2}, []int{1}) },
+		"extra_symbols":   func() (*HuffmanTable, error) { return NewHuffmanTableFromCounts([]int{1}, []int{1, 2}) },
+		"code_too_wide": func() (*HuffmanTable, error) {
+			return NewHuffmanTable([]HuffmanCode{{Symbol: 0, Code: 4, Length: 2}})
+		},
+		"duplicate_symbol": func() (*HuffmanTable, error) {
+			return NewHuffmanTable([]HuffmanCode{{Symbol: 0, Code: 0, Length: 1}, {Symbol: 0, Code: 1, Length: 1}})
+		},
+		"not_prefix_free": func() (*HuffmanTable, error) {
+			return NewHuffmanTable([]HuffmanCode{{Symbol: 0, Code: 1, Length: 1}, {Symbol: 1, Code: 0x5ff, Length: 11}})
+		},
+	}
+	for name, build := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := build()
+			assert.True(t, errors.Is(err, ErrInvalidTable))
+		})
+	}
+}
+
+func TestBitStream_ReadHuffman(t *testing.T) {
+	t.Run("jpeg_dc", func(t *testing.T) {
+		table := jpegLuminanceDC(t)
+		bs := NewBitStream(NewSliceByteAccessor(bitStringBytes("00 010 1110 111111110 110")))
+		for _, expected := range []int{0, 1, 6, 11, 5} {
+			symbol, ok := bs.ReadHuffman(table)
+			assert.True(t, ok)
+			assert.Equal(t, expected, symbol)
+		}
+
+		// Three bits of zero padding decode as symbol 0 with one bit left
+		// over, which is too short for any code.
+		symbol, ok := bs.ReadHuffman(table)
+		assert.True(t, ok)
+		assert.Equal(t, 0, symbol)
+		_, ok = bs.ReadHuffman(table)
+		assert.False(t, ok)
+		assert.True(t, errors.Is(bs.Err(), ErrUnexpectedEOF))
+		assert.Equal(t, int64(23), bs.Tell())
+	})
+
+	t.Run("deflate_lsb_first", func(t *testing.T) {
+		table := deflateFixedLiterals(t)
+		// A fixed-Huffman DEFLATE block holding "abca".
+		bs := NewBitStreamWithBitOrder(NewSliceByteAccessor([]byte{0x4b, 0x4c, 0x4a, 0x4e, 0x04, 0x00}), LSBFirst)
+		header, ok := bs.ReadBits(3)
+		assert.True(t, ok)
+		assert.Equal(t, uint64(3), header)
+
+		decoded := []int{}
+		for {
+			symbol, ok := bs.ReadHuffman(table)
+			assert.True(t, ok)
+			if !ok || symbol == 256 {
+				break
+			}
+			decoded = append(decoded, symbol)
+		}
+		assert.Equal(t, []int{'a', 'b', 'c', 'a'}, decoded)
+	})
+
+	t.Run("long_codes", func(t *testing.T) {
+		counts := []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0, 0, 0, 1}
+		symbols := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 16}
+		table, err := NewHuffmanTableFromCounts(counts, symbols)
+		assert.NoError(t, err)
+
+		bs := NewBitStream(NewSliceByteAccessor(bitStringBytes("1111111111110000 111111111110 0 111")))
+		for _, expected := range []int{16, 12, 1} {
+			symbol, ok := bs.ReadHuffman(table)
+			assert.True(t, ok)
+			assert.Equal(t, expected, symbol)
+		}
+
+		// Only the first three bits of the 16-bit code are left.
+		pos := bs.Tell()
+		_, ok := bs.ReadHuffman(table)
+		assert.False(t, ok)
+		assert.True(t, errors.Is(bs.Err(), ErrUnexpectedEOF))
+		assert.Equal(t, pos, bs.Tell())
+
+		// The 16-bit prefix 1111111111110001 is not assigned.
+		bs = NewBitStream(NewSliceByteAccessor(bitStringBytes("1111111111110001")))
+		_, ok = bs.ReadHuffman(table)
+		assert.False(t, ok)
+		assert.True(t, errors.Is(bs.Err(), ErrMalformedCode))
+		assert.Equal(t, int64(0), bs.Tell())
+	})
+
+	t.Run("incomplete_code", func(t *testing.T) {
+		table := jpegLuminanceDC(t)
+		bs := NewBitStream(NewSliceByteAccessor([]byte{0xff, 0xff}))
+		_, ok := bs.ReadHuffman(table)
+		assert.False(t, ok)
+		assert.True(t, errors.Is(bs.Err(), ErrMalformedCode))
+	})
+}
+
+func TestBitStream_WriteHuffman(t *testing.T) {
+	for _, order := range []BitOrder{MSBFirst, LSBFirst} {
+		table := deflateFixedLiterals(t)
+		ba := NewGrowableByteAccessor(nil)
+		bs := NewBitStreamWithBitOrder(ba, order)
+		symbols := []int{0, 143, 144, 255, 256, 279, 280, 287, 'x'}
+		for _, symbol := range symbols {
+			assert.True(t, bs.WriteHuffman(table, symbol))
+		}
+		assert.True(t, bs.PadToByteBoundary(PadZeros))
+
+		bs = NewBitStreamWithBitOrder(NewSliceByteAccessor(ba.Bytes()), order)
+		for _, expected := range symbols {
+			symbol, ok := bs.ReadHuffman(table)
+			assert.True(t, ok)
+			assert.Equal(t, expected, symbol)
+		}
+	}
+
+	bs := NewBitStream(NewGrowableByteAccessor(nil))
+	assert.False(t, bs.WriteHuffman(jpegLuminanceDC(t), 12))
+	assert.True(t, errors.Is(bs.Err(), ErrOutOfRange))
+
+	ba := NewGrowableByteAccessor(nil)
+	bs = NewBitStreamWithBitOrder(ba, LSBFirst)
+	assert.True(t, bs.WriteBits(3, 3))
+	for _, symbol := range []int{'a', 'b', 'c', 'a', 256} {
+		assert.True(t, bs.WriteHuffman(deflateFixedLiterals(t), symbol))
+	}
+	assert.True(t, bs.PadToByteBoundary(PadZeros))
+	assert.Equal(t, []byte{0x4b, 0x4c, 0x4a, 0x4e, 0x04, 0x00}, ba.Bytes())
+}
@@ -15,9 +15,9 @@ type IOByteAccessor struct {
 	bufferSize  int64
 }
 
-func (ba *IOByteAccessor) renewBuffer(byteOffset int64) bool {
+func (ba *IOByteAccessor) renewBuffer(byteOffset int64) error {
 	if ba.bufferIndex <= byteOffset && byteOffset < ba.bufferIndex+ba.bufferSize {
-		return true
+		return nil
 	}
 
 	newByteOffset := byteOffset - (maxBufferSize / 2)
@@ -25,35 +25,47 @@ func (ba *IOByteAccessor) renewBuffer(byteOffset int64) bool {
 		newByteOffset = 0
 	}
 
-	bufferIndex, err := ba.rwseeker.Seek(newByteOffset, 0)
+	bufferIndex, err := ba.rwseeker.Seek(newByteOffset, io.SeekStart)
 	if err != nil {
-		return false
+		ba.bufferSize = 0
+		return &IOError{Op: "seek", Offset: newByteOffset, Err: err}
 	}
 
 	ba.bufferIndex = bufferIndex
-	bufferSize, err := ba.rwseeker.Read(ba.buffer)
+	// A short read near the end of data shrinks the buffer; read into its
+	// full capacity so that it grows back once the window moves away.
+	bufferSize, err := io.ReadFull(ba.rwseeker, ba.buffer[:cap(ba.buffer)])
 	ba.bufferSize = int64(bufferSize)
 
 	ba.buffer = ba.buffer[:ba.bufferSize]
 
-	if err != nil {
-		return false
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return &IOError{Op: "read", Offset: bufferIndex, Err: err}
 	}
 
-	return true
+	return nil
 }
 
-func (ba *IOByteAccessor) At(byteOffset int64) (byte, bool) {
-	if !ba.renewBuffer(byteOffset) {
-		return 0, false
+func (ba *IOByteAccessor) ByteAt(byteOffset int64) (byte, error) {
+	if byteOffset < 0 {
+		return 0, ErrInvalidOffset
+	}
+
+	if err := ba.renewBuffer(byteOffset); err != nil {
+		return 0, err
 	}
 
 	if byteOffset < ba.bufferIndex || ba.bufferIndex+ba.bufferSize <= byteOffset {
-		return 0, false
+		return 0, ErrUnexpectedEOF
 	}
 
 	at := byteOffset - ba.bufferIndex
-	return ba.buffer[at], true
+	return ba.buffer[at], nil
+}
+
+func (ba *IOByteAccessor) At(byteOffset int64) (byte, bool) {
+	b, err := ba.ByteAt(byteOffset)
+	return b, err == nil
 }
 
 func (ba *IOByteAccessor) Slice(byteOffset, length int64) []byte {
@@ -67,8 +79,8 @@ func (ba *IOByteAccessor) Slice(byteOffset, length int64) []byte {
 	}
 
 	bytes := make([]byte, length)
-	actualLength, err := ba.rwseeker.Read(bytes)
-	if err != nil {
+	actualLength, err := io.ReadFull(ba.rwseeker, bytes)
+	if err != nil && err != io.ErrUnexpectedEOF {
 		return []byte{}
 	}
 
@@ -77,22 +89,22 @@ func (ba *IOByteAccessor) Slice(byteOffset, length int64) []byte {
 	return bytes
 }
 
-func (ba *IOByteAccessor) Put(bytes []byte, byteOffset int64) bool {
+func (ba *IOByteAccessor) PutBytes(bytes []byte, byteOffset int64) error {
 	if byteOffset < 0 || bytes == nil {
-		return false
+		return ErrInvalidOffset
 	}
 	if len(bytes) == 0 {
-		return true
+		return nil
 	}
 
-	_, err := ba.rwseeker.Seek(byteOffset, 0)
+	_, err := ba.rwseeker.Seek(byteOffset, io.SeekStart)
 	if err != nil {
-		return false
+		return &IOError{Op: "seek", Offset: byteOffset, Err: err}
 	}
 
 	actualLength, err := ba.rwseeker.Write(bytes)
 	if err != nil {
-		return false
+		return &IOError{Op: "write", Offset: byteOffset, Err: err}
 	}
 
 	// sync the buffer
@@ -126,7 +138,15 @@ func (ba *IOByteAccessor) Put(bytes []byte, byteOffset int64) bool {
 		copy(ba.buffer[:], bytes[bufStart-byteStart:])
 	}
 
-	return actualLength == len(bytes)
+	if actualLength != len(bytes) {
+		return &IOError{Op: "write", Offset: byteOffset, Err: io.ErrShortWrite}
+	}
+
+	return nil
+}
+
+func (ba *IOByteAccessor) Put(bytes []byte, byteOffset int64) bool {
+	return ba.PutBytes(bytes, byteOffset) == nil
 }
 
 func (ba *IOByteAccessor) Length() int64 {
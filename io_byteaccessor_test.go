@@ -1,6 +1,7 @@
 package gobits
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -237,3 +238,53 @@ func TestIOByteAccessor_Reset(t *testing.T) {
 	assert.Equal(t, rawAt(rwseeker, 0), ba.buffer[0])
 	assert.Equal(t, rawAt(rwseeker, 4095), ba.buffer[len(ba.buffer)-1])
 }
+
+func TestIOByteAccessor_ByteAt(t *testing.T) {
+	rwseeker, teardown := setupTestDataFile(t)
+	defer teardown()
+	ba := NewIOByteAccessor(rwseeker)
+
+	b, err := ba.ByteAt(7247)
+	assert.Nil(t, err)
+	assert.Equal(t, rawAt(rwseeker, 7247), b)
+
+	_, err = ba.ByteAt(7248)
+	assert.Equal(t, ErrUnexpectedEOF, err)
+
+	_, err = ba.ByteAt(-1)
+	assert.Equal(t, ErrInvalidOffset, err)
+
+	_, err = NewIOByteAccessor(brokenReadWriteSeeker{}).ByteAt(0)
+	assert.True(t, errors.Is(err, ErrIO))
+	assert.True(t, errors.Is(err, errBrokenDevice))
+}
+
+func TestIOByteAccessor_PutBytes(t *testing.T) {
+	ba := NewIOByteAccessor(brokenReadWriteSeeker{})
+
+	assert.Equal(t, ErrInvalidOffset, ba.PutBytes([]byte{1}, -1))
+	assert.Nil(t, ba.PutBytes([]byte{}, 0))
+
+	err := ba.PutBytes([]byte{1}, 0)
+	assert.True(t, errors.Is(err, ErrIO))
+	assert.True(t, errors.Is(err, errBrokenDevice))
+}
+
+func TestIOByteAccessor_BufferRecovers(t *testing.T) {
+	rwseeker, teardown := setupTestDataFile(t)
+	defer teardown()
+	ba := NewIOByteAccessor(rwseeker)
+
+	_, ok := ba.At(100000)
+	assert.False(t, ok)
+	assert.Len(t, ba.buffer, 0)
+
+	at4095, ok := ba.At(4095)
+	assert.True(t, ok)
+	assert.Equal(t, rawAt(rwseeker, 4095), at4095)
+	assert.Len(t, ba.buffer, 4096)
+
+	at6143, ok := ba.At(6143)
+	assert.True(t, ok)
+	assert.Equal(t, rawAt(rwseeker, 6143), at6143)
+}
@@ -0,0 +1,237 @@
+package gobits
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+const (
+	jpegMarkerSOI = 0xd8
+	jpegMarkerEOI = 0xd9
+	jpegMarkerSOS = 0xda
+	jpegMarkerRST = 0xd0
+	jpegMarkerTEM = 0x01
+)
+
+// JPEGMarker is a marker found in or after entropy-coded data.
+type JPEGMarker struct {
+	// Code is the byte following 0xFF, e.g. 0xD0 for RST0.
+	Code byte
+	// Offset is the position in the unstuffed data at which the marker
+	// occurred, i.e. the number of data bytes preceding it.
+	Offset int64
+	// FileOffset is the position of the marker's 0xFF byte in the
+	// underlying accessor.
+	FileOffset int64
+}
+
+func (m JPEGMarker) IsRST() bool {
+	return m.Code&0xf8 == jpegMarkerRST
+}
+
+// JPEGMarkerError is returned when reading reaches the marker that ends an
+// entropy-coded segment. It matches ErrMarker and, since no more data
+// follows, ErrUnexpectedEOF.
+type JPEGMarkerError struct {
+	Marker JPEGMarker
+}
+
+func (e *JPEGMarkerError) Error() string {
+	return fmt.Sprintf("gobits: marker 0x%02x at offset %d", e.Marker.Code, e.Marker.FileOffset)
+}
+
+func (e *JPEGMarkerError) Is(target error) bool {
+	return target == ErrMarker || target == ErrUnexpectedEOF
+}
+
+type jpegSkip struct {
+	offset  int64
+	skipped int64
+}
+
+// JPEGScanByteAccessor presents the entropy-coded data of a JPEG scan with
+// stuffed 0x00 bytes and restart markers removed. The data is read once at
+// construction; Put modifies this copy, and Stuffed re-encodes it.
+type JPEGScanByteAccessor struct {
+	SliceByteAccessor
+	byteOffset int64
+	skips      []jpegSkip
+	restarts   []JPEGMarker
+	end        *JPEGMarker
+}
+
+// FindJPEGScan walks the marker segments starting at byteOffset, which must
+// point at a marker, and returns the offset of the entropy-coded data that
+// follows the next SOS segment.
+func FindJPEGScan(ba ByteAccessor, byteOffset int64) (int64, error) {
+	for {
+		prefix, err := byteAt(ba, byteOffset)
+		if err != nil {
+			return 0, err
+		}
+		code, err := byteAt(ba, byteOffset+1)
+		if err != nil {
+			return 0, err
+		}
+		if prefix != 0xff {
+			return 0, ErrInvalidSyntax
+		}
+		byteOffset += 2
+
+		switch {
+		case code == 0xff:
+			byteOffset--
+			continue
+		case code == jpegMarkerSOI || code == jpegMarkerTEM || code&0xf8 == jpegMarkerRST:
+			continue
+		case code == jpegMarkerEOI || code == 0x00:
+			return 0, ErrInvalidSyntax
+		}
+
+		high, err := byteAt(ba, byteOffset)
+		if err != nil {
+			return 0, err
+		}
+		low, err := byteAt(ba, byteOffset+1)
+		if err != nil {
+			return 0, err
+		}
+		length := int64(high)<<8 | int64(low)
+		if length < 2 {
+			return 0, ErrInvalidSyntax
+		}
+		byteOffset += length
+		if code == jpegMarkerSOS {
+			return byteOffset, nil
+		}
+	}
+}
+
+func (ba *JPEGScanByteAccessor) scan(src ByteAccessor) error {
+	data := []byte{}
+	skipped := int64(0)
+	skip := func(count int64) {
+		skipped += count
+		ba.skips = append(ba.skips, jpegSkip{offset: int64(len(data)), skipped: skipped})
+	}
+
+	for fileOffset := ba.byteOffset; ; {
+		b, err := byteAt(src, fileOffset)
+		if errors.Is(err, ErrUnexpectedEOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+		if b != 0xff {
+			data = append(data, b)
+			fileOffset++
+			continue
+		}
+
+		code, err := byteAt(src, fileOffset+1)
+		if errors.Is(err, ErrUnexpectedEOF) {
+			data = append(data, b)
+			break
+		} else if err != nil {
+			return err
+		}
+		switch {
+		case code == 0x00:
+			data = append(data, b)
+			skip(1)
+			fileOffset += 2
+		case code == 0xff:
+			// Fill byte preceding a marker.
+			skip(1)
+			fileOffset++
+		case code&0xf8 == jpegMarkerRST:
+			ba.restarts = append(ba.restarts, JPEGMarker{Code: code, Offset: int64(len(data)), FileOffset: fileOffset})
+			skip(2)
+			fileOffset += 2
+		default:
+			ba.end = &JPEGMarker{Code: code, Offset: int64(len(data)), FileOffset: fileOffset}
+			ba.bytes = data
+			return nil
+		}
+	}
+	ba.bytes = data
+	return nil
+}
+
+func (ba *JPEGScanByteAccessor) ByteAt(byteOffset int64) (byte, error) {
+	b, err := ba.SliceByteAccessor.ByteAt(byteOffset)
+	if errors.Is(err, ErrUnexpectedEOF) && ba.end != nil {
+		return 0, &JPEGMarkerError{Marker: *ba.end}
+	}
+	return b, err
+}
+
+func (ba *JPEGScanByteAccessor) At(byteOffset int64) (byte, bool) {
+	b, err := ba.ByteAt(byteOffset)
+	return b, err == nil
+}
+
+// RestartMarkers returns the RST markers removed from the data, in order.
+func (ba *JPEGScanByteAccessor) RestartMarkers() []JPEGMarker {
+	return append([]JPEGMarker{}, ba.restarts...)
+}
+
+// EndMarker returns the marker that terminated the entropy-coded data. It
+// reports false if the data ran to the end of the underlying accessor.
+func (ba *JPEGScanByteAccessor) EndMarker() (JPEGMarker, bool) {
+	if ba.end == nil {
+		return JPEGMarker{}, false
+	}
+	return *ba.end, true
+}
+
+// FileOffset maps an offset in the unstuffed data to the offset of the same
+// byte in the underlying accessor.
+func (ba *JPEGScanByteAccessor) FileOffset(byteOffset int64) (int64, bool) {
+	if byteOffset < 0 || ba.Length() < byteOffset {
+		return 0, false
+	}
+	i := sort.Search(len(ba.skips), func(i int) bool {
+		return ba.skips[i].offset > byteOffset
+	})
+	if i == 0 {
+		return ba.byteOffset + byteOffset, true
+	}
+	return ba.byteOffset + byteOffset + ba.skips[i-1].skipped, true
+}
+
+// Stuffed returns the data encoded for a JPEG file, with 0x00 stuffed after
+// every 0xFF and the restart markers at their original positions. The
+// terminating marker is not included.
+func (ba *JPEGScanByteAccessor) Stuffed() []byte {
+	stuffed := make([]byte, 0, len(ba.bytes)+len(ba.restarts)*2)
+	restarts := ba.restarts
+	for i, b := range ba.bytes {
+		for len(restarts) > 0 && restarts[0].Offset == int64(i) {
+			stuffed = append(stuffed, 0xff, restarts[0].Code)
+			restarts = restarts[1:]
+		}
+		stuffed = append(stuffed, b)
+		if b == 0xff {
+			stuffed = append(stuffed, 0x00)
+		}
+	}
+	for _, restart := range restarts {
+		stuffed = append(stuffed, 0xff, restart.Code)
+	}
+	return stuffed
+}
+
+// NewJPEGScanByteAccessor reads the entropy-coded data starting at
+// byteOffset of ba, up to the first marker other than RSTn.
+func NewJPEGScanByteAccessor(ba ByteAccessor, byteOffset int64) (*JPEGScanByteAccessor, error) {
+	if byteOffset < 0 {
+		return nil, ErrInvalidOffset
+	}
+	jba := &JPEGScanByteAccessor{byteOffset: byteOffset}
+	if err := jba.scan(ba); err != nil {
+		return nil, err
+	}
+	return jba, nil
+}
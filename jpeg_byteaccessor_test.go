@@ -0,0 +1,179 @@
+package gobits
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readJPEGHuffmanTables parses the DHT segments of testdata/Lenna.jpg, keyed
+// by table class and destination.
+func readJPEGHuffmanTables(t *testing.T, ba ByteAccessor) map[byte]*HuffmanTable {
+	tables := map[byte]*HuffmanTable{}
+	for _, segment := range []int64{0xb1, 0xd2, 0x189, 0x1aa} {
+		bs := NewBitStream(ba)
+		assert.True(t, bs.Seek(segment+4, 0))
+		class, _ := bs.ReadUint8()
+		counts := make([]int, 16)
+		symbols := []int{}
+		for i := range counts {
+			count, _ := bs.ReadUint8()
+			counts[i] = int(count)
+		}
+		for _, count := range counts {
+			for ; count > 0; count-- {
+				symbol, _ := bs.ReadUint8()
+				symbols = append(symbols, int(symbol))
+			}
+		}
+		table, err := NewHuffmanTableFromCounts(counts, symbols)
+		assert.NoError(t, err)
+		tables[class] = table
+	}
+	return tables
+}
+
+func decodeJPEGBlock(bs *BitStream, dc, ac *HuffmanTable) bool {
+	s, ok := bs.ReadHuffman(dc)
+	if !ok || !bs.ConsumeBits(int64(s)) {
+		return false
+	}
+	for k := 1; k < 64; k++ {
+		rs, ok := bs.ReadHuffman(ac)
+		if !ok {
+			return false
+		}
+		if rs == 0x00 {
+			break
+		}
+		k += rs >> 4
+		if !bs.ConsumeBits(int64(rs & 0x0f)) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestJPEGScanByteAccessor_Lenna(t *testing.T) {
+	file, teardown := setupTestDataFile(t)
+	defer teardown()
+	ba := NewIOByteAccessor(file)
+
+	scanOffset, err := FindJPEGScan(ba, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(623), scanOffset)
+
+	jba, err := NewJPEGScanByteAccessor(ba, scanOffset)
+	assert.NoError(t, err)
+	assert.Empty(t, jba.RestartMarkers())
+	end, ok := jba.EndMarker()
+	assert.True(t, ok)
+	assert.Equal(t, JPEGMarker{Code: 0xd9, Offset: 7246 - 623 - 29, FileOffset: 7246}, end)
+	assert.Equal(t, ba.Slice(scanOffset, 7246-623), jba.Stuffed())
+
+	// 150x150 pixels with 2x2 luma subsampling: 10x10 MCUs of four Y, one
+	// Cb and one Cr block.
+	tables := readJPEGHuffmanTables(t, ba)
+	bs := NewBitStream(jba)
+	for mcu := 0; mcu < 100; mcu++ {
+		for block := 0; block < 6; block++ {
+			dc, ac := tables[0x00], tables[0x10]
+			if block >= 4 {
+				dc, ac = tables[0x01], tables[0x11]
+			}
+			if !assert.True(t, decodeJPEGBlock(bs, dc, ac), "mcu %d block %d", mcu, block) {
+				fileOffset, _ := jba.FileOffset(bs.Tell() / 8)
+				t.Fatalf("corrupt MCU at file offset %d: %v", fileOffset, bs.Err())
+			}
+		}
+	}
+
+	assert.True(t, bs.AlignReadPadding(8, PadOnes))
+	_, ok = bs.ReadUint8()
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrMarker))
+	assert.True(t, errors.Is(bs.Err(), ErrUnexpectedEOF))
+	var markerErr *JPEGMarkerError
+	assert.True(t, errors.As(bs.Err(), &markerErr))
+	assert.Equal(t, end, markerErr.Marker)
+
+	fileOffset, ok := jba.FileOffset(jba.Length())
+	assert.True(t, ok)
+	assert.Equal(t, int64(7246), fileOffset)
+}
+
+func TestJPEGScanByteAccessor(t *testing.T) {
+	data := []byte{
+		0x12, 0xff, 0x00, 0x34, // stuffed 0xff
+		0xff, 0xd0, // RST0
+		0x56, 0xff, 0xff, 0xd1, // fill byte and RST1
+		0xff, 0x00, 0x78,
+		0xff, 0xd9, // EOI
+		0x9a,
+	}
+	jba, err := NewJPEGScanByteAccessor(NewSliceByteAccessor(data), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), jba.Length())
+	assert.Equal(t, []byte{0x12, 0xff, 0x34, 0x56, 0xff, 0x78}, jba.Slice(0, 6))
+	assert.Equal(t, []JPEGMarker{
+		{Code: 0xd0, Offset: 3, FileOffset: 4},
+		{Code: 0xd1, Offset: 4, FileOffset: 8},
+	}, jba.RestartMarkers())
+	assert.True(t, jba.RestartMarkers()[1].IsRST())
+
+	end, ok := jba.EndMarker()
+	assert.True(t, ok)
+	assert.Equal(t, JPEGMarker{Code: 0xd9, Offset: 6, FileOffset: 13}, end)
+	assert.False(t, end.IsRST())
+
+	fileOffsets := []int64{0, 1, 3, 6, 10, 12, 13}
+	for i, expected := range fileOffsets {
+		fileOffset, ok := jba.FileOffset(int64(i))
+		assert.True(t, ok)
+		assert.Equal(t, expected, fileOffset, "offset %d", i)
+	}
+	_, ok = jba.FileOffset(7)
+	assert.False(t, ok)
+
+	_, err = jba.ByteAt(6)
+	var markerErr *JPEGMarkerError
+	assert.True(t, errors.As(err, &markerErr))
+	assert.Equal(t, "gobits: marker 0xd9 at offset 13", err.Error())
+	_, err = jba.ByteAt(-1)
+	assert.True(t, errors.Is(err, ErrInvalidOffset))
+	_, ok = jba.At(6)
+	assert.False(t, ok)
+
+	assert.True(t, jba.Put([]byte{0x00, 0xff}, 2))
+	assert.Equal(t, []byte{0x12, 0xff, 0x00, 0x00, 0xff, 0xd0, 0xff, 0x00, 0xff, 0xd1, 0xff, 0x00, 0x78}, jba.Stuffed())
+}
+
+func TestJPEGScanByteAccessor_NoMarker(t *testing.T) {
+	jba, err := NewJPEGScanByteAccessor(NewSliceByteAccessor([]byte{0x00, 0x11, 0xff, 0xd7, 0xff}), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x11, 0xff}, jba.Slice(0, 2))
+	assert.Len(t, jba.RestartMarkers(), 1)
+	_, ok := jba.EndMarker()
+	assert.False(t, ok)
+
+	_, err = jba.ByteAt(2)
+	assert.True(t, errors.Is(err, ErrUnexpectedEOF))
+	assert.False(t, errors.Is(err, ErrMarker))
+
+	_, err = NewJPEGScanByteAccessor(NewSliceByteAccessor(nil), -1)
+	assert.True(t, errors.Is(err, ErrInvalidOffset))
+}
+
+func TestFindJPEGScan(t *testing.T) {
+	_, err := FindJPEGScan(NewSliceByteAccessor([]byte{0xff, 0xd8, 0xff, 0xd9}), 0)
+	assert.True(t, errors.Is(err, ErrInvalidSyntax))
+	_, err = FindJPEGScan(NewSliceByteAccessor([]byte{0xff, 0xd8, 0x00}), 0)
+	assert.True(t, errors.Is(err, ErrUnexpectedEOF))
+	_, err = FindJPEGScan(NewSliceByteAccessor([]byte{0xff, 0xd8, 0x12, 0x34}), 0)
+	assert.True(t, errors.Is(err, ErrInvalidSyntax))
+
+	offset, err := FindJPEGScan(NewSliceByteAccessor([]byte{0xff, 0xd8, 0xff, 0xff, 0xda, 0x00, 0x03, 0x01, 0x55}), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(8), offset)
+}
@@ -0,0 +1,177 @@
+package mpegaudio
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+)
+
+// Header is the header of an audio frame and, when ProtectionBit is
+// clear, the CRC that follows it.
+type Header struct {
+	Version byte
+	Layer   byte
+	// ProtectionBit is set when no CRC follows the header.
+	ProtectionBit     bool
+	BitrateIndex      byte
+	SamplingFrequency byte
+	PaddingBit        bool
+	PrivateBit        bool
+	Mode              byte
+	ModeExtension     byte
+	Copyright         bool
+	OriginalHome      bool
+	Emphasis          byte
+	CRCCheck          uint16
+}
+
+func (h *Header) syntax(s *gobits.Syntax) {
+	sync := uint32(syncword)
+	s.Bits(&sync, 11, "syncword")
+	if s.Err() == nil && sync != syncword {
+		s.Fail("syncword", gobits.ErrInvalidSyntax)
+	}
+	// Reserved values are rejected, as the scanner tells headers from
+	// random data by them.
+	check := func(invalid bool, field string) {
+		if s.Err() == nil && invalid {
+			s.Fail(field, gobits.ErrInvalidSyntax)
+		}
+	}
+	s.Byte(&h.Version, 2, "version")
+	check(h.Version == 1, "version")
+	s.Byte(&h.Layer, 2, "layer")
+	check(h.Layer == 0, "layer")
+	s.Flag(&h.ProtectionBit, "protection_bit")
+	s.Byte(&h.BitrateIndex, 4, "bitrate_index")
+	check(h.BitrateIndex == 0xf, "bitrate_index")
+	s.Byte(&h.SamplingFrequency, 2, "sampling_frequency")
+	check(h.SamplingFrequency == 3, "sampling_frequency")
+	s.Flag(&h.PaddingBit, "padding_bit")
+	s.Flag(&h.PrivateBit, "private_bit")
+	s.Byte(&h.Mode, 2, "mode")
+	s.Byte(&h.ModeExtension, 2, "mode_extension")
+	s.Flag(&h.Copyright, "copyright")
+	s.Flag(&h.OriginalHome, "original_home")
+	s.Byte(&h.Emphasis, 2, "emphasis")
+	check(h.Emphasis == 2, "emphasis")
+	if !h.ProtectionBit {
+		s.Bits16(&h.CRCCheck, 16, "crc_check")
+	}
+}
+
+// ParseHeader parses the frame header at the start of ba.
+func ParseHeader(ba gobits.ByteAccessor) (*Header, error) {
+	s := gobits.NewReadingSyntax(gobits.NewBitStream(ba))
+	h := &Header{}
+	h.syntax(s)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	return h, nil
+}
+
+// Marshal encodes the header as is, CRC included.
+func (h *Header) Marshal() ([]byte, error) {
+	ba := gobits.NewGrowableByteAccessor(nil)
+	s := gobits.NewWritingSyntax(gobits.NewBitStream(ba))
+	h.syntax(s)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	return ba.Bytes(), nil
+}
+
+// HeaderLength returns the length of the header in bytes, CRC included.
+func (h *Header) HeaderLength() int {
+	if h.ProtectionBit {
+		return headerLength
+	}
+	return headerLength + crcLength
+}
+
+func (h *Header) mpeg1() int {
+	if h.Version == VersionMPEG1 {
+		return 1
+	}
+	return 0
+}
+
+// Bitrate returns the bitrate in bit/s, or 0 for free format.
+func (h *Header) Bitrate() uint32 {
+	if h.Layer == 0 || h.BitrateIndex >= 0xf {
+		return 0
+	}
+	return bitrates[h.mpeg1()][h.Layer][h.BitrateIndex] * 1000
+}
+
+// SampleRate returns the sampling frequency in Hz.
+func (h *Header) SampleRate() uint32 {
+	if h.SamplingFrequency >= 3 {
+		return 0
+	}
+	return samplingFrequencies[h.Version][h.SamplingFrequency]
+}
+
+// SamplesPerFrame returns the number of samples per channel that a frame
+// holds.
+func (h *Header) SamplesPerFrame() int {
+	switch {
+	case h.Layer == LayerI:
+		return 384
+	case h.Layer == LayerIII && h.Version != VersionMPEG1:
+		return 576
+	}
+	return 1152
+}
+
+// Channels returns the number of channels.
+func (h *Header) Channels() int {
+	if h.Mode == ModeSingleChannel {
+		return 1
+	}
+	return 2
+}
+
+// FrameLength returns the length of the frame in bytes, header included,
+// or 0 for free format.
+func (h *Header) FrameLength() int {
+	bitrate, sampleRate := h.Bitrate(), h.SampleRate()
+	if bitrate == 0 || sampleRate == 0 {
+		return 0
+	}
+	padding := 0
+	if h.PaddingBit {
+		padding = 1
+	}
+	if h.Layer == LayerI {
+		// Layer I frames are made of 4-byte slots.
+		return (12*int(bitrate)/int(sampleRate) + padding) * 4
+	}
+	return int(bitrate)*h.SamplesPerFrame()/8/int(sampleRate) + padding
+}
+
+// sideInfoLength returns the length in bytes of the layer III side
+// information, which follows the header.
+func (h *Header) sideInfoLength() int {
+	switch {
+	case h.Version == VersionMPEG1 && h.Mode == ModeSingleChannel:
+		return 17
+	case h.Version == VersionMPEG1:
+		return 32
+	case h.Mode == ModeSingleChannel:
+		return 9
+	}
+	return 17
+}
+
+// streamKey identifies the stream a frame belongs to.
+type streamKey struct {
+	version           byte
+	layer             byte
+	samplingFrequency byte
+}
+
+// stream returns what frames of the same stream share: version, layer and
+// sampling frequency.
+func (h *Header) stream() streamKey {
+	return streamKey{h.Version, h.Layer, h.SamplingFrequency}
+}
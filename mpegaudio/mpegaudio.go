@@ -0,0 +1,66 @@
+// Package mpegaudio scans MPEG-1, MPEG-2 and MPEG-2.5 audio layer I, II
+// and III streams, MP3 files among them, for frame headers, as specified
+// in ISO/IEC 11172-3 and ISO/IEC 13818-3, and reads and writes the Xing,
+// Info, LAME and VBRI tags that encoders put in the first frame to give
+// the length of the stream and a seek table.
+//
+// Headers and tags are described once as a gobits.Syntax, so parsing one
+// and marshalling the result reproduces it bit for bit. Fields are named
+// after the syntax elements of the specifications, or after the tag
+// documentation of the encoders that introduced them. The audio data is
+// not decoded.
+package mpegaudio
+
+// Values of the version field. MPEG-2.5 is an unofficial extension of
+// MPEG-2 to lower sampling frequencies.
+const (
+	VersionMPEG25 = 0
+	VersionMPEG2  = 2
+	VersionMPEG1  = 3
+)
+
+// Values of the layer field.
+const (
+	LayerIII = 1
+	LayerII  = 2
+	LayerI   = 3
+)
+
+// Values of the mode field.
+const (
+	ModeStereo        = 0
+	ModeJointStereo   = 1
+	ModeDualChannel   = 2
+	ModeSingleChannel = 3
+)
+
+const (
+	syncword     = 0x7ff
+	headerLength = 4
+	crcLength    = 2
+)
+
+// bitrates holds the bitrates in kbit/s, by MPEG-1 or not, then layer
+// field, then bitrate index.
+var bitrates = [2][4][15]uint32{
+	{
+		{},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+	},
+	{
+		{},
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+	},
+}
+
+// samplingFrequencies holds the sampling frequencies in Hz, by version
+// field, then sampling frequency field.
+var samplingFrequencies = [4][3]uint32{
+	VersionMPEG25: {11025, 12000, 8000},
+	VersionMPEG2:  {22050, 24000, 16000},
+	VersionMPEG1:  {44100, 48000, 32000},
+}
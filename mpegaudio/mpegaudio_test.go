@@ -0,0 +1,177 @@
+package mpegaudio
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/stretchr/testify/assert"
+)
+
+// mp3Header is the header that LAME writes for 128 kbit/s joint stereo at
+// 44.1 kHz.
+var mp3Header = []byte{0xff, 0xfb, 0x90, 0x64}
+
+// mp3Frame returns a frame with mp3Header, padded if padding is set, and
+// audio data of zeros.
+func mp3Frame(padding bool) []byte {
+	header := append([]byte{}, mp3Header...)
+	length := 417
+	if padding {
+		header[2] |= 0x02
+		length++
+	}
+	return append(header, make([]byte, length-len(header))...)
+}
+
+func TestParseHeader(t *testing.T) {
+	h, err := ParseHeader(gobits.NewSliceByteAccessor(mp3Header))
+	assert.NoError(t, err)
+	assert.Equal(t, &Header{
+		Version:       VersionMPEG1,
+		Layer:         LayerIII,
+		ProtectionBit: true,
+		BitrateIndex:  9,
+		Mode:          ModeJointStereo,
+		ModeExtension: 2,
+		OriginalHome:  true,
+	}, h)
+	assert.Equal(t, uint32(128000), h.Bitrate())
+	assert.Equal(t, uint32(44100), h.SampleRate())
+	assert.Equal(t, 1152, h.SamplesPerFrame())
+	assert.Equal(t, 2, h.Channels())
+	assert.Equal(t, 417, h.FrameLength())
+	assert.Equal(t, 4, h.HeaderLength())
+	data, err := h.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, mp3Header, data)
+
+	protected := []byte{0xff, 0xfa, 0x92, 0x64, 0x12, 0x34}
+	h, err = ParseHeader(gobits.NewSliceByteAccessor(protected))
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(0x1234), h.CRCCheck)
+	assert.Equal(t, 6, h.HeaderLength())
+	assert.Equal(t, 418, h.FrameLength())
+	data, err = h.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, protected, data)
+
+	var fe *gobits.FieldError
+	for field, header := range map[string][]byte{
+		"syncword":           {0xff, 0x1b, 0x90, 0x64},
+		"version":            {0xff, 0xeb, 0x90, 0x64},
+		"layer":              {0xff, 0xf9, 0x90, 0x64},
+		"bitrate_index":      {0xff, 0xfb, 0xf0, 0x64},
+		"sampling_frequency": {0xff, 0xfb, 0x9c, 0x64},
+		"emphasis":           {0xff, 0xfb, 0x90, 0x66},
+	} {
+		_, err = ParseHeader(gobits.NewSliceByteAccessor(header))
+		assert.True(t, errors.As(err, &fe), field)
+		assert.Equal(t, field, fe.Field)
+		assert.True(t, errors.Is(err, gobits.ErrInvalidSyntax), field)
+	}
+}
+
+func TestHeader_FrameLength(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      []byte
+		bitrate     uint32
+		sampleRate  uint32
+		samples     int
+		frameLength int
+	}{
+		{"MPEG-2 layer III mono", []byte{0xff, 0xf3, 0x80, 0xc0}, 64000, 22050, 576, 208},
+		{"MPEG-2.5 layer III", []byte{0xff, 0xe3, 0x48, 0xc0}, 32000, 8000, 576, 288},
+		{"MPEG-1 layer II", []byte{0xff, 0xfd, 0xa4, 0x00}, 192000, 48000, 1152, 576},
+		{"MPEG-1 layer I", []byte{0xff, 0xff, 0xc8, 0x00}, 384000, 32000, 384, 576},
+		{"MPEG-1 layer I padded", []byte{0xff, 0xff, 0xca, 0x00}, 384000, 32000, 384, 580},
+		{"free format", []byte{0xff, 0xfb, 0x00, 0x00}, 0, 44100, 1152, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := ParseHeader(gobits.NewSliceByteAccessor(tt.header))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.bitrate, h.Bitrate())
+			assert.Equal(t, tt.sampleRate, h.SampleRate())
+			assert.Equal(t, tt.samples, h.SamplesPerFrame())
+			assert.Equal(t, tt.frameLength, h.FrameLength())
+		})
+	}
+}
+
+func TestScanner(t *testing.T) {
+	var data []byte
+	// An ID3v2 tag of 20 bytes, with a footer, that holds a syncword.
+	data = append(data, 'I', 'D', '3', 4, 0, id3v2FlagFooter, 0, 0, 0, 20)
+	data = append(data, append(append([]byte{}, mp3Header...), make([]byte, 16)...)...)
+	data = append(data, '3', 'D', 'I', 4, 0, id3v2FlagFooter, 0, 0, 0, 20)
+	offsets := []int64{}
+	for i := 0; i < 5; i++ {
+		offsets = append(offsets, int64(len(data)))
+		data = append(data, mp3Frame(i%2 == 1)...)
+	}
+	// Junk with a false syncword, then more frames.
+	data = append(data, 0x00, 0xff, 0xfb, 0x90, 0x64, 0x00)
+	for i := 0; i < 2; i++ {
+		offsets = append(offsets, int64(len(data)))
+		data = append(data, mp3Frame(false)...)
+	}
+	data = append(data, append([]byte("TAG"), bytes.Repeat([]byte{0xff}, id3v1Length-3)...)...)
+
+	f, err := ioutil.TempFile("", "mpegaudio")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+	_, err = f.Write(data)
+	assert.NoError(t, err)
+
+	for _, ba := range []gobits.ByteAccessor{gobits.NewSliceByteAccessor(data), gobits.NewIOByteAccessor(f)} {
+		sc := NewScanner(ba)
+		found := []int64{}
+		lengths := []int64{}
+		for sc.Next() {
+			found = append(found, sc.Frame().Offset)
+			lengths = append(lengths, sc.Frame().Data.Length())
+		}
+		assert.NoError(t, sc.Err())
+		assert.Equal(t, offsets, found)
+		assert.Equal(t, []int64{417, 418, 417, 418, 417, 417, 417}, lengths)
+		assert.Equal(t, int64(40+6), sc.Discarded())
+	}
+
+	// A frame cut short.
+	var truncated []byte
+	for i := 0; i <= syncChecks; i++ {
+		truncated = append(truncated, mp3Frame(false)...)
+	}
+	sc := NewScanner(gobits.NewSliceByteAccessor(truncated[:len(truncated)-1]))
+	count := 0
+	for sc.Next() {
+		count++
+	}
+	assert.Equal(t, syncChecks, count)
+	var fe *gobits.FieldError
+	assert.True(t, errors.As(sc.Err(), &fe))
+	assert.Equal(t, "frame", fe.Field)
+	assert.True(t, errors.Is(sc.Err(), gobits.ErrUnexpectedEOF))
+
+	// The scanner follows a change of stream once it can lock on to it.
+	var mixed []byte
+	for i := 0; i < syncChecks; i++ {
+		mixed = append(mixed, mp3Frame(false)...)
+	}
+	mixed = append(mixed, 0xff, 0xf3, 0x80, 0xc0)
+	mixed = append(mixed, make([]byte, 204)...)
+	sc = NewScanner(gobits.NewSliceByteAccessor(mixed))
+	versions := []byte{}
+	for sc.Next() {
+		versions = append(versions, sc.Frame().Header.Version)
+	}
+	assert.NoError(t, sc.Err())
+	assert.Equal(t, []byte{VersionMPEG1, VersionMPEG1, VersionMPEG1, VersionMPEG1, VersionMPEG2}, versions)
+	assert.Equal(t, int64(0), sc.Discarded())
+}
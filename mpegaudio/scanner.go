@@ -0,0 +1,131 @@
+package mpegaudio
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+)
+
+const (
+	// syncChecks is how many consecutive frames of one stream the scanner
+	// requires before it locks on to a position.
+	syncChecks = 4
+
+	id3v2HeaderLength = 10
+	id3v2FlagFooter   = 0x10
+	id3v1Length       = 128
+)
+
+var (
+	// syncPattern matches the 11-bit syncword and serves as its own mask.
+	syncPattern = []byte{0xff, 0xe0}
+)
+
+// Frame is a frame found by a Scanner. Data covers the whole frame, header
+// included, as tags are located from its start, and shares the scanned
+// accessor.
+type Frame struct {
+	Offset int64
+	Header *Header
+	Data   *gobits.SectionByteAccessor
+}
+
+// Scanner iterates over the frames of an MPEG audio stream, such as an MP3
+// file. It skips an ID3v2 tag at the start, an ID3v1 tag at the end and
+// any data that does not start a run of frames with the same version,
+// layer and sampling frequency, which rules out most false syncwords in
+// tags and corrupt data. Free format streams are not supported. Call Next
+// until it returns false, then check Err.
+type Scanner struct {
+	// ba is the scanned accessor without any ID3v1 tag.
+	ba    gobits.ByteAccessor
+	sync  *gobits.FrameSync
+	frame *Frame
+	err   error
+}
+
+// id3v2Length returns the length of the ID3v2 tag at the start of the
+// data, or 0 if there is none.
+func (sc *Scanner) id3v2Length() int64 {
+	header := sc.ba.Slice(0, id3v2HeaderLength)
+	if len(header) < id3v2HeaderLength || string(header[:3]) != "ID3" {
+		return 0
+	}
+	// The size is a 28-bit integer in 4 bytes of 7 bits.
+	size := int64(0)
+	for _, b := range header[6:] {
+		if b&0x80 != 0 {
+			return 0
+		}
+		size = size<<7 | int64(b)
+	}
+	length := id3v2HeaderLength + size
+	if header[5]&id3v2FlagFooter != 0 {
+		length += id3v2HeaderLength
+	}
+	if length > sc.ba.Length() {
+		return sc.ba.Length()
+	}
+	return length
+}
+
+// header parses the header at byteOffset for the FrameSync. Free format
+// frames, whose length is unknown, are rejected.
+func (sc *Scanner) header(byteOffset int64) (int64, interface{}, bool) {
+	h, err := ParseHeader(gobits.NewSectionByteAccessor(sc.ba, byteOffset, sc.ba.Length()-byteOffset))
+	if err != nil || h.FrameLength() == 0 {
+		return 0, nil, false
+	}
+	return int64(h.FrameLength()), h.stream(), true
+}
+
+// Next advances to the next frame. It returns false at the end of the
+// data or on error.
+func (sc *Scanner) Next() bool {
+	if sc.err != nil {
+		return false
+	}
+	if !sc.sync.Next() {
+		if err := sc.sync.Err(); err != nil {
+			sc.err = &gobits.FieldError{Field: "frame", Err: err}
+		}
+		return false
+	}
+	offset, length := sc.sync.Offset(), sc.sync.Length()
+	h, err := ParseHeader(gobits.NewSectionByteAccessor(sc.ba, offset, length))
+	if err != nil {
+		sc.err = err
+		return false
+	}
+	sc.frame = &Frame{
+		Offset: offset,
+		Header: h,
+		Data:   gobits.NewSectionByteAccessor(sc.ba, offset, length),
+	}
+	return true
+}
+
+// Frame returns the frame found by the last successful call to Next.
+func (sc *Scanner) Frame() *Frame {
+	return sc.frame
+}
+
+// Discarded returns the number of bytes skipped so far, in an ID3v2 tag or
+// while searching for frames.
+func (sc *Scanner) Discarded() int64 {
+	return sc.sync.Discarded()
+}
+
+func (sc *Scanner) Err() error {
+	return sc.err
+}
+
+// NewScanner returns a scanner for the frames in ba.
+func NewScanner(ba gobits.ByteAccessor) *Scanner {
+	end := ba.Length()
+	if end >= id3v1Length && string(ba.Slice(end-id3v1Length, 3)) == "TAG" {
+		end -= id3v1Length
+	}
+	sc := &Scanner{ba: gobits.NewSectionByteAccessor(ba, 0, end)}
+	sc.sync = gobits.NewFrameSync(sc.ba, syncPattern, syncPattern, syncChecks, sc.header)
+	sc.sync.Skip(sc.id3v2Length())
+	return sc
+}
@@ -0,0 +1,401 @@
+package mpegaudio
+
+import (
+	"time"
+
+	"github.com/ibbbpbbbp/gobits"
+)
+
+// Flags of a Xing or Info tag, which say which fields are present.
+const (
+	XingFlagFrames  = 0x1
+	XingFlagBytes   = 0x2
+	XingFlagTOC     = 0x4
+	XingFlagQuality = 0x8
+)
+
+const (
+	xingTOCLength = 100
+	vbriOffset    = headerLength + 32
+)
+
+// lameEncoders are the prefixes of the encoder strings of the encoders
+// known to write a LAME tag after the Xing or Info tag.
+var lameEncoders = []string{"LAME", "Lavf", "Lavc"}
+
+// LAMETag is the extension of a Xing or Info tag that LAME and FFmpeg
+// write, which gives the encoder delay and padding needed for gapless
+// playback.
+type LAMETag struct {
+	Encoder     [9]byte
+	TagRevision byte
+	VBRMethod   byte
+	// Lowpass is the lowpass filter frequency in units of 100 Hz.
+	Lowpass              byte
+	PeakSignalAmplitude  uint32
+	RadioReplayGain      uint16
+	AudiophileReplayGain uint16
+	EncodingFlags        byte
+	ATHType              byte
+	// Bitrate is the average bitrate of ABR streams, or the minimal or
+	// constant bitrate of others, in kbit/s.
+	Bitrate byte
+	// EncoderDelay and EncoderPadding count the samples that the encoder
+	// added at the start and end of the stream.
+	EncoderDelay          uint16
+	EncoderPadding        uint16
+	NoiseShaping          byte
+	StereoMode            byte
+	Unwise                bool
+	SourceSampleFrequency byte
+	MP3Gain               int8
+	Surround              byte
+	Preset                uint16
+	// MusicLength is the length of the stream in bytes, tag frame
+	// included.
+	MusicLength uint32
+	MusicCRC    uint16
+	// TagCRC covers the frame up to itself. Marshal writes it as is.
+	TagCRC uint16
+}
+
+func (t *LAMETag) syntax(s *gobits.Syntax) {
+	for i := range t.Encoder {
+		s.Byte(&t.Encoder[i], 8, "encoder")
+	}
+	s.Byte(&t.TagRevision, 4, "info_tag_revision")
+	s.Byte(&t.VBRMethod, 4, "vbr_method")
+	s.Byte(&t.Lowpass, 8, "lowpass_filter_value")
+	s.Bits(&t.PeakSignalAmplitude, 32, "peak_signal_amplitude")
+	s.Bits16(&t.RadioReplayGain, 16, "radio_replay_gain")
+	s.Bits16(&t.AudiophileReplayGain, 16, "audiophile_replay_gain")
+	s.Byte(&t.EncodingFlags, 4, "encoding_flags")
+	s.Byte(&t.ATHType, 4, "ath_type")
+	s.Byte(&t.Bitrate, 8, "bitrate")
+	s.Bits16(&t.EncoderDelay, 12, "encoder_delay")
+	s.Bits16(&t.EncoderPadding, 12, "encoder_padding")
+	s.Byte(&t.NoiseShaping, 2, "noise_shaping")
+	s.Byte(&t.StereoMode, 3, "stereo_mode")
+	s.Flag(&t.Unwise, "unwise_settings")
+	s.Byte(&t.SourceSampleFrequency, 2, "source_sample_frequency")
+	gain := int32(t.MP3Gain)
+	s.SignedBits(&gain, 8, "mp3_gain")
+	t.MP3Gain = int8(gain)
+	unused := byte(0)
+	s.Byte(&unused, 2, "unused")
+	s.Byte(&t.Surround, 3, "surround_info")
+	s.Bits16(&t.Preset, 11, "preset")
+	s.Bits(&t.MusicLength, 32, "music_length")
+	s.Bits16(&t.MusicCRC, 16, "music_crc")
+	s.Bits16(&t.TagCRC, 16, "info_tag_crc")
+}
+
+// XingTag is the Xing tag of a VBR stream, or the Info tag of a CBR
+// stream, that takes the place of audio data in the first layer III
+// frame.
+type XingTag struct {
+	// Info is set for an Info tag.
+	Info  bool
+	Flags uint32
+	// Frames is the number of frames in the stream, tag frame excluded.
+	Frames uint32
+	// Bytes is the length of the stream in bytes, tag frame included.
+	Bytes uint32
+	// TOC maps each percent of the duration to a position in the stream,
+	// in units of Bytes/256.
+	TOC     [xingTOCLength]byte
+	Quality uint32
+	// LAME is the LAME tag that follows, if any.
+	LAME *LAMETag
+}
+
+func (x *XingTag) syntax(s *gobits.Syntax) {
+	id := []byte("Xing")
+	if x.Info {
+		id = []byte("Info")
+	}
+	for i := range id {
+		s.Byte(&id[i], 8, "tag_id")
+	}
+	switch string(id) {
+	case "Xing":
+		x.Info = false
+	case "Info":
+		x.Info = true
+	default:
+		s.Fail("tag_id", gobits.ErrInvalidSyntax)
+		return
+	}
+	s.Bits(&x.Flags, 32, "flags")
+	if x.Flags&XingFlagFrames != 0 {
+		s.Bits(&x.Frames, 32, "frames")
+	}
+	if x.Flags&XingFlagBytes != 0 {
+		s.Bits(&x.Bytes, 32, "bytes")
+	}
+	if x.Flags&XingFlagTOC != 0 {
+		for i := range x.TOC {
+			s.Byte(&x.TOC[i], 8, "toc")
+		}
+	}
+	if x.Flags&XingFlagQuality != 0 {
+		s.Bits(&x.Quality, 32, "quality")
+	}
+	if s.Err() != nil {
+		return
+	}
+	if s.Reading() && isLAME(s.BitStream()) {
+		x.LAME = &LAMETag{}
+	}
+	if x.LAME != nil {
+		x.LAME.syntax(s)
+	}
+}
+
+// isLAME reports whether bs goes on with the encoder string of a LAME
+// tag. It consumes nothing.
+func isLAME(bs *gobits.BitStream) bool {
+	if !bs.RemainingBits(32) {
+		return false
+	}
+	v, _ := bs.PeekBits(32)
+	for _, encoder := range lameEncoders {
+		if string([]byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}) == encoder {
+			return true
+		}
+	}
+	return false
+}
+
+// xingOffset returns the offset of the Xing tag from the start of the
+// frame, after the side information. As in the encoders that write it,
+// the CRC of a protected frame is not accounted for.
+func xingOffset(h *Header) int64 {
+	return int64(headerLength + h.sideInfoLength())
+}
+
+// ParseXingTag parses the Xing or Info tag in f, and the LAME tag after
+// it, whose CRC it checks. It returns nil if f has no such tag.
+func ParseXingTag(f *Frame) (*XingTag, error) {
+	offset := xingOffset(f.Header)
+	if f.Header.Layer != LayerIII || !hasID(f.Data, offset, "Xing", "Info") {
+		return nil, nil
+	}
+	bs := gobits.NewBitStream(gobits.NewSectionByteAccessor(f.Data, offset, f.Data.Length()-offset))
+	s := gobits.NewReadingSyntax(bs)
+	x := &XingTag{}
+	x.syntax(s)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	if x.LAME != nil {
+		// The CRC covers the frame up to itself.
+		if lameCRC(f.Data.Slice(0, offset+bs.Tell()/8-2)) != x.LAME.TagCRC {
+			return nil, &gobits.FieldError{Field: "info_tag_crc", Err: gobits.ErrChecksum}
+		}
+	}
+	return x, nil
+}
+
+// Marshal encodes the tag, and the LAME tag after it, to be put at the
+// offset of the Xing tag in the frame.
+func (x *XingTag) Marshal() ([]byte, error) {
+	ba := gobits.NewGrowableByteAccessor(nil)
+	s := gobits.NewWritingSyntax(gobits.NewBitStream(ba))
+	x.syntax(s)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	return ba.Bytes(), nil
+}
+
+// Duration returns the duration of the stream whose first frame has
+// header h, from Frames and, if there is a LAME tag, the encoder delay and
+// padding. It returns 0 if Frames is not present.
+func (x *XingTag) Duration(h *Header) time.Duration {
+	if x.Flags&XingFlagFrames == 0 || h.SampleRate() == 0 {
+		return 0
+	}
+	samples := int64(x.Frames) * int64(h.SamplesPerFrame())
+	if x.LAME != nil {
+		samples -= int64(x.LAME.EncoderDelay) + int64(x.LAME.EncoderPadding)
+		if samples < 0 {
+			samples = 0
+		}
+	}
+	return time.Duration(samples) * time.Second / time.Duration(h.SampleRate())
+}
+
+// SeekOffset returns the offset, from the start of the tag frame, of the
+// point at fraction of the duration of the stream, interpolating the TOC.
+// It returns false if the TOC or Bytes is not present.
+func (x *XingTag) SeekOffset(fraction float64) (int64, bool) {
+	if x.Flags&(XingFlagTOC|XingFlagBytes) != XingFlagTOC|XingFlagBytes {
+		return 0, false
+	}
+	percent := fraction * 100
+	if percent < 0 {
+		percent = 0
+	} else if percent > 100 {
+		percent = 100
+	}
+	i := int(percent)
+	if i > xingTOCLength-1 {
+		i = xingTOCLength - 1
+	}
+	before := float64(x.TOC[i])
+	after := 256.0
+	if i < xingTOCLength-1 {
+		after = float64(x.TOC[i+1])
+	}
+	position := before + (after-before)*(percent-float64(i))
+	return int64(position / 256 * float64(x.Bytes)), true
+}
+
+// VBRITag is the tag that the Fraunhofer encoder puts in the first layer
+// III frame, after 32 bytes of side information whatever the mode.
+type VBRITag struct {
+	Version uint16
+	Delay   uint16
+	Quality uint16
+	// Bytes is the length of the stream in bytes, tag frame included.
+	Bytes uint32
+	// Frames is the number of frames in the stream, tag frame excluded.
+	Frames       uint32
+	TOCScale     uint16
+	TOCEntrySize uint16
+	// FramesPerEntry is the number of frames that each TOC entry covers.
+	FramesPerEntry uint16
+	// TOC holds the length of each run of FramesPerEntry frames in bytes,
+	// divided by TOCScale.
+	TOC []uint32
+}
+
+func (v *VBRITag) syntax(s *gobits.Syntax) {
+	id := []byte("VBRI")
+	for i := range id {
+		s.Byte(&id[i], 8, "tag_id")
+	}
+	if s.Err() == nil && string(id) != "VBRI" {
+		s.Fail("tag_id", gobits.ErrInvalidSyntax)
+		return
+	}
+	s.Bits16(&v.Version, 16, "version")
+	s.Bits16(&v.Delay, 16, "delay")
+	s.Bits16(&v.Quality, 16, "quality")
+	s.Bits(&v.Bytes, 32, "bytes")
+	s.Bits(&v.Frames, 32, "frames")
+	entries := uint16(len(v.TOC))
+	if !s.Reading() && len(v.TOC) > 0xffff {
+		s.Fail("toc_entries", gobits.ErrOutOfRange)
+		return
+	}
+	s.Bits16(&entries, 16, "toc_entries")
+	s.Bits16(&v.TOCScale, 16, "toc_scale")
+	s.Bits16(&v.TOCEntrySize, 16, "toc_entry_size")
+	s.Bits16(&v.FramesPerEntry, 16, "toc_frames_per_entry")
+	if s.Err() == nil && (v.TOCEntrySize == 0 || v.TOCEntrySize > 4) {
+		s.Fail("toc_entry_size", gobits.ErrInvalidSyntax)
+	}
+	if s.Err() != nil {
+		return
+	}
+	if s.Reading() {
+		if !s.BitStream().RemainingBits(int64(entries) * int64(v.TOCEntrySize) * 8) {
+			s.Fail("toc_entries", gobits.ErrUnexpectedEOF)
+			return
+		}
+		v.TOC = make([]uint32, entries)
+	}
+	for i := range v.TOC {
+		s.Bits(&v.TOC[i], byte(v.TOCEntrySize*8), "toc")
+	}
+}
+
+// ParseVBRITag parses the VBRI tag in f. It returns nil if f has no such
+// tag.
+func ParseVBRITag(f *Frame) (*VBRITag, error) {
+	if f.Header.Layer != LayerIII || !hasID(f.Data, vbriOffset, "VBRI") {
+		return nil, nil
+	}
+	s := gobits.NewReadingSyntax(gobits.NewBitStream(
+		gobits.NewSectionByteAccessor(f.Data, vbriOffset, f.Data.Length()-vbriOffset)))
+	v := &VBRITag{}
+	v.syntax(s)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	return v, nil
+}
+
+// Marshal encodes the tag, to be put 36 bytes into the frame.
+func (v *VBRITag) Marshal() ([]byte, error) {
+	ba := gobits.NewGrowableByteAccessor(nil)
+	s := gobits.NewWritingSyntax(gobits.NewBitStream(ba))
+	v.syntax(s)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	return ba.Bytes(), nil
+}
+
+// Duration returns the duration of the stream whose first frame has
+// header h.
+func (v *VBRITag) Duration(h *Header) time.Duration {
+	if h.SampleRate() == 0 {
+		return 0
+	}
+	samples := int64(v.Frames) * int64(h.SamplesPerFrame())
+	return time.Duration(samples) * time.Second / time.Duration(h.SampleRate())
+}
+
+// SeekOffset returns the offset, from the start of the tag frame, of the
+// point at fraction of the duration of the stream, interpolating within
+// the TOC entry that covers it. It returns false if there is no TOC.
+func (v *VBRITag) SeekOffset(fraction float64) (int64, bool) {
+	if len(v.TOC) == 0 || v.FramesPerEntry == 0 {
+		return 0, false
+	}
+	if fraction < 0 {
+		fraction = 0
+	} else if fraction > 1 {
+		fraction = 1
+	}
+	entry := fraction * float64(v.Frames) / float64(v.FramesPerEntry)
+	offset := int64(0)
+	for i, length := range v.TOC {
+		length := int64(length) * int64(v.TOCScale)
+		if float64(i+1) > entry {
+			return offset + int64(float64(length)*(entry-float64(i))), true
+		}
+		offset += length
+	}
+	return offset, true
+}
+
+// hasID reports whether ba holds one of ids at byteOffset.
+func hasID(ba gobits.ByteAccessor, byteOffset int64, ids ...string) bool {
+	data := string(ba.Slice(byteOffset, 4))
+	for _, id := range ids {
+		if data == id {
+			return true
+		}
+	}
+	return false
+}
+
+// lameCRC computes the CRC-16/ARC that the LAME tag carries.
+func lameCRC(data []byte) uint16 {
+	crc := uint16(0)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xa001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
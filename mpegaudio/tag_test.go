@@ -0,0 +1,133 @@
+package mpegaudio
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/stretchr/testify/assert"
+)
+
+// tagFrame returns a frame with mp3Header that holds tag at offset.
+func tagFrame(t *testing.T, tag []byte, offset int) *Frame {
+	data := mp3Frame(false)
+	copy(data[offset:], tag)
+	h, err := ParseHeader(gobits.NewSliceByteAccessor(data))
+	assert.NoError(t, err)
+	return &Frame{
+		Header: h,
+		Data:   gobits.NewSectionByteAccessor(gobits.NewSliceByteAccessor(data), 0, int64(len(data))),
+	}
+}
+
+func TestParseXingTag(t *testing.T) {
+	x := &XingTag{
+		Flags:   XingFlagFrames | XingFlagBytes | XingFlagTOC | XingFlagQuality,
+		Frames:  1000,
+		Bytes:   256000,
+		Quality: 78,
+		LAME: &LAMETag{
+			TagRevision:    1,
+			VBRMethod:      4,
+			Lowpass:        195,
+			EncodingFlags:  0xf,
+			ATHType:        4,
+			Bitrate:        128,
+			EncoderDelay:   576,
+			EncoderPadding: 1104,
+			StereoMode:     3,
+			MP3Gain:        -2,
+			Preset:         1001,
+			MusicLength:    256000,
+			MusicCRC:       0xbeef,
+		},
+	}
+	copy(x.LAME.Encoder[:], "LAME3.100")
+	for i := range x.TOC {
+		x.TOC[i] = byte(i * 256 / 100)
+	}
+	data, err := x.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, 120+36, len(data))
+	assert.Equal(t, "Xing", string(data[:4]))
+
+	// The CRC covers the frame up to itself.
+	f := tagFrame(t, data, 36)
+	x.LAME.TagCRC = lameCRC(f.Data.Slice(0, 36+int64(len(data))-2))
+	data, err = x.Marshal()
+	assert.NoError(t, err)
+	f = tagFrame(t, data, 36)
+
+	parsed, err := ParseXingTag(f)
+	assert.NoError(t, err)
+	assert.Equal(t, x, parsed)
+	assert.Equal(t, time.Duration(1000*1152-576-1104)*time.Second/44100, parsed.Duration(f.Header))
+	offset, ok := parsed.SeekOffset(0.5)
+	assert.True(t, ok)
+	assert.Equal(t, int64(128000), offset)
+	offset, ok = parsed.SeekOffset(1)
+	assert.True(t, ok)
+	assert.Equal(t, int64(256000), offset)
+
+	data[50] ^= 0x01
+	_, err = ParseXingTag(tagFrame(t, data, 36))
+	var fe *gobits.FieldError
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "info_tag_crc", fe.Field)
+	assert.True(t, errors.Is(err, gobits.ErrChecksum))
+
+	// An Info tag without LAME tag and TOC.
+	x = &XingTag{Info: true, Flags: XingFlagFrames, Frames: 10}
+	data, err = x.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{'I', 'n', 'f', 'o', 0, 0, 0, 1, 0, 0, 0, 10}, data)
+	parsed, err = ParseXingTag(tagFrame(t, data, 36))
+	assert.NoError(t, err)
+	assert.Equal(t, x, parsed)
+	_, ok = parsed.SeekOffset(0.5)
+	assert.False(t, ok)
+
+	parsed, err = ParseXingTag(tagFrame(t, nil, 36))
+	assert.NoError(t, err)
+	assert.Nil(t, parsed)
+}
+
+func TestParseVBRITag(t *testing.T) {
+	v := &VBRITag{
+		Version:        1,
+		Delay:          0x3f00,
+		Quality:        75,
+		Bytes:          10000,
+		Frames:         100,
+		TOCScale:       1,
+		TOCEntrySize:   2,
+		FramesPerEntry: 25,
+		TOC:            []uint32{1000, 2000, 3000, 4000},
+	}
+	data, err := v.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, 26+4*2, len(data))
+	f := tagFrame(t, data, vbriOffset)
+
+	parsed, err := ParseVBRITag(f)
+	assert.NoError(t, err)
+	assert.Equal(t, v, parsed)
+	assert.Equal(t, time.Duration(100*1152)*time.Second/44100, parsed.Duration(f.Header))
+	for fraction, want := range map[float64]int64{0: 0, 0.25: 1000, 0.375: 2000, 0.5: 3000, 1: 10000} {
+		offset, ok := parsed.SeekOffset(fraction)
+		assert.True(t, ok)
+		assert.Equal(t, want, offset, fraction)
+	}
+
+	var fe *gobits.FieldError
+	data[22] = 0
+	data[23] = 5
+	_, err = ParseVBRITag(tagFrame(t, data, vbriOffset))
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "toc_entry_size", fe.Field)
+
+	parsed, err = ParseVBRITag(tagFrame(t, nil, vbriOffset))
+	assert.NoError(t, err)
+	assert.Nil(t, parsed)
+}
@@ -0,0 +1,111 @@
+// Package mpegts reads and writes MPEG-2 transport stream packets, the PSI
+// and SI sections carried in them and the headers of PES packets and
+// program stream packs, as specified in ISO/IEC 13818-1 and ETSI EN 300
+// 468.
+//
+// Packets, sections and headers are described once as a gobits.Syntax, so
+// parsing one and marshalling the result reproduces it bit for bit,
+// reserved bits aside, which are always written as ones. Fields are named
+// after the syntax elements of the specifications.
+package mpegts
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+)
+
+// Packet sizes. M2TS packets, as found on Blu-ray discs, prefix each
+// transport packet with a 4-byte TP_extra_header; DVB-ASI and some
+// satellite captures append 16 bytes of Reed-Solomon parity.
+const (
+	PacketSize     = 188
+	M2TSPacketSize = 192
+	FECPacketSize  = 204
+
+	SyncByte = 0x47
+)
+
+// Well-known PIDs.
+const (
+	PIDPAT  = 0x0000
+	PIDCAT  = 0x0001
+	PIDTSDT = 0x0002
+	PIDNIT  = 0x0010
+	PIDSDT  = 0x0011
+	PIDEIT  = 0x0012
+	PIDNull = 0x1fff
+)
+
+// Table IDs.
+const (
+	TableIDPAT       = 0x00
+	TableIDCAT       = 0x01
+	TableIDPMT       = 0x02
+	TableIDSDTActual = 0x42
+	TableIDSDTOther  = 0x46
+	tableIDStuffing  = 0xff
+)
+
+const (
+	headerSize   = 4
+	payloadSize  = PacketSize - headerSize
+	m2tsPrefix   = M2TSPacketSize - PacketSize
+	stuffingByte = 0xff
+)
+
+// reserved handles reserved bits, which are written as ones and ignored
+// when read.
+func reserved(s *gobits.Syntax, bitCount byte) {
+	ones := uint32(1)<<bitCount - 1
+	s.Bits(&ones, bitCount, "reserved")
+}
+
+// byteString handles a run of length bytes.
+func byteString(s *gobits.Syntax, val *[]byte, length int, field string) {
+	if s.Reading() {
+		*val = make([]byte, length)
+	} else if len(*val) != length {
+		s.Fail(field, gobits.ErrOutOfRange)
+		return
+	}
+	for i := range *val {
+		s.Byte(&(*val)[i], 8, field)
+	}
+}
+
+// lengthPrefixed handles a byte string preceded by its length in an
+// 8-bit field.
+func lengthPrefixed(s *gobits.Syntax, val *[]byte, lengthField, field string) {
+	length := byte(len(*val))
+	if !s.Reading() && len(*val) > 0xff {
+		s.Fail(lengthField, gobits.ErrOutOfRange)
+		return
+	}
+	s.Byte(&length, 8, lengthField)
+	byteString(s, val, int(length), field)
+}
+
+var crcTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		crc := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+// CRC32 computes the CRC-32/MPEG-2 of data, which sections carry in their
+// CRC_32 field. Computed over a whole section, CRC_32 included, it is zero.
+func CRC32(data []byte) uint32 {
+	crc := uint32(0xffffffff)
+	for _, b := range data {
+		crc = crc<<8 ^ crcTable[byte(crc>>24)^b]
+	}
+	return crc
+}
@@ -0,0 +1,209 @@
+package mpegts
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/stretchr/testify/assert"
+)
+
+// patSection is the PAT that FFmpeg writes: transport stream 1 with
+// program 1, whose PMT is on PID 0x1000.
+var patSection = []byte{
+	0x00, 0xb0, 0x0d, 0x00, 0x01, 0xc1, 0x00, 0x00, 0x00, 0x01, 0xf0, 0x00,
+	0x2a, 0xb1, 0x04, 0xb2,
+}
+
+func patPacket() []byte {
+	p := append([]byte{0x47, 0x40, 0x00, 0x10, 0x00}, patSection...)
+	return append(p, bytes.Repeat([]byte{0xff}, PacketSize-len(p))...)
+}
+
+// pcrPacket returns a packet of PID 0x100 with a PCR and a payload of
+// zeros.
+func pcrPacket(continuityCounter byte) []byte {
+	p := []byte{0x47, 0x01, 0x00, 0x30 | continuityCounter, 0x07, 0x50, 0x91, 0xa2, 0xb3, 0xc4, 0xfe, 0x12}
+	return append(p, make([]byte, PacketSize-len(p))...)
+}
+
+func TestCRC32(t *testing.T) {
+	assert.Equal(t, uint32(0x2ab104b2), CRC32(patSection[:len(patSection)-4]))
+	assert.Equal(t, uint32(0), CRC32(patSection))
+}
+
+func TestParsePacket(t *testing.T) {
+	data := patPacket()
+	p, err := ParsePacket(gobits.NewSliceByteAccessor(data))
+	assert.NoError(t, err)
+	assert.Equal(t, Header{PayloadUnitStartIndicator: true, PID: PIDPAT, AdaptationFieldControl: 1}, p.Header)
+	assert.Nil(t, p.AdaptationField)
+	assert.Equal(t, int64(payloadSize), p.Payload.Length())
+	marshalled, err := p.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, data, marshalled)
+
+	data = pcrPacket(5)
+	p, err = ParsePacket(gobits.NewSliceByteAccessor(data))
+	assert.NoError(t, err)
+	assert.Equal(t, Header{PID: 0x100, AdaptationFieldControl: 3, ContinuityCounter: 5}, p.Header)
+	assert.Equal(t, &AdaptationField{
+		RandomAccessIndicator: true,
+		PCRFlag:               true,
+		PCR:                   0x123456789*300 + 18,
+	}, p.AdaptationField)
+	assert.Equal(t, int64(PacketSize-12), p.Payload.Length())
+	marshalled, err = p.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, data, marshalled)
+
+	// A short payload is preceded by adaptation field stuffing.
+	p = &Packet{
+		Header: Header{PID: 0x101, ContinuityCounter: 3},
+		AdaptationField: &AdaptationField{
+			DiscontinuityIndicator:   true,
+			SplicingPointFlag:        true,
+			SpliceCountdown:          -2,
+			TransportPrivateDataFlag: true,
+			TransportPrivateData:     []byte{0xde, 0xad},
+		},
+		Payload: gobits.NewSliceByteAccessor([]byte{1, 2, 3}),
+	}
+	marshalled, err = p.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, PacketSize, len(marshalled))
+	assert.Equal(t, []byte{0x47, 0x01, 0x01, 0x33, 180, 0x86, 0xfe, 0x02, 0xde, 0xad, 0xff}, marshalled[:11])
+	assert.Equal(t, []byte{0xff, 1, 2, 3}, marshalled[PacketSize-4:])
+	parsed, err := ParsePacket(gobits.NewSliceByteAccessor(marshalled))
+	assert.NoError(t, err)
+	assert.Equal(t, p.AdaptationField, parsed.AdaptationField)
+	assert.Equal(t, []byte{1, 2, 3}, parsed.Payload.Slice(0, parsed.Payload.Length()))
+
+	// A single byte of stuffing is an adaptation_field_length of zero.
+	p = &Packet{Header: Header{PID: 0x101}, Payload: gobits.NewSliceByteAccessor(make([]byte, payloadSize-1))}
+	marshalled, err = p.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x47, 0x01, 0x01, 0x30, 0x00, 0x00}, marshalled[:6])
+	parsed, err = ParsePacket(gobits.NewSliceByteAccessor(marshalled))
+	assert.NoError(t, err)
+	assert.Equal(t, &AdaptationField{}, parsed.AdaptationField)
+
+	p.AdaptationField = &AdaptationField{PCRFlag: true}
+	_, err = p.Marshal()
+	var fe *gobits.FieldError
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "adaptation_field_length", fe.Field)
+	assert.True(t, errors.Is(err, gobits.ErrOutOfRange))
+
+	data = pcrPacket(0)
+	data[4] = 183
+	_, err = ParsePacket(gobits.NewSliceByteAccessor(data))
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "adaptation_field_length", fe.Field)
+
+	data = pcrPacket(0)
+	data[0] = 0x48
+	_, err = ParsePacket(gobits.NewSliceByteAccessor(data))
+	assert.True(t, errors.Is(err, gobits.ErrInvalidSyntax))
+
+	_, err = ParsePacket(gobits.NewSliceByteAccessor(data[:PacketSize-1]))
+	assert.True(t, errors.Is(err, gobits.ErrUnexpectedEOF))
+}
+
+func TestScanner(t *testing.T) {
+	var data []byte
+	data = append(data, 0x00, 0x01, 0x02, 0x03, 0x04)
+	data = append(data, patPacket()...)
+	data = append(data, pcrPacket(0)...)
+	data = append(data, pcrPacket(1)...)
+	data = append(data, make([]byte, 37)...)
+	data = append(data, patPacket()...)
+	data = append(data, pcrPacket(2)...)
+
+	sc := NewScanner(gobits.NewSliceByteAccessor(data), 0)
+	offsets := []int64{}
+	pids := []uint16{}
+	for sc.Next() {
+		offsets = append(offsets, sc.Packet().Offset)
+		pids = append(pids, sc.Packet().Header.PID)
+	}
+	assert.NoError(t, sc.Err())
+	assert.Equal(t, []int64{5, 5 + 188, 5 + 2*188, 5 + 3*188 + 37, 5 + 4*188 + 37}, offsets)
+	assert.Equal(t, []uint16{PIDPAT, 0x100, 0x100, PIDPAT, 0x100}, pids)
+	assert.Equal(t, PacketSize, sc.PacketSize())
+	assert.Equal(t, int64(42), sc.Discarded())
+
+	f, err := ioutil.TempFile("", "mpegts")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+	_, err = f.Write(data)
+	assert.NoError(t, err)
+	sc = NewScanner(gobits.NewIOByteAccessor(f), PacketSize)
+	count := 0
+	for sc.Next() {
+		count++
+	}
+	assert.NoError(t, sc.Err())
+	assert.Equal(t, 5, count)
+	assert.Equal(t, int64(42), sc.Discarded())
+
+	sc = NewScanner(gobits.NewSliceByteAccessor(data[:len(data)-1]), PacketSize)
+	for sc.Next() {
+	}
+	assert.True(t, errors.Is(sc.Err(), gobits.ErrUnexpectedEOF))
+
+	assert.Error(t, NewScanner(gobits.NewSliceByteAccessor(data), 200).Err())
+}
+
+func TestScanner_PacketSizes(t *testing.T) {
+	for _, size := range []int{M2TSPacketSize, FECPacketSize} {
+		var data []byte
+		for i := byte(0); i < 4; i++ {
+			if size == M2TSPacketSize {
+				data = append(data, 0x12, 0x34, 0x56, i)
+			}
+			data = append(data, pcrPacket(i)...)
+			if size == FECPacketSize {
+				data = append(data, bytes.Repeat([]byte{i}, FECPacketSize-PacketSize)...)
+			}
+		}
+
+		sc := NewScanner(gobits.NewSliceByteAccessor(data), 0)
+		counters := []byte{}
+		for sc.Next() {
+			counters = append(counters, sc.Packet().Header.ContinuityCounter)
+		}
+		assert.NoError(t, sc.Err())
+		assert.Equal(t, size, sc.PacketSize())
+		assert.Equal(t, []byte{0, 1, 2, 3}, counters)
+		assert.Equal(t, int64(0), sc.Discarded())
+	}
+}
+
+func TestPacket_RewriteInPlace(t *testing.T) {
+	data := append(patPacket(), pcrPacket(0)...)
+	ba := gobits.NewSliceByteAccessor(data)
+	sc := NewScanner(ba, PacketSize)
+	for sc.Next() {
+		p := sc.Packet()
+		if p.AdaptationField == nil || !p.AdaptationField.PCRFlag {
+			continue
+		}
+		p.Header.PID = 0x200
+		p.AdaptationField.PCR += 2 * SystemClockFrequency
+		marshalled, err := p.Marshal()
+		assert.NoError(t, err)
+		assert.True(t, ba.Put(marshalled, p.Offset))
+	}
+	assert.NoError(t, sc.Err())
+
+	assert.Equal(t, patPacket(), data[:PacketSize])
+	p, err := ParsePacket(gobits.NewSectionByteAccessor(ba, PacketSize, PacketSize))
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(0x200), p.Header.PID)
+	assert.Equal(t, uint64(0x123456789*300+18+2*SystemClockFrequency), p.AdaptationField.PCR)
+}
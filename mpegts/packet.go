@@ -0,0 +1,249 @@
+package mpegts
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+)
+
+const (
+	// SystemClockFrequency is the frequency of the system clock that PCR
+	// values count, 27 MHz.
+	SystemClockFrequency = 27000000
+
+	pcrExtensionModulus = 300
+)
+
+// Header is the 4-byte header of a transport packet.
+type Header struct {
+	TransportErrorIndicator    bool
+	PayloadUnitStartIndicator  bool
+	TransportPriority          bool
+	PID                        uint16
+	TransportScramblingControl byte
+	// AdaptationFieldControl is recomputed by Packet.Marshal from the
+	// presence of the adaptation field and the payload.
+	AdaptationFieldControl byte
+	ContinuityCounter      byte
+}
+
+func (h *Header) HasAdaptationField() bool {
+	return h.AdaptationFieldControl&0x2 != 0
+}
+
+func (h *Header) HasPayload() bool {
+	return h.AdaptationFieldControl&0x1 != 0
+}
+
+func (h *Header) syntax(s *gobits.Syntax) {
+	syncByte := byte(SyncByte)
+	s.Byte(&syncByte, 8, "sync_byte")
+	if s.Err() == nil && syncByte != SyncByte {
+		s.Fail("sync_byte", gobits.ErrInvalidSyntax)
+	}
+	s.Flag(&h.TransportErrorIndicator, "transport_error_indicator")
+	s.Flag(&h.PayloadUnitStartIndicator, "payload_unit_start_indicator")
+	s.Flag(&h.TransportPriority, "transport_priority")
+	s.Bits16(&h.PID, 13, "PID")
+	s.Byte(&h.TransportScramblingControl, 2, "transport_scrambling_control")
+	s.Byte(&h.AdaptationFieldControl, 2, "adaptation_field_control")
+	s.Byte(&h.ContinuityCounter, 4, "continuity_counter")
+}
+
+// AdaptationField is an adaptation_field() without its length and
+// stuffing bytes, which Packet.Marshal derives from the payload size.
+type AdaptationField struct {
+	DiscontinuityIndicator            bool
+	RandomAccessIndicator             bool
+	ElementaryStreamPriorityIndicator bool
+	PCRFlag                           bool
+	OPCRFlag                          bool
+	SplicingPointFlag                 bool
+	TransportPrivateDataFlag          bool
+	AdaptationFieldExtensionFlag      bool
+	// PCR and OPCR count the 27 MHz system clock: the base times 300 plus
+	// the extension.
+	PCR                  uint64
+	OPCR                 uint64
+	SpliceCountdown      int32
+	TransportPrivateData []byte
+	// Extension holds the bytes of adaptation_field_extension() after
+	// adaptation_field_extension_length, undecoded.
+	Extension []byte
+}
+
+// pcr handles a program_clock_reference_base, 6 reserved bits and a
+// program_clock_reference_extension as one 27 MHz count.
+func pcr(s *gobits.Syntax, val *uint64, field string) {
+	base := *val / pcrExtensionModulus
+	extension := uint32(*val % pcrExtensionModulus)
+	if !s.Reading() && base >= 1<<33 {
+		s.Fail(field, gobits.ErrOutOfRange)
+		return
+	}
+	s.Bits64(&base, 33, field+"_base")
+	reserved(s, 6)
+	s.Bits(&extension, 9, field+"_extension")
+	if !s.Reading() || s.Err() != nil {
+		return
+	}
+	if extension >= pcrExtensionModulus {
+		s.Fail(field+"_extension", gobits.ErrOutOfRange)
+		return
+	}
+	*val = base*pcrExtensionModulus + uint64(extension)
+}
+
+// empty reports whether the adaptation field has no flag set, so that it
+// can be coded as a lone adaptation_field_length of zero.
+func (af *AdaptationField) empty() bool {
+	return !af.DiscontinuityIndicator && !af.RandomAccessIndicator &&
+		!af.ElementaryStreamPriorityIndicator && !af.PCRFlag && !af.OPCRFlag &&
+		!af.SplicingPointFlag && !af.TransportPrivateDataFlag &&
+		!af.AdaptationFieldExtensionFlag
+}
+
+func (af *AdaptationField) syntax(s *gobits.Syntax) {
+	s.Flag(&af.DiscontinuityIndicator, "discontinuity_indicator")
+	s.Flag(&af.RandomAccessIndicator, "random_access_indicator")
+	s.Flag(&af.ElementaryStreamPriorityIndicator, "elementary_stream_priority_indicator")
+	s.Flag(&af.PCRFlag, "PCR_flag")
+	s.Flag(&af.OPCRFlag, "OPCR_flag")
+	s.Flag(&af.SplicingPointFlag, "splicing_point_flag")
+	s.Flag(&af.TransportPrivateDataFlag, "transport_private_data_flag")
+	s.Flag(&af.AdaptationFieldExtensionFlag, "adaptation_field_extension_flag")
+	if af.PCRFlag {
+		pcr(s, &af.PCR, "program_clock_reference")
+	}
+	if af.OPCRFlag {
+		pcr(s, &af.OPCR, "original_program_clock_reference")
+	}
+	if af.SplicingPointFlag {
+		s.SignedBits(&af.SpliceCountdown, 8, "splice_countdown")
+	}
+	if af.TransportPrivateDataFlag {
+		lengthPrefixed(s, &af.TransportPrivateData, "transport_private_data_length", "private_data_byte")
+	}
+	if af.AdaptationFieldExtensionFlag {
+		lengthPrefixed(s, &af.Extension, "adaptation_field_extension_length", "adaptation_field_extension")
+	}
+}
+
+// length returns the number of bytes that the adaptation field needs after
+// adaptation_field_length.
+func (af *AdaptationField) length() (int64, error) {
+	ba := gobits.NewGrowableByteAccessor(nil)
+	s := gobits.NewWritingSyntax(gobits.NewBitStream(ba))
+	af.syntax(s)
+	return ba.Length(), s.Err()
+}
+
+// Packet is a 188-byte transport packet. AdaptationField is nil when the
+// packet has none, and Payload when it carries no payload.
+type Packet struct {
+	// Offset is the position of the sync byte in the scanned data.
+	Offset          int64
+	Header          Header
+	AdaptationField *AdaptationField
+	Payload         gobits.ByteAccessor
+}
+
+// ParsePacket parses the transport packet at the start of ba. The payload
+// shares ba.
+func ParsePacket(ba gobits.ByteAccessor) (*Packet, error) {
+	bs := gobits.NewBitStream(ba)
+	s := gobits.NewReadingSyntax(bs)
+	if ba.Length() < PacketSize {
+		s.Fail("transport_packet", gobits.ErrUnexpectedEOF)
+		return nil, s.Err()
+	}
+
+	p := &Packet{}
+	p.Header.syntax(s)
+	offset := int64(headerSize)
+	if s.Err() == nil && p.Header.HasAdaptationField() {
+		length := byte(0)
+		s.Byte(&length, 8, "adaptation_field_length")
+		maxLength := byte(payloadSize - 1)
+		if p.Header.HasPayload() {
+			maxLength--
+		}
+		if s.Err() == nil && length > maxLength {
+			s.Fail("adaptation_field_length", gobits.ErrOutOfRange)
+		}
+		p.AdaptationField = &AdaptationField{}
+		offset += 1 + int64(length)
+		if length > 0 {
+			p.AdaptationField.syntax(s)
+		}
+		if s.Err() == nil && bs.Tell() > offset*8 {
+			s.Fail("adaptation_field_length", gobits.ErrInvalidSyntax)
+		}
+	}
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	if p.Header.HasPayload() {
+		p.Payload = gobits.NewSectionByteAccessor(ba, offset, PacketSize-offset)
+	}
+	return p, nil
+}
+
+// Marshal encodes the packet in 188 bytes. adaptation_field_control
+// follows from AdaptationField and Payload, and a payload shorter than 184
+// bytes is preceded by adaptation field stuffing, adding an empty
+// adaptation field if there is none. To rewrite a scanned packet in place,
+// put the result at Offset.
+func (p *Packet) Marshal() ([]byte, error) {
+	payload := []byte{}
+	if p.Payload != nil {
+		payload = p.Payload.Slice(0, p.Payload.Length())
+	}
+	space := int64(payloadSize - len(payload))
+
+	h := p.Header
+	h.AdaptationFieldControl = 0
+	if p.Payload != nil {
+		h.AdaptationFieldControl |= 0x1
+	}
+	af := p.AdaptationField
+	if af == nil && space > 0 {
+		af = &AdaptationField{}
+	}
+	if af != nil {
+		h.AdaptationFieldControl |= 0x2
+	}
+
+	ba := gobits.NewGrowableByteAccessor(nil)
+	s := gobits.NewWritingSyntax(gobits.NewBitStream(ba))
+	if space < 0 {
+		s.Fail("data_byte", gobits.ErrOutOfRange)
+		return nil, s.Err()
+	}
+	h.syntax(s)
+	if af != nil {
+		needed, err := af.length()
+		if err != nil {
+			return nil, err
+		}
+		length := byte(space - 1)
+		switch {
+		case space == 1 && af.empty():
+			s.Byte(&length, 8, "adaptation_field_length")
+		case space-1 < needed:
+			s.Fail("adaptation_field_length", gobits.ErrOutOfRange)
+		default:
+			s.Byte(&length, 8, "adaptation_field_length")
+			af.syntax(s)
+			for i := needed; i < int64(length); i++ {
+				stuffing := byte(stuffingByte)
+				s.Byte(&stuffing, 8, "stuffing_byte")
+			}
+		}
+	}
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	if len(payload) > 0 && !ba.Put(payload, ba.Length()) {
+		return nil, gobits.ErrInvalidOffset
+	}
+	return ba.Bytes(), nil
+}
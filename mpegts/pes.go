@@ -0,0 +1,365 @@
+package mpegts
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+)
+
+// Stream IDs. Audio streams have IDs 0xc0 to 0xdf and video streams 0xe0
+// to 0xef.
+const (
+	StreamIDProgramStreamMap       = 0xbc
+	StreamIDPrivateStream1         = 0xbd
+	StreamIDPaddingStream          = 0xbe
+	StreamIDPrivateStream2         = 0xbf
+	StreamIDAudio                  = 0xc0
+	StreamIDVideo                  = 0xe0
+	StreamIDECM                    = 0xf0
+	StreamIDEMM                    = 0xf1
+	StreamIDDSMCC                  = 0xf2
+	StreamIDH2221TypeE             = 0xf8
+	StreamIDExtended               = 0xfd
+	StreamIDProgramStreamDirectory = 0xff
+)
+
+// Values of PTS_DTS_flags.
+const (
+	PTSDTSFlagsNone   = 0
+	PTSDTSFlagsPTS    = 2
+	PTSDTSFlagsPTSDTS = 3
+)
+
+const (
+	packetStartCodePrefix = 0x000001
+	packStartCode         = 0x000001ba
+)
+
+// hasOptionalHeader reports whether PES packets of streamID have the
+// fields that follow PES_packet_length in most streams.
+func hasOptionalHeader(streamID byte) bool {
+	switch streamID {
+	case StreamIDProgramStreamMap, StreamIDPaddingStream, StreamIDPrivateStream2,
+		StreamIDECM, StreamIDEMM, StreamIDProgramStreamDirectory, StreamIDDSMCC,
+		StreamIDH2221TypeE:
+		return false
+	}
+	return true
+}
+
+// PESExtension holds the fields that PES_extension_flag adds to a PES
+// header.
+type PESExtension struct {
+	PESPrivateDataFlag               bool
+	PackHeaderFieldFlag              bool
+	ProgramPacketSequenceCounterFlag bool
+	PSTDBufferFlag                   bool
+	PESExtensionFlag2                bool
+	PESPrivateData                   [16]byte
+	// PackHeader holds the pack_header() after pack_field_length,
+	// undecoded.
+	PackHeader                   []byte
+	ProgramPacketSequenceCounter byte
+	MPEG1MPEG2Identifier         bool
+	OriginalStuffLength          byte
+	PSTDBufferScale              bool
+	PSTDBufferSize               uint16
+	StreamIDExtensionFlag        bool
+	StreamIDExtension            byte
+	// TREF is present when TREFExtensionFlag is clear.
+	TREFExtensionFlag bool
+	TREF              Timestamp
+	// Reserved holds the reserved bytes that end the second extension.
+	Reserved []byte
+}
+
+func (e *PESExtension) syntax(s *gobits.Syntax) {
+	s.Flag(&e.PESPrivateDataFlag, "PES_private_data_flag")
+	s.Flag(&e.PackHeaderFieldFlag, "pack_header_field_flag")
+	s.Flag(&e.ProgramPacketSequenceCounterFlag, "program_packet_sequence_counter_flag")
+	s.Flag(&e.PSTDBufferFlag, "P-STD_buffer_flag")
+	reserved(s, 3)
+	s.Flag(&e.PESExtensionFlag2, "PES_extension_flag_2")
+	if e.PESPrivateDataFlag {
+		for i := range e.PESPrivateData {
+			s.Byte(&e.PESPrivateData[i], 8, "PES_private_data")
+		}
+	}
+	if e.PackHeaderFieldFlag {
+		lengthPrefixed(s, &e.PackHeader, "pack_field_length", "pack_header")
+	}
+	if e.ProgramPacketSequenceCounterFlag {
+		marker(s)
+		s.Byte(&e.ProgramPacketSequenceCounter, 7, "program_packet_sequence_counter")
+		marker(s)
+		s.Flag(&e.MPEG1MPEG2Identifier, "MPEG1_MPEG2_identifier")
+		s.Byte(&e.OriginalStuffLength, 6, "original_stuff_length")
+	}
+	if e.PSTDBufferFlag {
+		bits := byte(0x1)
+		s.Byte(&bits, 2, "'01'")
+		if s.Err() == nil && bits != 0x1 {
+			s.Fail("'01'", gobits.ErrInvalidSyntax)
+		}
+		s.Flag(&e.PSTDBufferScale, "P-STD_buffer_scale")
+		s.Bits16(&e.PSTDBufferSize, 13, "P-STD_buffer_size")
+	}
+	if e.PESExtensionFlag2 {
+		e.extension2(s)
+	}
+}
+
+func (e *PESExtension) extension2(s *gobits.Syntax) {
+	marker(s)
+	length := byte(1 + len(e.Reserved))
+	if e.StreamIDExtensionFlag && !e.TREFExtensionFlag {
+		length += 5
+	}
+	if !s.Reading() && length > 0x7f {
+		s.Fail("PES_extension_field_length", gobits.ErrOutOfRange)
+		return
+	}
+	s.Byte(&length, 7, "PES_extension_field_length")
+	bs := s.BitStream()
+	end := bs.Tell() + int64(length)*8
+
+	s.Flag(&e.StreamIDExtensionFlag, "stream_id_extension_flag")
+	if !e.StreamIDExtensionFlag {
+		s.Byte(&e.StreamIDExtension, 7, "stream_id_extension")
+	} else {
+		reserved(s, 6)
+		s.Flag(&e.TREFExtensionFlag, "tref_extension_flag")
+		if !e.TREFExtensionFlag {
+			timestamp(s, 0xf, &e.TREF, "TREF")
+		}
+	}
+	if s.Err() == nil && bs.Tell() > end {
+		s.Fail("PES_extension_field_length", gobits.ErrInvalidSyntax)
+	}
+	if s.Err() != nil {
+		return
+	}
+	byteString(s, &e.Reserved, int(end-bs.Tell())/8, "reserved")
+}
+
+// PESHeader is the header of a PES packet, up to the first
+// PES_packet_data_byte. Streams such as padding and private_stream_2 only
+// have StreamID and PESPacketLength.
+type PESHeader struct {
+	StreamID byte
+	// PESPacketLength counts the bytes that follow it. It may be 0 for
+	// video in transport streams, meaning that the packet is unbounded.
+	// Marshal writes it as is.
+	PESPacketLength        uint16
+	PESScramblingControl   byte
+	PESPriority            bool
+	DataAlignmentIndicator bool
+	Copyright              bool
+	OriginalOrCopy         bool
+	PTSDTSFlags            byte
+	ESCRFlag               bool
+	ESRateFlag             bool
+	DSMTrickModeFlag       bool
+	AdditionalCopyInfoFlag bool
+	PESCRCFlag             bool
+	PESExtensionFlag       bool
+	PTS                    Timestamp
+	DTS                    Timestamp
+	// ESCR counts the 27 MHz system clock, as Packet.PCR does.
+	ESCR   uint64
+	ESRate uint32
+	// DSMTrickMode holds trick_mode_control and the 5 bits after it,
+	// undecoded.
+	DSMTrickMode         byte
+	AdditionalCopyInfo   byte
+	PreviousPESPacketCRC uint16
+	Extension            PESExtension
+	// StuffingLength is the number of stuffing bytes that end the header.
+	StuffingLength int
+
+	// HeaderLength is the length of the header in bytes, as parsed. The
+	// packet data follows.
+	HeaderLength int64
+}
+
+func (h *PESHeader) optionalFields(s *gobits.Syntax) {
+	switch h.PTSDTSFlags {
+	case PTSDTSFlagsPTS:
+		timestamp(s, 0x2, &h.PTS, "PTS")
+	case PTSDTSFlagsPTSDTS:
+		timestamp(s, 0x3, &h.PTS, "PTS")
+		timestamp(s, 0x1, &h.DTS, "DTS")
+	case PTSDTSFlagsNone:
+	default:
+		s.Fail("PTS_DTS_flags", gobits.ErrInvalidSyntax)
+	}
+	if h.ESCRFlag {
+		reserved(s, 2)
+		clockReference(s, &h.ESCR, "ESCR")
+	}
+	if h.ESRateFlag {
+		marker(s)
+		s.Bits(&h.ESRate, 22, "ES_rate")
+		marker(s)
+	}
+	if h.DSMTrickModeFlag {
+		s.Byte(&h.DSMTrickMode, 8, "trick_mode")
+	}
+	if h.AdditionalCopyInfoFlag {
+		marker(s)
+		s.Byte(&h.AdditionalCopyInfo, 7, "additional_copy_info")
+	}
+	if h.PESCRCFlag {
+		s.Bits16(&h.PreviousPESPacketCRC, 16, "previous_PES_packet_CRC")
+	}
+	if h.PESExtensionFlag {
+		h.Extension.syntax(s)
+	}
+}
+
+// optionalFieldsLength returns the number of bytes that optionalFields
+// writes.
+func (h *PESHeader) optionalFieldsLength() (int64, error) {
+	ba := gobits.NewGrowableByteAccessor(nil)
+	s := gobits.NewWritingSyntax(gobits.NewBitStream(ba))
+	h.optionalFields(s)
+	return ba.Length(), s.Err()
+}
+
+func (h *PESHeader) syntax(s *gobits.Syntax) {
+	prefix := uint32(packetStartCodePrefix)
+	s.Bits(&prefix, 24, "packet_start_code_prefix")
+	if s.Err() == nil && prefix != packetStartCodePrefix {
+		s.Fail("packet_start_code_prefix", gobits.ErrInvalidSyntax)
+	}
+	s.Byte(&h.StreamID, 8, "stream_id")
+	s.Bits16(&h.PESPacketLength, 16, "PES_packet_length")
+	if s.Err() != nil || !hasOptionalHeader(h.StreamID) {
+		return
+	}
+
+	bits := byte(0x2)
+	s.Byte(&bits, 2, "'10'")
+	if s.Err() == nil && bits != 0x2 {
+		s.Fail("'10'", gobits.ErrInvalidSyntax)
+	}
+	s.Byte(&h.PESScramblingControl, 2, "PES_scrambling_control")
+	s.Flag(&h.PESPriority, "PES_priority")
+	s.Flag(&h.DataAlignmentIndicator, "data_alignment_indicator")
+	s.Flag(&h.Copyright, "copyright")
+	s.Flag(&h.OriginalOrCopy, "original_or_copy")
+	s.Byte(&h.PTSDTSFlags, 2, "PTS_DTS_flags")
+	s.Flag(&h.ESCRFlag, "ESCR_flag")
+	s.Flag(&h.ESRateFlag, "ES_rate_flag")
+	s.Flag(&h.DSMTrickModeFlag, "DSM_trick_mode_flag")
+	s.Flag(&h.AdditionalCopyInfoFlag, "additional_copy_info_flag")
+	s.Flag(&h.PESCRCFlag, "PES_CRC_flag")
+	s.Flag(&h.PESExtensionFlag, "PES_extension_flag")
+
+	length := byte(0)
+	if !s.Reading() {
+		// A field that cannot be written fails again, under its own name,
+		// below.
+		fieldsLength, _ := h.optionalFieldsLength()
+		if fieldsLength+int64(h.StuffingLength) > 0xff || h.StuffingLength < 0 {
+			s.Fail("PES_header_data_length", gobits.ErrOutOfRange)
+			return
+		}
+		length = byte(fieldsLength) + byte(h.StuffingLength)
+	}
+	s.Byte(&length, 8, "PES_header_data_length")
+	bs := s.BitStream()
+	end := bs.Tell() + int64(length)*8
+	h.optionalFields(s)
+	if s.Err() == nil && bs.Tell() > end {
+		s.Fail("PES_header_data_length", gobits.ErrInvalidSyntax)
+	}
+	if s.Err() != nil {
+		return
+	}
+	if s.Reading() {
+		h.StuffingLength = int(end-bs.Tell()) / 8
+	}
+	for i := 0; i < h.StuffingLength; i++ {
+		stuffing := byte(stuffingByte)
+		s.Byte(&stuffing, 8, "stuffing_byte")
+	}
+}
+
+// ParsePESHeader parses the PES header at the start of ba.
+func ParsePESHeader(ba gobits.ByteAccessor) (*PESHeader, error) {
+	bs := gobits.NewBitStream(ba)
+	s := gobits.NewReadingSyntax(bs)
+	h := &PESHeader{}
+	h.syntax(s)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	h.HeaderLength = bs.Tell() / 8
+	return h, nil
+}
+
+// Marshal encodes the PES header. PES_header_data_length follows from the
+// flags and StuffingLength.
+func (h *PESHeader) Marshal() ([]byte, error) {
+	ba := gobits.NewGrowableByteAccessor(nil)
+	s := gobits.NewWritingSyntax(gobits.NewBitStream(ba))
+	h.syntax(s)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	return ba.Bytes(), nil
+}
+
+// PackHeader is the MPEG-2 pack_header() of a program stream, without the
+// system header that may follow it.
+type PackHeader struct {
+	// SCR counts the 27 MHz system clock, as Packet.PCR does.
+	SCR                uint64
+	ProgramMuxRate     uint32
+	PackStuffingLength byte
+}
+
+func (h *PackHeader) syntax(s *gobits.Syntax) {
+	startCode := uint32(packStartCode)
+	s.Bits(&startCode, 32, "pack_start_code")
+	if s.Err() == nil && startCode != packStartCode {
+		s.Fail("pack_start_code", gobits.ErrInvalidSyntax)
+	}
+	bits := byte(0x1)
+	s.Byte(&bits, 2, "'01'")
+	if s.Err() == nil && bits != 0x1 {
+		s.Fail("'01'", gobits.ErrInvalidSyntax)
+	}
+	clockReference(s, &h.SCR, "system_clock_reference")
+	s.Bits(&h.ProgramMuxRate, 22, "program_mux_rate")
+	marker(s)
+	marker(s)
+	reserved(s, 5)
+	s.Byte(&h.PackStuffingLength, 3, "pack_stuffing_length")
+	for i := byte(0); i < h.PackStuffingLength; i++ {
+		stuffing := byte(stuffingByte)
+		s.Byte(&stuffing, 8, "stuffing_byte")
+	}
+}
+
+// ParsePackHeader parses the pack header at the start of ba.
+func ParsePackHeader(ba gobits.ByteAccessor) (*PackHeader, error) {
+	s := gobits.NewReadingSyntax(gobits.NewBitStream(ba))
+	h := &PackHeader{}
+	h.syntax(s)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	return h, nil
+}
+
+// Marshal encodes the pack header, followed by PackStuffingLength stuffing
+// bytes.
+func (h *PackHeader) Marshal() ([]byte, error) {
+	ba := gobits.NewGrowableByteAccessor(nil)
+	s := gobits.NewWritingSyntax(gobits.NewBitStream(ba))
+	h.syntax(s)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	return ba.Bytes(), nil
+}
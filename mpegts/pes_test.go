@@ -0,0 +1,161 @@
+package mpegts
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimestamp(t *testing.T) {
+	last := Timestamp(timestampModulus - 1)
+	assert.Equal(t, Timestamp(1), last.Add(2))
+	assert.Equal(t, last, Timestamp(1).Add(-2))
+	assert.Equal(t, int64(2), Timestamp(1).Sub(last))
+	assert.Equal(t, int64(-2), last.Sub(1))
+	assert.True(t, last.Before(1))
+	assert.False(t, Timestamp(1).Before(last))
+	assert.Equal(t, int64(3*TimestampFrequency), Timestamp(3*TimestampFrequency).Sub(0))
+}
+
+func TestParsePESHeader(t *testing.T) {
+	data := []byte{
+		0x00, 0x00, 0x01, 0xe0, 0x00, 0x00, 0x84, 0x80, 0x05,
+		0x29, 0x8d, 0x15, 0xcf, 0x13,
+		0x00, 0x00, 0x00, 0x01, 0x09,
+	}
+	h, err := ParsePESHeader(gobits.NewSliceByteAccessor(data))
+	assert.NoError(t, err)
+	assert.Equal(t, &PESHeader{
+		StreamID:               StreamIDVideo,
+		DataAlignmentIndicator: true,
+		PTSDTSFlags:            PTSDTSFlagsPTS,
+		PTS:                    0x123456789,
+		HeaderLength:           14,
+	}, h)
+	marshalled, err := h.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, data[:14], marshalled)
+
+	// Padding streams have no optional header.
+	h, err = ParsePESHeader(gobits.NewSliceByteAccessor([]byte{0x00, 0x00, 0x01, 0xbe, 0x00, 0x02, 0xff, 0xff}))
+	assert.NoError(t, err)
+	assert.Equal(t, &PESHeader{StreamID: StreamIDPaddingStream, PESPacketLength: 2, HeaderLength: 6}, h)
+
+	var fe *gobits.FieldError
+	corrupted := append([]byte{}, data...)
+	corrupted[11] &^= 0x01
+	_, err = ParsePESHeader(gobits.NewSliceByteAccessor(corrupted))
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "marker_bit", fe.Field)
+	assert.True(t, errors.Is(err, gobits.ErrInvalidSyntax))
+
+	corrupted = append([]byte{}, data...)
+	corrupted[8] = 4
+	_, err = ParsePESHeader(gobits.NewSliceByteAccessor(corrupted))
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "PES_header_data_length", fe.Field)
+
+	corrupted = append([]byte{}, data...)
+	corrupted[7] = 0x40
+	_, err = ParsePESHeader(gobits.NewSliceByteAccessor(corrupted))
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "PTS_DTS_flags", fe.Field)
+
+	_, err = ParsePESHeader(gobits.NewSliceByteAccessor(data[1:]))
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "packet_start_code_prefix", fe.Field)
+
+	_, err = ParsePESHeader(gobits.NewSliceByteAccessor(data[:12]))
+	assert.True(t, errors.Is(err, gobits.ErrUnexpectedEOF))
+}
+
+func TestPESHeader_RoundTrip(t *testing.T) {
+	h := &PESHeader{
+		StreamID:               StreamIDAudio,
+		PESPacketLength:        1234,
+		PESPriority:            true,
+		OriginalOrCopy:         true,
+		PTSDTSFlags:            PTSDTSFlagsPTSDTS,
+		ESCRFlag:               true,
+		ESRateFlag:             true,
+		DSMTrickModeFlag:       true,
+		AdditionalCopyInfoFlag: true,
+		PESCRCFlag:             true,
+		PESExtensionFlag:       true,
+		PTS:                    timestampModulus - 1,
+		DTS:                    Timestamp(timestampModulus - 1).Add(-3003),
+		ESCR:                   0x1abcdef01*pcrExtensionModulus + 299,
+		ESRate:                 0x3fffff,
+		DSMTrickMode:           0x5a,
+		AdditionalCopyInfo:     0x55,
+		PreviousPESPacketCRC:   0xbeef,
+		Extension: PESExtension{
+			PESPrivateDataFlag:               true,
+			PackHeaderFieldFlag:              true,
+			ProgramPacketSequenceCounterFlag: true,
+			PSTDBufferFlag:                   true,
+			PESExtensionFlag2:                true,
+			PESPrivateData:                   [16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+			PackHeader:                       []byte{0x44, 0x00, 0x04, 0x00, 0x04, 0x01},
+			ProgramPacketSequenceCounter:     0x7f,
+			MPEG1MPEG2Identifier:             true,
+			OriginalStuffLength:              0x2a,
+			PSTDBufferScale:                  true,
+			PSTDBufferSize:                   0x1fff,
+			StreamIDExtensionFlag:            true,
+			TREF:                             0x1deadbeef,
+			Reserved:                         []byte{0xaa, 0xbb},
+		},
+		StuffingLength: 3,
+	}
+	data, err := h.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xff, 0xff, 0xff}, data[len(data)-3:])
+	parsed, err := ParsePESHeader(gobits.NewSliceByteAccessor(data))
+	assert.NoError(t, err)
+	h.HeaderLength = int64(len(data))
+	assert.Equal(t, h, parsed)
+	assert.Equal(t, int64(-3003), parsed.DTS.Sub(parsed.PTS))
+
+	h.Extension.StreamIDExtensionFlag = false
+	h.Extension.StreamIDExtension = 0x71
+	h.Extension.TREF = 0
+	data, err = h.Marshal()
+	assert.NoError(t, err)
+	parsed, err = ParsePESHeader(gobits.NewSliceByteAccessor(data))
+	assert.NoError(t, err)
+	h.HeaderLength = int64(len(data))
+	assert.Equal(t, h, parsed)
+
+	h.PTS = timestampModulus
+	_, err = h.Marshal()
+	var fe *gobits.FieldError
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "PTS", fe.Field)
+	assert.True(t, errors.Is(err, gobits.ErrOutOfRange))
+
+	h.PTS = 0
+	h.StuffingLength = 250
+	_, err = h.Marshal()
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "PES_header_data_length", fe.Field)
+}
+
+func TestPackHeader_RoundTrip(t *testing.T) {
+	h := &PackHeader{SCR: 0x123456789*pcrExtensionModulus + 18, ProgramMuxRate: 25200, PackStuffingLength: 2}
+	data, err := h.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x00, 0x01, 0xba}, data[:4])
+	assert.Equal(t, 16, len(data))
+	parsed, err := ParsePackHeader(gobits.NewSliceByteAccessor(data))
+	assert.NoError(t, err)
+	assert.Equal(t, h, parsed)
+
+	data[4] &^= 0x40
+	_, err = ParsePackHeader(gobits.NewSliceByteAccessor(data))
+	var fe *gobits.FieldError
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "'01'", fe.Field)
+}
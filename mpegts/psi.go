@@ -0,0 +1,205 @@
+package mpegts
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+)
+
+// Stream types of a PMT.
+const (
+	StreamTypeMPEG1Video      = 0x01
+	StreamTypeMPEG2Video      = 0x02
+	StreamTypeMPEG1Audio      = 0x03
+	StreamTypeMPEG2Audio      = 0x04
+	StreamTypePrivateSections = 0x05
+	StreamTypePrivateData     = 0x06
+	StreamTypeADTS            = 0x0f
+	StreamTypeLATM            = 0x11
+	StreamTypeH264            = 0x1b
+	StreamTypeHEVC            = 0x24
+)
+
+// Running status values of an SDT service.
+const (
+	RunningStatusUndefined  = 0
+	RunningStatusNotRunning = 1
+	RunningStatusStartsSoon = 2
+	RunningStatusPausing    = 3
+	RunningStatusRunning    = 4
+	RunningStatusOffAir     = 5
+)
+
+var (
+	patFormat = sectionFormat{tableIDs: []byte{TableIDPAT}, extensionField: "transport_stream_id"}
+	pmtFormat = sectionFormat{tableIDs: []byte{TableIDPMT}, extensionField: "program_number"}
+	sdtFormat = sectionFormat{
+		tableIDs:         []byte{TableIDSDTActual, TableIDSDTOther},
+		extensionField:   "transport_stream_id",
+		privateIndicator: true,
+	}
+)
+
+// PATProgram maps a program to the PID of its PMT. Program number 0 maps
+// to the network PID instead.
+type PATProgram struct {
+	ProgramNumber uint16
+	PID           uint16
+}
+
+func (p *PATProgram) syntax(s *gobits.Syntax) {
+	s.Bits16(&p.ProgramNumber, 16, "program_number")
+	reserved(s, 3)
+	s.Bits16(&p.PID, 13, "program_map_PID")
+}
+
+// PAT is a program_association_section().
+type PAT struct {
+	SectionHeader
+	TransportStreamID uint16
+	Programs          []PATProgram
+}
+
+func (t *PAT) syntax(s *gobits.Syntax, end int64) {
+	if !s.Reading() {
+		for i := range t.Programs {
+			t.Programs[i].syntax(s)
+		}
+		return
+	}
+	t.Programs = []PATProgram{}
+	for more(s, end) {
+		p := PATProgram{}
+		p.syntax(s)
+		t.Programs = append(t.Programs, p)
+	}
+}
+
+// ParsePAT parses a whole PAT section, as returned by SectionAssembler.
+func ParsePAT(section []byte) (*PAT, error) {
+	t := &PAT{}
+	if err := patFormat.readSection(section, &t.SectionHeader, &t.TransportStreamID, t.syntax); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Marshal encodes the PAT as a section, CRC_32 included.
+func (t *PAT) Marshal() ([]byte, error) {
+	return patFormat.writeSection(&t.SectionHeader, t.TransportStreamID, t.syntax)
+}
+
+// PMTStream is an elementary stream of a PMT.
+type PMTStream struct {
+	StreamType    byte
+	ElementaryPID uint16
+	ESInfo        []Descriptor
+}
+
+func (e *PMTStream) syntax(s *gobits.Syntax) {
+	s.Byte(&e.StreamType, 8, "stream_type")
+	reserved(s, 3)
+	s.Bits16(&e.ElementaryPID, 13, "elementary_PID")
+	reserved(s, 4)
+	descriptors(s, &e.ESInfo, "ES_info_length")
+}
+
+// PMT is a TS_program_map_section().
+type PMT struct {
+	SectionHeader
+	ProgramNumber uint16
+	PCRPID        uint16
+	ProgramInfo   []Descriptor
+	Streams       []PMTStream
+}
+
+func (t *PMT) syntax(s *gobits.Syntax, end int64) {
+	reserved(s, 3)
+	s.Bits16(&t.PCRPID, 13, "PCR_PID")
+	reserved(s, 4)
+	descriptors(s, &t.ProgramInfo, "program_info_length")
+	if !s.Reading() {
+		for i := range t.Streams {
+			t.Streams[i].syntax(s)
+		}
+		return
+	}
+	t.Streams = []PMTStream{}
+	for more(s, end) {
+		e := PMTStream{}
+		e.syntax(s)
+		t.Streams = append(t.Streams, e)
+	}
+}
+
+// ParsePMT parses a whole PMT section, as returned by SectionAssembler.
+func ParsePMT(section []byte) (*PMT, error) {
+	t := &PMT{}
+	if err := pmtFormat.readSection(section, &t.SectionHeader, &t.ProgramNumber, t.syntax); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Marshal encodes the PMT as a section, CRC_32 included.
+func (t *PMT) Marshal() ([]byte, error) {
+	return pmtFormat.writeSection(&t.SectionHeader, t.ProgramNumber, t.syntax)
+}
+
+// SDTService is a service of an SDT.
+type SDTService struct {
+	ServiceID               uint16
+	EITScheduleFlag         bool
+	EITPresentFollowingFlag bool
+	RunningStatus           byte
+	FreeCAMode              bool
+	Descriptors             []Descriptor
+}
+
+func (v *SDTService) syntax(s *gobits.Syntax) {
+	s.Bits16(&v.ServiceID, 16, "service_id")
+	reserved(s, 6)
+	s.Flag(&v.EITScheduleFlag, "EIT_schedule_flag")
+	s.Flag(&v.EITPresentFollowingFlag, "EIT_present_following_flag")
+	s.Byte(&v.RunningStatus, 3, "running_status")
+	s.Flag(&v.FreeCAMode, "free_CA_mode")
+	descriptors(s, &v.Descriptors, "descriptors_loop_length")
+}
+
+// SDT is a service_description_section() of ETSI EN 300 468, describing
+// the services of either the actual or another transport stream.
+type SDT struct {
+	SectionHeader
+	TransportStreamID uint16
+	OriginalNetworkID uint16
+	Services          []SDTService
+}
+
+func (t *SDT) syntax(s *gobits.Syntax, end int64) {
+	s.Bits16(&t.OriginalNetworkID, 16, "original_network_id")
+	reserved(s, 8)
+	if !s.Reading() {
+		for i := range t.Services {
+			t.Services[i].syntax(s)
+		}
+		return
+	}
+	t.Services = []SDTService{}
+	for more(s, end) {
+		v := SDTService{}
+		v.syntax(s)
+		t.Services = append(t.Services, v)
+	}
+}
+
+// ParseSDT parses a whole SDT section, as returned by SectionAssembler.
+func ParseSDT(section []byte) (*SDT, error) {
+	t := &SDT{}
+	if err := sdtFormat.readSection(section, &t.SectionHeader, &t.TransportStreamID, t.syntax); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Marshal encodes the SDT as a section, CRC_32 included.
+func (t *SDT) Marshal() ([]byte, error) {
+	return sdtFormat.writeSection(&t.SectionHeader, t.TransportStreamID, t.syntax)
+}
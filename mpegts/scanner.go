@@ -0,0 +1,150 @@
+package mpegts
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+)
+
+const (
+	// syncChecks is how many sync bytes, one packet apart, the scanner
+	// requires before it locks on to a position.
+	syncChecks = 3
+)
+
+var (
+	packetSizes = []int64{PacketSize, M2TSPacketSize, FECPacketSize}
+	syncPattern = []byte{SyncByte}
+)
+
+// Scanner iterates over the packets of a transport stream. When a packet
+// does not start with a sync byte where expected, the scanner skips ahead
+// to the next position from which sync bytes recur at the packet size.
+// Call Next until it returns false, then check Err.
+type Scanner struct {
+	ba         gobits.ByteAccessor
+	packetSize int64
+	// offset is the start of the next packet, TP_extra_header included.
+	offset    int64
+	discarded int64
+	packet    *Packet
+	err       error
+}
+
+// prefixSize returns the number of bytes that precede the sync byte of
+// each packet.
+func (sc *Scanner) prefixSize() int64 {
+	if sc.packetSize == M2TSPacketSize {
+		return m2tsPrefix
+	}
+	return 0
+}
+
+// locked reports whether sync bytes follow the one at byteOffset every
+// packetSize bytes, as far as the data goes.
+func (sc *Scanner) locked(byteOffset, packetSize int64) bool {
+	for i := int64(1); i < syncChecks; i++ {
+		b, ok := sc.ba.At(byteOffset + i*packetSize)
+		if !ok {
+			return true
+		}
+		if b != SyncByte {
+			return false
+		}
+	}
+	return true
+}
+
+// resync returns the offset of the first sync byte at or after byteOffset
+// that the scanner can lock on to, detecting the packet size if it is not
+// known yet, or -1 if there is none.
+func (sc *Scanner) resync(byteOffset int64) int64 {
+	for {
+		byteOffset = gobits.FindSyncword(sc.ba, byteOffset, syncPattern, nil)
+		if byteOffset < 0 {
+			return -1
+		}
+		if sc.packetSize != 0 {
+			if sc.locked(byteOffset, sc.packetSize) {
+				return byteOffset
+			}
+		} else {
+			for _, size := range packetSizes {
+				if sc.locked(byteOffset, size) {
+					sc.packetSize = size
+					return byteOffset
+				}
+			}
+		}
+		byteOffset++
+	}
+}
+
+// Next advances to the next packet. It returns false at the end of the
+// data or on error.
+func (sc *Scanner) Next() bool {
+	if sc.err != nil || sc.offset >= sc.ba.Length() {
+		return false
+	}
+
+	syncOffset := sc.offset + sc.prefixSize()
+	if b, ok := sc.ba.At(syncOffset); sc.packetSize == 0 || !ok || b != SyncByte {
+		syncOffset = sc.resync(syncOffset)
+		if syncOffset < 0 {
+			sc.discarded += sc.ba.Length() - sc.offset
+			sc.offset = sc.ba.Length()
+			return false
+		}
+		start := syncOffset - sc.prefixSize()
+		if start > sc.offset {
+			sc.discarded += start - sc.offset
+			sc.offset = start
+		}
+	}
+
+	if sc.ba.Length()-syncOffset < PacketSize {
+		sc.err = &gobits.FieldError{Field: "transport_packet", Err: gobits.ErrUnexpectedEOF}
+		return false
+	}
+	p, err := ParsePacket(gobits.NewSectionByteAccessor(sc.ba, syncOffset, PacketSize))
+	if err != nil {
+		sc.err = err
+		return false
+	}
+	p.Offset = syncOffset
+	sc.packet = p
+	sc.offset += sc.packetSize
+	return true
+}
+
+// Packet returns the packet found by the last successful call to Next.
+func (sc *Scanner) Packet() *Packet {
+	return sc.packet
+}
+
+// PacketSize returns the size of the packets, including any
+// TP_extra_header or parity bytes, or 0 until the first packet is found.
+func (sc *Scanner) PacketSize() int {
+	return int(sc.packetSize)
+}
+
+// Discarded returns the number of bytes skipped so far while searching
+// for sync bytes.
+func (sc *Scanner) Discarded() int64 {
+	return sc.discarded
+}
+
+func (sc *Scanner) Err() error {
+	return sc.err
+}
+
+// NewScanner returns a scanner for packets of packetSize bytes, which is
+// PacketSize, M2TSPacketSize or FECPacketSize, or 0 to detect the size
+// from the data.
+func NewScanner(ba gobits.ByteAccessor, packetSize int) *Scanner {
+	sc := &Scanner{ba: ba, packetSize: int64(packetSize)}
+	switch packetSize {
+	case 0, PacketSize, M2TSPacketSize, FECPacketSize:
+	default:
+		sc.err = gobits.ErrOutOfRange
+	}
+	return sc
+}
@@ -0,0 +1,332 @@
+package mpegts
+
+import (
+	"encoding/binary"
+
+	"github.com/ibbbpbbbp/gobits"
+)
+
+const (
+	// maxSectionLength and maxPrivateSectionLength bound section_length in
+	// PSI and SI tables and in private sections.
+	maxSectionLength        = 1021
+	maxPrivateSectionLength = 4093
+
+	sectionHeaderLength = 3
+	crcLength           = 4
+)
+
+// Descriptor is a descriptor of a descriptor loop, undecoded.
+type Descriptor struct {
+	Tag  byte
+	Data []byte
+}
+
+func (d *Descriptor) syntax(s *gobits.Syntax) {
+	s.Byte(&d.Tag, 8, "descriptor_tag")
+	lengthPrefixed(s, &d.Data, "descriptor_length", "descriptor")
+}
+
+// descriptors handles a descriptor loop preceded by its length in bytes in
+// a 12-bit field.
+func descriptors(s *gobits.Syntax, val *[]Descriptor, lengthField string) {
+	length := uint32(0)
+	for i := range *val {
+		length += 2 + uint32(len((*val)[i].Data))
+	}
+	if !s.Reading() && length >= 1<<12 {
+		s.Fail(lengthField, gobits.ErrOutOfRange)
+		return
+	}
+	s.Bits(&length, 12, lengthField)
+	if !s.Reading() {
+		for i := range *val {
+			(*val)[i].syntax(s)
+		}
+		return
+	}
+
+	bs := s.BitStream()
+	end := bs.Tell() + int64(length)*8
+	if s.Err() == nil && !bs.RemainingBits(int64(length)*8) {
+		s.Fail(lengthField, gobits.ErrUnexpectedEOF)
+	}
+	*val = []Descriptor{}
+	for more(s, end) {
+		d := Descriptor{}
+		d.syntax(s)
+		*val = append(*val, d)
+	}
+	if s.Err() == nil && bs.Tell() != end {
+		s.Fail(lengthField, gobits.ErrInvalidSyntax)
+	}
+}
+
+// more reports whether a loop that runs to the bit position end has
+// another entry to read.
+func more(s *gobits.Syntax, end int64) bool {
+	return s.Err() == nil && s.BitStream().Tell() < end
+}
+
+// SectionHeader holds the fields of the long section header shared by the
+// tables of this package, except table_id_extension, which each table
+// names after its meaning.
+type SectionHeader struct {
+	TableID              byte
+	VersionNumber        byte
+	CurrentNextIndicator bool
+	SectionNumber        byte
+	LastSectionNumber    byte
+}
+
+// sectionFormat describes what distinguishes the long section headers of
+// a table.
+type sectionFormat struct {
+	tableIDs []byte
+	// extensionField names table_id_extension.
+	extensionField string
+	// privateIndicator is the bit after section_syntax_indicator, which is
+	// private_indicator in PSI tables and reserved_future_use in SI tables.
+	privateIndicator bool
+}
+
+func (f *sectionFormat) syntax(s *gobits.Syntax, h *SectionHeader, extension *uint16, length *uint32) {
+	s.Byte(&h.TableID, 8, "table_id")
+	if s.Err() == nil {
+		known := false
+		for _, id := range f.tableIDs {
+			known = known || h.TableID == id
+		}
+		if !known {
+			s.Fail("table_id", gobits.ErrInvalidSyntax)
+		}
+	}
+	sectionSyntaxIndicator := true
+	s.Flag(&sectionSyntaxIndicator, "section_syntax_indicator")
+	if s.Err() == nil && !sectionSyntaxIndicator {
+		s.Fail("section_syntax_indicator", gobits.ErrInvalidSyntax)
+	}
+	privateIndicator := f.privateIndicator
+	s.Flag(&privateIndicator, "private_indicator")
+	reserved(s, 2)
+	s.Bits(length, 12, "section_length")
+	s.Bits16(extension, 16, f.extensionField)
+	reserved(s, 2)
+	s.Byte(&h.VersionNumber, 5, "version_number")
+	s.Flag(&h.CurrentNextIndicator, "current_next_indicator")
+	s.Byte(&h.SectionNumber, 8, "section_number")
+	s.Byte(&h.LastSectionNumber, 8, "last_section_number")
+}
+
+// readSection parses a whole section, CRC_32 included. fields reads what
+// lies between the header and CRC_32, which ends at the bit position it is
+// given.
+func (f *sectionFormat) readSection(data []byte, h *SectionHeader, extension *uint16, fields func(s *gobits.Syntax, end int64)) error {
+	body := data
+	if len(body) >= crcLength {
+		body = body[:len(body)-crcLength]
+	}
+	bs := gobits.NewBitStream(gobits.NewSliceByteAccessor(body))
+	s := gobits.NewReadingSyntax(bs)
+	length := uint32(0)
+	f.syntax(s, h, extension, &length)
+	if s.Err() == nil {
+		switch {
+		case length > maxSectionLength || length < uint32(bs.Tell()/8-sectionHeaderLength+crcLength):
+			s.Fail("section_length", gobits.ErrOutOfRange)
+		case int(length)+sectionHeaderLength != len(data):
+			s.Fail("section_length", gobits.ErrUnexpectedEOF)
+		case CRC32(data) != 0:
+			s.Fail("CRC_32", gobits.ErrChecksum)
+		}
+	}
+	end := int64(len(body)) * 8
+	fields(s, end)
+	if s.Err() == nil && bs.Tell() != end {
+		s.Fail("section_length", gobits.ErrInvalidSyntax)
+	}
+	return s.Err()
+}
+
+// writeSection encodes a section around the fields that body writes and
+// appends CRC_32.
+func (f *sectionFormat) writeSection(h *SectionHeader, extension uint16, body func(s *gobits.Syntax, end int64)) ([]byte, error) {
+	bodyBA := gobits.NewGrowableByteAccessor(nil)
+	bodySyntax := gobits.NewWritingSyntax(gobits.NewBitStream(bodyBA))
+	body(bodySyntax, 0)
+	if bodySyntax.Err() != nil {
+		return nil, bodySyntax.Err()
+	}
+
+	ba := gobits.NewGrowableByteAccessor(nil)
+	s := gobits.NewWritingSyntax(gobits.NewBitStream(ba))
+	length := uint32(5 + bodyBA.Length() + crcLength)
+	if length > maxSectionLength {
+		s.Fail("section_length", gobits.ErrOutOfRange)
+		return nil, s.Err()
+	}
+	f.syntax(s, h, &extension, &length)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	section := append(ba.Bytes(), bodyBA.Bytes()...)
+	crc := make([]byte, crcLength)
+	binary.BigEndian.PutUint32(crc, CRC32(section))
+	return append(section, crc...), nil
+}
+
+// sectionBuffer holds the partial section of a PID.
+type sectionBuffer struct {
+	continuityCounter byte
+	// data is nil when no section is pending.
+	data []byte
+}
+
+// fill appends the bytes of payload that belong to the pending section
+// and returns those that follow it. Once the section is complete, it is
+// returned and no section is pending.
+func (b *sectionBuffer) fill(payload []byte) (section, rest []byte, err error) {
+	take := func(needed int) bool {
+		if n := needed - len(b.data); n > 0 {
+			if n > len(payload) {
+				n = len(payload)
+			}
+			b.data = append(b.data, payload[:n]...)
+			payload = payload[n:]
+		}
+		return len(b.data) >= needed
+	}
+	if !take(sectionHeaderLength) {
+		return nil, payload, nil
+	}
+	length := int(b.data[1]&0x0f)<<8 | int(b.data[2])
+	if length > maxPrivateSectionLength {
+		b.data = nil
+		return nil, nil, &gobits.FieldError{Field: "section_length", Err: gobits.ErrOutOfRange}
+	}
+	if !take(sectionHeaderLength + length) {
+		return nil, payload, nil
+	}
+
+	section, b.data = b.data, nil
+	if section[1]&0x80 != 0 && CRC32(section) != 0 {
+		return nil, payload, &gobits.FieldError{Field: "CRC_32", Err: gobits.ErrChecksum}
+	}
+	return section, payload, nil
+}
+
+// SectionAssembler reassembles the sections carried in transport packets.
+// Push the packets of the PIDs of interest in stream order.
+type SectionAssembler struct {
+	buffers map[uint16]*sectionBuffer
+}
+
+// Push adds the payload of p and returns the sections that it completes,
+// whole from table_id on. Sections with section_syntax_indicator set are
+// only returned if their CRC_32 is correct. A continuity counter gap, a
+// truncated section or a CRC_32 mismatch drops the sections affected and
+// is reported in err, alongside the sections that were completed; the
+// assembler remains usable.
+func (a *SectionAssembler) Push(p *Packet) (sections [][]byte, err error) {
+	if a.buffers == nil {
+		a.buffers = map[uint16]*sectionBuffer{}
+	}
+	h := &p.Header
+	b, seen := a.buffers[h.PID]
+	if h.TransportErrorIndicator {
+		delete(a.buffers, h.PID)
+		return nil, nil
+	}
+	if !h.HasPayload() || p.Payload == nil {
+		return nil, nil
+	}
+	if !seen {
+		b = &sectionBuffer{}
+		a.buffers[h.PID] = b
+	} else if h.ContinuityCounter == b.continuityCounter {
+		// A duplicate packet.
+		return nil, nil
+	}
+
+	collect := func(section []byte, e error) {
+		if section != nil {
+			sections = append(sections, section)
+		}
+		if err == nil {
+			err = e
+		}
+	}
+	discontinuity := p.AdaptationField != nil && p.AdaptationField.DiscontinuityIndicator
+	if seen && h.ContinuityCounter != (b.continuityCounter+1)&0x0f && !discontinuity && b.data != nil {
+		b.data = nil
+		collect(nil, &gobits.FieldError{Field: "continuity_counter", Err: gobits.ErrInvalidSyntax})
+	}
+	b.continuityCounter = h.ContinuityCounter
+
+	payload := p.Payload.Slice(0, p.Payload.Length())
+	if !h.PayloadUnitStartIndicator {
+		if b.data != nil {
+			section, _, e := b.fill(payload)
+			collect(section, e)
+		}
+		return sections, err
+	}
+
+	if len(payload) == 0 {
+		return sections, err
+	}
+	pointer := int(payload[0])
+	payload = payload[1:]
+	if pointer > len(payload) {
+		b.data = nil
+		collect(nil, &gobits.FieldError{Field: "pointer_field", Err: gobits.ErrOutOfRange})
+		return sections, err
+	}
+	if b.data != nil {
+		section, _, e := b.fill(payload[:pointer])
+		collect(section, e)
+		if b.data != nil {
+			b.data = nil
+			collect(nil, &gobits.FieldError{Field: "section_length", Err: gobits.ErrUnexpectedEOF})
+		}
+	}
+	payload = payload[pointer:]
+	for len(payload) > 0 && payload[0] != tableIDStuffing {
+		b.data = []byte{}
+		var section []byte
+		var e error
+		section, payload, e = b.fill(payload)
+		collect(section, e)
+	}
+	return sections, err
+}
+
+func NewSectionAssembler() *SectionAssembler {
+	return &SectionAssembler{buffers: map[uint16]*sectionBuffer{}}
+}
+
+// SectionPackets splits a section into the payloads of packets of the
+// given PID, numbered from continuityCounter. The first packet has
+// payload_unit_start_indicator set and a zero pointer_field, and the last
+// one is filled up with stuffing bytes.
+func SectionPackets(pid uint16, continuityCounter byte, section []byte) []*Packet {
+	data := append([]byte{0}, section...)
+	packets := []*Packet{}
+	for len(data) > 0 {
+		payload := make([]byte, payloadSize)
+		n := copy(payload, data)
+		for i := n; i < len(payload); i++ {
+			payload[i] = stuffingByte
+		}
+		packets = append(packets, &Packet{
+			Header: Header{
+				PayloadUnitStartIndicator: len(packets) == 0,
+				PID:                       pid,
+				ContinuityCounter:         (continuityCounter + byte(len(packets))) & 0x0f,
+			},
+			Payload: gobits.NewSliceByteAccessor(payload),
+		})
+		data = data[n:]
+	}
+	return packets
+}
@@ -0,0 +1,193 @@
+package mpegts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/stretchr/testify/assert"
+)
+
+func testPMT() *PMT {
+	return &PMT{
+		SectionHeader: SectionHeader{TableID: TableIDPMT, VersionNumber: 3, CurrentNextIndicator: true},
+		ProgramNumber: 1,
+		PCRPID:        0x100,
+		ProgramInfo:   []Descriptor{{Tag: 0x0e, Data: []byte{0xc0, 0x12, 0x34}}},
+		Streams: []PMTStream{
+			{StreamType: StreamTypeH264, ElementaryPID: 0x100, ESInfo: []Descriptor{}},
+			{
+				StreamType:    StreamTypeADTS,
+				ElementaryPID: 0x101,
+				ESInfo: []Descriptor{
+					{Tag: 0x0a, Data: []byte{'j', 'p', 'n', 0x00}},
+					{Tag: 0x52, Data: bytes.Repeat([]byte{0x10}, 200)},
+				},
+			},
+		},
+	}
+}
+
+func TestParsePAT(t *testing.T) {
+	pat, err := ParsePAT(patSection)
+	assert.NoError(t, err)
+	assert.Equal(t, &PAT{
+		SectionHeader:     SectionHeader{TableID: TableIDPAT, CurrentNextIndicator: true},
+		TransportStreamID: 1,
+		Programs:          []PATProgram{{ProgramNumber: 1, PID: 0x1000}},
+	}, pat)
+	section, err := pat.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, patSection, section)
+
+	corrupted := append([]byte{}, patSection...)
+	corrupted[9] = 0x02
+	_, err = ParsePAT(corrupted)
+	var fe *gobits.FieldError
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "CRC_32", fe.Field)
+	assert.True(t, errors.Is(err, gobits.ErrChecksum))
+
+	_, err = ParsePAT(patSection[:len(patSection)-1])
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "section_length", fe.Field)
+
+	_, err = ParsePMT(patSection)
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "table_id", fe.Field)
+	assert.True(t, errors.Is(err, gobits.ErrInvalidSyntax))
+}
+
+func TestPMT_RoundTrip(t *testing.T) {
+	pmt := testPMT()
+	section, err := pmt.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x02, 0xb0, byte(len(section) - 3)}, section[:3])
+	assert.Equal(t, uint32(0), CRC32(section))
+	parsed, err := ParsePMT(section)
+	assert.NoError(t, err)
+	assert.Equal(t, pmt, parsed)
+
+	pmt.TableID = TableIDPAT
+	_, err = pmt.Marshal()
+	assert.True(t, errors.Is(err, gobits.ErrInvalidSyntax))
+
+	// An ES_info_length that overruns the section.
+	crcOffset := len(section) - crcLength
+	section[crcOffset-len(pmt.Streams[1].ESInfo[1].Data)-2-6-1]++
+	binary.BigEndian.PutUint32(section[crcOffset:], CRC32(section[:crcOffset]))
+	_, err = ParsePMT(section)
+	var fe *gobits.FieldError
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "ES_info_length", fe.Field)
+	assert.True(t, errors.Is(err, gobits.ErrUnexpectedEOF))
+}
+
+func TestSDT_RoundTrip(t *testing.T) {
+	sdt := &SDT{
+		SectionHeader:     SectionHeader{TableID: TableIDSDTActual, VersionNumber: 1, CurrentNextIndicator: true},
+		TransportStreamID: 1,
+		OriginalNetworkID: 0xff01,
+		Services: []SDTService{{
+			ServiceID:     1,
+			RunningStatus: RunningStatusRunning,
+			Descriptors: []Descriptor{{
+				Tag:  0x48,
+				Data: append([]byte{0x01, 0x06}, append([]byte("FFmpeg"), append([]byte{0x09}, "Service01"...)...)...),
+			}},
+		}},
+	}
+	section, err := sdt.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x42, 0xf0}, section[:2])
+	parsed, err := ParseSDT(section)
+	assert.NoError(t, err)
+	assert.Equal(t, sdt, parsed)
+}
+
+func TestSectionAssembler(t *testing.T) {
+	section, err := testPMT().Marshal()
+	assert.NoError(t, err)
+	packets := SectionPackets(0x1000, 14, section)
+	assert.Equal(t, 2, len(packets))
+
+	// Packets go through Marshal and ParsePacket as they would through a
+	// file.
+	reparse := func(p *Packet) *Packet {
+		data, err := p.Marshal()
+		assert.NoError(t, err)
+		parsed, err := ParsePacket(gobits.NewSliceByteAccessor(data))
+		assert.NoError(t, err)
+		return parsed
+	}
+
+	a := NewSectionAssembler()
+	sections, err := a.Push(reparse(packets[0]))
+	assert.NoError(t, err)
+	assert.Empty(t, sections)
+	duplicate := reparse(packets[0])
+	sections, err = a.Push(duplicate)
+	assert.NoError(t, err)
+	assert.Empty(t, sections)
+	sections, err = a.Push(reparse(packets[1]))
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{section}, sections)
+	assert.Equal(t, byte(15), packets[1].Header.ContinuityCounter)
+
+	// A packet that ends one section and carries two more, followed by
+	// stuffing.
+	pat, err := (&PAT{
+		SectionHeader:     SectionHeader{TableID: TableIDPAT, VersionNumber: 1, CurrentNextIndicator: true},
+		TransportStreamID: 1,
+		Programs:          []PATProgram{{ProgramNumber: 0, PID: PIDNIT}, {ProgramNumber: 1, PID: 0x1000}},
+	}).Marshal()
+	assert.NoError(t, err)
+	first := SectionPackets(PIDPAT, 0, patSection)[0]
+	payload := append([]byte{0}, pat[:10]...)
+	first.Payload = gobits.NewSliceByteAccessor(payload)
+	payload = append([]byte{byte(len(pat) - 10)}, pat[10:]...)
+	payload = append(append(payload, patSection...), pat...)
+	payload = append(payload, bytes.Repeat([]byte{0xff}, payloadSize-len(payload))...)
+	second := &Packet{
+		Header:  Header{PayloadUnitStartIndicator: true, PID: PIDPAT, ContinuityCounter: 1},
+		Payload: gobits.NewSliceByteAccessor(payload),
+	}
+	sections, err = a.Push(reparse(first))
+	assert.NoError(t, err)
+	assert.Empty(t, sections)
+	sections, err = a.Push(reparse(second))
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{pat, patSection, pat}, sections)
+
+	// A lost packet drops the section it belonged to.
+	packets = SectionPackets(0x1000, 0, section)
+	_, err = a.Push(reparse(packets[0]))
+	assert.NoError(t, err)
+	lost := reparse(packets[1])
+	lost.Header.ContinuityCounter = 2
+	sections, err = a.Push(lost)
+	var fe *gobits.FieldError
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "continuity_counter", fe.Field)
+	assert.Empty(t, sections)
+
+	// Unless the discontinuity is signalled.
+	_, err = a.Push(reparse(packets[0]))
+	assert.NoError(t, err)
+	lost.AdaptationField = &AdaptationField{DiscontinuityIndicator: true}
+	lost.Header.ContinuityCounter = 9
+	sections, err = a.Push(lost)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{section}, sections)
+
+	corrupted := append([]byte{}, section...)
+	corrupted[20]++
+	packets = SectionPackets(0x1000, 10, corrupted)
+	_, err = a.Push(reparse(packets[0]))
+	assert.NoError(t, err)
+	sections, err = a.Push(reparse(packets[1]))
+	assert.True(t, errors.Is(err, gobits.ErrChecksum))
+	assert.Empty(t, sections)
+}
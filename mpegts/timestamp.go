@@ -0,0 +1,97 @@
+package mpegts
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+)
+
+const (
+	// TimestampFrequency is the frequency of the clock that PTS, DTS and
+	// the bases of clock references count, 90 kHz.
+	TimestampFrequency = 90000
+
+	timestampModulus = 1 << 33
+)
+
+// Timestamp is a PTS or DTS, a 33-bit count of the 90 kHz clock that
+// wraps around to zero. Its arithmetic is modulo 2^33.
+type Timestamp uint64
+
+// Add returns t advanced by ticks, which may be negative.
+func (t Timestamp) Add(ticks int64) Timestamp {
+	return Timestamp((int64(t) + ticks) & (timestampModulus - 1))
+}
+
+// Sub returns the number of ticks from u to t, taking the shorter way
+// around the 2^33 cycle, so that a timestamp just after a wraparound is
+// later than one just before it.
+func (t Timestamp) Sub(u Timestamp) int64 {
+	d := (int64(t) - int64(u)) & (timestampModulus - 1)
+	if d >= timestampModulus/2 {
+		d -= timestampModulus
+	}
+	return d
+}
+
+// Before reports whether t is earlier than u, assuming that they are less
+// than 2^32 ticks apart.
+func (t Timestamp) Before(u Timestamp) bool {
+	return t.Sub(u) < 0
+}
+
+// marker handles a marker_bit, which must be one.
+func marker(s *gobits.Syntax) {
+	bit := true
+	s.Flag(&bit, "marker_bit")
+	if s.Err() == nil && !bit {
+		s.Fail("marker_bit", gobits.ErrInvalidSyntax)
+	}
+}
+
+// markedBits33 handles a 33-bit value coded as 3, 15 and 15 bits, each
+// followed by a marker bit.
+func markedBits33(s *gobits.Syntax, val *uint64, field string) {
+	if !s.Reading() && *val >= timestampModulus {
+		s.Fail(field, gobits.ErrOutOfRange)
+		return
+	}
+	high := uint32(*val>>30) & 0x7
+	middle := uint32(*val>>15) & 0x7fff
+	low := uint32(*val) & 0x7fff
+	s.Bits(&high, 3, field+"[32..30]")
+	marker(s)
+	s.Bits(&middle, 15, field+"[29..15]")
+	marker(s)
+	s.Bits(&low, 15, field+"[14..0]")
+	marker(s)
+	if s.Reading() {
+		*val = uint64(high)<<30 | uint64(middle)<<15 | uint64(low)
+	}
+}
+
+// timestamp handles a PTS, DTS or TREF and the 4 bits before it. Those
+// bits are written as prefix and ignored when read, as some muxers get
+// them wrong.
+func timestamp(s *gobits.Syntax, prefix byte, val *Timestamp, field string) {
+	s.Byte(&prefix, 4, field+"_prefix")
+	v := uint64(*val)
+	markedBits33(s, &v, field)
+	*val = Timestamp(v)
+}
+
+// clockReference handles an SCR or ESCR, a base coded as markedBits33 and
+// a 9-bit extension followed by a marker bit, as one 27 MHz count.
+func clockReference(s *gobits.Syntax, val *uint64, field string) {
+	base := *val / pcrExtensionModulus
+	extension := uint32(*val % pcrExtensionModulus)
+	markedBits33(s, &base, field+"_base")
+	s.Bits(&extension, 9, field+"_extension")
+	marker(s)
+	if !s.Reading() || s.Err() != nil {
+		return
+	}
+	if extension >= pcrExtensionModulus {
+		s.Fail(field+"_extension", gobits.ErrOutOfRange)
+		return
+	}
+	*val = base*pcrExtensionModulus + uint64(extension)
+}
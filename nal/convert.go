@@ -0,0 +1,53 @@
+package nal
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+)
+
+func writeUnits(s *Scanner, prefix func(u Unit) ([]byte, error)) ([]byte, error) {
+	out := []byte{}
+	for s.Next() {
+		u := s.Unit()
+		p, err := prefix(u)
+		if err != nil {
+			return nil, err
+		}
+		data := u.Data.Slice(0, u.Length())
+		if int64(len(data)) != u.Length() {
+			return nil, gobits.ErrUnexpectedEOF
+		}
+		out = append(append(out, p...), data...)
+	}
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	return out, nil
+}
+
+// ToAVCC converts an Annex B byte stream to NAL units prefixed by their
+// length in lengthSize bytes.
+func ToAVCC(ba gobits.ByteAccessor, codec Codec, lengthSize int) ([]byte, error) {
+	if lengthSize != 1 && lengthSize != 2 && lengthSize != 4 {
+		return nil, gobits.ErrOutOfRange
+	}
+	return writeUnits(NewScanner(ba, codec), func(u Unit) ([]byte, error) {
+		length := u.Length()
+		if length >= int64(1)<<(8*lengthSize) {
+			return nil, gobits.ErrOutOfRange
+		}
+		p := make([]byte, lengthSize)
+		for i := lengthSize - 1; i >= 0; i-- {
+			p[i] = byte(length)
+			length >>= 8
+		}
+		return p, nil
+	})
+}
+
+// ToAnnexB converts length-prefixed NAL units to an Annex B byte stream
+// using four-byte start codes.
+func ToAnnexB(ba gobits.ByteAccessor, codec Codec, lengthSize int) ([]byte, error) {
+	return writeUnits(NewAVCCScanner(ba, codec, lengthSize), func(u Unit) ([]byte, error) {
+		return []byte{0x00, 0x00, 0x00, 0x01}, nil
+	})
+}
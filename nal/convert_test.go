@@ -0,0 +1,42 @@
+package nal
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToAVCC(t *testing.T) {
+	avcc, err := ToAVCC(gobits.NewSliceByteAccessor(annexB), H264, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x00, 0x00, 0x00, 0x04, 0x67, 0x42, 0x00, 0x1e,
+		0x00, 0x00, 0x00, 0x04, 0x68, 0xce, 0x38, 0x80,
+		0x00, 0x00, 0x00, 0x06, 0x65, 0x88, 0x00, 0x00, 0x03, 0x01,
+	}, avcc)
+
+	annexB2, err := ToAnnexB(gobits.NewSliceByteAccessor(avcc), H264, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x00, 0x00, 0x00, 0x01, 0x67, 0x42, 0x00, 0x1e,
+		0x00, 0x00, 0x00, 0x01, 0x68, 0xce, 0x38, 0x80,
+		0x00, 0x00, 0x00, 0x01, 0x65, 0x88, 0x00, 0x00, 0x03, 0x01,
+	}, annexB2)
+
+	avcc, err = ToAVCC(gobits.NewSliceByteAccessor(annexB), H264, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(4), avcc[0])
+
+	_, err = ToAVCC(gobits.NewSliceByteAccessor(annexB), H264, 3)
+	assert.True(t, errors.Is(err, gobits.ErrOutOfRange))
+
+	long := append([]byte{0x00, 0x00, 0x01, 0x65}, make([]byte, 256)...)
+	long = append(long, 0x80)
+	_, err = ToAVCC(gobits.NewSliceByteAccessor(long), H264, 1)
+	assert.True(t, errors.Is(err, gobits.ErrOutOfRange))
+
+	_, err = ToAnnexB(gobits.NewSliceByteAccessor([]byte{0x00, 0x03, 0x65}), H264, 2)
+	assert.True(t, errors.Is(err, gobits.ErrUnexpectedEOF))
+}
@@ -0,0 +1,138 @@
+// Package nal splits H.264 and HEVC elementary streams into NAL units and
+// converts between Annex B and AVCC framing.
+package nal
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+)
+
+type Codec int
+
+const (
+	H264 Codec = iota
+	HEVC
+)
+
+// HeaderLength returns the length of the NAL unit header in bytes.
+func (c Codec) HeaderLength() int64 {
+	if c == HEVC {
+		return 2
+	}
+	return 1
+}
+
+// Header holds the fields of a NAL unit header. RefIdc is only used by
+// H.264; LayerID and TemporalIDPlus1 only by HEVC.
+type Header struct {
+	ForbiddenZeroBit byte
+	RefIdc           byte
+	Type             byte
+	LayerID          byte
+	TemporalIDPlus1  byte
+}
+
+// ParseHeader decodes the NAL unit header at the start of ba.
+func ParseHeader(ba gobits.ByteAccessor, codec Codec) (Header, error) {
+	r := gobits.NewSyntaxReader(gobits.NewBitStream(ba))
+	h := Header{}
+	h.ForbiddenZeroBit = byte(r.ReadBits(1, "forbidden_zero_bit"))
+	if codec == HEVC {
+		h.Type = byte(r.ReadBits(6, "nal_unit_type"))
+		h.LayerID = byte(r.ReadBits(6, "nuh_layer_id"))
+		h.TemporalIDPlus1 = byte(r.ReadBits(3, "nuh_temporal_id_plus1"))
+	} else {
+		h.RefIdc = byte(r.ReadBits(2, "nal_ref_idc"))
+		h.Type = byte(r.ReadBits(5, "nal_unit_type"))
+	}
+	return h, r.Err()
+}
+
+// Write encodes the header at the current position of bs.
+func (h Header) Write(bs *gobits.BitStream, codec Codec) bool {
+	if !bs.WriteBits(uint64(h.ForbiddenZeroBit), 1) {
+		return false
+	}
+	if codec == HEVC {
+		return bs.WriteBits(uint64(h.Type), 6) &&
+			bs.WriteBits(uint64(h.LayerID), 6) &&
+			bs.WriteBits(uint64(h.TemporalIDPlus1), 3)
+	}
+	return bs.WriteBits(uint64(h.RefIdc), 2) && bs.WriteBits(uint64(h.Type), 5)
+}
+
+// IsType returns a predicate for ReadRBSP that accepts the given NAL unit
+// types.
+func IsType(types ...byte) func(byte) bool {
+	return func(t byte) bool {
+		for _, u := range types {
+			if t == u {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ReadRBSP opens the RBSP of a NAL unit of codec and positions a reading
+// Syntax after its header. NAL unit types that isType rejects fail with
+// ErrInvalidSyntax.
+func ReadRBSP(ba gobits.ByteAccessor, codec Codec, isType func(byte) bool) (Header, *gobits.RBSPByteAccessor, *gobits.Syntax, error) {
+	rbsp, err := gobits.NewRBSPByteAccessor(ba)
+	if err != nil {
+		return Header{}, nil, nil, err
+	}
+	header, err := ParseHeader(rbsp, codec)
+	if err != nil {
+		return Header{}, nil, nil, err
+	}
+	if !isType(header.Type) {
+		return Header{}, nil, nil, &gobits.FieldError{Field: "nal_unit_type", Err: gobits.ErrInvalidSyntax}
+	}
+
+	bs := gobits.NewBitStream(rbsp)
+	bs.Seek(codec.HeaderLength(), 0)
+	return header, rbsp, gobits.NewReadingSyntax(bs), nil
+}
+
+// WriteRBSP encodes a NAL unit of codec whose RBSP is produced by syntax,
+// inserting emulation prevention bytes.
+func WriteRBSP(header Header, codec Codec, syntax func(s *gobits.Syntax)) ([]byte, error) {
+	ba := gobits.NewGrowableByteAccessor(nil)
+	rbsp, err := gobits.NewRBSPByteAccessor(ba)
+	if err != nil {
+		return nil, err
+	}
+	bs := gobits.NewBitStream(rbsp)
+	if !header.Write(bs, codec) {
+		return nil, bs.Err()
+	}
+	s := gobits.NewWritingSyntax(bs)
+	syntax(s)
+	if s.Err() != nil {
+		return nil, s.Err()
+	}
+	return ba.Bytes(), nil
+}
+
+// Unit is a NAL unit found by a Scanner. Data covers the NAL unit, header
+// included, without start code or length prefix and shares the scanned
+// accessor.
+type Unit struct {
+	// Offset is the position of the first NAL unit byte in the stream.
+	Offset int64
+	// PrefixLength is the length of the start code or length field in
+	// front of the NAL unit.
+	PrefixLength int
+	Header       Header
+	Data         *gobits.SectionByteAccessor
+}
+
+func (u Unit) Length() int64 {
+	return u.Data.Length()
+}
+
+// RBSP returns an accessor for the unit's payload with emulation
+// prevention bytes removed.
+func (u Unit) RBSP() (*gobits.RBSPByteAccessor, error) {
+	return gobits.NewRBSPByteAccessor(u.Data)
+}
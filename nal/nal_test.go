@@ -0,0 +1,67 @@
+package nal
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHeader(t *testing.T) {
+	h, err := ParseHeader(gobits.NewSliceByteAccessor([]byte{0x67}), H264)
+	assert.NoError(t, err)
+	assert.Equal(t, Header{RefIdc: 3, Type: 7}, h)
+
+	h, err = ParseHeader(gobits.NewSliceByteAccessor([]byte{0x40, 0x01}), HEVC)
+	assert.NoError(t, err)
+	assert.Equal(t, Header{Type: 32, TemporalIDPlus1: 1}, h)
+
+	h, err = ParseHeader(gobits.NewSliceByteAccessor([]byte{0xcb, 0x0a}), HEVC)
+	assert.NoError(t, err)
+	assert.Equal(t, Header{ForbiddenZeroBit: 1, Type: 37, LayerID: 33, TemporalIDPlus1: 2}, h)
+
+	_, err = ParseHeader(gobits.NewSliceByteAccessor([]byte{0x40}), HEVC)
+	var fieldErr *gobits.FieldError
+	assert.True(t, errors.As(err, &fieldErr))
+	assert.Equal(t, "nuh_layer_id", fieldErr.Field)
+	assert.True(t, errors.Is(err, gobits.ErrUnexpectedEOF))
+}
+
+func TestHeader_Write(t *testing.T) {
+	for codec, raw := range map[Codec][]byte{H264: {0x65}, HEVC: {0xcb, 0x0a}} {
+		h, err := ParseHeader(gobits.NewSliceByteAccessor(raw), codec)
+		assert.NoError(t, err)
+
+		ba := gobits.NewGrowableByteAccessor(nil)
+		assert.True(t, h.Write(gobits.NewBitStream(ba), codec))
+		assert.Equal(t, raw, ba.Bytes())
+		assert.Equal(t, int64(len(raw)), codec.HeaderLength())
+	}
+}
+
+func TestReadRBSP(t *testing.T) {
+	header := Header{Type: 32, TemporalIDPlus1: 1}
+	payload := uint32(1)
+	raw, err := WriteRBSP(header, HEVC, func(s *gobits.Syntax) {
+		s.Bits(&payload, 24, "payload")
+		s.RBSPTrailingBits()
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x40, 0x01, 0x00, 0x00, 0x03, 0x01, 0x80}, raw)
+
+	parsed, rbsp, s, err := ReadRBSP(gobits.NewSliceByteAccessor(raw), HEVC, IsType(32, 33))
+	assert.NoError(t, err)
+	assert.Equal(t, header, parsed)
+	payload = 0
+	s.Bits(&payload, 24, "payload")
+	assert.NoError(t, s.Err())
+	assert.Equal(t, uint32(1), payload)
+	assert.False(t, rbsp.MoreRBSPData(s.BitStream().Tell()))
+
+	_, _, _, err = ReadRBSP(gobits.NewSliceByteAccessor(raw), HEVC, IsType(33))
+	var fieldErr *gobits.FieldError
+	assert.True(t, errors.As(err, &fieldErr))
+	assert.Equal(t, "nal_unit_type", fieldErr.Field)
+	assert.True(t, errors.Is(err, gobits.ErrInvalidSyntax))
+}
@@ -0,0 +1,145 @@
+package nal
+
+import (
+	"github.com/ibbbpbbbp/gobits"
+)
+
+var (
+	startCode = []byte{0x00, 0x00, 0x01}
+)
+
+// Scanner iterates over the NAL units of an Annex B byte stream or of
+// AVCC length-prefixed data. Call Next until it returns false, then check
+// Err.
+type Scanner struct {
+	ba         gobits.ByteAccessor
+	codec      Codec
+	lengthSize int
+	offset     int64
+	unit       Unit
+	err        error
+}
+
+// findStartCode returns the offset of the next 00 00 01 sequence at or
+// after byteOffset, or -1 if there is none.
+func findStartCode(ba gobits.ByteAccessor, byteOffset int64) int64 {
+	return gobits.FindSyncword(ba, byteOffset, startCode, nil)
+}
+
+func (s *Scanner) isZero(byteOffset int64) bool {
+	b, ok := s.ba.At(byteOffset)
+	return ok && b == 0x00
+}
+
+func (s *Scanner) nextAnnexB() (start, end int64, prefixLength int, ok bool) {
+	for {
+		startCodeOffset := findStartCode(s.ba, s.offset)
+		if startCodeOffset < 0 {
+			s.offset = s.ba.Length()
+			return 0, 0, 0, false
+		}
+
+		start = startCodeOffset + int64(len(startCode))
+		end = findStartCode(s.ba, start)
+		if end < 0 {
+			end = s.ba.Length()
+		}
+		s.offset = end
+
+		// Drop trailing_zero_8bits and the zero_byte of a following
+		// four-byte start code.
+		for end > start && s.isZero(end-1) {
+			end--
+		}
+		if end == start {
+			continue
+		}
+
+		prefixLength = len(startCode)
+		if startCodeOffset > 0 && s.isZero(startCodeOffset-1) {
+			prefixLength++
+		}
+		return start, end, prefixLength, true
+	}
+}
+
+func (s *Scanner) nextAVCC() (start, end int64, prefixLength int, ok bool, err error) {
+	for {
+		if s.offset == s.ba.Length() {
+			return 0, 0, 0, false, nil
+		}
+		prefix := s.ba.Slice(s.offset, int64(s.lengthSize))
+		if len(prefix) != s.lengthSize {
+			return 0, 0, 0, false, gobits.ErrUnexpectedEOF
+		}
+		length := int64(0)
+		for _, b := range prefix {
+			length = length<<8 | int64(b)
+		}
+
+		start = s.offset + int64(s.lengthSize)
+		end = start + length
+		if s.ba.Length() < end {
+			return 0, 0, 0, false, gobits.ErrUnexpectedEOF
+		}
+		s.offset = end
+		if length > 0 {
+			return start, end, s.lengthSize, true, nil
+		}
+	}
+}
+
+// Next advances to the next NAL unit. It returns false at the end of the
+// data or on error.
+func (s *Scanner) Next() bool {
+	if s.err != nil {
+		return false
+	}
+
+	var start, end int64
+	var prefixLength int
+	var ok bool
+	if s.lengthSize == 0 {
+		start, end, prefixLength, ok = s.nextAnnexB()
+	} else {
+		start, end, prefixLength, ok, s.err = s.nextAVCC()
+	}
+	if !ok {
+		return false
+	}
+
+	data := gobits.NewSectionByteAccessor(s.ba, start, end-start)
+	header, err := ParseHeader(data, s.codec)
+	if err != nil {
+		s.err = err
+		return false
+	}
+	s.unit = Unit{Offset: start, PrefixLength: prefixLength, Header: header, Data: data}
+	return true
+}
+
+// Unit returns the NAL unit found by the last successful call to Next.
+func (s *Scanner) Unit() Unit {
+	return s.unit
+}
+
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// NewScanner returns a scanner for an Annex B byte stream, in which NAL
+// units are separated by 00 00 01 or 00 00 00 01 start codes.
+func NewScanner(ba gobits.ByteAccessor, codec Codec) *Scanner {
+	return &Scanner{ba: ba, codec: codec}
+}
+
+// NewAVCCScanner returns a scanner for NAL units that are each prefixed by
+// their length as a big-endian integer of lengthSize bytes, as in MP4
+// samples.
+func NewAVCCScanner(ba gobits.ByteAccessor, codec Codec, lengthSize int) *Scanner {
+	s := &Scanner{ba: ba, codec: codec, lengthSize: lengthSize}
+	if lengthSize != 1 && lengthSize != 2 && lengthSize != 4 {
+		s.err = gobits.ErrOutOfRange
+	}
+	return s
+}
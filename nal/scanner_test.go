@@ -0,0 +1,122 @@
+package nal
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ibbbpbbbp/gobits"
+	"github.com/stretchr/testify/assert"
+)
+
+var annexB = []byte{
+	0x00, 0x00, 0x00, 0x01, 0x67, 0x42, 0x00, 0x1e, // SPS
+	0x00, 0x00, 0x01, 0x68, 0xce, 0x38, 0x80, 0x00, // PPS, trailing_zero_8bits
+	0x00, 0x00, 0x00, 0x01, 0x65, 0x88, 0x00, 0x00, 0x03, 0x01, // IDR slice
+}
+
+func TestScanner(t *testing.T) {
+	s := NewScanner(gobits.NewSliceByteAccessor(annexB), H264)
+	units := []Unit{}
+	for s.Next() {
+		units = append(units, s.Unit())
+	}
+	assert.NoError(t, s.Err())
+	assert.False(t, s.Next())
+
+	assert.Len(t, units, 3)
+	assert.Equal(t, int64(4), units[0].Offset)
+	assert.Equal(t, 4, units[0].PrefixLength)
+	assert.Equal(t, Header{RefIdc: 3, Type: 7}, units[0].Header)
+	assert.Equal(t, []byte{0x67, 0x42, 0x00, 0x1e}, units[0].Data.Slice(0, 10))
+
+	assert.Equal(t, int64(11), units[1].Offset)
+	assert.Equal(t, 3, units[1].PrefixLength)
+	assert.Equal(t, int64(4), units[1].Length())
+	assert.Equal(t, byte(8), units[1].Header.Type)
+
+	assert.Equal(t, int64(20), units[2].Offset)
+	assert.Equal(t, 4, units[2].PrefixLength)
+	assert.Equal(t, byte(5), units[2].Header.Type)
+	rbsp, err := units[2].RBSP()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x65, 0x88, 0x00, 0x00, 0x01}, rbsp.Slice(0, 10))
+}
+
+func TestScanner_Empty(t *testing.T) {
+	for _, data := range [][]byte{nil, {0x12, 0x34}, {0x00, 0x00, 0x01}, {0x00, 0x00, 0x01, 0x00, 0x00, 0x01}} {
+		s := NewScanner(gobits.NewSliceByteAccessor(data), H264)
+		assert.False(t, s.Next())
+		assert.NoError(t, s.Err())
+	}
+
+	s := NewScanner(gobits.NewSliceByteAccessor([]byte{0x00, 0x00, 0x01, 0x40}), HEVC)
+	assert.False(t, s.Next())
+	assert.True(t, errors.Is(s.Err(), gobits.ErrUnexpectedEOF))
+}
+
+// TestScanner_IOByteAccessor places start codes across the accessor's
+// buffer boundaries and the scanner's chunk boundaries.
+func TestScanner_IOByteAccessor(t *testing.T) {
+	file, err := ioutil.TempFile("", "gobits-nal")
+	assert.NoError(t, err)
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	// The chunk size of gobits.FindSyncword.
+	const scanChunkSize = int64(64 * 1024)
+	data := make([]byte, 3*scanChunkSize)
+	for i := range data {
+		data[i] = byte(i%250) + 1
+	}
+	offsets := []int64{0, 4095, 8191, scanChunkSize - 2, scanChunkSize*2 - 1, int64(len(data)) - 5}
+	for _, offset := range offsets {
+		copy(data[offset:], []byte{0x00, 0x00, 0x01, 0x09, 0xf0})
+	}
+	_, err = file.Write(data)
+	assert.NoError(t, err)
+
+	s := NewScanner(gobits.NewIOByteAccessor(file), H264)
+	for i, offset := range offsets {
+		assert.True(t, s.Next(), "unit %d", i)
+		u := s.Unit()
+		assert.Equal(t, offset+3, u.Offset)
+		assert.Equal(t, byte(9), u.Header.Type)
+		if i+1 < len(offsets) {
+			assert.Equal(t, offsets[i+1]-offset-3, u.Length())
+		} else {
+			assert.Equal(t, int64(2), u.Length())
+		}
+		b, ok := u.Data.At(1)
+		assert.True(t, ok)
+		assert.Equal(t, byte(0xf0), b)
+	}
+	assert.False(t, s.Next())
+	assert.NoError(t, s.Err())
+}
+
+func TestAVCCScanner(t *testing.T) {
+	data := []byte{0x00, 0x02, 0x09, 0xf0, 0x00, 0x00, 0x00, 0x01, 0x68}
+	s := NewAVCCScanner(gobits.NewSliceByteAccessor(data), H264, 2)
+	assert.True(t, s.Next())
+	assert.Equal(t, Unit{Offset: 2, PrefixLength: 2, Header: Header{Type: 9}, Data: s.Unit().Data}, s.Unit())
+	assert.True(t, s.Next())
+	assert.Equal(t, int64(8), s.Unit().Offset)
+	assert.Equal(t, byte(8), s.Unit().Header.Type)
+	assert.False(t, s.Next())
+	assert.NoError(t, s.Err())
+
+	s = NewAVCCScanner(gobits.NewSliceByteAccessor([]byte{0x00, 0x05, 0x09}), H264, 2)
+	assert.False(t, s.Next())
+	assert.True(t, errors.Is(s.Err(), gobits.ErrUnexpectedEOF))
+
+	s = NewAVCCScanner(gobits.NewSliceByteAccessor([]byte{0x00, 0x01, 0x09, 0x00}), H264, 2)
+	assert.True(t, s.Next())
+	assert.False(t, s.Next())
+	assert.True(t, errors.Is(s.Err(), gobits.ErrUnexpectedEOF))
+
+	s = NewAVCCScanner(gobits.NewSliceByteAccessor(data), H264, 3)
+	assert.False(t, s.Next())
+	assert.True(t, errors.Is(s.Err(), gobits.ErrOutOfRange))
+}
@@ -0,0 +1,253 @@
+package gobits
+
+import (
+	"errors"
+	"math/bits"
+	"sort"
+)
+
+const (
+	emulationPreventionByte = 0x03
+)
+
+// RBSPByteAccessor presents the raw byte sequence payload (RBSP) of an H.264
+// or HEVC NAL unit, removing emulation prevention bytes from the wrapped
+// accessor on read and re-inserting them on write.
+//
+// The RBSP is decoded once at construction. Writes update it and re-encode
+// the NAL unit from the first modified byte onwards; writes that lengthen
+// the encoded NAL unit require the wrapped accessor to implement
+// ExtendableByteAccessor. When a write removes emulation prevention bytes,
+// the wrapped accessor is truncated if it implements
+// TruncatableByteAccessor and otherwise keeps stale bytes beyond RawLength.
+type RBSPByteAccessor struct {
+	SliceByteAccessor
+	raw       ByteAccessor
+	rawLength int64
+	// epb holds, in order, the RBSP offsets that are preceded by an
+	// emulation prevention byte. An offset equal to the RBSP length stands
+	// for a final 0x03 appended after trailing zero bytes.
+	epb []int64
+}
+
+func (ba *RBSPByteAccessor) decode() error {
+	data := []byte{}
+	zeros := 0
+	length := ba.raw.Length()
+	for offset := int64(0); offset < length; offset++ {
+		b, err := byteAt(ba.raw, offset)
+		if errors.Is(err, ErrUnexpectedEOF) {
+			length = offset
+			break
+		} else if err != nil {
+			return err
+		}
+		if zeros >= 2 && b == emulationPreventionByte {
+			ba.epb = append(ba.epb, int64(len(data)))
+			zeros = 0
+			continue
+		}
+		data = append(data, b)
+		if b == 0x00 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	ba.bytes = data
+	ba.rawLength = length
+	return nil
+}
+
+// epbBefore returns the number of emulation prevention bytes preceding the
+// RBSP byte at byteOffset.
+func (ba *RBSPByteAccessor) epbBefore(byteOffset int64) int {
+	return sort.Search(len(ba.epb), func(i int) bool {
+		return ba.epb[i] >= byteOffset
+	})
+}
+
+func (ba *RBSPByteAccessor) hasEPB(byteOffset int64) bool {
+	i := ba.epbBefore(byteOffset)
+	return i < len(ba.epb) && ba.epb[i] == byteOffset
+}
+
+// zerosBefore returns how many zero bytes, up to two, immediately precede
+// byteOffset in the encoded NAL unit.
+func (ba *RBSPByteAccessor) zerosBefore(byteOffset int64) int {
+	zeros := 0
+	for i := byteOffset - 1; i >= 0 && zeros < 2 && ba.bytes[i] == 0x00; i-- {
+		zeros++
+		if ba.hasEPB(i) {
+			break
+		}
+	}
+	return zeros
+}
+
+// encode returns the encoded NAL unit bytes for the RBSP from byteOffset
+// onwards, together with the emulation prevention byte offsets they contain.
+func (ba *RBSPByteAccessor) encode(byteOffset int64) ([]byte, []int64) {
+	raw := make([]byte, 0, int64(len(ba.bytes))-byteOffset)
+	epb := []int64{}
+	zeros := ba.zerosBefore(byteOffset)
+	for i := byteOffset; i < int64(len(ba.bytes)); i++ {
+		b := ba.bytes[i]
+		if zeros >= 2 && b <= emulationPreventionByte {
+			raw = append(raw, emulationPreventionByte)
+			epb = append(epb, i)
+			zeros = 0
+		}
+		raw = append(raw, b)
+		if b == 0x00 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	if zeros >= 2 {
+		raw = append(raw, emulationPreventionByte)
+		epb = append(epb, int64(len(ba.bytes)))
+	}
+	return raw, epb
+}
+
+// update re-encodes the NAL unit from byteOffset and writes it through to
+// the wrapped accessor.
+func (ba *RBSPByteAccessor) update(byteOffset int64) error {
+	kept := ba.epbBefore(byteOffset)
+	rawOffset := byteOffset + int64(kept)
+	raw, epb := ba.encode(byteOffset)
+
+	rawLength := rawOffset + int64(len(raw))
+	if rawLength > ba.raw.Length() {
+		eba, ok := ba.raw.(ExtendableByteAccessor)
+		if !ok {
+			return ErrOutOfRange
+		}
+		if err := eba.Extend(rawLength); err != nil {
+			return err
+		}
+	}
+	if err := putBytes(ba.raw, raw, rawOffset); err != nil {
+		return err
+	}
+	if tba, ok := ba.raw.(TruncatableByteAccessor); ok && rawLength < ba.rawLength {
+		if err := tba.Truncate(rawLength); err != nil {
+			return err
+		}
+	}
+
+	ba.epb = append(ba.epb[:kept], epb...)
+	ba.rawLength = rawLength
+	return nil
+}
+
+func (ba *RBSPByteAccessor) PutBytes(bytes []byte, byteOffset int64) error {
+	if byteOffset < 0 || int64(len(ba.bytes)) < byteOffset || bytes == nil {
+		return ErrInvalidOffset
+	}
+
+	oldLength := len(ba.bytes)
+	end := byteOffset + int64(len(bytes))
+	if end > int64(oldLength) {
+		ba.bytes = append(ba.bytes, make([]byte, end-int64(oldLength))...)
+	}
+	old := append([]byte{}, ba.bytes[byteOffset:end]...)
+	copy(ba.bytes[byteOffset:], bytes)
+
+	if err := ba.update(byteOffset); err != nil {
+		copy(ba.bytes[byteOffset:], old)
+		ba.bytes = ba.bytes[:oldLength]
+		ba.restore(byteOffset)
+		return err
+	}
+	return nil
+}
+
+// restore re-encodes the unchanged RBSP from byteOffset after a failed
+// update, undoing whatever part of the write reached the wrapped accessor.
+// The emulation prevention bytes before byteOffset are never touched by an
+// update, and those from byteOffset on are recomputed as before. The
+// restore is best effort: an accessor that failed the write may fail again.
+func (ba *RBSPByteAccessor) restore(byteOffset int64) {
+	raw, _ := ba.encode(byteOffset)
+	putBytes(ba.raw, raw, byteOffset+int64(ba.epbBefore(byteOffset)))
+}
+
+func (ba *RBSPByteAccessor) Put(bytes []byte, byteOffset int64) bool {
+	return ba.PutBytes(bytes, byteOffset) == nil
+}
+
+// Extend appends zero bytes to the RBSP. It fails unless the wrapped
+// accessor can be extended as well.
+func (ba *RBSPByteAccessor) Extend(length int64) error {
+	if length < 0 {
+		return ErrInvalidOffset
+	}
+	if length <= int64(len(ba.bytes)) {
+		return nil
+	}
+	return ba.PutBytes(make([]byte, length-int64(len(ba.bytes))), int64(len(ba.bytes)))
+}
+
+// RawLength returns the length of the encoded NAL unit.
+func (ba *RBSPByteAccessor) RawLength() int64 {
+	return ba.rawLength
+}
+
+// Raw returns the encoded NAL unit.
+func (ba *RBSPByteAccessor) Raw() []byte {
+	return ba.raw.Slice(0, ba.rawLength)
+}
+
+// EmulationPreventionBytes returns the number of emulation prevention bytes
+// in the encoded NAL unit.
+func (ba *RBSPByteAccessor) EmulationPreventionBytes() int {
+	return len(ba.epb)
+}
+
+// RawOffset maps an RBSP byte offset to the offset of the same byte in the
+// encoded NAL unit.
+func (ba *RBSPByteAccessor) RawOffset(byteOffset int64) (int64, bool) {
+	if byteOffset < 0 || ba.Length() < byteOffset {
+		return 0, false
+	}
+	return byteOffset + int64(ba.epbBefore(byteOffset+1)), true
+}
+
+// RawBitOffset maps an RBSP bit position, such as BitStream.Tell returns, to
+// the bit position in the encoded NAL unit.
+func (ba *RBSPByteAccessor) RawBitOffset(bitOffset int64) (int64, bool) {
+	rawOffset, ok := ba.RawOffset(bitOffset / 8)
+	return rawOffset*8 + bitOffset%8, ok
+}
+
+// TrailingBitsOffset returns the bit position of rbsp_stop_one_bit, skipping
+// any trailing zero bytes such as cabac_zero_words. It reports false if the
+// RBSP contains no set bit.
+func (ba *RBSPByteAccessor) TrailingBitsOffset() (int64, bool) {
+	for i := len(ba.bytes) - 1; i >= 0; i-- {
+		if b := ba.bytes[i]; b != 0x00 {
+			return int64(i)*8 + 7 - int64(bits.TrailingZeros8(b)), true
+		}
+	}
+	return 0, false
+}
+
+// MoreRBSPData implements more_rbsp_data() for a stream positioned at
+// bitOffset: it reports whether syntax data remains before
+// rbsp_trailing_bits.
+func (ba *RBSPByteAccessor) MoreRBSPData(bitOffset int64) bool {
+	stop, ok := ba.TrailingBitsOffset()
+	return ok && bitOffset < stop
+}
+
+// NewRBSPByteAccessor decodes the NAL unit held in ba.
+func NewRBSPByteAccessor(ba ByteAccessor) (*RBSPByteAccessor, error) {
+	rba := &RBSPByteAccessor{raw: ba}
+	if err := rba.decode(); err != nil {
+		return nil, err
+	}
+	return rba, nil
+}
@@ -0,0 +1,153 @@
+package gobits
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRBSPByteAccessor_Read(t *testing.T) {
+	raw := []byte{0x67, 0x00, 0x00, 0x03, 0x01, 0x00, 0x00, 0x03, 0x00, 0x00, 0x03, 0x03, 0x80, 0x00, 0x00, 0x03}
+	rba, err := NewRBSPByteAccessor(NewSliceByteAccessor(raw))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12), rba.Length())
+	assert.Equal(t, []byte{0x67, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x03, 0x80, 0x00, 0x00}, rba.Slice(0, 12))
+	assert.Equal(t, 4, rba.EmulationPreventionBytes())
+	assert.Equal(t, int64(16), rba.RawLength())
+	assert.Equal(t, raw, rba.Raw())
+
+	rawOffsets := []int64{0, 1, 2, 4, 5, 6, 8, 9, 11, 12, 13, 14, 16}
+	for i, expected := range rawOffsets {
+		rawOffset, ok := rba.RawOffset(int64(i))
+		assert.True(t, ok)
+		assert.Equal(t, expected, rawOffset, "offset %d", i)
+	}
+	_, ok := rba.RawOffset(13)
+	assert.False(t, ok)
+	rawBitOffset, ok := rba.RawBitOffset(3*8 + 5)
+	assert.True(t, ok)
+	assert.Equal(t, int64(4*8+5), rawBitOffset)
+
+	bs := NewBitStream(rba)
+	assert.True(t, bs.ConsumeBytes(1))
+	val, ok := bs.ReadBits(24)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), val)
+
+	_, err = rba.ByteAt(12)
+	assert.True(t, errors.Is(err, ErrUnexpectedEOF))
+}
+
+func TestRBSPByteAccessor_TrailingBits(t *testing.T) {
+	// ue(v) 3, se(v) -1, rbsp_trailing_bits and a cabac_zero_word.
+	rba, err := NewRBSPByteAccessor(NewSliceByteAccessor([]byte{0x23, 0x80, 0x00, 0x00, 0x03}))
+	assert.NoError(t, err)
+	stop, ok := rba.TrailingBitsOffset()
+	assert.True(t, ok)
+	assert.Equal(t, int64(8), stop)
+
+	bs := NewBitStream(rba)
+	assert.True(t, rba.MoreRBSPData(bs.Tell()))
+	val, ok := bs.ReadExponentialGolomb()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(3), val)
+	assert.True(t, rba.MoreRBSPData(bs.Tell()))
+	sval, ok := bs.ReadSignedExponentialGolomb()
+	assert.True(t, ok)
+	assert.Equal(t, int64(-1), sval)
+	assert.False(t, rba.MoreRBSPData(bs.Tell()))
+	assert.True(t, bs.AlignReadPadding(8, PadRBSPTrailingBits))
+
+	rba, err = NewRBSPByteAccessor(NewSliceByteAccessor([]byte{0x00, 0x00}))
+	assert.NoError(t, err)
+	_, ok = rba.TrailingBitsOffset()
+	assert.False(t, ok)
+	assert.False(t, rba.MoreRBSPData(0))
+}
+
+func TestRBSPByteAccessor_Write(t *testing.T) {
+	ba := NewGrowableByteAccessor(nil)
+	rba, err := NewRBSPByteAccessor(ba)
+	assert.NoError(t, err)
+
+	bs := NewBitStream(rba)
+	for _, b := range []uint64{0x00, 0x00, 0x01, 0x00, 0x00, 0x00} {
+		assert.True(t, bs.WriteBits(b, 8))
+	}
+	assert.Equal(t, []byte{0x00, 0x00, 0x03, 0x01, 0x00, 0x00, 0x03, 0x00}, rba.Raw())
+	assert.True(t, bs.WriteBits(1, 1))
+	assert.True(t, bs.PadToByteBoundary(PadZeros))
+	assert.Equal(t, []byte{0x00, 0x00, 0x03, 0x01, 0x00, 0x00, 0x03, 0x00, 0x80}, rba.Raw())
+	assert.Equal(t, ba.Bytes(), rba.Raw())
+
+	// Clearing the emulated start code removes its prevention byte.
+	assert.True(t, rba.Put([]byte{0xff}, 4))
+	assert.Equal(t, []byte{0x00, 0x00, 0x03, 0x01, 0x00, 0xff, 0x00, 0x80}, rba.Raw())
+	assert.Equal(t, ba.Bytes(), rba.Raw())
+	assert.Equal(t, int64(8), rba.RawLength())
+	assert.Equal(t, 1, rba.EmulationPreventionBytes())
+
+	rba, err = NewRBSPByteAccessor(NewSliceByteAccessor(rba.Raw()))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x00, 0x01, 0x00, 0xff, 0x00, 0x80}, rba.Slice(0, 7))
+}
+
+func TestRBSPByteAccessor_WriteCABACZeroWord(t *testing.T) {
+	ba := NewGrowableByteAccessor(nil)
+	rba, err := NewRBSPByteAccessor(ba)
+	assert.NoError(t, err)
+
+	assert.True(t, rba.Put([]byte{0x80, 0x00, 0x00}, 0))
+	assert.Equal(t, []byte{0x80, 0x00, 0x00, 0x03}, ba.Bytes())
+	assert.True(t, rba.Put([]byte{0x00, 0x00}, 3))
+	assert.Equal(t, []byte{0x80, 0x00, 0x00, 0x03, 0x00, 0x00, 0x03}, ba.Bytes())
+	assert.Equal(t, int64(5), rba.Length())
+}
+
+func TestRBSPByteAccessor_WriteFixedLength(t *testing.T) {
+	raw := []byte{0x00, 0x01, 0x00, 0x02}
+	rba, err := NewRBSPByteAccessor(NewSliceByteAccessor(raw))
+	assert.NoError(t, err)
+
+	assert.True(t, rba.Put([]byte{0x05}, 1))
+	assert.Equal(t, []byte{0x00, 0x05, 0x00, 0x02}, raw)
+
+	// An emulation prevention byte would not fit.
+	err = rba.PutBytes([]byte{0x00}, 1)
+	assert.True(t, errors.Is(err, ErrOutOfRange))
+	assert.Equal(t, []byte{0x00, 0x05, 0x00, 0x02}, rba.Slice(0, 4))
+	assert.Equal(t, []byte{0x00, 0x05, 0x00, 0x02}, raw)
+
+	assert.True(t, errors.Is(rba.Extend(5), ErrOutOfRange))
+	assert.Equal(t, int64(4), rba.Length())
+	assert.NoError(t, rba.Extend(2))
+	assert.True(t, errors.Is(rba.Extend(-1), ErrInvalidOffset))
+	assert.True(t, errors.Is(rba.PutBytes([]byte{0x00}, 5), ErrInvalidOffset))
+	assert.False(t, rba.Put(nil, 0))
+}
+
+// failingTruncateByteAccessor is a growable accessor whose Truncate fails.
+type failingTruncateByteAccessor struct {
+	*GrowableByteAccessor
+}
+
+func (ba failingTruncateByteAccessor) Truncate(length int64) error {
+	return ErrIO
+}
+
+func TestRBSPByteAccessor_WriteRollback(t *testing.T) {
+	raw := []byte{0x00, 0x00, 0x03, 0x01, 0x80}
+	ba := failingTruncateByteAccessor{NewGrowableByteAccessor(append([]byte{}, raw...))}
+	rba, err := NewRBSPByteAccessor(ba)
+	assert.NoError(t, err)
+
+	// Dropping the prevention byte shortens the NAL unit, which fails after
+	// the shorter form has been written.
+	err = rba.PutBytes([]byte{0xff}, 1)
+	assert.True(t, errors.Is(err, ErrIO))
+	assert.Equal(t, raw, ba.Bytes())
+	assert.Equal(t, raw, rba.Raw())
+	assert.Equal(t, []byte{0x00, 0x00, 0x01, 0x80}, rba.Slice(0, 4))
+	assert.Equal(t, 1, rba.EmulationPreventionBytes())
+}
@@ -0,0 +1,67 @@
+package gobits
+
+// SectionByteAccessor exposes length bytes of another accessor starting at
+// a fixed offset. Reads and writes are confined to the section; the
+// underlying data is shared, not copied.
+type SectionByteAccessor struct {
+	ba         ByteAccessor
+	byteOffset int64
+	length     int64
+}
+
+func (ba *SectionByteAccessor) ByteAt(byteOffset int64) (byte, error) {
+	if byteOffset < 0 {
+		return 0, ErrInvalidOffset
+	}
+	if ba.length <= byteOffset {
+		return 0, ErrUnexpectedEOF
+	}
+	return byteAt(ba.ba, ba.byteOffset+byteOffset)
+}
+
+func (ba *SectionByteAccessor) At(byteOffset int64) (byte, bool) {
+	b, err := ba.ByteAt(byteOffset)
+	return b, err == nil
+}
+
+func (ba *SectionByteAccessor) Slice(byteOffset, length int64) []byte {
+	if byteOffset < 0 || length <= 0 || ba.length <= byteOffset {
+		return []byte{}
+	}
+	if ba.length < byteOffset+length {
+		length = ba.length - byteOffset
+	}
+	return ba.ba.Slice(ba.byteOffset+byteOffset, length)
+}
+
+func (ba *SectionByteAccessor) PutBytes(bytes []byte, byteOffset int64) error {
+	if byteOffset < 0 || ba.length < byteOffset+int64(len(bytes)) || bytes == nil {
+		return ErrInvalidOffset
+	}
+	return putBytes(ba.ba, bytes, ba.byteOffset+byteOffset)
+}
+
+func (ba *SectionByteAccessor) Put(bytes []byte, byteOffset int64) bool {
+	return ba.PutBytes(bytes, byteOffset) == nil
+}
+
+func (ba *SectionByteAccessor) Length() int64 {
+	return ba.length
+}
+
+// Offset returns the offset of the section in the underlying accessor.
+func (ba *SectionByteAccessor) Offset() int64 {
+	return ba.byteOffset
+}
+
+// NewSectionByteAccessor returns an accessor for length bytes of ba
+// starting at byteOffset.
+func NewSectionByteAccessor(ba ByteAccessor, byteOffset, length int64) *SectionByteAccessor {
+	if byteOffset < 0 {
+		byteOffset = 0
+	}
+	if length < 0 {
+		length = 0
+	}
+	return &SectionByteAccessor{ba: ba, byteOffset: byteOffset, length: length}
+}
@@ -0,0 +1,39 @@
+package gobits
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSectionByteAccessor(t *testing.T) {
+	bytes := []byte{0, 1, 2, 3, 4, 5, 6, 7}
+	ba := NewSectionByteAccessor(NewSliceByteAccessor(bytes), 2, 4)
+	assert.Equal(t, int64(4), ba.Length())
+	assert.Equal(t, int64(2), ba.Offset())
+
+	b, ok := ba.At(0)
+	assert.True(t, ok)
+	assert.Equal(t, byte(2), b)
+	_, err := ba.ByteAt(4)
+	assert.True(t, errors.Is(err, ErrUnexpectedEOF))
+	_, err = ba.ByteAt(-1)
+	assert.True(t, errors.Is(err, ErrInvalidOffset))
+
+	assert.Equal(t, []byte{3, 4, 5}, ba.Slice(1, 10))
+	assert.Equal(t, []byte{}, ba.Slice(4, 1))
+	assert.Equal(t, []byte{}, ba.Slice(-1, 1))
+
+	assert.True(t, ba.Put([]byte{0xaa, 0xbb}, 2))
+	assert.Equal(t, []byte{0, 1, 2, 3, 0xaa, 0xbb, 6, 7}, bytes)
+	assert.False(t, ba.Put([]byte{0xcc, 0xdd}, 3))
+	assert.Equal(t, []byte{0, 1, 2, 3, 0xaa, 0xbb, 6, 7}, bytes)
+
+	bs := NewBitStream(ba)
+	val, ok := bs.ReadBits(32)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(0x0203aabb), val)
+	_, ok = bs.ReadBits(1)
+	assert.False(t, ok)
+}
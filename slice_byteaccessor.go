@@ -4,11 +4,19 @@ type SliceByteAccessor struct {
 	bytes []byte
 }
 
-func (ba *SliceByteAccessor) At(byteOffset int64) (byte, bool) {
-	if byteOffset < 0 || int64(len(ba.bytes)) <= byteOffset {
-		return 0, false
+func (ba *SliceByteAccessor) ByteAt(byteOffset int64) (byte, error) {
+	if byteOffset < 0 {
+		return 0, ErrInvalidOffset
+	}
+	if int64(len(ba.bytes)) <= byteOffset {
+		return 0, ErrUnexpectedEOF
 	}
-	return ba.bytes[byteOffset], true
+	return ba.bytes[byteOffset], nil
+}
+
+func (ba *SliceByteAccessor) At(byteOffset int64) (byte, bool) {
+	b, err := ba.ByteAt(byteOffset)
+	return b, err == nil
 }
 
 func (ba *SliceByteAccessor) Slice(byteOffset, length int64) []byte {
@@ -23,7 +31,7 @@ func (ba *SliceByteAccessor) Slice(byteOffset, length int64) []byte {
 		last = bytesLen
 	}
 
-	if length == 0 {
+	if length <= 0 {
 		return []byte{}
 	}
 
@@ -32,13 +40,17 @@ func (ba *SliceByteAccessor) Slice(byteOffset, length int64) []byte {
 	return bytes
 }
 
-func (ba *SliceByteAccessor) Put(bytes []byte, byteOffset int64) bool {
-	if byteOffset < 0 || bytes == nil {
-		return false
+func (ba *SliceByteAccessor) PutBytes(bytes []byte, byteOffset int64) error {
+	if byteOffset < 0 || int64(len(ba.bytes)) < byteOffset || bytes == nil {
+		return ErrInvalidOffset
 	}
 
 	copy(ba.bytes[byteOffset:], bytes)
-	return true
+	return nil
+}
+
+func (ba *SliceByteAccessor) Put(bytes []byte, byteOffset int64) bool {
+	return ba.PutBytes(bytes, byteOffset) == nil
 }
 
 func (ba *SliceByteAccessor) Length() int64 {
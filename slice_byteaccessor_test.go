@@ -116,3 +116,28 @@ func TestSliceByteAccessor_Length(t *testing.T) {
 
 	assert.Equal(t, int64(6), ba.Length())
 }
+
+func TestSliceByteAccessor_ByteAt(t *testing.T) {
+	ba := NewSliceByteAccessor([]byte{1, 2, 3})
+
+	b, err := ba.ByteAt(2)
+	assert.Nil(t, err)
+	assert.Equal(t, byte(3), b)
+
+	_, err = ba.ByteAt(3)
+	assert.Equal(t, ErrUnexpectedEOF, err)
+
+	_, err = ba.ByteAt(-1)
+	assert.Equal(t, ErrInvalidOffset, err)
+}
+
+func TestSliceByteAccessor_PutBytes(t *testing.T) {
+	ba := NewSliceByteAccessor([]byte{1, 2, 3})
+
+	assert.Nil(t, ba.PutBytes([]byte{10}, 1))
+	assert.Equal(t, []byte{1, 10, 3}, ba.Slice(0, 3))
+
+	assert.Equal(t, ErrInvalidOffset, ba.PutBytes([]byte{10}, 4))
+	assert.Equal(t, ErrInvalidOffset, ba.PutBytes([]byte{10}, -1))
+	assert.Equal(t, ErrInvalidOffset, ba.PutBytes(nil, 0))
+}
@@ -0,0 +1,54 @@
+package gobits
+
+import (
+	"bytes"
+)
+
+const (
+	// syncChunkSize is how much data FindSyncword fetches from the accessor
+	// at a time.
+	syncChunkSize = int64(64 * 1024)
+)
+
+// FindSyncword returns the offset of the first occurrence of pattern at or
+// after byteOffset in ba, or -1 if there is none. Only the bits set in mask
+// are compared, except in the first byte, which must match in full; a nil
+// mask compares all bits. It returns -1 for an empty pattern or a mask
+// shorter than pattern. The data is fetched in chunks, so large
+// IOByteAccessors are searched without being read at once.
+func FindSyncword(ba ByteAccessor, byteOffset int64, pattern, mask []byte) int64 {
+	if len(pattern) == 0 || (mask != nil && len(mask) < len(pattern)) {
+		return -1
+	}
+	overlap := len(pattern) - 1
+	for {
+		chunk := ba.Slice(byteOffset, syncChunkSize+int64(overlap))
+		for i := 0; i+len(pattern) <= len(chunk); i++ {
+			j := bytes.IndexByte(chunk[i:len(chunk)-overlap], pattern[0])
+			if j < 0 {
+				break
+			}
+			i += j
+			if matchSyncword(chunk[i:], pattern, mask) {
+				return byteOffset + int64(i)
+			}
+		}
+		if int64(len(chunk)) < syncChunkSize+int64(overlap) {
+			return -1
+		}
+		byteOffset += syncChunkSize
+	}
+}
+
+func matchSyncword(data, pattern, mask []byte) bool {
+	for k := 1; k < len(pattern); k++ {
+		m := byte(0xff)
+		if mask != nil {
+			m = mask[k]
+		}
+		if data[k]&m != pattern[k]&m {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,25 @@
+package gobits
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindSyncword(t *testing.T) {
+	data := make([]byte, 2*syncChunkSize+16)
+	// A syncword split across the first two chunks, after a near miss.
+	data[100], data[101] = 0xff, 0x1f
+	data[syncChunkSize-1], data[syncChunkSize] = 0xff, 0xf3
+	data[2*syncChunkSize+8] = 0x47
+	ba := NewSliceByteAccessor(data)
+
+	pattern, mask := []byte{0xff, 0xe0}, []byte{0xff, 0xe0}
+	assert.Equal(t, syncChunkSize-1, FindSyncword(ba, 0, pattern, mask))
+	assert.Equal(t, int64(-1), FindSyncword(ba, syncChunkSize, pattern, mask))
+	assert.Equal(t, int64(-1), FindSyncword(ba, 0, []byte{0xff, 0xe0}, nil))
+	assert.Equal(t, 2*syncChunkSize+8, FindSyncword(ba, 0, []byte{0x47}, nil))
+	assert.Equal(t, int64(-1), FindSyncword(ba, 2*syncChunkSize+9, []byte{0x47}, nil))
+	assert.Equal(t, int64(-1), FindSyncword(ba, 0, nil, nil))
+	assert.Equal(t, int64(-1), FindSyncword(ba, 0, pattern, []byte{0xff}))
+}
@@ -0,0 +1,203 @@
+package gobits
+
+import (
+	"math"
+)
+
+// Syntax lets a bitstream syntax be described once and run in either
+// direction. Each method takes a pointer to a field: when reading, the
+// syntax element is read into it; when writing, its current value is
+// written. Conditions and loop bounds therefore see the same values in both
+// directions, which keeps a parse followed by a write bit-exact.
+type Syntax struct {
+	r *SyntaxReader
+	w *SyntaxWriter
+}
+
+func (s *Syntax) Reading() bool {
+	return s.r != nil
+}
+
+func (s *Syntax) BitStream() *BitStream {
+	if s.r != nil {
+		return s.r.BitStream()
+	}
+	return s.w.BitStream()
+}
+
+func (s *Syntax) Err() error {
+	if s.r != nil {
+		return s.r.Err()
+	}
+	return s.w.Err()
+}
+
+func (s *Syntax) Fail(field string, err error) {
+	if s.r != nil {
+		s.r.Fail(field, err)
+	} else {
+		s.w.Fail(field, err)
+	}
+}
+
+// Bits handles a fixed-length unsigned field of up to 32 bits, u(n).
+func (s *Syntax) Bits(val *uint32, bitCount byte, field string) {
+	if bitCount > 32 {
+		s.Fail(field, ErrInvalidBitCount)
+		return
+	}
+	if s.r != nil {
+		*val = uint32(s.r.ReadBits(bitCount, field))
+	} else {
+		s.w.WriteBits(uint64(*val), bitCount, field)
+	}
+}
+
+// Bits64 handles a fixed-length unsigned field of up to 64 bits.
+func (s *Syntax) Bits64(val *uint64, bitCount byte, field string) {
+	if s.r != nil {
+		*val = s.r.ReadBits(bitCount, field)
+	} else {
+		s.w.WriteBits(*val, bitCount, field)
+	}
+}
+
+// Bits16 handles a fixed-length unsigned field of up to 16 bits.
+func (s *Syntax) Bits16(val *uint16, bitCount byte, field string) {
+	if bitCount > 16 {
+		s.Fail(field, ErrInvalidBitCount)
+		return
+	}
+	if s.r != nil {
+		*val = uint16(s.r.ReadBits(bitCount, field))
+	} else {
+		s.w.WriteBits(uint64(*val), bitCount, field)
+	}
+}
+
+// Byte handles a fixed-length unsigned field of up to 8 bits.
+func (s *Syntax) Byte(val *byte, bitCount byte, field string) {
+	if bitCount > 8 {
+		s.Fail(field, ErrInvalidBitCount)
+		return
+	}
+	if s.r != nil {
+		*val = byte(s.r.ReadBits(bitCount, field))
+	} else {
+		s.w.WriteBits(uint64(*val), bitCount, field)
+	}
+}
+
+func (s *Syntax) Flag(val *bool, field string) {
+	if s.r != nil {
+		*val = s.r.ReadFlag(field)
+	} else {
+		s.w.WriteFlag(*val, field)
+	}
+}
+
+// SignedBits handles a two's complement field, i(n).
+func (s *Syntax) SignedBits(val *int32, bitCount byte, field string) {
+	if bitCount > 32 {
+		s.Fail(field, ErrInvalidBitCount)
+		return
+	}
+	if s.r != nil {
+		*val = int32(s.r.ReadSignedBits(bitCount, field))
+	} else {
+		s.w.WriteSignedBits(int64(*val), bitCount, field)
+	}
+}
+
+// ExponentialGolomb handles an ue(v) field. Values that do not fit in 32
+// bits are rejected with ErrOutOfRange.
+func (s *Syntax) ExponentialGolomb(val *uint32, field string) {
+	if s.r != nil {
+		v := s.r.ReadExponentialGolomb(field)
+		if v > math.MaxUint32 {
+			s.Fail(field, ErrOutOfRange)
+			return
+		}
+		*val = uint32(v)
+	} else {
+		s.w.WriteExponentialGolomb(uint64(*val), field)
+	}
+}
+
+// SignedExponentialGolomb handles an se(v) field.
+func (s *Syntax) SignedExponentialGolomb(val *int32, field string) {
+	if s.r != nil {
+		v := s.r.ReadSignedExponentialGolomb(field)
+		if v < math.MinInt32 || math.MaxInt32 < v {
+			s.Fail(field, ErrOutOfRange)
+			return
+		}
+		*val = int32(v)
+	} else {
+		s.w.WriteSignedExponentialGolomb(int64(*val), field)
+	}
+}
+
+// Align reads or writes padding up to the next multiple of alignBits.
+func (s *Syntax) Align(alignBits int64, padding Padding, field string) {
+	if s.r != nil {
+		s.r.AlignReadPadding(alignBits, padding, field)
+	} else {
+		s.w.AlignWrite(alignBits, padding, field)
+	}
+}
+
+// RBSPTrailingBits handles the rbsp_trailing_bits() of H.264 and HEVC, and
+// the byte_alignment() of HEVC, which has the same form.
+func (s *Syntax) RBSPTrailingBits() {
+	s.Align(8, PadRBSPTrailingBits, "rbsp_trailing_bits")
+}
+
+// Count handles an ue(v) field that gives the length of a list. When
+// writing, the caller sets it from the list; when reading, it is checked
+// against max before the caller allocates the list. It reports whether the
+// syntax may go on.
+func (s *Syntax) Count(n *uint32, max uint32, field string) bool {
+	s.ExponentialGolomb(n, field)
+	if s.Err() != nil {
+		return false
+	}
+	if *n > max {
+		s.Fail(field, ErrOutOfRange)
+		return false
+	}
+	return true
+}
+
+// ListLength checks, when writing, that a list has the length other syntax
+// elements imply. It reports whether the syntax may go on.
+func (s *Syntax) ListLength(length, expected int, field string) bool {
+	if s.Err() != nil {
+		return false
+	}
+	if !s.Reading() && length != expected {
+		s.Fail(field, ErrOutOfRange)
+		return false
+	}
+	return true
+}
+
+// CeilLog2 returns Ceil(Log2(n)), the width of u(v) fields that index n
+// entries.
+func CeilLog2(n uint32) byte {
+	bits := byte(0)
+	for uint64(1)<<bits < uint64(n) {
+		bits++
+	}
+	return bits
+}
+
+// NewReadingSyntax returns a Syntax that reads from bs.
+func NewReadingSyntax(bs *BitStream) *Syntax {
+	return &Syntax{r: NewSyntaxReader(bs)}
+}
+
+// NewWritingSyntax returns a Syntax that writes to bs.
+func NewWritingSyntax(bs *BitStream) *Syntax {
+	return &Syntax{w: NewSyntaxWriter(bs)}
+}
@@ -0,0 +1,182 @@
+package gobits
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// FieldError records the syntax element whose read failed first.
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// SyntaxReader wraps a BitStream with sticky error handling. Once a read
+// fails, every following read is a no-op returning zero, and Err reports the
+// first failure together with the field name and position.
+type SyntaxReader struct {
+	bs  *BitStream
+	err error
+}
+
+func (r *SyntaxReader) BitStream() *BitStream {
+	return r.bs
+}
+
+func (r *SyntaxReader) Err() error {
+	return r.err
+}
+
+// newFieldError attributes err to field, adding the current position of bs
+// unless err already carries one.
+func newFieldError(bs *BitStream, field string, err error) error {
+	if _, ok := err.(*BitStreamError); !ok {
+		err = &BitStreamError{
+			Op:         "Fail",
+			ByteOffset: bs.byteOffset,
+			BitOffset:  bs.bitOffset,
+			Err:        err,
+		}
+	}
+	return &FieldError{Field: field, Err: err}
+}
+
+func (r *SyntaxReader) Fail(field string, err error) {
+	if r.err != nil || err == nil {
+		return
+	}
+	r.err = newFieldError(r.bs, field, err)
+}
+
+func (r *SyntaxReader) check(field string, ok bool) {
+	if !ok {
+		r.Fail(field, r.bs.Err())
+	}
+}
+
+func (r *SyntaxReader) ReadBits(bitCount byte, field string) uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, ok := r.bs.ReadBits(bitCount)
+	r.check(field, ok)
+	return v
+}
+
+func (r *SyntaxReader) ReadFlag(field string) bool {
+	return r.ReadBits(1, field) != 0
+}
+
+func (r *SyntaxReader) PeekBits(bitCount byte, field string) uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, ok := r.bs.PeekBits(bitCount)
+	r.check(field, ok)
+	return v
+}
+
+func (r *SyntaxReader) ConsumeBits(bitCount int64, field string) {
+	if r.err != nil {
+		return
+	}
+	r.check(field, r.bs.ConsumeBits(bitCount))
+}
+
+func (r *SyntaxReader) ReadSignedBits(bitCount byte, field string) int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, ok := r.bs.ReadSignedBits(bitCount)
+	r.check(field, ok)
+	return v
+}
+
+func (r *SyntaxReader) AlignRead(alignBits int64, field string) {
+	if r.err != nil {
+		return
+	}
+	r.check(field, r.bs.AlignRead(alignBits))
+}
+
+func (r *SyntaxReader) AlignReadPadding(alignBits int64, padding Padding, field string) {
+	if r.err != nil {
+		return
+	}
+	r.check(field, r.bs.AlignReadPadding(alignBits, padding))
+}
+
+func (r *SyntaxReader) ReadUint8(field string) uint8 {
+	if r.err != nil {
+		return 0
+	}
+	v, ok := r.bs.ReadUint8()
+	r.check(field, ok)
+	return v
+}
+
+func (r *SyntaxReader) ReadUint16(bo binary.ByteOrder, field string) uint16 {
+	if r.err != nil {
+		return 0
+	}
+	v, ok := r.bs.ReadUint16(bo)
+	r.check(field, ok)
+	return v
+}
+
+func (r *SyntaxReader) ReadUint32(bo binary.ByteOrder, field string) uint32 {
+	if r.err != nil {
+		return 0
+	}
+	v, ok := r.bs.ReadUint32(bo)
+	r.check(field, ok)
+	return v
+}
+
+func (r *SyntaxReader) ReadUint64(bo binary.ByteOrder, field string) uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, ok := r.bs.ReadUint64(bo)
+	r.check(field, ok)
+	return v
+}
+
+func (r *SyntaxReader) ReadExponentialGolomb(field string) uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, ok := r.bs.ReadExponentialGolomb()
+	r.check(field, ok)
+	return v
+}
+
+func (r *SyntaxReader) ReadSignedExponentialGolomb(field string) int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, ok := r.bs.ReadSignedExponentialGolomb()
+	r.check(field, ok)
+	return v
+}
+
+func (r *SyntaxReader) ReadVLC(t *VLCTable, field string) interface{} {
+	if r.err != nil {
+		return nil
+	}
+	v, ok := r.bs.ReadVLC(t)
+	r.check(field, ok)
+	return v
+}
+
+func NewSyntaxReader(bs *BitStream) *SyntaxReader {
+	return &SyntaxReader{bs: bs}
+}
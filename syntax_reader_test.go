@@ -0,0 +1,90 @@
+package gobits
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSyntaxReader(t *testing.T) {
+	bs := NewBitStream(NewSliceByteAccessor([]byte{1}))
+	r := NewSyntaxReader(bs)
+	assert.NotNil(t, r)
+	assert.Equal(t, bs, r.BitStream())
+	assert.Nil(t, r.Err())
+}
+
+func TestSyntaxReader_Read(t *testing.T) {
+	r := NewSyntaxReader(NewBitStream(NewSliceByteAccessor(
+		[]byte{0x64, 0x00, 0x1f, 0xbc, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99})))
+
+	assert.Equal(t, uint64(0x64), r.ReadBits(8, "profile_idc"))
+	assert.False(t, r.ReadFlag("constraint_set0_flag"))
+	assert.Equal(t, uint64(0), r.PeekBits(7, "constraint_flags"))
+	r.ConsumeBits(7, "constraint_flags")
+	assert.Equal(t, uint8(0x1f), r.ReadUint8("level_idc"))
+	assert.Equal(t, uint64(0), r.ReadExponentialGolomb("seq_parameter_set_id"))
+	assert.Equal(t, int64(-1), r.ReadSignedExponentialGolomb("offset"))
+	r.ConsumeBits(4, "reserved")
+	assert.Equal(t, uint16(0x2211), r.ReadUint16(binary.LittleEndian, "u16"))
+	assert.Equal(t, uint32(0x33445566), r.ReadUint32(binary.BigEndian, "u32"))
+	assert.Nil(t, r.Err())
+}
+
+func TestSyntaxReader_Err(t *testing.T) {
+	r := NewSyntaxReader(NewBitStream(NewSliceByteAccessor([]byte{0xff, 0x00})))
+
+	assert.Equal(t, uint64(0xf), r.ReadBits(4, "first"))
+	assert.Equal(t, uint64(0), r.ReadBits(16, "second"))
+	assert.Equal(t, uint64(0), r.ReadBits(4, "third"))
+	assert.Equal(t, uint64(0), r.ReadExponentialGolomb("fourth"))
+
+	var fieldErr *FieldError
+	assert.True(t, errors.As(r.Err(), &fieldErr))
+	assert.Equal(t, "second", fieldErr.Field)
+
+	var bsErr *BitStreamError
+	assert.True(t, errors.As(r.Err(), &bsErr))
+	assert.Equal(t, int64(0), bsErr.ByteOffset)
+	assert.Equal(t, byte(4), bsErr.BitOffset)
+	assert.True(t, errors.Is(r.Err(), ErrUnexpectedEOF))
+
+	assert.Equal(t, int64(0), r.BitStream().byteOffset)
+	assert.Equal(t, byte(4), r.BitStream().bitOffset)
+}
+
+func TestSyntaxReader_Fail(t *testing.T) {
+	errReserved := errors.New("reserved value")
+	r := NewSyntaxReader(NewBitStream(NewSliceByteAccessor([]byte{0xff})))
+
+	r.Fail("ok", nil)
+	assert.Nil(t, r.Err())
+
+	assert.Equal(t, uint64(0x7), r.ReadBits(3, "level"))
+	r.Fail("level", errReserved)
+	r.Fail("other", ErrOutOfRange)
+	assert.True(t, errors.Is(r.Err(), errReserved))
+	assert.Equal(t, "level: gobits: Fail at byte 0 bit 3: reserved value", r.Err().Error())
+}
+
+func TestSyntaxReader_ReadSignedBits(t *testing.T) {
+	r := NewSyntaxReader(NewBitStream(NewSliceByteAccessor([]byte{0xf0})))
+	assert.Equal(t, int64(-1), r.ReadSignedBits(4, "delta_q"))
+	assert.Equal(t, int64(0), r.ReadSignedBits(8, "delta_lf"))
+	assert.True(t, errors.Is(r.Err(), ErrUnexpectedEOF))
+}
+
+func TestSyntaxReader_AlignRead(t *testing.T) {
+	r := NewSyntaxReader(NewBitStream(NewSliceByteAccessor([]byte{0xc0, 0xff, 0x81})))
+	assert.True(t, r.ReadFlag("flag"))
+	r.AlignReadPadding(8, PadRBSPTrailingBits, "byte_alignment")
+	assert.Nil(t, r.Err())
+	r.ReadBits(3, "bits")
+	r.AlignRead(8, "skip")
+	assert.Nil(t, r.Err())
+	assert.True(t, r.ReadFlag("marker"))
+	r.AlignReadPadding(8, PadZeros, "zero_bits")
+	assert.True(t, errors.Is(r.Err(), ErrInvalidPadding))
+}
@@ -0,0 +1,96 @@
+package gobits
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type syntaxSample struct {
+	Kind    byte
+	Present bool
+	Count   uint32
+	Values  []int32
+	Offset  int32
+	Short   uint16
+	Wide    uint64
+}
+
+func (v *syntaxSample) syntax(s *Syntax) {
+	s.Byte(&v.Kind, 3, "kind")
+	s.Flag(&v.Present, "present")
+	if v.Present {
+		s.ExponentialGolomb(&v.Count, "count")
+		if s.Reading() {
+			v.Values = make([]int32, v.Count)
+		}
+		for i := range v.Values {
+			s.SignedExponentialGolomb(&v.Values[i], "value")
+		}
+	}
+	s.SignedBits(&v.Offset, 5, "offset")
+	s.Bits16(&v.Short, 12, "short")
+	s.Bits64(&v.Wide, 40, "wide")
+	s.Align(8, PadRBSPTrailingBits, "rbsp_trailing_bits")
+}
+
+func TestSyntax_RoundTrip(t *testing.T) {
+	sample := syntaxSample{Kind: 5, Present: true, Count: 3, Values: []int32{0, -2, 7}, Offset: -9, Short: 0xabc, Wide: 0xabcdef0123}
+
+	ba := NewGrowableByteAccessor(nil)
+	s := NewWritingSyntax(NewBitStream(ba))
+	assert.False(t, s.Reading())
+	sample.syntax(s)
+	assert.Nil(t, s.Err())
+
+	parsed := syntaxSample{}
+	s = NewReadingSyntax(NewBitStream(NewSliceByteAccessor(ba.Bytes())))
+	assert.True(t, s.Reading())
+	parsed.syntax(s)
+	assert.Nil(t, s.Err())
+	assert.Equal(t, sample, parsed)
+	assert.Equal(t, int64(len(ba.Bytes())*8), s.BitStream().Tell())
+}
+
+func TestSyntax_Err(t *testing.T) {
+	val := uint32(0)
+	s := NewReadingSyntax(NewBitStream(NewSliceByteAccessor([]byte{0x00, 0x00, 0x00, 0x00, 0x80, 0x00, 0x00, 0x00, 0x80})))
+	s.ExponentialGolomb(&val, "too_large")
+	assert.True(t, errors.Is(s.Err(), ErrOutOfRange))
+
+	s = NewWritingSyntax(NewBitStream(NewGrowableByteAccessor(nil)))
+	s.Bits(&val, 33, "too_wide")
+	var fieldErr *FieldError
+	assert.True(t, errors.As(s.Err(), &fieldErr))
+	assert.Equal(t, "too_wide", fieldErr.Field)
+	assert.True(t, errors.Is(s.Err(), ErrInvalidBitCount))
+
+	short := uint16(0)
+	s = NewWritingSyntax(NewBitStream(NewGrowableByteAccessor(nil)))
+	s.Bits16(&short, 17, "too_wide")
+	assert.True(t, errors.Is(s.Err(), ErrInvalidBitCount))
+}
+
+func TestSyntax_Count(t *testing.T) {
+	ba := NewGrowableByteAccessor(nil)
+	s := NewWritingSyntax(NewBitStream(ba))
+	n := uint32(3)
+	assert.True(t, s.Count(&n, 3, "count"))
+	assert.True(t, s.ListLength(3, 3, "list"))
+	assert.False(t, s.ListLength(2, 3, "list"))
+	var fieldErr *FieldError
+	assert.True(t, errors.As(s.Err(), &fieldErr))
+	assert.Equal(t, "list", fieldErr.Field)
+	assert.True(t, errors.Is(s.Err(), ErrOutOfRange))
+
+	s = NewReadingSyntax(NewBitStream(NewSliceByteAccessor(ba.Bytes())))
+	assert.False(t, s.Count(&n, 2, "count"))
+	assert.True(t, errors.As(s.Err(), &fieldErr))
+	assert.Equal(t, "count", fieldErr.Field)
+	assert.True(t, errors.Is(s.Err(), ErrOutOfRange))
+
+	for n, bits := range map[uint32]byte{0: 0, 1: 0, 2: 1, 3: 2, 4: 2, 5: 3, 1 << 31: 31, 1<<31 + 1: 32} {
+		assert.Equal(t, bits, CeilLog2(n), n)
+	}
+}
@@ -0,0 +1,109 @@
+package gobits
+
+import (
+	"encoding/binary"
+)
+
+// SyntaxWriter is the writing counterpart of SyntaxReader. Once a write
+// fails, every following write is a no-op, and Err reports the first
+// failure together with the field name and position.
+type SyntaxWriter struct {
+	bs  *BitStream
+	err error
+}
+
+func (w *SyntaxWriter) BitStream() *BitStream {
+	return w.bs
+}
+
+func (w *SyntaxWriter) Err() error {
+	return w.err
+}
+
+func (w *SyntaxWriter) Fail(field string, err error) {
+	if w.err != nil || err == nil {
+		return
+	}
+	w.err = newFieldError(w.bs, field, err)
+}
+
+func (w *SyntaxWriter) check(field string, ok bool) {
+	if !ok {
+		w.Fail(field, w.bs.Err())
+	}
+}
+
+func (w *SyntaxWriter) WriteBits(val uint64, bitCount byte, field string) {
+	if w.err != nil {
+		return
+	}
+	w.check(field, w.bs.WriteBits(val, bitCount))
+}
+
+func (w *SyntaxWriter) WriteFlag(val bool, field string) {
+	bit := uint64(0)
+	if val {
+		bit = 1
+	}
+	w.WriteBits(bit, 1, field)
+}
+
+func (w *SyntaxWriter) WriteSignedBits(val int64, bitCount byte, field string) {
+	if w.err != nil {
+		return
+	}
+	w.check(field, w.bs.WriteSignedBits(val, bitCount))
+}
+
+func (w *SyntaxWriter) AlignWrite(alignBits int64, padding Padding, field string) {
+	if w.err != nil {
+		return
+	}
+	w.check(field, w.bs.AlignWrite(alignBits, padding))
+}
+
+func (w *SyntaxWriter) WriteUint8(val uint8, field string) {
+	if w.err != nil {
+		return
+	}
+	w.check(field, w.bs.WriteUint8(val))
+}
+
+func (w *SyntaxWriter) WriteUint16(val uint16, bo binary.ByteOrder, field string) {
+	if w.err != nil {
+		return
+	}
+	w.check(field, w.bs.WriteUint16(val, bo))
+}
+
+func (w *SyntaxWriter) WriteUint32(val uint32, bo binary.ByteOrder, field string) {
+	if w.err != nil {
+		return
+	}
+	w.check(field, w.bs.WriteUint32(val, bo))
+}
+
+func (w *SyntaxWriter) WriteUint64(val uint64, bo binary.ByteOrder, field string) {
+	if w.err != nil {
+		return
+	}
+	w.check(field, w.bs.WriteUint64(val, bo))
+}
+
+func (w *SyntaxWriter) WriteExponentialGolomb(val uint64, field string) {
+	if w.err != nil {
+		return
+	}
+	w.check(field, w.bs.WriteExponentialGolomb(val))
+}
+
+func (w *SyntaxWriter) WriteSignedExponentialGolomb(val int64, field string) {
+	if w.err != nil {
+		return
+	}
+	w.check(field, w.bs.WriteSignedExponentialGolomb(val))
+}
+
+func NewSyntaxWriter(bs *BitStream) *SyntaxWriter {
+	return &SyntaxWriter{bs: bs}
+}
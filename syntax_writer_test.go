@@ -0,0 +1,53 @@
+package gobits
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyntaxWriter_Write(t *testing.T) {
+	ba := NewGrowableByteAccessor(nil)
+	w := NewSyntaxWriter(NewBitStream(ba))
+
+	w.WriteBits(0x64, 8, "profile_idc")
+	w.WriteFlag(false, "constraint_set0_flag")
+	w.WriteBits(0, 7, "constraint_flags")
+	w.WriteUint8(0x1f, "level_idc")
+	w.WriteExponentialGolomb(0, "seq_parameter_set_id")
+	w.WriteSignedExponentialGolomb(-1, "offset")
+	w.WriteSignedBits(-4, 4, "reserved")
+	w.WriteUint16(0x2211, binary.LittleEndian, "u16")
+	w.WriteUint32(0x33445566, binary.BigEndian, "u32")
+	w.WriteUint64(0x778899aabbccddee, binary.BigEndian, "u64")
+	w.AlignWrite(8, PadZeros, "alignment")
+	assert.Nil(t, w.Err())
+	assert.Equal(t, []byte{0x64, 0x00, 0x1f, 0xbc, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66,
+		0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee}, ba.Bytes())
+}
+
+func TestSyntaxWriter_Err(t *testing.T) {
+	bytes := []byte{0x00}
+	w := NewSyntaxWriter(NewBitStream(NewSliceByteAccessor(bytes)))
+
+	w.WriteBits(0xf, 4, "first")
+	w.WriteBits(0xffff, 16, "second")
+	w.WriteFlag(true, "third")
+	w.WriteExponentialGolomb(0, "fourth")
+
+	var fieldErr *FieldError
+	assert.True(t, errors.As(w.Err(), &fieldErr))
+	assert.Equal(t, "second", fieldErr.Field)
+	assert.True(t, errors.Is(w.Err(), ErrUnexpectedEOF))
+	assert.Equal(t, []byte{0xf0}, bytes)
+	assert.Equal(t, int64(4), w.BitStream().Tell())
+
+	errReserved := errors.New("reserved value")
+	w = NewSyntaxWriter(NewBitStream(NewGrowableByteAccessor(nil)))
+	w.Fail("field", nil)
+	assert.Nil(t, w.Err())
+	w.Fail("field", errReserved)
+	assert.True(t, errors.Is(w.Err(), errReserved))
+}
@@ -0,0 +1,103 @@
+package gobits
+
+func unaryParams(stopBit uint64, maxLength uint64) (uint64, int64, error) {
+	if stopBit > 1 {
+		return 0, 0, ErrOutOfRange
+	}
+	if maxLength > uint64(maxUnaryLength) {
+		return 0, 0, ErrOutOfRange
+	}
+	return stopBit ^ 1, int64(maxLength), nil
+}
+
+func (bs *BitStream) readUnary(stopBit uint64, maxLength uint64, truncated bool) (uint64, error) {
+	runBit, max, err := unaryParams(stopBit, maxLength)
+	if err != nil {
+		return 0, err
+	}
+
+	original := bs.pos
+	run, err := bs.countRun(runBit, max)
+	if err != nil {
+		bs.pos = original
+		return 0, err
+	}
+	if truncated && run == max {
+		return uint64(run), nil
+	}
+
+	bit, err := bs.readCode(1)
+	if err == nil && bit != stopBit {
+		err = ErrMalformedCode
+	}
+	if err != nil {
+		bs.pos = original
+		return 0, err
+	}
+	return uint64(run), nil
+}
+
+func (bs *BitStream) writeUnary(val uint64, stopBit uint64, maxLength uint64, truncated bool) error {
+	runBit, max, err := unaryParams(stopBit, maxLength)
+	if err != nil {
+		return err
+	}
+	if val > uint64(max) {
+		return ErrOutOfRange
+	}
+
+	bitCount := int64(val)
+	if !truncated || bitCount < max {
+		bitCount++
+	}
+	if err := bs.writableBits(bitCount); err != nil {
+		return err
+	}
+
+	original := bs.pos
+	if err := bs.writeRun(runBit, int64(val)); err != nil {
+		bs.pos = original
+		return err
+	}
+	if bitCount > int64(val) {
+		if err := bs.writeCode(stopBit, 1); err != nil {
+			bs.pos = original
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadUnary reads a run of bits terminated by stopBit and returns the length
+// of the run. Runs longer than maxLength are rejected, and so is a maxLength
+// above 2^24.
+func (bs *BitStream) ReadUnary(stopBit uint64, maxLength uint64) (uint64, bool) {
+	val, err := bs.readUnary(stopBit, maxLength, false)
+	return val, bs.record("ReadUnary", err)
+}
+
+func (bs *BitStream) WriteUnary(val uint64, stopBit uint64, maxLength uint64) bool {
+	return bs.record("WriteUnary", bs.writeUnary(val, stopBit, maxLength, false))
+}
+
+// ReadTruncatedUnary reads a unary code whose stop bit is omitted when the
+// run reaches cMax.
+func (bs *BitStream) ReadTruncatedUnary(stopBit uint64, cMax uint64) (uint64, bool) {
+	val, err := bs.readUnary(stopBit, cMax, true)
+	return val, bs.record("ReadTruncatedUnary", err)
+}
+
+func (bs *BitStream) WriteTruncatedUnary(val uint64, stopBit uint64, cMax uint64) bool {
+	return bs.record("WriteTruncatedUnary", bs.writeUnary(val, stopBit, cMax, true))
+}
+
+// ReadTruncatedBinary reads a value in [0, n) coded with truncated binary
+// as used by AV1 ns(n).
+func (bs *BitStream) ReadTruncatedBinary(n uint64) (uint64, bool) {
+	val, err := bs.readTruncatedBinary(n)
+	return val, bs.record("ReadTruncatedBinary", err)
+}
+
+func (bs *BitStream) WriteTruncatedBinary(val, n uint64) bool {
+	return bs.record("WriteTruncatedBinary", bs.writeTruncatedBinary(val, n))
+}
@@ -0,0 +1,156 @@
+package gobits
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBitStream_ReadUnary(t *testing.T) {
+	bs := NewBitStream(NewSliceByteAccessor([]byte{0x87, 0x81}))
+
+	v, ok := bs.ReadUnary(0, 8)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), v)
+
+	v, ok = bs.ReadUnary(1, 8)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(3), v)
+
+	v, ok = bs.ReadUnary(0, 8)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(3), v)
+
+	pos := bs.SavePos()
+	_, ok = bs.ReadUnary(1, 4)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrMalformedCode))
+	assert.Equal(t, pos, bs.SavePos())
+
+	v, ok = bs.ReadUnary(1, 5)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(5), v)
+
+	pos = bs.SavePos()
+	_, ok = bs.ReadUnary(0, 8)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrUnexpectedEOF))
+	assert.Equal(t, pos, bs.SavePos())
+
+	_, ok = bs.ReadUnary(2, 8)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrOutOfRange))
+}
+
+func TestBitStream_WriteUnary(t *testing.T) {
+	ba := NewGrowableByteAccessor(nil)
+	bs := NewBitStream(ba)
+
+	assert.True(t, bs.WriteUnary(1, 0, 8))
+	assert.True(t, bs.WriteUnary(3, 1, 8))
+	assert.True(t, bs.WriteUnary(3, 0, 8))
+	assert.True(t, bs.WriteUnary(5, 1, 5))
+	assert.False(t, bs.WriteUnary(6, 1, 5))
+	assert.True(t, errors.Is(bs.Err(), ErrOutOfRange))
+	assert.True(t, bs.PadToByteBoundary(PadZeros))
+	assert.Equal(t, []byte{0x87, 0x81}, ba.Bytes())
+
+	bs = NewBitStream(NewGrowableByteAccessor(nil))
+	assert.True(t, bs.WriteUnary(200, 0, 1000))
+	assert.True(t, bs.WriteUnary(0, 1, 1000))
+	assert.True(t, bs.Seek(0, 0))
+	v, ok := bs.ReadUnary(0, 1000)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(200), v)
+	v, ok = bs.ReadUnary(1, 1000)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(0), v)
+
+	bs = NewBitStream(NewSliceByteAccessor([]byte{0x00}))
+	assert.False(t, bs.WriteUnary(8, 0, 8))
+	assert.Equal(t, int64(0), bs.Tell())
+	assert.Equal(t, []byte{0x00}, bs.ba.Slice(0, 1))
+}
+
+func TestBitStream_TruncatedUnary(t *testing.T) {
+	ba := NewGrowableByteAccessor(nil)
+	bs := NewBitStream(ba)
+	for _, v := range []uint64{0, 1, 3, 4} {
+		assert.True(t, bs.WriteTruncatedUnary(v, 0, 4))
+	}
+	assert.False(t, bs.WriteTruncatedUnary(5, 0, 4))
+	assert.True(t, bs.PadToByteBoundary(PadZeros))
+	assert.Equal(t, []byte{0x5d, 0xe0}, ba.Bytes())
+
+	assert.True(t, bs.Seek(0, 0))
+	for _, expect := range []uint64{0, 1, 3, 4} {
+		v, ok := bs.ReadTruncatedUnary(0, 4)
+		assert.True(t, ok)
+		assert.Equal(t, expect, v)
+	}
+
+	bs = NewBitStream(NewSliceByteAccessor([]byte{0x00}))
+	v, ok := bs.ReadTruncatedUnary(1, 0)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(0), v)
+	assert.Equal(t, int64(0), bs.Tell())
+	v, ok = bs.ReadTruncatedUnary(1, 8)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(8), v)
+	_, ok = bs.ReadTruncatedUnary(1, 8)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrUnexpectedEOF))
+
+	// cMax is bounded like the Golomb prefix, on both sides.
+	bs = NewBitStream(NewSliceByteAccessor([]byte{0x01}))
+	v, ok = bs.ReadTruncatedUnary(1, uint64(maxUnaryLength))
+	assert.True(t, ok)
+	assert.Equal(t, uint64(7), v)
+	assert.True(t, bs.Seek(0, 0))
+	_, ok = bs.ReadTruncatedUnary(1, uint64(maxUnaryLength)+1)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrOutOfRange))
+	assert.Equal(t, int64(0), bs.Tell())
+	bs = NewBitStream(NewGrowableByteAccessor(nil))
+	assert.True(t, bs.WriteTruncatedUnary(7, 1, uint64(maxUnaryLength)))
+	assert.False(t, bs.WriteTruncatedUnary(7, 1, uint64(maxUnaryLength)+1))
+	assert.True(t, errors.Is(bs.Err(), ErrOutOfRange))
+}
+
+func TestBitStream_TruncatedBinary(t *testing.T) {
+	ba := NewGrowableByteAccessor(nil)
+	bs := NewBitStream(ba)
+	for _, v := range []uint64{0, 2, 3, 4} {
+		assert.True(t, bs.WriteTruncatedBinary(v, 5))
+	}
+	assert.False(t, bs.WriteTruncatedBinary(5, 5))
+	assert.True(t, errors.Is(bs.Err(), ErrOutOfRange))
+	assert.True(t, bs.PadToByteBoundary(PadZeros))
+	assert.Equal(t, []byte{0x2d, 0xc0}, ba.Bytes())
+
+	assert.True(t, bs.Seek(0, 0))
+	for _, expect := range []uint64{0, 2, 3, 4} {
+		v, ok := bs.ReadTruncatedBinary(5)
+		assert.True(t, ok)
+		assert.Equal(t, expect, v)
+	}
+
+	for _, n := range []uint64{1, 2, 3, 7, 8, 9, 100, 1 << 63, 1<<63 + 1} {
+		bs = NewBitStream(NewGrowableByteAccessor(nil))
+		values := []uint64{0, n / 3, n / 2, n - 1}
+		for _, v := range values {
+			assert.True(t, bs.WriteTruncatedBinary(v, n))
+		}
+		assert.True(t, bs.Seek(0, 0))
+		for _, expect := range values {
+			v, ok := bs.ReadTruncatedBinary(n)
+			assert.True(t, ok)
+			assert.Equal(t, expect, v)
+		}
+	}
+
+	_, ok := bs.ReadTruncatedBinary(0)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrOutOfRange))
+}
@@ -0,0 +1,228 @@
+package gobits
+
+import (
+	"math"
+)
+
+// fibonacci holds F(2), F(3), ... up to the largest Fibonacci number that
+// fits in a uint64.
+var fibonacci = func() []uint64 {
+	fibs := []uint64{1, 2}
+	for {
+		a, b := fibs[len(fibs)-2], fibs[len(fibs)-1]
+		if a > math.MaxUint64-b {
+			return fibs
+		}
+		fibs = append(fibs, a+b)
+	}
+}()
+
+func (bs *BitStream) readEliasGamma() (uint64, error) {
+	val, err := bs.readExpGolombK(0)
+	if err != nil {
+		return 0, err
+	}
+	return val + 1, nil
+}
+
+func (bs *BitStream) writeEliasGamma(val uint64) error {
+	if val == 0 {
+		return ErrOutOfRange
+	}
+	return bs.writeExpGolombK(val-1, 0)
+}
+
+// ReadEliasGamma reads an Elias gamma code. Elias and Fibonacci codes
+// represent positive integers, so zero is never returned on success.
+func (bs *BitStream) ReadEliasGamma() (uint64, bool) {
+	val, err := bs.readEliasGamma()
+	return val, bs.record("ReadEliasGamma", err)
+}
+
+func (bs *BitStream) WriteEliasGamma(val uint64) bool {
+	return bs.record("WriteEliasGamma", bs.writeEliasGamma(val))
+}
+
+func (bs *BitStream) readEliasDelta() (uint64, error) {
+	original := bs.pos
+	bitCount, err := bs.readEliasGamma()
+	if err == nil && bitCount > 64 {
+		err = ErrMalformedCode
+	}
+	if err != nil {
+		bs.pos = original
+		return 0, err
+	}
+
+	val, err := bs.readCode(byte(bitCount - 1))
+	if err != nil {
+		bs.pos = original
+		return 0, err
+	}
+	return 1<<(bitCount-1) | val, nil
+}
+
+func (bs *BitStream) writeEliasDelta(val uint64) error {
+	if val == 0 {
+		return ErrOutOfRange
+	}
+
+	bitCount := countEffectiveBits(val)
+	lengthBitCount := countEffectiveBits(uint64(bitCount))
+	if err := bs.writableBits(int64(lengthBitCount)*2 - 1 + int64(bitCount) - 1); err != nil {
+		return err
+	}
+
+	original := bs.pos
+	if err := bs.writeEliasGamma(uint64(bitCount)); err != nil {
+		bs.pos = original
+		return err
+	}
+	if err := bs.writeCode(val, bitCount-1); err != nil {
+		bs.pos = original
+		return err
+	}
+	return nil
+}
+
+func (bs *BitStream) ReadEliasDelta() (uint64, bool) {
+	val, err := bs.readEliasDelta()
+	return val, bs.record("ReadEliasDelta", err)
+}
+
+func (bs *BitStream) WriteEliasDelta(val uint64) bool {
+	return bs.record("WriteEliasDelta", bs.writeEliasDelta(val))
+}
+
+func (bs *BitStream) readEliasOmega() (uint64, error) {
+	original := bs.pos
+	val := uint64(1)
+	for {
+		bit, err := bs.peekCode(1)
+		if err != nil {
+			bs.pos = original
+			return 0, err
+		}
+		if bit == 0 {
+			bs.consumeBits(1)
+			return val, nil
+		}
+		if val >= 64 {
+			bs.pos = original
+			return 0, ErrMalformedCode
+		}
+		if val, err = bs.readCode(byte(val) + 1); err != nil {
+			bs.pos = original
+			return 0, err
+		}
+	}
+}
+
+func (bs *BitStream) writeEliasOmega(val uint64) error {
+	if val == 0 {
+		return ErrOutOfRange
+	}
+
+	groups := []uint64{}
+	bitCount := int64(1)
+	for val > 1 {
+		groups = append([]uint64{val}, groups...)
+		bitCount += int64(countEffectiveBits(val))
+		val = uint64(countEffectiveBits(val)) - 1
+	}
+	if err := bs.writableBits(bitCount); err != nil {
+		return err
+	}
+
+	original := bs.pos
+	for _, group := range groups {
+		if err := bs.writeCode(group, countEffectiveBits(group)); err != nil {
+			bs.pos = original
+			return err
+		}
+	}
+	if err := bs.writeCode(0, 1); err != nil {
+		bs.pos = original
+		return err
+	}
+	return nil
+}
+
+func (bs *BitStream) ReadEliasOmega() (uint64, bool) {
+	val, err := bs.readEliasOmega()
+	return val, bs.record("ReadEliasOmega", err)
+}
+
+func (bs *BitStream) WriteEliasOmega(val uint64) bool {
+	return bs.record("WriteEliasOmega", bs.writeEliasOmega(val))
+}
+
+func (bs *BitStream) readFibonacci() (uint64, error) {
+	original := bs.pos
+	val := uint64(0)
+	prevBit := uint64(0)
+	for i := 0; ; i++ {
+		bit, err := bs.readCode(1)
+		if err != nil {
+			bs.pos = original
+			return 0, err
+		}
+		if bit == 1 && prevBit == 1 {
+			return val, nil
+		}
+		if i >= len(fibonacci) {
+			bs.pos = original
+			return 0, ErrMalformedCode
+		}
+		if bit == 1 {
+			if val > math.MaxUint64-fibonacci[i] {
+				bs.pos = original
+				return 0, ErrMalformedCode
+			}
+			val += fibonacci[i]
+		}
+		prevBit = bit
+	}
+}
+
+func (bs *BitStream) writeFibonacci(val uint64) error {
+	if val == 0 {
+		return ErrOutOfRange
+	}
+
+	last := len(fibonacci) - 1
+	for fibonacci[last] > val {
+		last--
+	}
+	code := make([]uint64, last+2)
+	code[last+1] = 1
+	for i := last; i >= 0; i-- {
+		if fibonacci[i] <= val {
+			code[i] = 1
+			val -= fibonacci[i]
+		}
+	}
+	if err := bs.writableBits(int64(len(code))); err != nil {
+		return err
+	}
+
+	original := bs.pos
+	for _, bit := range code {
+		if err := bs.writeCode(bit, 1); err != nil {
+			bs.pos = original
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadFibonacci reads a Fibonacci code: the Zeckendorf representation
+// starting from the smallest term, terminated by an additional 1 bit.
+func (bs *BitStream) ReadFibonacci() (uint64, bool) {
+	val, err := bs.readFibonacci()
+	return val, bs.record("ReadFibonacci", err)
+}
+
+func (bs *BitStream) WriteFibonacci(val uint64) bool {
+	return bs.record("WriteFibonacci", bs.writeFibonacci(val))
+}
@@ -0,0 +1,120 @@
+package gobits
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// bitStringBytes packs a string such as "0101 1" MSB first, padding the
+// final byte with zeros. Spaces are ignored.
+func bitStringBytes(s string) []byte {
+	s = strings.Replace(s, " ", "", -1)
+	bytes := make([]byte, (len(s)+7)/8)
+	for i, c := range s {
+		if c == '1' {
+			bytes[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return bytes
+}
+
+type universalCode struct {
+	read  func(bs *BitStream) (uint64, bool)
+	write func(bs *BitStream, val uint64) bool
+}
+
+var universalCodes = map[string]universalCode{
+	"elias_gamma": {(*BitStream).ReadEliasGamma, (*BitStream).WriteEliasGamma},
+	"elias_delta": {(*BitStream).ReadEliasDelta, (*BitStream).WriteEliasDelta},
+	"elias_omega": {(*BitStream).ReadEliasOmega, (*BitStream).WriteEliasOmega},
+	"fibonacci":   {(*BitStream).ReadFibonacci, (*BitStream).WriteFibonacci},
+}
+
+func TestBitStream_UniversalCodes(t *testing.T) {
+	cases := map[string]string{
+		"elias_gamma": "1 010 011 00100 000010001",
+		"elias_delta": "1 0100 0101 01100 001010001",
+		"elias_omega": "0 100 110 101000 10100100010",
+		"fibonacci":   "11 011 0011 1011 1010011",
+	}
+	values := []uint64{1, 2, 3, 4, 17}
+
+	for name, code := range universalCodes {
+		t.Run(name, func(t *testing.T) {
+			bs := NewBitStream(NewSliceByteAccessor(bitStringBytes(cases[name])))
+			for _, expect := range values {
+				v, ok := code.read(bs)
+				assert.True(t, ok)
+				assert.Equal(t, expect, v)
+			}
+
+			ba := NewGrowableByteAccessor(nil)
+			bs = NewBitStream(ba)
+			for _, v := range values {
+				assert.True(t, code.write(bs, v))
+			}
+			assert.True(t, bs.PadToByteBoundary(PadZeros))
+			assert.Equal(t, bitStringBytes(cases[name]), ba.Bytes())
+
+			assert.False(t, code.write(bs, 0))
+			assert.True(t, errors.Is(bs.Err(), ErrOutOfRange))
+		})
+	}
+}
+
+func TestBitStream_UniversalCodesRoundTrip(t *testing.T) {
+	values := []uint64{1, 5, 100, 1 << 31, 1<<63 - 1, 1 << 63, math.MaxUint64 - 1, math.MaxUint64}
+	for name, code := range universalCodes {
+		t.Run(name, func(t *testing.T) {
+			for _, order := range []BitOrder{MSBFirst, LSBFirst} {
+				bs := NewBitStreamWithBitOrder(NewGrowableByteAccessor(nil), order)
+				for _, v := range values {
+					assert.True(t, code.write(bs, v))
+				}
+				assert.True(t, bs.Seek(0, 0))
+				for _, expect := range values {
+					v, ok := code.read(bs)
+					assert.True(t, ok)
+					assert.Equal(t, expect, v)
+				}
+			}
+		})
+	}
+}
+
+func TestBitStream_UniversalCodesMalformed(t *testing.T) {
+	cases := map[string]string{
+		"elias_gamma": strings.Repeat("0", 64) + "1" + strings.Repeat("0", 64),
+		"elias_delta": "000000 1000001" + strings.Repeat("0", 64),
+		"elias_omega": "11 1111 11111111 " + strings.Repeat("1", 64) + " 0",
+		"fibonacci":   strings.Repeat("10", 46) + "1011",
+	}
+	for name, code := range universalCodes {
+		t.Run(name, func(t *testing.T) {
+			bs := NewBitStream(NewSliceByteAccessor(bitStringBytes(cases[name])))
+			_, ok := code.read(bs)
+			assert.False(t, ok)
+			assert.True(t, errors.Is(bs.Err(), ErrMalformedCode))
+			assert.Equal(t, int64(0), bs.Tell())
+		})
+	}
+
+	bs := NewBitStream(NewSliceByteAccessor(bitStringBytes("0100")))
+	assert.True(t, bs.ConsumeBits(1))
+	_, ok := bs.ReadFibonacci()
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrUnexpectedEOF))
+	assert.Equal(t, int64(1), bs.Tell())
+
+	// A run of zeros fails once it passes the last Fibonacci number rather
+	// than at the end of the data.
+	bs = NewBitStream(NewSliceByteAccessor(make([]byte, 64)))
+	_, ok = bs.ReadFibonacci()
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrMalformedCode))
+	assert.Equal(t, int64(0), bs.Tell())
+}
@@ -0,0 +1,206 @@
+package gobits
+
+import (
+	"math"
+)
+
+const (
+	maxVarintLength = 10
+)
+
+func varintLimit(maxBytes int) int {
+	if maxBytes <= 0 || maxVarintLength < maxBytes {
+		return maxVarintLength
+	}
+	return maxBytes
+}
+
+func (bs *BitStream) writeVarintBytes(bytes []byte) error {
+	if err := bs.writableBits(int64(len(bytes)) * 8); err != nil {
+		return err
+	}
+	for _, b := range bytes {
+		if err := bs.writeBits(uint64(b), 8); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (bs *BitStream) readULEB128(maxBytes int) (uint64, error) {
+	original := bs.pos
+	val := uint64(0)
+	for i := 0; i < varintLimit(maxBytes); i++ {
+		b, err := bs.readBits(8)
+		if err != nil {
+			bs.pos = original
+			return 0, err
+		}
+		if i == maxVarintLength-1 && b&0x7f > 1 {
+			break
+		}
+		val |= (b & 0x7f) << (7 * i)
+		if b&0x80 == 0 {
+			return val, nil
+		}
+	}
+	bs.pos = original
+	return 0, ErrMalformedCode
+}
+
+func encodeULEB128(val uint64, byteCount int) ([]byte, error) {
+	bytes := []byte{}
+	for {
+		b := byte(val & 0x7f)
+		val >>= 7
+		if val == 0 && len(bytes)+1 >= byteCount {
+			bytes = append(bytes, b)
+			break
+		}
+		bytes = append(bytes, b|0x80)
+	}
+	if byteCount > 0 && len(bytes) != byteCount {
+		return nil, ErrOutOfRange
+	}
+	return bytes, nil
+}
+
+// ReadULEB128 reads an unsigned LEB128 value, also known as a protobuf
+// varint, of at most maxBytes bytes. A maxBytes of zero allows the longest
+// encoding of a 64-bit value.
+func (bs *BitStream) ReadULEB128(maxBytes int) (uint64, bool) {
+	val, err := bs.readULEB128(maxBytes)
+	return val, bs.record("ReadULEB128", err)
+}
+
+func (bs *BitStream) WriteULEB128(val uint64) bool {
+	bytes, _ := encodeULEB128(val, 0)
+	return bs.record("WriteULEB128", bs.writeVarintBytes(bytes))
+}
+
+// WriteULEB128Padded writes val using exactly byteCount bytes, as AV1 does
+// for fixed-size obu_size fields.
+func (bs *BitStream) WriteULEB128Padded(val uint64, byteCount int) bool {
+	if byteCount <= 0 || maxVarintLength < byteCount {
+		return bs.record("WriteULEB128Padded", ErrOutOfRange)
+	}
+	bytes, err := encodeULEB128(val, byteCount)
+	if err == nil {
+		err = bs.writeVarintBytes(bytes)
+	}
+	return bs.record("WriteULEB128Padded", err)
+}
+
+func (bs *BitStream) readSLEB128(maxBytes int) (int64, error) {
+	original := bs.pos
+	val := uint64(0)
+	for i := 0; i < varintLimit(maxBytes); i++ {
+		b, err := bs.readBits(8)
+		if err != nil {
+			bs.pos = original
+			return 0, err
+		}
+		if i == maxVarintLength-1 && b != 0x00 && b != 0x7f {
+			break
+		}
+		shift := uint(7 * i)
+		val |= (b & 0x7f) << shift
+		if b&0x80 == 0 {
+			if shift+7 < 64 && b&0x40 != 0 {
+				val |= math.MaxUint64 << (shift + 7)
+			}
+			return int64(val), nil
+		}
+	}
+	bs.pos = original
+	return 0, ErrMalformedCode
+}
+
+func (bs *BitStream) ReadSLEB128(maxBytes int) (int64, bool) {
+	val, err := bs.readSLEB128(maxBytes)
+	return val, bs.record("ReadSLEB128", err)
+}
+
+func (bs *BitStream) WriteSLEB128(val int64) bool {
+	bytes := []byte{}
+	for {
+		b := byte(val & 0x7f)
+		val >>= 7
+		if (val == 0 && b&0x40 == 0) || (val == -1 && b&0x40 != 0) {
+			bytes = append(bytes, b)
+			break
+		}
+		bytes = append(bytes, b|0x80)
+	}
+	return bs.record("WriteSLEB128", bs.writeVarintBytes(bytes))
+}
+
+// ReadZigZagVarint reads a protobuf sint64, a varint holding a zigzag
+// encoded value.
+func (bs *BitStream) ReadZigZagVarint(maxBytes int) (int64, bool) {
+	val, err := bs.readULEB128(maxBytes)
+	return zigzagDecode(val), bs.record("ReadZigZagVarint", err)
+}
+
+func (bs *BitStream) WriteZigZagVarint(val int64) bool {
+	bytes, _ := encodeULEB128(zigzagEncode(val), 0)
+	return bs.record("WriteZigZagVarint", bs.writeVarintBytes(bytes))
+}
+
+func (bs *BitStream) readVLQ(maxBytes int) (uint64, error) {
+	original := bs.pos
+	val := uint64(0)
+	for i := 0; i < varintLimit(maxBytes); i++ {
+		b, err := bs.readBits(8)
+		if err != nil {
+			bs.pos = original
+			return 0, err
+		}
+		if val > math.MaxUint64>>7 {
+			break
+		}
+		val = val<<7 | b&0x7f
+		if b&0x80 == 0 {
+			return val, nil
+		}
+	}
+	bs.pos = original
+	return 0, ErrMalformedCode
+}
+
+func encodeVLQ(val uint64, byteCount int) ([]byte, error) {
+	bytes := []byte{byte(val & 0x7f)}
+	for val >>= 7; val != 0 || len(bytes) < byteCount; val >>= 7 {
+		bytes = append([]byte{byte(val&0x7f) | 0x80}, bytes...)
+	}
+	if byteCount > 0 && len(bytes) != byteCount {
+		return nil, ErrOutOfRange
+	}
+	return bytes, nil
+}
+
+// ReadVLQ reads a big-endian variable-length quantity with 7 bits per byte
+// and a continuation flag in the top bit, as used by MIDI and the MPEG-4
+// descriptor size field.
+func (bs *BitStream) ReadVLQ(maxBytes int) (uint64, bool) {
+	val, err := bs.readVLQ(maxBytes)
+	return val, bs.record("ReadVLQ", err)
+}
+
+func (bs *BitStream) WriteVLQ(val uint64) bool {
+	bytes, _ := encodeVLQ(val, 0)
+	return bs.record("WriteVLQ", bs.writeVarintBytes(bytes))
+}
+
+// WriteVLQPadded writes val using exactly byteCount bytes, e.g. the 4-byte
+// descriptor sizes many MP4 muxers emit.
+func (bs *BitStream) WriteVLQPadded(val uint64, byteCount int) bool {
+	if byteCount <= 0 || maxVarintLength < byteCount {
+		return bs.record("WriteVLQPadded", ErrOutOfRange)
+	}
+	bytes, err := encodeVLQ(val, byteCount)
+	if err == nil {
+		err = bs.writeVarintBytes(bytes)
+	}
+	return bs.record("WriteVLQPadded", err)
+}
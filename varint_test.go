@@ -0,0 +1,150 @@
+package gobits
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBitStream_ULEB128(t *testing.T) {
+	bs := NewBitStream(NewSliceByteAccessor([]byte{0x02, 0xe5, 0x8e, 0x26, 0x80, 0x00}))
+	for _, expect := range []uint64{2, 624485, 0} {
+		v, ok := bs.ReadULEB128(0)
+		assert.True(t, ok)
+		assert.Equal(t, expect, v)
+	}
+
+	ba := NewGrowableByteAccessor(nil)
+	bs = NewBitStream(ba)
+	assert.True(t, bs.WriteULEB128(2))
+	assert.True(t, bs.WriteULEB128(624485))
+	assert.True(t, bs.WriteULEB128Padded(0, 2))
+	assert.Equal(t, []byte{0x02, 0xe5, 0x8e, 0x26, 0x80, 0x00}, ba.Bytes())
+
+	assert.False(t, bs.WriteULEB128Padded(624485, 2))
+	assert.True(t, errors.Is(bs.Err(), ErrOutOfRange))
+	assert.False(t, bs.WriteULEB128Padded(1, 0))
+
+	bs = NewBitStream(NewGrowableByteAccessor(nil))
+	values := []uint64{0, 127, 128, 1<<32 - 1, 1 << 56, math.MaxUint64}
+	assert.True(t, bs.WriteBits(1, 3))
+	for _, v := range values {
+		assert.True(t, bs.WriteULEB128(v))
+	}
+	assert.True(t, bs.Seek(0, 3))
+	for _, expect := range values {
+		v, ok := bs.ReadULEB128(0)
+		assert.True(t, ok)
+		assert.Equal(t, expect, v)
+	}
+}
+
+func TestBitStream_ULEB128Malformed(t *testing.T) {
+	bs := NewBitStream(NewSliceByteAccessor([]byte{0x80, 0x80, 0x80, 0x01}))
+	_, ok := bs.ReadULEB128(3)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrMalformedCode))
+	assert.Equal(t, int64(0), bs.Tell())
+	v, ok := bs.ReadULEB128(4)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1<<21), v)
+
+	bs = NewBitStream(NewSliceByteAccessor([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x02}))
+	_, ok = bs.ReadULEB128(0)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrMalformedCode))
+
+	bs = NewBitStream(NewSliceByteAccessor([]byte{0x80, 0x80}))
+	_, ok = bs.ReadULEB128(0)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrUnexpectedEOF))
+	assert.Equal(t, int64(0), bs.Tell())
+}
+
+func TestBitStream_SLEB128(t *testing.T) {
+	bs := NewBitStream(NewSliceByteAccessor([]byte{0x02, 0x7e, 0xc0, 0xbb, 0x78}))
+	for _, expect := range []int64{2, -2, -123456} {
+		v, ok := bs.ReadSLEB128(0)
+		assert.True(t, ok)
+		assert.Equal(t, expect, v)
+	}
+
+	ba := NewGrowableByteAccessor(nil)
+	bs = NewBitStream(ba)
+	for _, v := range []int64{2, -2, -123456} {
+		assert.True(t, bs.WriteSLEB128(v))
+	}
+	assert.Equal(t, []byte{0x02, 0x7e, 0xc0, 0xbb, 0x78}, ba.Bytes())
+
+	bs = NewBitStream(NewGrowableByteAccessor(nil))
+	values := []int64{0, 63, 64, -64, -65, math.MaxInt64, math.MinInt64}
+	for _, v := range values {
+		assert.True(t, bs.WriteSLEB128(v))
+	}
+	assert.True(t, bs.Seek(0, 0))
+	for _, expect := range values {
+		v, ok := bs.ReadSLEB128(0)
+		assert.True(t, ok)
+		assert.Equal(t, expect, v)
+	}
+
+	bs = NewBitStream(NewSliceByteAccessor([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}))
+	_, ok := bs.ReadSLEB128(0)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrMalformedCode))
+}
+
+func TestBitStream_ZigZagVarint(t *testing.T) {
+	ba := NewGrowableByteAccessor(nil)
+	bs := NewBitStream(ba)
+	for _, v := range []int64{0, -1, 1, -2, 2147483647, -2147483648} {
+		assert.True(t, bs.WriteZigZagVarint(v))
+	}
+	assert.Equal(t, []byte{0x00, 0x01, 0x02, 0x03, 0xfe, 0xff, 0xff, 0xff, 0x0f, 0xff, 0xff, 0xff, 0xff, 0x0f}, ba.Bytes())
+
+	assert.True(t, bs.Seek(0, 0))
+	for _, expect := range []int64{0, -1, 1, -2, 2147483647, -2147483648} {
+		v, ok := bs.ReadZigZagVarint(0)
+		assert.True(t, ok)
+		assert.Equal(t, expect, v)
+	}
+}
+
+func TestBitStream_VLQ(t *testing.T) {
+	bs := NewBitStream(NewSliceByteAccessor([]byte{0x00, 0x7f, 0x81, 0x00, 0xff, 0xff, 0x7f, 0x80, 0x80, 0x80, 0x0b}))
+	for _, expect := range []uint64{0, 0x7f, 0x80, 0x1fffff, 0x0b} {
+		v, ok := bs.ReadVLQ(4)
+		assert.True(t, ok)
+		assert.Equal(t, expect, v)
+	}
+
+	ba := NewGrowableByteAccessor(nil)
+	bs = NewBitStream(ba)
+	for _, v := range []uint64{0, 0x7f, 0x80, 0x1fffff} {
+		assert.True(t, bs.WriteVLQ(v))
+	}
+	assert.True(t, bs.WriteVLQPadded(0x0b, 4))
+	assert.Equal(t, []byte{0x00, 0x7f, 0x81, 0x00, 0xff, 0xff, 0x7f, 0x80, 0x80, 0x80, 0x0b}, ba.Bytes())
+	assert.False(t, bs.WriteVLQPadded(0x1fffff, 2))
+	assert.True(t, errors.Is(bs.Err(), ErrOutOfRange))
+
+	bs = NewBitStream(NewGrowableByteAccessor(nil))
+	assert.True(t, bs.WriteVLQ(math.MaxUint64))
+	assert.True(t, bs.Seek(0, 0))
+	v, ok := bs.ReadVLQ(0)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(math.MaxUint64), v)
+
+	bs = NewBitStream(NewSliceByteAccessor([]byte{0x80, 0x80, 0x80, 0x80, 0x01}))
+	_, ok = bs.ReadVLQ(4)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrMalformedCode))
+	assert.Equal(t, int64(0), bs.Tell())
+
+	bs = NewBitStream(NewSliceByteAccessor([]byte{0xc0, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x00}))
+	_, ok = bs.ReadVLQ(0)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrMalformedCode))
+}
@@ -0,0 +1,210 @@
+package gobits
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	// vlcLevelBits is the widest lookup table used at any level of a
+	// VLCTable. Codes longer than this are resolved through sub-tables.
+	vlcLevelBits = 8
+)
+
+// VLCEntry maps a code to the value it decodes to. Code holds the Length
+// code bits right-aligned, first bit most significant.
+type VLCEntry struct {
+	Code   uint64
+	Length byte
+	Value  interface{}
+}
+
+type vlcSlot struct {
+	length byte
+	value  interface{}
+	next   *vlcLevel
+}
+
+type vlcLevel struct {
+	bits  byte
+	slots []vlcSlot
+}
+
+// VLCTable decodes variable-length codes as specified by codec syntax tables.
+// Tables are immutable once built and may be shared between goroutines.
+type VLCTable struct {
+	entries   []VLCEntry
+	maxLength byte
+	root      *vlcLevel
+}
+
+// ParseVLCCode parses a code written as a string of '0' and '1' characters,
+// as codec specifications print them. Spaces are ignored.
+func ParseVLCCode(s string) (code uint64, length byte, err error) {
+	for _, c := range s {
+		switch c {
+		case ' ':
+			continue
+		case '0', '1':
+			if length == 64 {
+				return 0, 0, ErrInvalidTable
+			}
+			code = code<<1 | uint64(c-'0')
+			length++
+		default:
+			return 0, 0, ErrInvalidTable
+		}
+	}
+	if length == 0 {
+		return 0, 0, ErrInvalidTable
+	}
+	return code, length, nil
+}
+
+// NewVLCTableFromStrings builds a table from a specification-style table
+// such as {"0001 01": [2]int{0, 1}, "01": [2]int{1, 1}}.
+func NewVLCTableFromStrings(table map[string]interface{}) (*VLCTable, error) {
+	entries := make([]VLCEntry, 0, len(table))
+	for s, value := range table {
+		code, length, err := ParseVLCCode(s)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, VLCEntry{Code: code, Length: length, Value: value})
+	}
+	return NewVLCTable(entries)
+}
+
+// NewVLCTableFromArrays builds a table from parallel code, length and value
+// arrays, the layout most reference decoders use for their static tables.
+func NewVLCTableFromArrays(codes []uint64, lengths []byte, values []interface{}) (*VLCTable, error) {
+	if len(codes) != len(lengths) || len(codes) != len(values) {
+		return nil, ErrInvalidTable
+	}
+	entries := make([]VLCEntry, len(codes))
+	for i := range codes {
+		entries[i] = VLCEntry{Code: codes[i], Length: lengths[i], Value: values[i]}
+	}
+	return NewVLCTable(entries)
+}
+
+// NewVLCTable builds a table from its entries. The codes must be
+// prefix-free; the code does not need to be complete.
+func NewVLCTable(entries []VLCEntry) (*VLCTable, error) {
+	if len(entries) == 0 {
+		return nil, ErrInvalidTable
+	}
+
+	keys := make([]huffmanKey, len(entries))
+	for i, e := range entries {
+		keys[i] = huffmanKey{e.Code, e.Length}
+	}
+	if err := checkPrefixFree(keys); err != nil {
+		return nil, err
+	}
+
+	t := &VLCTable{entries: append([]VLCEntry{}, entries...)}
+	sort.Slice(t.entries, func(i, j int) bool {
+		if t.entries[i].Length != t.entries[j].Length {
+			return t.entries[i].Length < t.entries[j].Length
+		}
+		return t.entries[i].Code < t.entries[j].Code
+	})
+	t.maxLength = t.entries[len(t.entries)-1].Length
+	t.root = buildVLCLevel(t.entries, 0)
+	return t, nil
+}
+
+func buildVLCLevel(entries []VLCEntry, consumed byte) *vlcLevel {
+	bits := byte(0)
+	for _, e := range entries {
+		if e.Length-consumed > bits {
+			bits = e.Length - consumed
+		}
+	}
+	if bits > vlcLevelBits {
+		bits = vlcLevelBits
+	}
+
+	level := &vlcLevel{bits: bits, slots: make([]vlcSlot, 1<<bits)}
+	groups := map[uint64][]VLCEntry{}
+	for _, e := range entries {
+		remaining := e.Length - consumed
+		rest := e.Code & (uint64(1)<<remaining - 1)
+		if remaining <= bits {
+			first := rest << (bits - remaining)
+			for i := uint64(0); i < 1<<(bits-remaining); i++ {
+				level.slots[first+i] = vlcSlot{length: e.Length, value: e.Value}
+			}
+		} else {
+			index := rest >> (remaining - bits)
+			groups[index] = append(groups[index], e)
+		}
+	}
+	for index, group := range groups {
+		level.slots[index].next = buildVLCLevel(group, consumed+bits)
+	}
+	return level
+}
+
+// Entries returns the table entries ordered by length and then by code.
+func (t *VLCTable) Entries() []VLCEntry {
+	return append([]VLCEntry{}, t.entries...)
+}
+
+func (t *VLCTable) MaxLength() byte {
+	return t.maxLength
+}
+
+// String lists the table in specification style, one code per line.
+func (t *VLCTable) String() string {
+	lines := make([]string, len(t.entries))
+	for i, e := range t.entries {
+		code := make([]byte, e.Length)
+		for j := range code {
+			code[j] = '0' + byte(e.Code>>(e.Length-1-byte(j))&1)
+		}
+		lines[i] = string(code)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (bs *BitStream) readVLC(t *VLCTable) (interface{}, byte, error) {
+	level := t.root
+	consumed := byte(0)
+	for {
+		val, available, err := bs.peekCodeUpTo(consumed + level.bits)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		slot := level.slots[val&(uint64(1)<<level.bits-1)]
+		if slot.length != 0 {
+			if slot.length > available {
+				return nil, 0, ErrUnexpectedEOF
+			}
+			return slot.value, slot.length, bs.consumeBits(int64(slot.length))
+		}
+		if slot.next == nil {
+			if available < consumed+level.bits {
+				return nil, 0, ErrUnexpectedEOF
+			}
+			return nil, 0, ErrMalformedCode
+		}
+		consumed += level.bits
+		level = slot.next
+	}
+}
+
+// ReadVLC decodes one code from t and returns the value it maps to.
+func (bs *BitStream) ReadVLC(t *VLCTable) (interface{}, bool) {
+	value, _, err := bs.readVLC(t)
+	return value, bs.record("ReadVLC", err)
+}
+
+// ReadVLCWithLength is like ReadVLC but also returns the length of the code
+// that was consumed, for tracing syntax elements.
+func (bs *BitStream) ReadVLCWithLength(t *VLCTable) (interface{}, byte, bool) {
+	value, length, err := bs.readVLC(t)
+	return value, length, bs.record("ReadVLCWithLength", err)
+}
@@ -0,0 +1,132 @@
+package gobits
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// coeffToken is part of the H.264 coeff_token table for 0 <= nC < 2, mapping
+// codes to (TrailingOnes, TotalCoeff).
+var coeffToken = map[string]interface{}{
+	"1":           [2]int{0, 0},
+	"0001 01":     [2]int{0, 1},
+	"01":          [2]int{1, 1},
+	"0000 0111":   [2]int{0, 2},
+	"0001 00":     [2]int{1, 2},
+	"001":         [2]int{2, 2},
+	"0000 0011 1": [2]int{0, 3},
+	"0000 0110":   [2]int{1, 3},
+	"0000 101":    [2]int{2, 3},
+	"0001 1":      [2]int{3, 3},
+}
+
+func TestParseVLCCode(t *testing.T) {
+	code, length, err := ParseVLCCode("0000 0011 1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(7), code)
+	assert.Equal(t, byte(9), length)
+
+	for _, s := range []string{"", " ", "01x", "0000000000000000000000000000000000000000000000000000000000000000 1"} {
+		_, _, err := ParseVLCCode(s)
+		assert.True(t, errors.Is(err, ErrInvalidTable), s)
+	}
+}
+
+func TestNewVLCTable(t *testing.T) {
+	table, err := NewVLCTableFromStrings(coeffToken)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(9), table.MaxLength())
+	assert.Equal(t, VLCEntry{Code: 1, Length: 1, Value: [2]int{0, 0}}, table.Entries()[0])
+	assert.Equal(t, "1\n01\n001\n00011\n000100\n000101\n0000101\n00000110\n00000111\n000000111", table.String())
+
+	table, err = NewVLCTableFromArrays([]uint64{1, 1, 0}, []byte{1, 2, 2}, []interface{}{"a", "b", "c"})
+	assert.NoError(t, err)
+	assert.Len(t, table.Entries(), 3)
+
+	_, err = NewVLCTableFromArrays([]uint64{1}, []byte{1, 2}, []interface{}{"a"})
+	assert.True(t, errors.Is(err, ErrInvalidTable))
+	_, err = NewVLCTableFromStrings(map[string]interface{}{"01": 1, "010": 2})
+	assert.True(t, errors.Is(err, ErrInvalidTable))
+	_, err = NewVLCTableFromStrings(map[string]interface{}{"01": 1, "0 1": 2})
+	assert.True(t, errors.Is(err, ErrInvalidTable))
+	_, err = NewVLCTableFromStrings(map[string]interface{}{"2": 1})
+	assert.True(t, errors.Is(err, ErrInvalidTable))
+	_, err = NewVLCTable(nil)
+	assert.True(t, errors.Is(err, ErrInvalidTable))
+}
+
+func TestBitStream_ReadVLC(t *testing.T) {
+	table, err := NewVLCTableFromStrings(coeffToken)
+	assert.NoError(t, err)
+
+	bs := NewBitStream(NewSliceByteAccessor(bitStringBytes("0001 01 1 0000 0011 1 001 0000 0110 0001 1")))
+	expected := [][2]int{{0, 1}, {0, 0}, {0, 3}, {2, 2}, {1, 3}, {3, 3}}
+	lengths := []byte{6, 1, 9, 3, 8, 5}
+	for i := range expected {
+		value, length, ok := bs.ReadVLCWithLength(table)
+		assert.True(t, ok)
+		assert.Equal(t, expected[i], value)
+		assert.Equal(t, lengths[i], length)
+	}
+
+	// Four bits of padding remain, a prefix of several longer codes.
+	_, ok := bs.ReadVLC(table)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrUnexpectedEOF))
+	assert.Equal(t, int64(32), bs.Tell())
+
+	bs = NewBitStream(NewSliceByteAccessor(bitStringBytes("0000 0000 0")))
+	_, ok = bs.ReadVLC(table)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(bs.Err(), ErrMalformedCode))
+	assert.Equal(t, int64(0), bs.Tell())
+
+	t.Run("multi_level", func(t *testing.T) {
+		table, err := NewVLCTableFromStrings(map[string]interface{}{
+			"1":                          "short",
+			"0000 0000 0000 0000 01":     "second",
+			"0000 0000 0000 0000 0000 1": "third",
+			"0000 0000 1":                "nine",
+		})
+		assert.NoError(t, err)
+
+		bs := NewBitStream(NewSliceByteAccessor(bitStringBytes(
+			"0000 0000 0000 0000 0000 1 0000 0000 0000 0000 01 1 0000 0000 1")))
+		for _, expected := range []string{"third", "second", "short", "nine"} {
+			value, ok := bs.ReadVLC(table)
+			assert.True(t, ok)
+			assert.Equal(t, expected, value)
+		}
+
+		bs = NewBitStream(NewSliceByteAccessor(bitStringBytes("0000 0000 0000 0000 1")))
+		_, ok := bs.ReadVLC(table)
+		assert.False(t, ok)
+		assert.True(t, errors.Is(bs.Err(), ErrMalformedCode))
+	})
+
+	t.Run("lsb_first", func(t *testing.T) {
+		bs := NewBitStreamWithBitOrder(NewGrowableByteAccessor(nil), LSBFirst)
+		assert.True(t, bs.WriteBits(0x0e, 5)) // 0111 0
+		bs.ResetPos()
+		value, length, ok := bs.ReadVLCWithLength(table)
+		assert.True(t, ok)
+		assert.Equal(t, [2]int{1, 1}, value)
+		assert.Equal(t, byte(2), length)
+	})
+}
+
+func TestSyntaxReader_ReadVLC(t *testing.T) {
+	table, err := NewVLCTableFromStrings(coeffToken)
+	assert.NoError(t, err)
+
+	r := NewSyntaxReader(NewBitStream(NewSliceByteAccessor([]byte{0x14})))
+	assert.Equal(t, [2]int{0, 1}, r.ReadVLC(table, "coeff_token"))
+	assert.Nil(t, r.ReadVLC(table, "coeff_token"))
+
+	var fieldErr *FieldError
+	assert.True(t, errors.As(r.Err(), &fieldErr))
+	assert.Equal(t, "coeff_token", fieldErr.Field)
+	assert.Nil(t, r.ReadVLC(table, "coeff_token"))
+}